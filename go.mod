@@ -1,9 +1,40 @@
 module layeh.com/gumble
 
-go 1.12
+// Go 1.21 is required by log/slog, which gumble uses for logging, and by
+// sync/atomic.Pointer, errors.Join, and the min builtin.
+go 1.21
 
 require (
+	github.com/BurntSushi/toml v1.3.2
 	github.com/dchote/go-openal v0.0.0-20171116030048-f4a9a141d372
-	github.com/golang/protobuf v1.3.1
+	github.com/eclipse/paho.mqtt.golang v1.4.3
+	github.com/gen2brain/malgo v0.11.21
+	github.com/go-ole/go-ole v1.2.6
+	github.com/golang/protobuf v1.5.4
+	github.com/gordonklaus/portaudio v0.0.0-20230709114228-aafa478834f5
+	github.com/jfreymuth/pulse v0.1.1
+	github.com/moutend/go-wca v0.3.0
+	github.com/prometheus/client_golang v1.19.1
+	github.com/yuin/gopher-lua v1.1.1
+	go.opentelemetry.io/otel v1.28.0
+	go.opentelemetry.io/otel/trace v1.28.0
+	google.golang.org/grpc v1.65.0
+	gopkg.in/yaml.v3 v3.0.1
 	layeh.com/gopus v0.0.0-20161224163843-0ebf989153aa
 )
+
+require (
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/gorilla/websocket v1.5.0 // indirect
+	github.com/kr/text v0.2.0 // indirect
+	github.com/prometheus/client_model v0.5.0 // indirect
+	github.com/prometheus/common v0.48.0 // indirect
+	github.com/prometheus/procfs v0.12.0 // indirect
+	golang.org/x/net v0.25.0 // indirect
+	golang.org/x/sync v0.7.0 // indirect
+	golang.org/x/sys v0.20.0 // indirect
+	golang.org/x/text v0.15.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240528184218-531527333157 // indirect
+	google.golang.org/protobuf v1.34.1 // indirect
+)
@@ -268,7 +268,10 @@ type Version struct {
 	// Client OS name.
 	Os *string `protobuf:"bytes,3,opt,name=os" json:"os,omitempty"`
 	// Client OS version.
-	OsVersion            *string  `protobuf:"bytes,4,opt,name=os_version,json=osVersion" json:"os_version,omitempty"`
+	OsVersion *string `protobuf:"bytes,4,opt,name=os_version,json=osVersion" json:"os_version,omitempty"`
+	// 2-byte Major, 2-byte Minor and 2-byte Patch version number, followed
+	// by 2 bytes that are currently unused.
+	VersionV2            *uint64  `protobuf:"varint,5,opt,name=version_v2,json=versionV2" json:"version_v2,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -327,6 +330,13 @@ func (m *Version) GetOsVersion() string {
 	return ""
 }
 
+func (m *Version) GetVersionV2() uint64 {
+	if m != nil && m.VersionV2 != nil {
+		return *m.VersionV2
+	}
+	return 0
+}
+
 // Not used. Not even for tunneling UDP through TCP.
 type UDPTunnel struct {
 	// Not used.
@@ -0,0 +1,121 @@
+package MumbleProto
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// Hand-written marshalers for the messages that are sent most often. They
+// avoid the cost of the table-driven proto.Marshal, and append to a
+// caller-provided buffer, so that they do not allocate.
+//
+// They must be updated when fields are added to the messages in Mumble.proto
+// (TestMarshalFields checks that all of the fields are handled).
+
+// Protocol buffer wire types.
+const (
+	wireVarint  = 0
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+func appendKey(b []byte, field, wireType int) []byte {
+	return binary.AppendUvarint(b, uint64(field<<3|wireType))
+}
+
+func appendUint64(b []byte, field int, v *uint64) []byte {
+	if v == nil {
+		return b
+	}
+	return binary.AppendUvarint(appendKey(b, field, wireVarint), *v)
+}
+
+func appendUint32(b []byte, field int, v *uint32) []byte {
+	if v == nil {
+		return b
+	}
+	return binary.AppendUvarint(appendKey(b, field, wireVarint), uint64(*v))
+}
+
+func appendBool(b []byte, field int, v *bool) []byte {
+	if v == nil {
+		return b
+	}
+	b = appendKey(b, field, wireVarint)
+	if *v {
+		return append(b, 1)
+	}
+	return append(b, 0)
+}
+
+func appendFloat32(b []byte, field int, v *float32) []byte {
+	if v == nil {
+		return b
+	}
+	return binary.LittleEndian.AppendUint32(appendKey(b, field, wireFixed32), math.Float32bits(*v))
+}
+
+func appendBytes(b []byte, field int, v []byte) []byte {
+	if v == nil {
+		return b
+	}
+	b = binary.AppendUvarint(appendKey(b, field, wireBytes), uint64(len(v)))
+	return append(b, v...)
+}
+
+func appendString(b []byte, field int, v *string) []byte {
+	if v == nil {
+		return b
+	}
+	b = binary.AppendUvarint(appendKey(b, field, wireBytes), uint64(len(*v)))
+	return append(b, *v...)
+}
+
+// MarshalAppend appends the wire encoding of m to b.
+func (m *UDPTunnel) MarshalAppend(b []byte) []byte {
+	b = appendBytes(b, 1, m.Packet)
+	return append(b, m.XXX_unrecognized...)
+}
+
+// MarshalAppend appends the wire encoding of m to b.
+func (m *Ping) MarshalAppend(b []byte) []byte {
+	b = appendUint64(b, 1, m.Timestamp)
+	b = appendUint32(b, 2, m.Good)
+	b = appendUint32(b, 3, m.Late)
+	b = appendUint32(b, 4, m.Lost)
+	b = appendUint32(b, 5, m.Resync)
+	b = appendUint32(b, 6, m.UdpPackets)
+	b = appendUint32(b, 7, m.TcpPackets)
+	b = appendFloat32(b, 8, m.UdpPingAvg)
+	b = appendFloat32(b, 9, m.UdpPingVar)
+	b = appendFloat32(b, 10, m.TcpPingAvg)
+	b = appendFloat32(b, 11, m.TcpPingVar)
+	return append(b, m.XXX_unrecognized...)
+}
+
+// MarshalAppend appends the wire encoding of m to b.
+func (m *UserState) MarshalAppend(b []byte) []byte {
+	b = appendUint32(b, 1, m.Session)
+	b = appendUint32(b, 2, m.Actor)
+	b = appendString(b, 3, m.Name)
+	b = appendUint32(b, 4, m.UserId)
+	b = appendUint32(b, 5, m.ChannelId)
+	b = appendBool(b, 6, m.Mute)
+	b = appendBool(b, 7, m.Deaf)
+	b = appendBool(b, 8, m.Suppress)
+	b = appendBool(b, 9, m.SelfMute)
+	b = appendBool(b, 10, m.SelfDeaf)
+	b = appendBytes(b, 11, m.Texture)
+	b = appendBytes(b, 12, m.PluginContext)
+	b = appendString(b, 13, m.PluginIdentity)
+	b = appendString(b, 14, m.Comment)
+	b = appendString(b, 15, m.Hash)
+	b = appendBytes(b, 16, m.CommentHash)
+	b = appendBytes(b, 17, m.TextureHash)
+	b = appendBool(b, 18, m.PrioritySpeaker)
+	b = appendBool(b, 19, m.Recording)
+	for i := range m.TemporaryAccessTokens {
+		b = appendString(b, 20, &m.TemporaryAccessTokens[i])
+	}
+	return append(b, m.XXX_unrecognized...)
+}
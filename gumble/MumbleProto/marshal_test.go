@@ -0,0 +1,76 @@
+package MumbleProto
+
+import (
+	"bytes"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// marshalFields are the fields that the hand-written marshalers encode.
+var marshalFields = map[interface{ MarshalAppend([]byte) []byte }]int{
+	&UDPTunnel{}: 1,
+	&Ping{}:      11,
+	&UserState{}: 20,
+}
+
+// TestMarshalFields checks that the hand-written marshalers have been updated
+// for the fields of the generated messages.
+func TestMarshalFields(t *testing.T) {
+	for message, handled := range marshalFields {
+		typ := reflect.TypeOf(message).Elem()
+		for i := 0; i < typ.NumField(); i++ {
+			tag := typ.Field(i).Tag.Get("protobuf")
+			if tag == "" {
+				continue
+			}
+			number, err := strconv.Atoi(strings.Split(tag, ",")[1])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if number > handled {
+				t.Errorf("%s.%s (field %d) is not marshalled", typ.Name(), typ.Field(i).Name, number)
+			}
+		}
+	}
+}
+
+func TestMarshalAppend(t *testing.T) {
+	session, channel := uint32(300), uint32(1)
+	name, selfMute := "gumble", true
+	timestamp, avg := uint64(5), float32(1)
+	tests := []struct {
+		message  interface{ MarshalAppend([]byte) []byte }
+		expected []byte
+	}{
+		{
+			&Ping{Timestamp: &timestamp, TcpPingAvg: &avg},
+			[]byte{0x08, 0x05, 0x55, 0x00, 0x00, 0x80, 0x3F},
+		},
+		{
+			&UserState{
+				Session:               &session,
+				ChannelId:             &channel,
+				Name:                  &name,
+				SelfMute:              &selfMute,
+				TextureHash:           []byte{},
+				TemporaryAccessTokens: []string{"a", "b"},
+			},
+			[]byte{
+				0x08, 0xAC, 0x02,
+				0x1A, 0x06, 'g', 'u', 'm', 'b', 'l', 'e',
+				0x28, 0x01,
+				0x48, 0x01,
+				0x8A, 0x01, 0x00,
+				0xA2, 0x01, 0x01, 'a',
+				0xA2, 0x01, 0x01, 'b',
+			},
+		},
+	}
+	for _, test := range tests {
+		if data := test.message.MarshalAppend(nil); !bytes.Equal(data, test.expected) {
+			t.Errorf("%T: got % X, expected % X", test.message, data, test.expected)
+		}
+	}
+}
@@ -14,3 +14,37 @@ func (a AccessTokens) writeMessage(client *Client) error {
 	}
 	return client.Conn.WriteProto(&packet)
 }
+
+// TokenProvider provides access tokens from an external source (e.g. a file,
+// the environment, or a secrets store). See Config.TokenProvider.
+type TokenProvider interface {
+	AccessTokens() (AccessTokens, error)
+}
+
+// TokenProviderFunc is a function that implements TokenProvider.
+type TokenProviderFunc func() (AccessTokens, error)
+
+// AccessTokens implements TokenProvider.
+func (f TokenProviderFunc) AccessTokens() (AccessTokens, error) {
+	return f()
+}
+
+// accessTokens returns the access tokens that are sent to the server when
+// connecting.
+func (c *Config) accessTokens() (AccessTokens, error) {
+	if c.TokenProvider == nil {
+		return c.Tokens, nil
+	}
+	return c.TokenProvider.AccessTokens()
+}
+
+// RefreshTokens fetches the access tokens from Config.TokenProvider, or uses
+// Config.Tokens if it is nil, and sends them to the server. The server
+// replaces the client's previous access tokens with them.
+func (c *Client) RefreshTokens() error {
+	tokens, err := c.Config.accessTokens()
+	if err != nil {
+		return err
+	}
+	return tokens.writeMessage(c)
+}
@@ -1,6 +1,10 @@
 package gumble
 
 import (
+	"fmt"
+	"sort"
+	"strings"
+
 	"github.com/golang/protobuf/proto"
 	"layeh.com/gumble/gumble/MumbleProto"
 )
@@ -114,3 +118,323 @@ type ACLRule struct {
 	// The ACL group the rule applies to. Can be nil.
 	Group *ACLGroup
 }
+
+// Permissions returns the effective permissions of the user in the ACL's
+// channel, by applying the ACL's rules to PermissionDefault in order.
+//
+// The result is computed from the ACL alone: for it to be accurate, the ACL
+// must have been fetched from the server (so that it includes the rules it
+// inherits from its parent channels), and the user must be registered for
+// their membership of non built-in groups to be known. Super users, who
+// always have every permission, cannot be detected.
+func (a *ACL) Permissions(user *User) Permission {
+	granted := PermissionDefault
+	for _, rule := range a.Rules {
+		applies := rule.AppliesCurrent
+		if rule.Inherited {
+			applies = rule.AppliesChildren
+		}
+		if !applies || !a.ruleMatches(rule, user) {
+			continue
+		}
+		granted |= rule.Granted
+		granted &^= rule.Denied
+	}
+	return granted
+}
+
+// ruleMatches returns true if the rule applies to the user.
+func (a *ACL) ruleMatches(rule *ACLRule, user *User) bool {
+	if rule.User != nil {
+		return user.IsRegistered() && rule.User.UserID == user.UserID
+	}
+	if rule.Group == nil {
+		return false
+	}
+	name := rule.Group.Name
+	invert := strings.HasPrefix(name, "!")
+	if invert {
+		name = name[1:]
+	}
+	name = strings.TrimPrefix(name, "~")
+
+	var member bool
+	switch {
+	case name == ACLGroupEveryone:
+		member = true
+	case name == ACLGroupAuthenticated:
+		member = user.IsRegistered()
+	case name == ACLGroupInsideChannel:
+		member = user.Channel == a.Channel
+	case name == ACLGroupOutsideChannel:
+		member = user.Channel != a.Channel
+	case strings.HasPrefix(name, "$"):
+		member = user.Hash != "" && strings.EqualFold(user.Hash, name[1:])
+	default:
+		for _, group := range a.Groups {
+			if group.Name == name {
+				member = group.HasUser(user)
+				break
+			}
+		}
+	}
+	return member != invert
+}
+
+// HasUser returns true if the registered user is a member of the group.
+// Membership of the built-in groups (e.g. ACLGroupEveryone) is not computed
+// by this function.
+func (g *ACLGroup) HasUser(user *User) bool {
+	if !user.IsRegistered() {
+		return false
+	}
+	if _, ok := g.UsersRemove[user.UserID]; ok {
+		return false
+	}
+	if _, ok := g.UsersAdd[user.UserID]; ok {
+		return true
+	}
+	_, ok := g.UsersInherited[user.UserID]
+	return ok
+}
+
+// Group returns the group with the given name, or nil if the ACL does not
+// have such a group.
+func (a *ACL) Group(name string) *ACLGroup {
+	for _, group := range a.Groups {
+		if group.Name == name {
+			return group
+		}
+	}
+	return nil
+}
+
+// AddGroup returns the group with the given name, adding a new group to the
+// ACL if it does not have one. New groups inherit their members, and can be
+// inherited, as in the Mumble client.
+func (a *ACL) AddGroup(name string) *ACLGroup {
+	if group := a.Group(name); group != nil {
+		return group
+	}
+	group := &ACLGroup{
+		Name:         name,
+		InheritUsers: true,
+		Inheritable:  true,
+	}
+	a.Groups = append(a.Groups, group)
+	return group
+}
+
+// RemoveGroup removes the group with the given name from the ACL. false is
+// returned if the ACL does not have such a group.
+func (a *ACL) RemoveGroup(name string) bool {
+	for i, group := range a.Groups {
+		if group.Name == name {
+			a.Groups = append(a.Groups[:i], a.Groups[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// AddUser adds the user to the group's members.
+func (g *ACLGroup) AddUser(user *ACLUser) {
+	delete(g.UsersRemove, user.UserID)
+	if _, ok := g.UsersInherited[user.UserID]; ok && g.InheritUsers {
+		return
+	}
+	if g.UsersAdd == nil {
+		g.UsersAdd = make(map[uint32]*ACLUser)
+	}
+	g.UsersAdd[user.UserID] = user
+}
+
+// RemoveUser removes the user from the group's members. A user who is
+// inherited into the group is explicitly removed.
+func (g *ACLGroup) RemoveUser(user *ACLUser) {
+	delete(g.UsersAdd, user.UserID)
+	if _, ok := g.UsersInherited[user.UserID]; ok && g.InheritUsers {
+		if g.UsersRemove == nil {
+			g.UsersRemove = make(map[uint32]*ACLUser)
+		}
+		g.UsersRemove[user.UserID] = user
+	}
+}
+
+// AddUserNames adds the registered users with the given names to the group's
+// members. users is the server's list of registered users (see
+// Client.RequestUserList). An error is returned, and no users are added, if
+// a name is not in users.
+func (g *ACLGroup) AddUserNames(users RegisteredUsers, names ...string) error {
+	resolved, err := users.resolve(names)
+	if err != nil {
+		return err
+	}
+	for _, user := range resolved {
+		g.AddUser(user.ACLUser())
+	}
+	return nil
+}
+
+// RemoveUserNames removes the registered users with the given names from the
+// group's members. See AddUserNames.
+func (g *ACLGroup) RemoveUserNames(users RegisteredUsers, names ...string) error {
+	resolved, err := users.resolve(names)
+	if err != nil {
+		return err
+	}
+	for _, user := range resolved {
+		g.RemoveUser(user.ACLUser())
+	}
+	return nil
+}
+
+// SetInheritUsers sets whether the group's members are inherited from the
+// parent channel's group of the same name, and returns the group.
+func (g *ACLGroup) SetInheritUsers(inherit bool) *ACLGroup {
+	g.InheritUsers = inherit
+	return g
+}
+
+// SetInheritable sets whether the group can be inherited by child channels,
+// and returns the group.
+func (g *ACLGroup) SetInheritable(inheritable bool) *ACLGroup {
+	g.Inheritable = inheritable
+	return g
+}
+
+// Diff returns a description of the changes from a to updated, one change per
+// line, for review before updated is sent to the server (with Client.Send).
+// nil is returned if the ACLs are the same.
+func (a *ACL) Diff(updated *ACL) []string {
+	var changes []string
+	change := func(format string, args ...interface{}) {
+		changes = append(changes, fmt.Sprintf(format, args...))
+	}
+
+	if a.Inherits != updated.Inherits {
+		change("inherit ACLs: %t -> %t", a.Inherits, updated.Inherits)
+	}
+
+	for _, group := range a.Groups {
+		if updated.Group(group.Name) == nil {
+			change("- group %q", group.Name)
+		}
+	}
+	for _, group := range updated.Groups {
+		previous := a.Group(group.Name)
+		if previous == nil {
+			change("+ group %q", group.Name)
+			previous = &ACLGroup{
+				InheritUsers: group.InheritUsers,
+				Inheritable:  group.Inheritable,
+			}
+		}
+		if previous.InheritUsers != group.InheritUsers {
+			change("group %q: inherit members: %t -> %t", group.Name, previous.InheritUsers, group.InheritUsers)
+		}
+		if previous.Inheritable != group.Inheritable {
+			change("group %q: inheritable: %t -> %t", group.Name, previous.Inheritable, group.Inheritable)
+		}
+		for _, user := range diffACLUsers(previous.UsersAdd, group.UsersAdd) {
+			change("group %q: + member %s", group.Name, user)
+		}
+		for _, user := range diffACLUsers(group.UsersAdd, previous.UsersAdd) {
+			change("group %q: - member %s", group.Name, user)
+		}
+		for _, user := range diffACLUsers(previous.UsersRemove, group.UsersRemove) {
+			change("group %q: + excluded member %s", group.Name, user)
+		}
+		for _, user := range diffACLUsers(group.UsersRemove, previous.UsersRemove) {
+			change("group %q: - excluded member %s", group.Name, user)
+		}
+	}
+
+	previous := make(map[string]int)
+	for _, rule := range a.Rules {
+		previous[rule.String()]++
+	}
+	current := make(map[string]int)
+	for _, rule := range updated.Rules {
+		current[rule.String()]++
+	}
+	var rulesChanged bool
+	for _, rule := range a.Rules {
+		if s := rule.String(); current[s] > 0 {
+			current[s]--
+		} else {
+			change("- rule %s", s)
+			rulesChanged = true
+		}
+	}
+	for _, rule := range updated.Rules {
+		if s := rule.String(); previous[s] > 0 {
+			previous[s]--
+		} else {
+			change("+ rule %s", s)
+			rulesChanged = true
+		}
+	}
+	if !rulesChanged && len(a.Rules) == len(updated.Rules) {
+		for i, rule := range a.Rules {
+			if rule.String() != updated.Rules[i].String() {
+				change("rules reordered")
+				break
+			}
+		}
+	}
+	return changes
+}
+
+// diffACLUsers returns descriptions of the users who are in b but not in a,
+// sorted by user ID.
+func diffACLUsers(a, b map[uint32]*ACLUser) []string {
+	var ids []uint32
+	for id := range b {
+		if _, ok := a[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+	users := make([]string, len(ids))
+	for i, id := range ids {
+		users[i] = b[id].String()
+	}
+	return users
+}
+
+// String returns the user's name and ID (e.g. "alice (12)").
+func (u *ACLUser) String() string {
+	return fmt.Sprintf("%s (%d)", u.Name, u.UserID)
+}
+
+// String returns a description of the rule (e.g. "@admin here,subs grant
+// Kick|Ban deny None").
+func (r *ACLRule) String() string {
+	var subject string
+	switch {
+	case r.User != nil:
+		subject = r.User.String()
+	case r.Group != nil:
+		subject = "@" + r.Group.Name
+	default:
+		subject = "nobody"
+	}
+	var applies []string
+	if r.AppliesCurrent {
+		applies = append(applies, "here")
+	}
+	if r.AppliesChildren {
+		applies = append(applies, "subs")
+	}
+	if len(applies) == 0 {
+		applies = append(applies, "nowhere")
+	}
+	s := subject + " " + strings.Join(applies, ",") + " grant " + r.Granted.String() + " deny " + r.Denied.String()
+	if r.Inherited {
+		s += " (inherited)"
+	}
+	return s
+}
@@ -1,6 +1,7 @@
 package gumble
 
 import (
+	"sync/atomic"
 	"time"
 )
 
@@ -36,6 +37,10 @@ const (
 // OnAudioStream is called when an audio stream for a user starts. It is the
 // implementer's responsibility to continuously process AudioStreamEvent.C
 // until it is closed.
+//
+// A stream lasts for one transmission: C is closed after the packet that has
+// IsLast set, or when the user disconnects. The user's next transmission
+// starts a new stream.
 type AudioListener interface {
 	OnAudioStream(e *AudioStreamEvent)
 }
@@ -55,7 +60,12 @@ func (a AudioBuffer) writeAudio(client *Client, seq int64, final bool) error {
 	if encoder == nil {
 		return nil
 	}
-	dataBytes := client.Config.AudioDataBytes
+	if bitrate := client.audioBitrate.Swap(0); bitrate != 0 {
+		if e, ok := encoder.(AudioBitrateEncoder); ok {
+			e.SetBitrate(int(bitrate))
+		}
+	}
+	dataBytes := client.audioDataBytes()
 	raw, err := encoder.Encode(a, len(a), dataBytes)
 	if final {
 		defer encoder.Reset()
@@ -69,7 +79,11 @@ func (a AudioBuffer) writeAudio(client *Client, seq int64, final bool) error {
 		targetID = byte(target.ID)
 	}
 	// TODO: re-enable positional audio
-	return client.Conn.WriteAudio(byte(4), targetID, seq, final, raw, nil, nil, nil)
+	if err := client.Conn.WriteAudio(byte(4), targetID, seq, final, raw, nil, nil, nil); err != nil {
+		return err
+	}
+	client.audioFramesSent.Add(1)
+	return nil
 }
 
 // AudioPacket contains incoming audio samples and information.
@@ -82,4 +96,32 @@ type AudioPacket struct {
 
 	HasPosition bool
 	X, Y, Z     float32
+
+	// Is this the last packet of the user's transmission? Its AudioBuffer can
+	// be empty.
+	IsLast bool
+
+	// The number of references to the packet that have not been released, if
+	// the packet is pooled.
+	refs int32
+	// The pooled buffer that holds AudioBuffer, if any.
+	pcm    *[]int16
+	target VoiceTarget
+}
+
+// Release returns the packet, and its AudioBuffer, to gumble so that they can
+// be reused for later packets. Neither can be used after calling Release.
+//
+// Calling Release is optional, and is done once by each audio listener that
+// has received the packet. Packets that are not released by all of their
+// listeners are garbage collected as usual.
+func (p *AudioPacket) Release() {
+	if atomic.AddInt32(&p.refs, -1) != 0 {
+		return
+	}
+	if p.pcm != nil {
+		putAudioBuffer(p.pcm)
+	}
+	*p = AudioPacket{}
+	audioPackets.Put(p)
 }
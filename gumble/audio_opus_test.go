@@ -0,0 +1,103 @@
+//go:build opus
+// +build opus
+
+package gumble_test
+
+import (
+	"io"
+	"math"
+	"net"
+	"testing"
+
+	"layeh.com/gumble/gumble"
+	"layeh.com/gumble/opus"
+)
+
+// The benchmarks cover the audio hot path, using the Opus codec, which requires
+// cgo and libopus. Run them with CPU profiling and Config.ProfileLabels to
+// attribute their cost:
+//
+//  go test -tags opus -run - -bench Audio -cpuprofile cpu.out ./gumble/
+
+func sine(n int) gumble.AudioBuffer {
+	frame := make(gumble.AudioBuffer, n)
+	for i := range frame {
+		frame[i] = int16(8000 * math.Sin(2*math.Pi*440*float64(i)/gumble.AudioSampleRate))
+	}
+	return frame
+}
+
+// newClient returns a client whose writes are discarded.
+func newClient(b *testing.B, profileLabels bool) *gumble.Client {
+	local, remote := net.Pipe()
+	go io.Copy(io.Discard, remote)
+	b.Cleanup(func() {
+		local.Close()
+		remote.Close()
+	})
+	config := gumble.NewConfig()
+	config.ProfileLabels = profileLabels
+	return gumble.NewTestClient(local, config, opus.Codec)
+}
+
+// BenchmarkAudioSend measures encoding, packetizing, and writing a frame of
+// outgoing audio.
+func BenchmarkAudioSend(b *testing.B) {
+	client := newClient(b, false)
+	frame := sine(gumble.AudioDefaultFrameSize)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := client.WriteAudio(frame, int64(i)%math.MaxInt32, false); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+type drain struct{}
+
+func (drain) OnAudioStream(e *gumble.AudioStreamEvent) {
+	go func() {
+		for packet := range e.C {
+			packet.Release()
+		}
+	}()
+}
+
+// BenchmarkAudioReceive measures parsing, decoding, and dispatching a packet
+// of incoming audio to an audio listener.
+func BenchmarkAudioReceive(b *testing.B) {
+	for _, bm := range []struct {
+		name          string
+		profileLabels bool
+	}{
+		{"Plain", false},
+		{"ProfileLabels", true},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			client := newClient(b, bm.profileLabels)
+			client.Config.AttachAudio(drain{})
+			client.Users[1] = &gumble.User{
+				Session: 1,
+				Name:    "speaker",
+			}
+
+			encoder := client.AudioEncoder
+			frame := sine(gumble.AudioDefaultFrameSize)
+			data, err := encoder.Encode(frame, len(frame), client.Config.AudioDataBytes)
+			if err != nil {
+				b.Fatal(err)
+			}
+			packet := gumble.NewAudioPacket(1, 0, data, false)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := client.HandlePacket(gumble.PacketUDPTunnel, packet); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package gumble_test
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"layeh.com/gumble/gumble"
+)
+
+// stubCodec is an AudioCodec that does not depend on a real codec library.
+// Every packet that it decodes is a frame of silence.
+type stubCodec struct{}
+
+func (stubCodec) ID() int                         { return 4 }
+func (stubCodec) NewEncoder() gumble.AudioEncoder { return stubCodec{} }
+func (stubCodec) NewDecoder() gumble.AudioDecoder { return stubCodec{} }
+func (stubCodec) Reset()                          {}
+
+func (stubCodec) Encode(pcm []int16, frameSize, maxDataBytes int) ([]byte, error) {
+	return []byte{0}, nil
+}
+
+func (stubCodec) Decode(data []byte, frameSize int) ([]int16, error) {
+	return make([]int16, gumble.AudioDefaultFrameSize), nil
+}
+
+type recorder chan *gumble.AudioPacket
+
+func (r recorder) OnAudioStream(e *gumble.AudioStreamEvent) {
+	go func() {
+		for packet := range e.C {
+			r <- packet
+		}
+	}()
+}
+
+func TestAudioPlayoutDelay(t *testing.T) {
+	local, remote := net.Pipe()
+	go io.Copy(io.Discard, remote)
+	t.Cleanup(func() {
+		local.Close()
+		remote.Close()
+	})
+	config := gumble.NewConfig()
+	config.AudioPlayoutDelay = 50 * time.Millisecond
+	client := gumble.NewTestClient(local, config, stubCodec{})
+	received := make(recorder, 3)
+	client.Config.AttachAudio(received)
+	client.Users[1] = &gumble.User{
+		Session: 1,
+		Name:    "speaker",
+	}
+
+	data := []byte{0}
+	// The packets arrive out of order, and are identified by their position.
+	start := time.Now()
+	for _, seq := range []int64{1, 0, 2} {
+		packet := gumble.EncodedAudioPacket{
+			Codec:       4,
+			Session:     1,
+			Sequence:    seq,
+			Data:        data,
+			HasPosition: true,
+			X:           float32(seq),
+		}
+		if err := client.HandlePacket(gumble.PacketUDPTunnel, packet.Append(nil)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for seq := 0; seq < 3; seq++ {
+		packet := <-received
+		if packet.X != float32(seq) {
+			t.Fatalf("packet %d passed in place of packet %d", int(packet.X), seq)
+		}
+		if seq == 0 {
+			if elapsed := time.Since(start); elapsed < config.AudioPlayoutDelay {
+				t.Fatalf("first packet passed after %v, before the playout delay", elapsed)
+			}
+		}
+		packet.Release()
+	}
+}
+
+type streamRecorder chan (<-chan *gumble.AudioPacket)
+
+func (r streamRecorder) OnAudioStream(e *gumble.AudioStreamEvent) {
+	r <- e.C
+}
+
+func TestAudioTerminator(t *testing.T) {
+	local, remote := net.Pipe()
+	go io.Copy(io.Discard, remote)
+	t.Cleanup(func() {
+		local.Close()
+		remote.Close()
+	})
+	client := gumble.NewTestClient(local, gumble.NewConfig(), stubCodec{})
+	streams := make(streamRecorder, 2)
+	client.Config.AttachAudio(streams)
+	client.Users[1] = &gumble.User{
+		Session: 1,
+		Name:    "speaker",
+	}
+
+	data := []byte{0}
+	packets := []gumble.EncodedAudioPacket{
+		{Codec: 4, Session: 1, Sequence: 0, Data: data},
+		{Codec: 4, Session: 1, Sequence: 1, Terminator: true},
+		{Codec: 4, Session: 1, Sequence: 2, Data: data},
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, packet := range packets {
+			if err := client.HandlePacket(gumble.PacketUDPTunnel, packet.Append(nil)); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+
+	first := <-streams
+	if packet := <-first; packet.IsLast {
+		t.Fatal("first packet has IsLast set")
+	}
+	if packet := <-first; !packet.IsLast || len(packet.AudioBuffer) != 0 {
+		t.Fatalf("terminator has IsLast %t and %d samples", packet.IsLast, len(packet.AudioBuffer))
+	}
+	if _, ok := <-first; ok {
+		t.Fatal("stream not closed after the terminator")
+	}
+	second := <-streams
+	if packet := <-second; packet.IsLast {
+		t.Fatal("packet of the next transmission has IsLast set")
+	}
+	<-done
+}
@@ -53,3 +53,20 @@ type AudioDecoder interface {
 	Decode(data []byte, frameSize int) ([]int16, error)
 	Reset()
 }
+
+// AudioBitrateEncoder can be implemented by AudioEncoders whose target bitrate
+// can be set. It is used by Config.AdjustToBandwidth.
+type AudioBitrateEncoder interface {
+	// SetBitrate sets the target bitrate, in bits per second.
+	SetBitrate(bitrate int)
+}
+
+// AudioBufferDecoder can be implemented by AudioDecoders that are able to
+// decode into a buffer provided by the caller. gumble then reuses the buffers
+// of incoming audio packets that have been released (see
+// AudioPacket.Release), rather than allocating a buffer for each packet.
+type AudioBufferDecoder interface {
+	// DecodeBuffer decodes data into pcm, whose length is the maximum frame
+	// size, and returns the slice of pcm that contains the decoded samples.
+	DecodeBuffer(pcm []int16, data []byte) ([]int16, error)
+}
@@ -0,0 +1,175 @@
+package gumble
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"time"
+)
+
+// AuditType is the type of an AuditRecord.
+type AuditType int
+
+// Audit record types.
+const (
+	// The TLS connection to the server has been established.
+	AuditConnected AuditType = iota
+	// The server accepted the client's credentials.
+	AuditAuthenticated
+	// The server rejected the client. Err is the *RejectError.
+	AuditRejected
+	// The client has been disconnected, including by being kicked or banned.
+	AuditDisconnected
+	// Another user has been kicked or banned from the server.
+	AuditUserKicked
+	AuditUserBanned
+	// The client was denied permission to perform an action.
+	AuditPermissionDenied
+)
+
+// String returns the name of the audit type.
+func (t AuditType) String() string {
+	switch t {
+	case AuditConnected:
+		return "connected"
+	case AuditAuthenticated:
+		return "authenticated"
+	case AuditRejected:
+		return "rejected"
+	case AuditDisconnected:
+		return "disconnected"
+	case AuditUserKicked:
+		return "user kicked"
+	case AuditUserBanned:
+		return "user banned"
+	case AuditPermissionDenied:
+		return "permission denied"
+	}
+	return "unknown"
+}
+
+// AuditRecord is a security relevant event that is passed to
+// Config.AuditHook. Fields that do not apply to a record's type are left
+// empty.
+type AuditRecord struct {
+	Time time.Time
+	Type AuditType
+
+	// The address of the server, as passed to DialWithDialer.
+	Server string
+	// The hex encoded SHA-256 hash of the server's certificate.
+	ServerFingerprint string
+	// The username and certificate hash (see User.Hash) that the client
+	// authenticated with.
+	Username        string
+	CertificateHash string
+
+	// The user that the record is about (e.g. the kicked user), and the user
+	// that performed the action.
+	User  string
+	Actor string
+	// The user's certificate hash, if known.
+	UserHash string
+	// The name of the channel that the record is about.
+	Channel string
+	// The disconnect type (for AuditDisconnected) or permission (for
+	// AuditPermissionDenied).
+	Disconnect DisconnectType
+	Permission Permission
+	// The reason given by the server.
+	Reason string
+	Err    error
+}
+
+// auditInfo is the connection information that is included in every audit
+// record of a client.
+type auditInfo struct {
+	server            string
+	serverFingerprint string
+	username          string
+	certificateHash   string
+}
+
+func newAuditInfo(addr string, config *Config, conn *tls.Conn, tlsConfig *tls.Config) *auditInfo {
+	info := &auditInfo{
+		server:   addr,
+		username: config.Username,
+	}
+	if certs := conn.ConnectionState().PeerCertificates; len(certs) > 0 {
+		hash := sha256.Sum256(certs[0].Raw)
+		info.serverFingerprint = hex.EncodeToString(hash[:])
+	}
+	if tlsConfig != nil && len(tlsConfig.Certificates) > 0 && len(tlsConfig.Certificates[0].Certificate) > 0 {
+		hash := sha1.Sum(tlsConfig.Certificates[0].Certificate[0])
+		info.certificateHash = hex.EncodeToString(hash[:])
+	}
+	return info
+}
+
+// audit passes a record of the given type to the audit hook.
+func (c *Client) audit(t AuditType, fill func(r *AuditRecord)) {
+	hook := c.Config.AuditHook
+	if hook == nil || c.auditInfo == nil {
+		return
+	}
+	record := AuditRecord{
+		Time:              time.Now(),
+		Type:              t,
+		Server:            c.auditInfo.server,
+		ServerFingerprint: c.auditInfo.serverFingerprint,
+		Username:          c.auditInfo.username,
+		CertificateHash:   c.auditInfo.certificateHash,
+	}
+	if fill != nil {
+		fill(&record)
+	}
+	hook(&record)
+}
+
+// auditEvent passes a record of the event to the audit hook, if the event is
+// security relevant.
+func (c *Client) auditEvent(event Event) {
+	if c.Config.AuditHook == nil {
+		return
+	}
+	switch e := event.(type) {
+	case *DisconnectEvent:
+		c.audit(AuditDisconnected, func(r *AuditRecord) {
+			r.Disconnect = e.Type
+			r.Reason = e.String
+			r.Err = e.Err
+		})
+	case *UserChangeEvent:
+		var t AuditType
+		switch {
+		case e.Type.Has(UserChangeBanned):
+			t = AuditUserBanned
+		case e.Type.Has(UserChangeKicked):
+			t = AuditUserKicked
+		default:
+			return
+		}
+		c.audit(t, func(r *AuditRecord) {
+			if e.User != nil {
+				r.User = e.User.Name
+				r.UserHash = e.User.Hash
+			}
+			if e.Actor != nil {
+				r.Actor = e.Actor.Name
+			}
+			r.Reason = e.String
+		})
+	case *PermissionDeniedEvent:
+		c.audit(AuditPermissionDenied, func(r *AuditRecord) {
+			if e.User != nil {
+				r.User = e.User.Name
+			}
+			if e.Channel != nil {
+				r.Channel = e.Channel.Name
+			}
+			r.Permission = e.Permission
+			r.Reason = e.String
+		})
+	}
+}
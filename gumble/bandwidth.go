@@ -0,0 +1,107 @@
+package gumble
+
+import (
+	"time"
+)
+
+// audioPacketOverhead is the number of bytes, other than the encoded audio,
+// that an outgoing audio packet takes up on the network, at most: the IP and
+// TCP headers (40), the TLS record header, nonce, and tag (29), the control
+// packet header (6), and the audio packet header (8).
+const audioPacketOverhead = 40 + 29 + 6 + 8
+
+// minimumAudioBitrate is the bitrate below which adjustBandwidth sends more
+// audio per packet, rather than lowering the bitrate further.
+const minimumAudioBitrate = 8000
+
+// audioIntervals are the valid values of Config.AudioInterval.
+var audioIntervals = []time.Duration{
+	10 * time.Millisecond,
+	20 * time.Millisecond,
+	40 * time.Millisecond,
+	60 * time.Millisecond,
+}
+
+// AudioInterval returns the interval at which audio should be sent:
+// Config.AudioInterval, unless it has been raised to fit the server's
+// bandwidth (see Config.AdjustToBandwidth). Audio sources should use it,
+// rather than Config.AudioInterval, to pace outgoing audio.
+func (c *Client) AudioInterval() time.Duration {
+	if interval := c.adjustedAudioInterval.Load(); interval != 0 {
+		return time.Duration(interval)
+	}
+	return c.Config.AudioInterval
+}
+
+// AudioFrameSize returns the audio frame size for the interval returned by
+// AudioInterval.
+func (c *Client) AudioFrameSize() int {
+	return int(c.AudioInterval()/AudioDefaultInterval) * AudioDefaultFrameSize
+}
+
+// audioDataBytes returns the number of bytes that an encoded audio frame can
+// use: Config.AudioDataBytes, unless it has been adjusted to fit the server's
+// bandwidth.
+func (c *Client) audioDataBytes() int {
+	if dataBytes := c.adjustedAudioDataBytes.Load(); dataBytes != 0 {
+		return int(dataBytes)
+	}
+	return c.Config.AudioDataBytes
+}
+
+// adjustBandwidth sets the audio settings so that outgoing audio fits
+// maxBitrate, the server's maximum bandwidth, if Config.AdjustToBandwidth is
+// set. It returns the event to trigger, or nil if the settings have not
+// changed.
+//
+// Starting from the settings that the client connected with, the interval is
+// raised (which lowers the packets' overhead) until the audio fits with a
+// bitrate of at least minimumAudioBitrate, or the longest interval is
+// reached.
+func (c *Client) adjustBandwidth(maxBitrate int) *AudioBandwidthEvent {
+	if !c.Config.AdjustToBandwidth || maxBitrate <= 0 {
+		return nil
+	}
+	baseInterval, baseDataBytes := c.baseAudioInterval, c.baseAudioDataBytes
+	if baseInterval <= 0 || baseDataBytes <= 0 {
+		return nil
+	}
+
+	var interval time.Duration
+	var dataBytes, packets int
+	for _, candidate := range audioIntervals {
+		if candidate < baseInterval {
+			continue
+		}
+		interval = candidate
+		packets = int(time.Second / interval)
+		// The base data rate is kept at longer intervals.
+		wanted := baseDataBytes * int(interval/baseInterval)
+		dataBytes = maxBitrate/8/packets - audioPacketOverhead
+		if dataBytes >= wanted {
+			dataBytes = wanted
+			break
+		}
+		if dataBytes*8*packets >= minimumAudioBitrate {
+			break
+		}
+	}
+	if dataBytes <= 0 {
+		return nil
+	}
+
+	if interval == c.AudioInterval() && dataBytes == c.audioDataBytes() {
+		return nil
+	}
+	c.adjustedAudioInterval.Store(int64(interval))
+	c.adjustedAudioDataBytes.Store(int64(dataBytes))
+	bitrate := dataBytes * 8 * packets
+	c.audioBitrate.Store(int64(bitrate))
+	return &AudioBandwidthEvent{
+		Client:         c,
+		MaximumBitrate: maxBitrate,
+		AudioInterval:  interval,
+		AudioDataBytes: dataBytes,
+		Bitrate:        bitrate,
+	}
+}
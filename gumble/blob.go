@@ -0,0 +1,192 @@
+package gumble
+
+import (
+	"container/list"
+	"crypto/sha1"
+	"sync"
+	"time"
+)
+
+// DefaultBlobCacheSize is the value of Config.BlobCacheSize set by NewConfig.
+const DefaultBlobCacheSize = 4 * 1024 * 1024
+
+// blobRequestTimeout is how long after a blob has been requested that
+// requests for the same blob are not sent again.
+const blobRequestTimeout = 10 * time.Second
+
+// Kinds of blobs. Servers send comments, channel descriptions, and textures
+// that are not short as hashes, whose content is requested separately (e.g.
+// User.RequestComment).
+const (
+	blobComment     = 'c'
+	blobDescription = 'd'
+	blobTexture     = 't'
+)
+
+// blobCache holds the blobs that have been received from the server, keyed
+// by their hash (SHA-1, as used by the server), so that blobs whose hashes
+// are sent again are filled in without requesting them. The least recently
+// used blobs are evicted once the blobs' total size exceeds maxSize.
+//
+// It also records the blob requests that are in flight, so that a blob is
+// requested only once when several users or channels share it.
+type blobCache struct {
+	mu      sync.Mutex
+	maxSize int
+	size    int
+	entries map[string]*list.Element
+	lru     list.List
+	pending map[string]time.Time
+}
+
+type blobEntry struct {
+	hash string
+	data []byte
+}
+
+// get returns the blob with the given hash, if it is cached.
+func (b *blobCache) get(hash []byte) ([]byte, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	element := b.entries[string(hash)]
+	if element == nil {
+		return nil, false
+	}
+	b.lru.MoveToFront(element)
+	return element.Value.(*blobEntry).data, true
+}
+
+// received caches data, a blob of the given kind, and returns its hash.
+func (b *blobCache) received(kind byte, data []byte) []byte {
+	sum := sha1.Sum(data)
+	hash := sum[:]
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.pending, string(kind)+string(hash))
+	if len(data) > b.maxSize || b.entries[string(hash)] != nil {
+		return hash
+	}
+	if b.entries == nil {
+		b.entries = make(map[string]*list.Element)
+	}
+	b.entries[string(hash)] = b.lru.PushFront(&blobEntry{
+		hash: string(hash),
+		data: data,
+	})
+	b.size += len(data)
+	for b.size > b.maxSize {
+		entry := b.lru.Remove(b.lru.Back()).(*blobEntry)
+		delete(b.entries, entry.hash)
+		b.size -= len(entry.data)
+	}
+	return hash
+}
+
+// request returns true if a request for the blob of the given kind and hash
+// should be sent, i.e. one is not already in flight. It is recorded as in
+// flight until it is received, or until blobRequestTimeout has passed.
+func (b *blobCache) request(kind byte, hash []byte, now time.Time) bool {
+	if hash == nil || b.maxSize <= 0 {
+		// Without the cache, a blob that is received for one user or channel
+		// is not filled in for the others.
+		return true
+	}
+	key := string(kind) + string(hash)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if sent, ok := b.pending[key]; ok && now.Sub(sent) < blobRequestTimeout {
+		return false
+	}
+	if b.pending == nil {
+		b.pending = make(map[string]time.Time)
+	}
+	for k, sent := range b.pending {
+		if now.Sub(sent) >= blobRequestTimeout {
+			delete(b.pending, k)
+		}
+	}
+	b.pending[key] = now
+	return true
+}
+
+// blobReceived caches data, a blob of the given kind that has been received
+// for one user or channel, and fills it in for the others that are waiting
+// for it. Their change events are returned, to be triggered once
+// c.volatile has been released.
+//
+// c.volatile must be held when calling this function.
+func (c *Client) blobReceived(kind byte, data []byte) []Event {
+	if c.Config.BlobCacheSize <= 0 || len(data) == 0 {
+		return nil
+	}
+	hash := string(c.blobs.received(kind, data))
+
+	var events []Event
+	switch kind {
+	case blobComment, blobTexture:
+		for _, user := range c.Users {
+			event := UserChangeEvent{
+				Client: c,
+				User:   user,
+			}
+			if kind == blobComment && user.CommentHash != nil && string(user.CommentHash) == hash {
+				previous := *user
+				event.Previous = &previous
+				event.Type = UserChangeComment
+				user.Comment = string(data)
+				user.CommentHash = nil
+			} else if kind == blobTexture && user.TextureHash != nil && string(user.TextureHash) == hash {
+				previous := *user
+				event.Previous = &previous
+				event.Type = UserChangeTexture
+				user.Texture = data
+				user.TextureHash = nil
+			} else {
+				continue
+			}
+			events = append(events, &event)
+		}
+	case blobDescription:
+		for _, channel := range c.Channels {
+			if channel.DescriptionHash == nil || string(channel.DescriptionHash) != hash {
+				continue
+			}
+			previous := *channel
+			channel.Description = string(data)
+			channel.DescriptionHash = nil
+			events = append(events, &ChannelChangeEvent{
+				Client:   c,
+				Channel:  channel,
+				Type:     ChannelChangeDescription,
+				Previous: &previous,
+			})
+		}
+	}
+	return events
+}
+
+// cachedBlob returns the blob with the given hash, if it is cached.
+func (c *Client) cachedBlob(hash []byte) ([]byte, bool) {
+	if c.Config.BlobCacheSize <= 0 {
+		return nil, false
+	}
+	return c.blobs.get(hash)
+}
+
+// triggerBlobEvents triggers the events returned by blobReceived.
+func (c *Client) triggerBlobEvents(events []Event) {
+	if c.State() != StateSynced {
+		return
+	}
+	for _, event := range events {
+		switch event := event.(type) {
+		case *UserChangeEvent:
+			c.Config.Listeners.onUserChange(event)
+		case *ChannelChangeEvent:
+			c.Config.Listeners.onChannelChange(event)
+			c.noteChannelChange(event.Channel, event.Type)
+		}
+	}
+}
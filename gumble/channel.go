@@ -1,6 +1,8 @@
 package gumble
 
 import (
+	"strings"
+
 	"github.com/golang/protobuf/proto"
 	"layeh.com/gumble/gumble/MumbleProto"
 )
@@ -43,59 +45,59 @@ func (c *Channel) IsRoot() bool {
 }
 
 // Add will add a sub-channel to the given channel.
-func (c *Channel) Add(name string, temporary bool) {
+func (c *Channel) Add(name string, temporary bool)error {
 	packet := MumbleProto.ChannelState{
 		Parent:    &c.ID,
 		Name:      &name,
 		Temporary: &temporary,
 	}
-	c.client.Conn.WriteProto(&packet)
+	return c.client.Conn.WriteProto(&packet)
 }
 
 // Remove will remove the given channel and all sub-channels from the server's
 // channel tree.
-func (c *Channel) Remove() {
+func (c *Channel) Remove()error {
 	packet := MumbleProto.ChannelRemove{
 		ChannelId: &c.ID,
 	}
-	c.client.Conn.WriteProto(&packet)
+	return c.client.Conn.WriteProto(&packet)
 }
 
 // SetName will set the name of the channel. This will have no effect if the
 // channel is the server's root channel.
-func (c *Channel) SetName(name string) {
+func (c *Channel) SetName(name string)error {
 	packet := MumbleProto.ChannelState{
 		ChannelId: &c.ID,
 		Name:      &name,
 	}
-	c.client.Conn.WriteProto(&packet)
+	return c.client.Conn.WriteProto(&packet)
 }
 
 // SetDescription will set the description of the channel.
-func (c *Channel) SetDescription(description string) {
+func (c *Channel) SetDescription(description string)error {
 	packet := MumbleProto.ChannelState{
 		ChannelId:   &c.ID,
 		Description: &description,
 	}
-	c.client.Conn.WriteProto(&packet)
+	return c.client.Conn.WriteProto(&packet)
 }
 
 // SetPosition will set the position of the channel.
-func (c *Channel) SetPosition(position int32) {
+func (c *Channel) SetPosition(position int32)error {
 	packet := MumbleProto.ChannelState{
 		ChannelId: &c.ID,
 		Position:  &position,
 	}
-	c.client.Conn.WriteProto(&packet)
+	return c.client.Conn.WriteProto(&packet)
 }
 
 // SetMaxUsers will set the maximum number of users allowed in the channel.
-func (c *Channel) SetMaxUsers(maxUsers uint32) {
+func (c *Channel) SetMaxUsers(maxUsers uint32)error {
 	packet := MumbleProto.ChannelState{
 		ChannelId: &c.ID,
 		MaxUsers:  &maxUsers,
 	}
-	c.client.Conn.WriteProto(&packet)
+	return c.client.Conn.WriteProto(&packet)
 }
 
 // Find returns a channel whose path (by channel name) from the current channel
@@ -123,22 +125,55 @@ func (c *Channel) Find(names ...string) *Channel {
 	return nil
 }
 
+// FindFold is like Find, but channel names are matched case-insensitively. If
+// several children match a name, the one whose name is equal to it is
+// preferred.
+func (c *Channel) FindFold(names ...string) *Channel {
+	channel := c
+	for _, name := range names {
+		if channel = channel.foldChild(name); channel == nil {
+			return nil
+		}
+	}
+	return channel
+}
+
+// foldChild returns the child of c whose name is equal to name, or,
+// if there is none, a child whose name is equal to name case-insensitively.
+func (c *Channel) foldChild(name string) *Channel {
+	var found *Channel
+	for _, child := range c.Children {
+		if child.Name == name {
+			return child
+		}
+		if strings.EqualFold(child.Name, name) && (found == nil || child.ID < found.ID) {
+			found = child
+		}
+	}
+	return found
+}
+
 // RequestDescription requests that the actual channel description
-// (i.e. non-hashed) be sent to the client.
-func (c *Channel) RequestDescription() {
+// (i.e. non-hashed) be sent to the client. The request is not sent if the same
+// description has already been requested, for this or another channel, and
+// has not yet been received.
+func (c *Channel) RequestDescription()error {
+	if !c.client.blobs.request(blobDescription, c.DescriptionHash, c.client.clock.Now()) {
+		return nil
+	}
 	packet := MumbleProto.RequestBlob{
 		ChannelDescription: []uint32{c.ID},
 	}
-	c.client.Conn.WriteProto(&packet)
+	return c.client.Conn.WriteProto(&packet)
 }
 
 // RequestACL requests that the channel's ACL to be sent to the client.
-func (c *Channel) RequestACL() {
+func (c *Channel) RequestACL()error {
 	packet := MumbleProto.ACL{
 		ChannelId: &c.ID,
 		Query:     proto.Bool(true),
 	}
-	c.client.Conn.WriteProto(&packet)
+	return c.client.Conn.WriteProto(&packet)
 }
 
 // RequestPermission requests that the channel's permission information to be
@@ -146,15 +181,18 @@ func (c *Channel) RequestACL() {
 //
 // Note: the server will not reply to the request if the client has up-to-date
 // permission information.
-func (c *Channel) RequestPermission() {
+func (c *Channel) RequestPermission()error {
 	packet := MumbleProto.PermissionQuery{
 		ChannelId: &c.ID,
 	}
-	c.client.Conn.WriteProto(&packet)
+	return c.client.Conn.WriteProto(&packet)
 }
 
 // Send will send a text message to the channel.
-func (c *Channel) Send(message string, recursive bool) {
+//
+// If the message is too long and Config.SplitMessage is not set, the message
+// is not sent and ErrMessageTooLong is returned.
+func (c *Channel) Send(message string, recursive bool) error {
 	textMessage := TextMessage{
 		Message: message,
 	}
@@ -163,7 +201,7 @@ func (c *Channel) Send(message string, recursive bool) {
 	} else {
 		textMessage.Channels = []*Channel{c}
 	}
-	c.client.Send(&textMessage)
+	return c.client.Send(&textMessage)
 }
 
 // Permission returns the permissions the user has in the channel, or nil if
@@ -173,7 +211,7 @@ func (c *Channel) Permission() *Permission {
 }
 
 // Link links the given channels to the channel.
-func (c *Channel) Link(channel ...*Channel) {
+func (c *Channel) Link(channel ...*Channel)error {
 	packet := MumbleProto.ChannelState{
 		ChannelId: &c.ID,
 		LinksAdd:  make([]uint32, len(channel)),
@@ -181,12 +219,12 @@ func (c *Channel) Link(channel ...*Channel) {
 	for i, ch := range channel {
 		packet.LinksAdd[i] = ch.ID
 	}
-	c.client.Conn.WriteProto(&packet)
+	return c.client.Conn.WriteProto(&packet)
 }
 
 // Unlink unlinks the given channels from the channel. If no arguments are
 // passed, all linked channels are unlinked.
-func (c *Channel) Unlink(channel ...*Channel) {
+func (c *Channel) Unlink(channel ...*Channel)error {
 	packet := MumbleProto.ChannelState{
 		ChannelId: &c.ID,
 	}
@@ -203,5 +241,5 @@ func (c *Channel) Unlink(channel ...*Channel) {
 			packet.LinksRemove[i] = ch.ID
 		}
 	}
-	c.client.Conn.WriteProto(&packet)
+	return c.client.Conn.WriteProto(&packet)
 }
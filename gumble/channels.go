@@ -26,3 +26,26 @@ func (c Channels) Find(names ...string) *Channel {
 	}
 	return root.Find(names...)
 }
+
+// FindFold is like Find, but channel names are matched case-insensitively. If
+// several channels match a name, the one whose name is equal to it is
+// preferred.
+func (c Channels) FindFold(names ...string) *Channel {
+	root := c[0]
+	if names == nil || root == nil {
+		return root
+	}
+	return root.FindFold(names...)
+}
+
+// FindFunc returns the channel with the lowest ID for which f returns true.
+// nil is returned if f does not return true for any channel.
+func (c Channels) FindFunc(f func(channel *Channel) bool) *Channel {
+	var found *Channel
+	for _, channel := range c {
+		if (found == nil || channel.ID < found.ID) && f(channel) {
+			found = channel
+		}
+	}
+	return found
+}
@@ -0,0 +1,129 @@
+package gumble
+
+import (
+	"time"
+)
+
+// DefaultChannelTreeDelay is the default value of Config.ChannelTreeDelay.
+const DefaultChannelTreeDelay = time.Millisecond * 100
+
+// channelTreeTicks is the number of times per Config.ChannelTreeDelay that
+// the client checks whether the delay has passed.
+const channelTreeTicks = 4
+
+// channelTree accumulates channel changes until they are passed to the
+// OnChannelTreeChange listeners.
+type channelTree struct {
+	// deadline is when the event is triggered, unless there are more
+	// changes before then.
+	deadline time.Time
+	created  map[*Channel]bool
+	removed  map[*Channel]bool
+	moved    map[*Channel]bool
+	changed  map[*Channel]bool
+}
+
+// noteChannelChange records a channel change for the next
+// ChannelTreeChangeEvent, and restarts the delay before the event is
+// triggered.
+func (c *Client) noteChannelChange(channel *Channel, changeType ChannelChangeType) {
+	delay := c.Config.ChannelTreeDelay
+	if delay <= 0 {
+		return
+	}
+
+	c.volatile.Lock()
+	defer c.volatile.Unlock()
+
+	t := c.channelTree
+	if t == nil {
+		t = &channelTree{
+			created: make(map[*Channel]bool),
+			removed: make(map[*Channel]bool),
+			moved:   make(map[*Channel]bool),
+			changed: make(map[*Channel]bool),
+		}
+		c.channelTree = t
+		// The delay is measured with Config.Clock, so that it can be
+		// controlled in tests.
+		period := delay / channelTreeTicks
+		if period <= 0 {
+			period = delay
+		}
+		go c.channelTreeRoutine(t, c.clock.NewTicker(period))
+	}
+	switch {
+	case changeType.Has(ChannelChangeCreated):
+		t.created[channel] = true
+	case changeType.Has(ChannelChangeRemoved):
+		if t.created[channel] {
+			delete(t.created, channel)
+		} else {
+			t.removed[channel] = true
+		}
+		delete(t.moved, channel)
+		delete(t.changed, channel)
+	case !t.created[channel]:
+		if changeType.Has(ChannelChangeMoved) {
+			t.moved[channel] = true
+		}
+		if changeType&^ChannelChangeMoved != 0 {
+			t.changed[channel] = true
+		}
+	}
+
+	t.deadline = c.clock.Now().Add(delay)
+}
+
+// channelTreeRoutine triggers a ChannelTreeChangeEvent with the changes
+// recorded in t once its deadline has passed.
+func (c *Client) channelTreeRoutine(t *channelTree, ticker Ticker) {
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C():
+			c.volatile.Lock()
+			due := !now.Before(t.deadline)
+			c.volatile.Unlock()
+			if due {
+				c.triggerAsync(c.triggerChannelTree)
+				return
+			}
+		case <-c.end:
+			return
+		}
+	}
+}
+
+func (c *Client) triggerChannelTree() {
+	c.volatile.Lock()
+	t := c.channelTree
+	c.channelTree = nil
+	c.volatile.Unlock()
+
+	if t == nil || c.State() != StateSynced {
+		return
+	}
+	event := ChannelTreeChangeEvent{
+		Client:  c,
+		Created: channelList(t.created),
+		Removed: channelList(t.removed),
+		Moved:   channelList(t.moved),
+		Changed: channelList(t.changed),
+	}
+	if event.Created == nil && event.Removed == nil && event.Moved == nil && event.Changed == nil {
+		return
+	}
+	c.Config.Listeners.onChannelTreeChange(&event)
+}
+
+func channelList(m map[*Channel]bool) []*Channel {
+	if len(m) == 0 {
+		return nil
+	}
+	channels := make([]*Channel, 0, len(m))
+	for channel := range m {
+		channels = append(channels, channel)
+	}
+	return channels
+}
@@ -0,0 +1,63 @@
+package gumble_test
+
+import (
+	"testing"
+	"time"
+
+	"layeh.com/gumble/gumble"
+	"layeh.com/gumble/gumbletest"
+)
+
+// waitTickers waits until clock has n tickers that have not been stopped.
+func waitTickers(t *testing.T, clock *gumbletest.Clock, n int) {
+	deadline := time.Now().Add(5 * time.Second)
+	for clock.Tickers() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("clock has %d tickers; want %d", clock.Tickers(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestChannelTreeDelayUsesClock(t *testing.T) {
+	server := gumbletest.NewServer()
+	defer server.Close()
+
+	clock := gumbletest.NewClock(time.Unix(0, 0))
+	changes := make(chan *gumble.ChannelTreeChangeEvent, 1)
+	config := gumble.NewConfig()
+	config.Clock = clock
+	config.ChannelTreeDelay = time.Second
+	config.AttachAll(func(event gumble.Event) {
+		if e, ok := event.(*gumble.ChannelTreeChangeEvent); ok {
+			changes <- e
+		}
+	})
+	client, err := server.Dial(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Disconnect()
+
+	// The ping ticker, and then the channel tree ticker.
+	waitTickers(t, clock, 1)
+	server.AddChannel(0, "Lobby")
+	waitTickers(t, clock, 2)
+
+	clock.Advance(config.ChannelTreeDelay - time.Millisecond)
+	select {
+	case <-changes:
+		t.Fatal("ChannelTreeChangeEvent triggered before the delay")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	clock.Advance(config.ChannelTreeDelay / 4)
+	select {
+	case e := <-changes:
+		if len(e.Created) != 1 || e.Created[0].Name != "Lobby" {
+			t.Errorf("Created = %v; want the Lobby channel", e.Created)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the ChannelTreeChangeEvent")
+	}
+}
@@ -1,12 +1,15 @@
 package gumble
 
 import (
-	"fmt"
+	"context"
 	"crypto/tls"
 	"errors"
+	"fmt"
+	"log/slog"
 	"math"
 	"net"
 	"runtime"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -14,14 +17,15 @@ import (
 	"layeh.com/gumble/gumble/MumbleProto"
 )
 
-// packSemver turns "MAJOR.MINOR.PATCH" into the uint32 used in Mumble's Version.
-func packSemver(s string) (uint32, error) {
-	var maj, min, pat uint32
+// packSemver turns "MAJOR.MINOR.PATCH" into the uint64 used in the
+// version_v2 field of Mumble's Version (see Version.VersionV2).
+func packSemver(s string) (uint64, error) {
+	var maj, min, pat uint64
 	n, err := fmt.Sscanf(s, "%d.%d.%d", &maj, &min, &pat)
-	if err != nil || n != 3 || maj > 0xFFFF || min > 0xFF || pat > 0xFF {
+	if err != nil || n != 3 || maj > 0xFFFF || min > 0xFF || pat > 0xFFFF {
 		return 0, fmt.Errorf("invalid semver %q", s)
 	}
-	return (maj<<16 | min<<8 | pat), nil
+	return maj<<48 | min<<32 | pat<<16, nil
 }
 
 // State is the current state of the client's connection to the server.
@@ -41,9 +45,25 @@ const (
 	StateSynced
 )
 
+// String returns the name of the state.
+func (s State) String() string {
+	switch s {
+	case StateDisconnected:
+		return "disconnected"
+	case StateConnected:
+		return "connected"
+	case StateSynced:
+		return "synced"
+	}
+	return "unknown"
+}
+
 // ClientVersion is the protocol version that Client implements.
 const ClientVersion = 1<<16 | 3<<8 | 0
 
+// ClientVersionV2 is ClientVersion in the format of Version.VersionV2.
+const ClientVersionV2 = 1<<48 | 3<<32 | 0<<16
+
 // Client is the type used to create a connection to a server.
 type Client struct {
 	// The User associated with the client.
@@ -53,12 +73,15 @@ type Client struct {
 	// The underlying Conn to the server.
 	Conn *Conn
 
-	// The users currently connected to the server.
+	// The users currently connected to the server. It must only be accessed
+	// from event listeners or inside Do; see also UsersSnapshot.
 	Users Users
-	// The connected server's channels.
+	// The connected server's channels. It must only be accessed from event
+	// listeners or inside Do; see also ChannelsSnapshot.
 	Channels    Channels
 	permissions map[uint32]*Permission
 	tmpACL      *ACL
+	tmpACLIDs   map[uint32]bool
 
 	// Ping stats
 	tcpPacketsReceived uint32
@@ -68,10 +91,16 @@ type Client struct {
 
 	// A collection containing the server's context actions.
 	ContextActions ContextActions
+	// The context actions registered by the client.
+	registered registeredContextActions
 
 	// The audio encoder used when sending audio to the server.
 	AudioEncoder AudioEncoder
 	audioCodec   AudioCodec
+	// The decoders of the users who have disconnected, for reuse.
+	decoders []AudioDecoder
+	// See User.SetPlayoutDelay.
+	playoutDelays playoutDelays
 	// To whom transmitted audio will be sent. The VoiceTarget must have already
 	// been sent to the server for targeting to work correctly. Setting to nil
 	// will disable voice targeting (i.e. switch back to regular speaking).
@@ -79,6 +108,10 @@ type Client struct {
 
 	state uint32
 
+	// Message length limits sent by the server.
+	maximumMessageLength      int32
+	maximumImageMessageLength int32
+
 	// volatile is held by the client when the internal data structures are being
 	// modified.
 	volatile rpwMutex
@@ -86,6 +119,70 @@ type Client struct {
 	connect         chan *RejectError
 	end             chan struct{}
 	disconnectEvent DisconnectEvent
+	// The error returned by the write to the server that failed.
+	writeErr atomic.Pointer[error]
+	// The audio settings when the client connected, the settings adjusted
+	// to fit the server's bandwidth (zero if they have not been), and the
+	// bitrate to set on the encoder before it next encodes audio. See
+	// Config.AdjustToBandwidth.
+	baseAudioInterval      time.Duration
+	baseAudioDataBytes     int
+	adjustedAudioInterval  atomic.Int64
+	adjustedAudioDataBytes atomic.Int64
+	audioBitrate           atomic.Int64
+	// Blobs that have been received, and blob requests in flight.
+	blobs blobCache
+	// Whether Self is self-muted or self-deafened, as last set by the client
+	// or the server. See Config.SkipEncodeWhenMuted.
+	selfMuted, selfDeafened atomic.Bool
+	// disconnecting is set by Disconnect.
+	disconnecting atomic.Bool
+	// done is closed after the DisconnectEvent has been handled. eventsEnded
+	// is then set, so that events triggered outside of readRoutine (see
+	// triggerAsync) are dropped.
+	done        chan struct{}
+	eventsMu    sync.RWMutex
+	eventsEnded bool
+
+	// dispatcher calls the event listeners when Config.EventWorkers is set.
+	dispatcher *dispatcher
+	// events is the channel returned by Events.
+	events *eventStream
+	// The ConnectEvent that was passed to the OnConnect listeners.
+	connectEvent *ConnectEvent
+	// Ring buffer of recent events, used by AttachWithHistory.
+	history     []Event
+	historyNext int
+	// Channel changes that have not yet been passed to the
+	// OnChannelTreeChange listeners.
+	channelTree *channelTree
+	// Audio frame counters, see Stats.
+	audioFramesSent, audioFramesReceived atomic.Uint64
+	// When the client connected to the server.
+	connected time.Time
+	// The published copy of Users and Channels, see UsersSnapshot. viewWanted
+	// is set once a snapshot has been requested.
+	view       atomic.Pointer[stateView]
+	viewWanted atomic.Bool
+	// Connection information included in audit records.
+	auditInfo *auditInfo
+	// The server configuration, as included in snapshots.
+	serverConfig SnapshotServer
+	// The Version message sent by the server.
+	serverVersion Version
+	// logger logs to Config.Logger.
+	logger *slog.Logger
+	// clock is Config.Clock, or SystemClock.
+	clock Clock
+	// loopAudio passes the channels returned by AudioOutgoing to loopRoutine,
+	// when Config.EventLoop is set, which is woken by loopWake.
+	loopMutex sync.Mutex
+	loopAudio []*outgoingAudio
+	loopWake  chan struct{}
+	loopEnded bool
+	// dispatchMutex serializes calls to the event listeners when there is no
+	// dispatcher, as events can be triggered from outside of readRoutine.
+	dispatchMutex sync.Mutex
 }
 
 // Dial is an alias of DialWithDialer(new(net.Dialer), addr, config, nil).
@@ -99,17 +196,128 @@ func Dial(addr string, config *Config) (*Client, error) {
 // server information has been synced, and the OnConnect handlers have been
 // called.
 //
-// nil and an error is returned if server synchronization does not complete by
-// min(time.Now() + dialer.Timeout, dialer.Deadline), or if the server rejects
-// the client.
+// nil and an error is returned if config is invalid (see Config.Validate), if
+// server synchronization does not complete by min(time.Now() + dialer.Timeout,
+// dialer.Deadline), or if the server rejects the client.
+//
+// If the server rejects the client's password and config.CredentialsProvider
+// is set, the client reconnects with the credentials it provides, until it
+// declines or the connection succeeds.
+//
+// tlsConfig can be nil, in which case the default TLS configuration is used.
+// See NewTLSConfig for a configuration that is restricted to the cipher suites
+// that Mumble servers support, and Config.TLSSessionCache for TLS session
+// resumption.
 func DialWithDialer(dialer *net.Dialer, addr string, config *Config, tlsConfig *tls.Config) (*Client, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
 	start := time.Now()
 
-	conn, err := tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	var timeout <-chan time.Time
+	{
+		var deadline time.Time
+		if !dialer.Deadline.IsZero() {
+			deadline = dialer.Deadline
+		}
+		if dialer.Timeout > 0 {
+			diff := start.Add(dialer.Timeout)
+			if deadline.IsZero() || diff.Before(deadline) {
+				deadline = diff
+			}
+		}
+		if !deadline.IsZero() {
+			timer := time.NewTimer(deadline.Sub(start))
+			defer timer.Stop()
+			timeout = timer.C
+		}
+	}
+
+	// credentials is nil until CredentialsProvider has been called, so that
+	// the first attempt uses config's credentials. config itself is not
+	// modified, as it may be shared with other clients.
+	var credentials *Credentials
+	for attempt := 0; ; attempt++ {
+		client, err := dial(dialer, addr, config, tlsConfig, credentials, timeout)
+		reject, ok := err.(*RejectError)
+		if !ok || config.CredentialsProvider == nil {
+			return client, err
+		}
+		if reject.Type != RejectServerPassword && reject.Type != RejectUserCredentials {
+			return nil, err
+		}
+		provided, retry := config.CredentialsProvider(reject, attempt)
+		if !retry {
+			return nil, err
+		}
+		credentials = &provided
+	}
+}
+
+var errConnectionClosed = errors.New("gumble: connection closed before synchronization")
+
+// dial makes a single connection attempt for DialWithDialer. If credentials
+// is nil, config's password and access tokens are used.
+func dial(dialer *net.Dialer, addr string, config *Config, tlsConfig *tls.Config, credentials *Credentials, timeout <-chan time.Time) (*Client, error) {
+	if credentials == nil {
+		tokens, err := config.accessTokens()
+		if err != nil {
+			return nil, err
+		}
+		credentials = &Credentials{
+			Password: config.Password,
+			Tokens:   tokens,
+		}
+	}
+
+	conn, err := tls.DialWithDialer(dialer, "tcp", addr, clientTLSConfig(config, tlsConfig))
+	if err != nil {
+		return nil, err
+	}
+	return connect(conn, addr, config, tlsConfig, *credentials, timeout)
+}
+
+// DialWithConn connects to a Mumble server over conn, an established
+// connection to the server (e.g. a proxied connection, or one that is wrapped
+// for testing). The TLS handshake is performed over conn.
+//
+// tlsConfig can be nil, in which case the default TLS configuration is used.
+// As there is no address to verify the server's certificate against,
+// tlsConfig.ServerName must be set unless tlsConfig.InsecureSkipVerify is.
+//
+// Unlike DialWithDialer, there is no timeout, and the client does not
+// reconnect when Config.CredentialsProvider is set. nil and an error is
+// returned if the server rejects the client, or if conn fails before server
+// synchronization completes. conn is closed if an error is returned.
+func DialWithConn(conn net.Conn, config *Config, tlsConfig *tls.Config) (*Client, error) {
+	if err := config.Validate(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	tokens, err := config.accessTokens()
 	if err != nil {
+		conn.Close()
 		return nil, err
 	}
 
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{}
+	}
+	tlsConn := tls.Client(conn, clientTLSConfig(config, tlsConfig))
+	if err := tlsConn.Handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	credentials := Credentials{
+		Password: config.Password,
+		Tokens:   tokens,
+	}
+	return connect(tlsConn, conn.RemoteAddr().String(), config, tlsConfig, credentials, nil)
+}
+
+// connect authenticates with the server over conn, and waits for server
+// synchronization to complete.
+func connect(conn *tls.Conn, addr string, config *Config, tlsConfig *tls.Config, credentials Credentials, timeout <-chan time.Time) (*Client, error) {
 	client := &Client{
 		Conn:     NewConn(conn),
 		Config:   config,
@@ -122,9 +330,38 @@ func DialWithDialer(dialer *net.Dialer, addr string, config *Config, tlsConfig *
 
 		connect: make(chan *RejectError),
 		end:     make(chan struct{}),
+		done:    make(chan struct{}),
+
+		logger: newLogger(config.Logger, addr),
+	}
+	client.Conn.CoalesceDelay = config.WriteCoalesceDelay
+	client.Conn.OnWriteError = client.writeFailed
+	client.blobs.maxSize = config.BlobCacheSize
+	client.baseAudioInterval = config.AudioInterval
+	client.baseAudioDataBytes = config.AudioDataBytes
+	if config.MaximumPacketBytes > 0 {
+		client.Conn.MaximumPacketBytes = config.MaximumPacketBytes
+	}
+	if config.ReadBufferSize > 0 {
+		client.Conn.ReadBufferSize = config.ReadBufferSize
+	}
+	client.clock = config.Clock
+	if client.clock == nil {
+		client.clock = SystemClock
 	}
+	client.connected = client.clock.Now()
+	client.auditInfo = newAuditInfo(addr, config, conn, tlsConfig)
+	client.audit(AuditConnected, nil)
 
-	go client.readRoutine()
+	if config.EventWorkers > 0 {
+		client.dispatcher = newDispatcher(config.EventOrdering, config.EventWorkers, config.EventQueueSize)
+	}
+
+	if config.EventLoop {
+		client.loopWake = make(chan struct{}, 1)
+	} else {
+		go client.readRoutine()
+	}
 
 	// -------- Build the initial Version packet (with optional overrides) --------
 	// Defaults reproduce original gumble behavior.
@@ -132,6 +369,7 @@ func DialWithDialer(dialer *net.Dialer, addr string, config *Config, tlsConfig *
 	osStr := runtime.GOOS
 	osVer := runtime.GOARCH
 	verU32 := uint32(ClientVersion)
+	verU64 := uint64(ClientVersionV2)
 
 	if client.Config != nil && client.Config.VersionOverride != nil {
 		vo := client.Config.VersionOverride
@@ -144,27 +382,29 @@ func DialWithDialer(dialer *net.Dialer, addr string, config *Config, tlsConfig *
 		if vo.OSVersion != "" {
 			osVer = vo.OSVersion
 		}
-		if vo.VersionUint32 != nil {
+		switch {
+		case vo.VersionUint32 != nil && vo.VersionUint64 != nil:
 			verU32 = *vo.VersionUint32
-		} else if vo.Semver != "" {
-		    if packed, err := packSemver(vo.Semver); err == nil {
-		        verU32 = packed
-		    } else {
-		        // NEW: accept builds like 1.5.735 -> pack 1.5.0 and show build in Release
-		        var maj, min, build uint32
-		        if _, err2 := fmt.Sscanf(vo.Semver, "%d.%d.%d", &maj, &min, &build); err2 == nil {
-		            verU32 = (maj<<16 | min<<8 | 0)
-		            if release == "" {
-		                release = fmt.Sprintf("%d.%d.%d", maj, min, build)
-		            }
-		        }
-		    }
+			verU64 = *vo.VersionUint64
+		case vo.VersionUint32 != nil:
+			verU32 = *vo.VersionUint32
+			verU64 = versionV1ToV2(verU32)
+		case vo.VersionUint64 != nil:
+			verU64 = *vo.VersionUint64
+			verU32 = versionV2ToV1(verU64)
+		case vo.Semver != "":
+			// The patch version is limited to 255 in the Version field, but
+			// not in VersionV2 (e.g. 1.5.735).
+			if packed, err := packSemver(vo.Semver); err == nil {
+				verU64 = packed
+				verU32 = versionV2ToV1(packed)
+			}
 		}
 	}
 
-
 	versionPacket := MumbleProto.Version{
 		Version:   proto.Uint32(verU32),
+		VersionV2: proto.Uint64(verU64),
 		Release:   proto.String(release),
 		Os:        proto.String(osStr),
 		OsVersion: proto.String(osVer),
@@ -172,48 +412,48 @@ func DialWithDialer(dialer *net.Dialer, addr string, config *Config, tlsConfig *
 
 	authenticationPacket := MumbleProto.Authenticate{
 		Username: &client.Config.Username,
-		Password: &client.Config.Password,
+		Password: &credentials.Password,
 		Opus:     proto.Bool(getAudioCodec(audioCodecIDOpus) != nil),
-		Tokens:   client.Config.Tokens,
+		Tokens:   credentials.Tokens,
 	}
 
 	client.Conn.WriteProto(&versionPacket)
 	client.Conn.WriteProto(&authenticationPacket)
 
-	go client.pingRoutine()
-
-	var timeout <-chan time.Time
-	{
-		var deadline time.Time
-		if !dialer.Deadline.IsZero() {
-			deadline = dialer.Deadline
-		}
-		if dialer.Timeout > 0 {
-			diff := start.Add(dialer.Timeout)
-			if deadline.IsZero() || diff.Before(deadline) {
-				deadline = diff
-			}
-		}
-		if !deadline.IsZero() {
-			timer := time.NewTimer(deadline.Sub(start))
-			defer timer.Stop()
-			timeout = timer.C
-		}
+	if config.EventLoop {
+		go client.loopRoutine()
+	} else {
+		go client.pingRoutine()
 	}
 
 	select {
 	case <-timeout:
 		client.Conn.Close()
 		return nil, errors.New("gumble: synchronization timeout")
+	case <-client.end:
+		if err := client.disconnectEvent.Err; err != nil {
+			return nil, err
+		}
+		return nil, errConnectionClosed
 	case err := <-client.connect:
 		if err != nil {
+			client.audit(AuditRejected, func(r *AuditRecord) {
+				r.Reason = err.Reason
+				r.Err = err
+			})
 			client.Conn.Close()
 			return nil, err
 		}
+		client.audit(AuditAuthenticated, nil)
 		return client, nil
 	}
 }
 
+// Clock returns the clock used by the client (see Config.Clock).
+func (c *Client) Clock() Clock {
+	return c.clock
+}
+
 // State returns the current state of the client.
 func (c *Client) State() State {
 	return State(atomic.LoadUint32(&c.state))
@@ -225,52 +465,135 @@ func (c *Client) State() State {
 // before opening another).
 func (c *Client) AudioOutgoing() chan<- AudioBuffer {
 	ch := make(chan AudioBuffer)
-	go func() {
-		var seq int64
-		previous := <-ch
-		for p := range ch {
-			previous.writeAudio(c, seq, false)
-			previous = p
-			seq = (seq + 1) % math.MaxInt32
+	stream := &outgoingAudio{
+		ch: ch,
+	}
+	if c.Config.EventLoop {
+		// Passed to loopRoutine without blocking, as AudioOutgoing can be
+		// called from an event listener, which runs on loopRoutine.
+		c.loopMutex.Lock()
+		ended := c.loopEnded
+		if !ended {
+			c.loopAudio = append(c.loopAudio, stream)
 		}
-		if previous != nil {
-			previous.writeAudio(c, seq, true)
+		c.loopMutex.Unlock()
+		if !ended {
+			select {
+			case c.loopWake <- struct{}{}:
+			default:
+			}
+			return ch
 		}
-	}()
+	}
+	go c.withProfileLabels("audio-outgoing", func() {
+		stream.run(c)
+	})
 	return ch
 }
 
+// outgoingAudio is a channel returned by AudioOutgoing, and the state of the
+// transmission.
+type outgoingAudio struct {
+	ch  chan AudioBuffer
+	seq int64
+	// The last frame received on ch, which is sent once it is known whether
+	// it is the final frame.
+	previous AudioBuffer
+	started  bool
+}
+
+// run sends the frames written to ch until it is closed.
+func (o *outgoingAudio) run(c *Client) {
+	for frame := range o.ch {
+		o.write(c, frame)
+	}
+	o.close(c)
+}
+
+// write sends the previous frame, and holds frame back.
+func (o *outgoingAudio) write(c *Client, frame AudioBuffer) {
+	if c.Config.SkipEncodeWhenMuted && c.selfMuted.Load() {
+		// The transmission ends with the previous frame, and starts again
+		// with the first frame written after the client is unmuted.
+		if o.previous != nil {
+			o.close(c)
+			o.seq = (o.seq + 1) % math.MaxInt32
+			o.previous = nil
+			o.started = false
+		}
+		return
+	}
+	if o.started {
+		if err := o.previous.writeAudio(c, o.seq, false); err != nil {
+			c.logger.Warn("gumble: failed to send audio", slog.Int64("sequence", o.seq), slog.Any("error", err))
+		}
+		o.seq = (o.seq + 1) % math.MaxInt32
+	}
+	o.previous = frame
+	o.started = true
+}
+
+// close sends the final frame, after ch has been closed.
+func (o *outgoingAudio) close(c *Client) {
+	if o.previous != nil {
+		if err := o.previous.writeAudio(c, o.seq, true); err != nil {
+			c.logger.Warn("gumble: failed to send audio", slog.Int64("sequence", o.seq), slog.Any("error", err))
+		}
+	}
+}
+
 // pingRoutine sends ping packets to the server at regular intervals.
 func (c *Client) pingRoutine() {
-	ticker := time.NewTicker(time.Second * 5)
+	ticker := c.clock.NewTicker(time.Second * 5)
 	defer ticker.Stop()
 
-	var timestamp uint64
-	var tcpPingAvg float32
-	var tcpPingVar float32
-	packet := MumbleProto.Ping{
-		Timestamp:  &timestamp,
-		TcpPackets: &c.tcpPacketsReceived,
-		TcpPingAvg: &tcpPingAvg,
-		TcpPingVar: &tcpPingVar,
-	}
-
-	t := time.Now()
+	ping := newPinger(c)
+	t := c.clock.Now()
 	for {
-		timestamp = uint64(t.UnixNano())
-		tcpPingAvg = math.Float32frombits(atomic.LoadUint32(&c.tcpPingAvg))
-		tcpPingVar = math.Float32frombits(atomic.LoadUint32(&c.tcpPingVar))
-		c.Conn.WriteProto(&packet)
+		ping.send(t)
 
 		select {
 		case <-c.end:
 			return
-		case t = <-ticker.C:
+		case t = <-ticker.C():
 			// continue to top of loop
 		}
 	}
 }
 
+// pinger sends ping packets to the server.
+type pinger struct {
+	client     *Client
+	timestamp  uint64
+	tcpPingAvg float32
+	tcpPingVar float32
+	packet     MumbleProto.Ping
+}
+
+func newPinger(c *Client) *pinger {
+	p := &pinger{
+		client: c,
+	}
+	p.packet = MumbleProto.Ping{
+		Timestamp:  &p.timestamp,
+		TcpPackets: &c.tcpPacketsReceived,
+		TcpPingAvg: &p.tcpPingAvg,
+		TcpPingVar: &p.tcpPingVar,
+	}
+	return p
+}
+
+// send sends a ping packet with the given timestamp.
+func (p *pinger) send(t time.Time) {
+	c := p.client
+	p.timestamp = uint64(t.UnixNano())
+	p.tcpPingAvg = math.Float32frombits(atomic.LoadUint32(&c.tcpPingAvg))
+	p.tcpPingVar = math.Float32frombits(atomic.LoadUint32(&c.tcpPingVar))
+	if err := c.Conn.WriteProto(&p.packet); err != nil {
+		c.logger.Warn("gumble: failed to send ping", slog.Any("error", err))
+	}
+}
+
 // readRoutine reads protocol buffer messages from the server.
 func (c *Client) readRoutine() {
 	c.disconnectEvent = DisconnectEvent{
@@ -280,12 +603,73 @@ func (c *Client) readRoutine() {
 
 	for {
 		pType, data, err := c.Conn.ReadPacket()
-		if err != nil {
+		if !c.handleRead(pType, data, err) {
 			break
 		}
-		if int(pType) < len(handlers) {
-			handlers[pType](c, data)
+	}
+	c.readEnded()
+}
+
+// handleRead handles the result of reading a packet from the server. false is
+// returned if the read failed, after which the client is disconnected (see
+// readEnded).
+func (c *Client) handleRead(pType uint16, data []byte, err error) bool {
+	if err != nil {
+		if c.disconnecting.Load() && c.disconnectEvent.Type == DisconnectError {
+			c.disconnectEvent.Type = DisconnectUser
 		}
+		if c.disconnectEvent.Type == DisconnectError {
+			if writeErr := c.writeErr.Load(); writeErr != nil {
+				err = *writeErr
+				if c.State() == StateSynced {
+					c.Config.Listeners.onWriteError(&WriteErrorEvent{
+						Client: c,
+						Err:    err,
+					})
+				}
+			}
+			c.disconnectEvent.Err = err
+			if err == errPacketTooLarge {
+				c.disconnectEvent.Type = DisconnectProtocolError
+			} else if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				c.disconnectEvent.Type = DisconnectPingTimeout
+			}
+		}
+		c.logger.Debug("gumble: read failed", slog.Any("error", err))
+		return false
+	}
+	if int(pType) >= len(handlers) {
+		c.logger.Warn("gumble: unknown packet type", slog.Int("type", int(pType)), slog.Int("length", len(data)))
+		c.protocolError(pType, nil, errUnknownPacket)
+		return true
+	}
+	if err := c.handlePacket(pType, data); err != nil {
+		level := slog.LevelWarn
+		if err == errUnimplementedHandler {
+			level = slog.LevelDebug
+		}
+		c.logger.Log(context.Background(), level, "gumble: failed to handle packet", slog.Int("type", int(pType)), slog.Int("length", len(data)), slog.Any("error", err))
+	}
+	return true
+}
+
+// writeFailed is called when writing to the server has failed. The connection
+// is closed, so that reading from it fails, and readRoutine disconnects the
+// client and triggers the WriteErrorEvent.
+func (c *Client) writeFailed(err error) {
+	c.writeErr.Store(&err)
+	if conn, ok := c.Conn.Conn.(*tls.Conn); ok {
+		// Closing the TLS connection would try to write to it.
+		conn.NetConn().Close()
+	} else {
+		c.Conn.Conn.Close()
+	}
+}
+
+// readEnded disconnects the client after reading from the server has failed.
+func (c *Client) readEnded() {
+	if policy := c.Config.ReconnectPolicy; policy != nil {
+		c.disconnectEvent.Reconnect = policy.ShouldReconnect(c.disconnectEvent.Type)
 	}
 
 	wasSynced := c.State() == StateSynced
@@ -294,33 +678,138 @@ func (c *Client) readRoutine() {
 	if wasSynced {
 		c.Config.Listeners.onDisconnect(&c.disconnectEvent)
 	}
+	if c.dispatcher != nil {
+		c.dispatcher.Close()
+		c.dispatcher.Wait()
+	}
+
+	c.eventsMu.Lock()
+	c.eventsEnded = true
+	c.eventsMu.Unlock()
+	c.eventStream().close()
+	close(c.done)
+}
+
+// triggerAsync calls trigger, which triggers an event from outside of
+// readRoutine (e.g. from a timer), unless the client has disconnected and
+// the DisconnectEvent has been handled, after which no events are triggered.
+func (c *Client) triggerAsync(trigger func()) {
+	c.eventsMu.RLock()
+	defer c.eventsMu.RUnlock()
+	if !c.eventsEnded {
+		trigger()
+	}
+}
+
+// Done returns a channel that is closed once the client has disconnected and
+// the listeners of the DisconnectEvent have returned. No events are triggered
+// after the channel is closed.
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// handlePacket decodes and handles a control packet.
+func (c *Client) handlePacket(pType uint16, data []byte) error {
+	var message proto.Message
+	var err error
+	if pType == PacketUDPTunnel {
+		if c.Config.ProfileLabels {
+			c.withProfileLabels("audio-incoming", func() {
+				err = c.handleUDPTunnel(data)
+			})
+		} else {
+			err = c.handleUDPTunnel(data)
+		}
+	} else if message, err = ParseControlPacket(pType, data); err == nil {
+		if c.Config.StrictProtocol {
+			if verr := c.validate(message); verr != nil {
+				c.protocolError(pType, message, verr)
+			}
+		}
+		err = handlers[pType](c, message)
+		c.publishState()
+	}
+	switch err {
+	case nil, errUnimplementedHandler, errUnsupportedAudio, errNoCodec:
+	default:
+		c.protocolError(pType, message, err)
+	}
+	return err
 }
 
 // RequestUserList requests that the server's registered user list be sent to
 // the client.
-func (c *Client) RequestUserList() {
+func (c *Client) RequestUserList()error {
 	packet := MumbleProto.UserList{}
-	c.Conn.WriteProto(&packet)
+	return c.Conn.WriteProto(&packet)
 }
 
 // RequestBanList requests that the server's ban list be sent to the client.
-func (c *Client) RequestBanList() {
+func (c *Client) RequestBanList()error {
 	packet := MumbleProto.BanList{
 		Query: proto.Bool(true),
 	}
-	c.Conn.WriteProto(&packet)
+	return c.Conn.WriteProto(&packet)
+}
+
+// RequestUserIDs requests the user IDs of the registered users with the given
+// names. The result is passed to the OnQueryUsers listeners.
+func (c *Client) RequestUserIDs(names ...string)error {
+	packet := MumbleProto.QueryUsers{
+		Names: names,
+	}
+	return c.Conn.WriteProto(&packet)
+}
+
+// RequestUserNames requests the names of the registered users with the given
+// user IDs. The result is passed to the OnQueryUsers listeners.
+func (c *Client) RequestUserNames(ids ...uint32)error {
+	packet := MumbleProto.QueryUsers{
+		Ids: ids,
+	}
+	return c.Conn.WriteProto(&packet)
 }
 
-// Disconnect disconnects the client from the server.
+// MaximumMessageLength returns the maximum length, in bytes, of a text
+// message that does not contain an image. Zero is returned if the limit is not
+// known or if there is no limit.
+func (c *Client) MaximumMessageLength() int {
+	return int(atomic.LoadInt32(&c.maximumMessageLength))
+}
+
+// MaximumImageMessageLength returns the maximum length, in bytes, of a text
+// message that contains an image. Zero is returned if the limit is not known
+// or if there is no limit.
+func (c *Client) MaximumImageMessageLength() int {
+	return int(atomic.LoadInt32(&c.maximumImageMessageLength))
+}
+
+// Disconnect disconnects the client from the server. It can be called from
+// any goroutine, including from event listeners, and more than once; calls
+// after the first have no effect, and nil is always returned.
+//
+// Disconnect does not wait for the client to disconnect. See Done.
 func (c *Client) Disconnect() error {
-	if c.State() == StateDisconnected {
-		return errors.New("gumble: client is already disconnected")
+	if c.State() == StateDisconnected || !c.disconnecting.CompareAndSwap(false, true) {
+		return nil
 	}
-	c.disconnectEvent.Type = DisconnectUser
 	c.Conn.Close()
 	return nil
 }
 
+// ServerVersion returns the version that the server sent when the client
+// connected: its version number, release (e.g. "1.5.735"), and operating
+// system. The zero Version is returned if the server did not send one.
+//
+// See Version.FullVersion and Version.AtLeast for the server's version
+// number, which includes patch versions greater than 255 for servers running
+// Mumble 1.5 or later.
+func (c *Client) ServerVersion() Version {
+	c.volatile.RLock()
+	defer c.volatile.RUnlock()
+	return c.serverVersion
+}
+
 // Do executes f in a thread-safe manner. It ensures that Client and its
 // associated data will not be changed during the lifetime of the function
 // call.
@@ -331,7 +820,9 @@ func (c *Client) Do(f func()) {
 	f()
 }
 
-// Send will send a Message to the server.
-func (c *Client) Send(message Message) {
-	message.writeMessage(c)
+// Send will send a Message to the server. An error is returned if the message
+// could not be written, after which the client disconnects (see
+// WriteErrorEvent).
+func (c *Client) Send(message Message) error {
+	return message.writeMessage(c)
 }
@@ -0,0 +1,38 @@
+package gumble
+
+import (
+	"time"
+)
+
+// ClientStats contains traffic counters of a client's connection to the
+// server.
+type ClientStats struct {
+	// When the connection to the server was established.
+	Connected time.Time `json:"connected"`
+
+	// The number of control protocol packets (including tunneled audio
+	// packets) received from and sent to the server, and their size in bytes.
+	PacketsReceived uint64 `json:"packets_received"`
+	PacketsSent     uint64 `json:"packets_sent"`
+	BytesReceived   uint64 `json:"bytes_received"`
+	BytesSent       uint64 `json:"bytes_sent"`
+
+	// The number of audio frames decoded from other users, and sent by the
+	// client.
+	AudioFramesReceived uint64 `json:"audio_frames_received"`
+	AudioFramesSent     uint64 `json:"audio_frames_sent"`
+}
+
+// Stats returns the client's traffic counters. It is safe to call from any
+// goroutine.
+func (c *Client) Stats() ClientStats {
+	return ClientStats{
+		Connected:           c.connected,
+		PacketsReceived:     c.Conn.packetsRead.Load(),
+		PacketsSent:         c.Conn.packetsWritten.Load(),
+		BytesReceived:       c.Conn.bytesRead.Load(),
+		BytesSent:           c.Conn.bytesWritten.Load(),
+		AudioFramesReceived: c.audioFramesReceived.Load(),
+		AudioFramesSent:     c.audioFramesSent.Load(),
+	}
+}
@@ -1,18 +1,28 @@
 package gumble
 
 import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
 	"time"
 )
 
 // VersionOverride controls the initial Version message sent during the TLS handshake.
 // If fields are empty/nil, gumble's defaults are used.
 type VersionOverride struct {
-    Release      string  // e.g. "my-bot/2.3"
-    OS           string  // e.g. "windows" or "linux"
-    OSVersion    string  // e.g. "amd64"
-    // One of Semver or VersionUint32 may be used to set Version:
-    Semver        string  // "MAJOR.MINOR.PATCH" -> packed (maj<<16 | min<<8 | pat)
-    VersionUint32 *uint32 // direct override, if you already have the packed value
+	Release   string // e.g. "my-bot/2.3"
+	OS        string // e.g. "windows" or "linux"
+	OSVersion string // e.g. "amd64"
+	// One of Semver, or VersionUint32 and VersionUint64, may be used to set
+	// the version. Semver ("MAJOR.MINOR.PATCH") sets both fields; the patch
+	// version is limited to 255 in Version.
+	Semver        string
+	VersionUint32 *uint32 // direct override, if you already have the packed value
+	// VersionUint64 overrides the Mumble 1.5 version_v2 field (see
+	// Version.VersionV2). If only one of VersionUint32 and VersionUint64 is
+	// set, the other is derived from it.
+	VersionUint64 *uint64
 }
 
 // Config holds the Mumble configuration used by Client. A single Config should
@@ -30,6 +40,10 @@ type Config struct {
     VersionOverride *VersionOverride
 	
 	Tokens AccessTokens
+	// TokenProvider, if non-nil, provides the access tokens that are sent to
+	// the server each time the client connects, instead of Tokens. See also
+	// Client.RefreshTokens.
+	TokenProvider TokenProvider
 
 	// AudioInterval is the interval at which audio packets are sent. Valid
 	// values are: 10ms, 20ms, 40ms, and 60ms.
@@ -40,14 +54,197 @@ type Config struct {
 	// The event listeners used when client events are triggered.
 	Listeners      Listeners
 	AudioListeners AudioListeners
+
+	// EventWorkers is the number of goroutines that call the event listeners.
+	// If zero, listeners are called synchronously from the goroutine that reads
+	// from the server, and a slow listener delays the handling of all incoming
+	// packets.
+	//
+	// When set, listeners may observe client state that is newer than the
+	// event being handled.
+	EventWorkers int
+	// EventOrdering is the ordering guarantee kept when EventWorkers is set.
+	// Defaults to DispatchSerial.
+	EventOrdering DispatchOrdering
+	// EventQueueSize is the number of events that each event worker can have
+	// queued before the client stops reading from the server. Defaults to
+	// DefaultEventQueueSize.
+	EventQueueSize int
+
+	// RecoverListenerPanics, when true, recovers panics raised by event
+	// listeners. Rather than crashing the program, the panic is passed to the
+	// OnListenerError listeners and the remaining listeners for the event are
+	// called.
+	RecoverListenerPanics bool
+
+	// EventBufferSize is the buffer size of the channel returned by
+	// Client.Events. Defaults to DefaultEventBufferSize.
+	EventBufferSize int
+	// EventOverflowPolicy specifies what happens when an event is triggered
+	// while the channel returned by Client.Events is full. Defaults to
+	// EventOverflowBlock.
+	EventOverflowPolicy EventOverflowPolicy
+
+	// ReconnectPolicy, if non-nil, describes when the client should reconnect
+	// after being disconnected. See ReconnectPolicy.
+	ReconnectPolicy *ReconnectPolicy
+
+	// EventHistory is the number of recent events that are kept by the client
+	// for Client.AttachWithHistory. If zero, no events are kept.
+	EventHistory int
+
+	// TLSSessionCache is used to resume TLS sessions, which makes reconnecting
+	// to a server faster. It is only used if the tls.Config passed to
+	// DialWithDialer does not have its own ClientSessionCache, and does not
+	// disable session tickets. NewConfig sets it to a cache that is shared by
+	// all configs; set it to nil to disable session resumption.
+	TLSSessionCache tls.ClientSessionCache
+
+	// CredentialsProvider, if non-nil, is called by DialWithDialer when the
+	// server rejects the client because of an incorrect server or user
+	// password (RejectServerPassword or RejectUserCredentials). attempt is
+	// the number of times it has been called before during the same dial.
+	//
+	// If it returns true, the client reconnects using the returned
+	// credentials in place of Password and Tokens, which are not modified.
+	// Otherwise, DialWithDialer returns the rejection.
+	CredentialsProvider func(reject *RejectError, attempt int) (Credentials, bool)
+
+	// AuditHook, if non-nil, is called with a record of each security
+	// relevant event (e.g. connecting, authenticating, being kicked, or being
+	// denied permission), for audit logging. It is called synchronously, and
+	// must not block.
+	AuditHook func(record *AuditRecord)
+
+	// ChannelTreeDelay is how long the client waits after a channel is
+	// created, removed, or changed before triggering a ChannelTreeChangeEvent.
+	// Changes made within the delay are combined into a single event. If zero,
+	// ChannelTreeChangeEvents are not triggered.
+	ChannelTreeDelay time.Duration
+
+	// Logger, if non-nil, receives the client's protocol level logs: packets
+	// that could not be handled, failures to send pings and audio, and other
+	// anomalies that do not cause a disconnect. Most messages are logged at
+	// slog.LevelDebug; those that may indicate a problem are logged at
+	// slog.LevelWarn.
+	Logger slog.Handler
+
+	// StrictProtocol, when true, triggers a ProtocolErrorEvent for each packet
+	// from the server that is malformed or out of spec, instead of silently
+	// ignoring the problem. It is intended for testing server
+	// implementations.
+	StrictProtocol bool
+
+	// ProfileLabels, when true, applies pprof labels to the goroutines that
+	// handle the client's audio ("gumble": "audio-outgoing" or
+	// "audio-incoming", and "server": the server address), so that their
+	// cost can be attributed in CPU profiles.
+	ProfileLabels bool
+
+	// MaximumPacketBytes, if non-zero, is the size of the largest packet that
+	// is accepted from the server. The client disconnects when a larger packet
+	// is received. Defaults to 10 MiB.
+	MaximumPacketBytes int
+	// ReadBufferSize, if non-zero, is the size of the buffer into which the
+	// client reads packets, which is kept for the lifetime of the connection.
+	// Larger packets are read into temporary buffers. Defaults to 64 KiB;
+	// lower values reduce the client's memory use on constrained devices.
+	ReadBufferSize int
+
+	// EventLoop, when true, runs the client on a single goroutine (plus one
+	// that blocks reading from the server), which handles the packets from the
+	// server, sends pings, and sends the audio written to the channels
+	// returned by Client.AudioOutgoing, in turn. This reduces the number of
+	// goroutines and context switches, and, with Clock, makes the order in
+	// which the client does things deterministic.
+	//
+	// The channels returned by Client.AudioOutgoing are sent from one at a
+	// time: a channel is only read from once the previous one has been
+	// closed.
+	EventLoop bool
+
+	// If SkipEncodeWhenMuted is set, the audio written to the channels
+	// returned by Client.AudioOutgoing is discarded, rather than encoded and
+	// sent, while Client.Self is self-muted. If SkipDecodeWhenDeafened is
+	// set, incoming audio is discarded, rather than decoded and passed to the
+	// AudioListeners, while Client.Self is self-deafened. The server does not
+	// forward such audio anyway.
+	SkipEncodeWhenMuted    bool
+	SkipDecodeWhenDeafened bool
+
+	// AudioPlayoutDelay is how long incoming audio is buffered before it is
+	// passed to the AudioListeners, so that packets that arrive late or out
+	// of order can still be played in order and without gaps. A longer delay
+	// gives smoother audio at the cost of latency (e.g. 20ms for an intercom,
+	// 200ms for relaying music). If zero, packets are passed as soon as they
+	// are decoded. See also User.SetPlayoutDelay.
+	AudioPlayoutDelay time.Duration
+
+	// SplitMessage, if non-nil, splits a TextMessage that is longer than the
+	// server's message length limit (limit, or imageLimit if it contains an
+	// image) into messages that are sent in turn. If nil, such messages are
+	// not sent, and Client.Send returns ErrMessageTooLong. See
+	// gumbleutil.SplitMessage.
+	SplitMessage func(message string, limit, imageLimit int) []string
+
+	// BlobCacheSize is the total size, in bytes, of the comments, channel
+	// descriptions, and textures that the client keeps after receiving them.
+	// When the server later sends the hash of a kept blob (e.g. as
+	// User.CommentHash), it is filled in without being requested. If zero,
+	// blobs are not kept.
+	BlobCacheSize int
+
+	// AdjustToBandwidth, if true, lowers the audio data size, and raises the
+	// audio interval if needed, so that outgoing audio (including the
+	// packets' overhead) fits the maximum bandwidth that the server sends, and
+	// sets the encoder's bitrate to match (see AudioBitrateEncoder). An
+	// AudioBandwidthEvent is triggered when the settings are changed. The
+	// Config is not modified: AudioInterval and AudioDataBytes, as they are
+	// when the client connects, are the most that are used, and audio sources
+	// should pace audio with Client.AudioInterval.
+	AdjustToBandwidth bool
+
+	// SlowListenerTimeout, if non-zero, is how long an event listener can run
+	// before a SlowListenerEvent is triggered. If DetachSlowListeners is also
+	// set, the listener is detached, so that it is not called with later
+	// events.
+	SlowListenerTimeout time.Duration
+	DetachSlowListeners bool
+
+	// WriteCoalesceDelay, if non-zero, is how long packets that are written to
+	// the server can be held back so that they are sent together, in a single
+	// write. This reduces the number of system calls and TCP segments when
+	// sending many packets (e.g. audio alongside control packets), at the
+	// cost of up to this much added latency. A few milliseconds is enough.
+	WriteCoalesceDelay time.Duration
+
+	// Clock is the source of time used by the client for its pings and by
+	// audio sources to pace outgoing audio. If nil, SystemClock is used.
+	Clock Clock
 }
 
-// NewConfig returns a new Config struct with default values set.
-func NewConfig() *Config {
-	return &Config{
+// NewConfig returns a new Config struct with default values set, and then
+// applies opts to it in order. The returned Config's fields can still be
+// modified directly:
+//
+//  config := gumble.NewConfig(
+//    gumble.WithUsername("gumble-bot"),
+//    gumble.WithReconnect(gumble.NewReconnectPolicy()),
+//  )
+//  config.StrictProtocol = true
+func NewConfig(opts ...Option) *Config {
+	config := &Config{
 		AudioInterval:  AudioDefaultInterval,
 		AudioDataBytes: AudioDefaultDataBytes,
+
+		ChannelTreeDelay: DefaultChannelTreeDelay,
+		TLSSessionCache:  defaultTLSSessionCache,
+		BlobCacheSize:    DefaultBlobCacheSize,
+	}
+	for _, opt := range opts {
+		opt(config)
 	}
+	return config
 }
 
 // Attach is an alias of c.Listeners.Attach.
@@ -55,11 +252,90 @@ func (c *Config) Attach(l EventListener) Detacher {
 	return c.Listeners.Attach(l)
 }
 
+// AttachFunc is an alias of c.Listeners.AttachFunc.
+func (c *Config) AttachFunc(f EventFunc) Detacher {
+	return c.Listeners.AttachFunc(f)
+}
+
+// Once is an alias of c.Listeners.Once.
+func (c *Config) Once(f EventFunc) Detacher {
+	return c.Listeners.Once(f)
+}
+
+// AttachAll is an alias of c.Listeners.AttachAll.
+func (c *Config) AttachAll(f func(event Event)) Detacher {
+	return c.Listeners.AttachAll(f)
+}
+
+// Use is an alias of c.Listeners.Use.
+func (c *Config) Use(m Middleware) Detacher {
+	return c.Listeners.Use(m)
+}
+
 // AttachAudio is an alias of c.AudioListeners.Attach.
 func (c *Config) AttachAudio(l AudioListener) Detacher {
 	return c.AudioListeners.Attach(l)
 }
 
+// ConfigError is an invalid value in a Config.
+type ConfigError struct {
+	// The name of the invalid field (e.g. "VersionOverride.Semver").
+	Field  string
+	Reason string
+}
+
+func (e *ConfigError) Error() string {
+	return "gumble: invalid Config." + e.Field + ": " + e.Reason
+}
+
+// Validate checks that the configuration's values are valid. The returned
+// error contains a *ConfigError for each invalid value. Validate is called by
+// DialWithDialer and DialWithConn before connecting.
+func (c *Config) Validate() error {
+	var errs []error
+	invalid := func(field, reason string, args ...interface{}) {
+		errs = append(errs, &ConfigError{Field: field, Reason: fmt.Sprintf(reason, args...)})
+	}
+
+	if c.Username == "" {
+		invalid("Username", "missing username")
+	}
+	switch c.AudioInterval {
+	case 10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond, 60 * time.Millisecond:
+	case 0:
+		invalid("AudioInterval", "not set (use NewConfig to create a Config with default values)")
+	default:
+		invalid("AudioInterval", "%v is not valid (use 10ms, 20ms, 40ms, or 60ms)", c.AudioInterval)
+	}
+	if c.AudioDataBytes <= 0 {
+		invalid("AudioDataBytes", "%d is not positive", c.AudioDataBytes)
+	}
+	if c.MaximumPacketBytes < 0 {
+		invalid("MaximumPacketBytes", "must not be negative")
+	}
+	if c.ReadBufferSize < 0 {
+		invalid("ReadBufferSize", "must not be negative")
+	}
+	if c.SlowListenerTimeout < 0 {
+		invalid("SlowListenerTimeout", "must not be negative")
+	} else if c.DetachSlowListeners && c.SlowListenerTimeout == 0 {
+		invalid("DetachSlowListeners", "set without SlowListenerTimeout")
+	}
+	if vo := c.VersionOverride; vo != nil {
+		if (vo.VersionUint32 != nil || vo.VersionUint64 != nil) && vo.Semver != "" {
+			invalid("VersionOverride", "both Semver and VersionUint32 or VersionUint64 are set")
+		} else if vo.Semver != "" {
+			var major, minor, patch uint32
+			if _, err := fmt.Sscanf(vo.Semver, "%d.%d.%d", &major, &minor, &patch); err != nil {
+				invalid("VersionOverride.Semver", "%q is not MAJOR.MINOR.PATCH", vo.Semver)
+			} else if major > 0xFFFF || minor > 0xFF || patch > 0xFFFF {
+				invalid("VersionOverride.Semver", "%q is out of range", vo.Semver)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // AudioFrameSize returns the appropriate audio frame size, based off of the
 // audio interval.
 func (c *Config) AudioFrameSize() int {
@@ -4,8 +4,10 @@ import (
 	"encoding/binary"
 	"errors"
 	"io"
+	"math"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/golang/protobuf/proto"
@@ -16,15 +18,44 @@ import (
 // DefaultPort is the default port on which Mumble servers listen.
 const DefaultPort = 64738
 
+var errPacketTooLarge = errors.New("gumble: packet larger than maximum allowed size")
+
 // Conn represents a control protocol connection to a Mumble client/server.
 type Conn struct {
 	sync.Mutex
 	net.Conn
 
+	// MaximumPacketBytes is the size of the largest packet that is accepted.
+	// ReadPacket returns an error, without reading the packet's data, when a
+	// larger packet is received.
 	MaximumPacketBytes int
-	Timeout            time.Duration
+	// ReadBufferSize is the size of the buffer into which packets are read,
+	// which is kept for the lifetime of the connection. Larger packets are
+	// read into temporary buffers.
+	ReadBufferSize int
+	Timeout        time.Duration
+	// If non-zero, written packets are queued for up to CoalesceDelay, or
+	// until CoalesceBytes are queued, and are then written together. It must
+	// be set before the first write.
+	CoalesceDelay time.Duration
+	CoalesceBytes int
+	// OnWriteError, if non-nil, is called when a write to the connection
+	// first fails (including a delayed write, when coalescing). Later writes
+	// return the same error. It is called with c locked, and must not write
+	// to c.
+	OnWriteError func(err error)
 
 	buffer []byte
+
+	// Queued packets, when coalescing writes.
+	pending    []byte
+	flushTimer *time.Timer
+	// The error returned by the write that failed.
+	writeErr error
+
+	// Traffic counters, see Client.Stats.
+	packetsRead, packetsWritten atomic.Uint64
+	bytesRead, bytesWritten     atomic.Uint64
 }
 
 // NewConn creates a new Conn with the given net.Conn.
@@ -33,11 +64,14 @@ func NewConn(conn net.Conn) *Conn {
 		Conn:               conn,
 		Timeout:            time.Second * 20,
 		MaximumPacketBytes: 1024 * 1024 * 10,
+		ReadBufferSize:     64 * 1024,
+		CoalesceBytes:      16 * 1024,
 	}
 }
 
 // ReadPacket reads a packet from the server. Returns the packet type, the
-// packet data, and nil on success.
+// packet data, and nil on success. The packet data is only valid until the
+// next call to ReadPacket.
 //
 // This function should only be called by a single go routine.
 func (c *Conn) ReadPacket() (uint16, []byte, error) {
@@ -48,91 +82,171 @@ func (c *Conn) ReadPacket() (uint16, []byte, error) {
 	}
 	pType := binary.BigEndian.Uint16(header[:])
 	pLength := binary.BigEndian.Uint32(header[2:])
-	pLengthInt := int(pLength)
-	if pLengthInt > c.MaximumPacketBytes {
-		return 0, nil, errors.New("gumble: packet larger than maximum allowed size")
+	if uint64(pLength) > uint64(c.MaximumPacketBytes) {
+		return 0, nil, errPacketTooLarge
 	}
-	if pLengthInt > len(c.buffer) {
-		c.buffer = make([]byte, pLengthInt)
+	pLengthInt := int(pLength)
+	var buffer []byte
+	if pLengthInt <= c.ReadBufferSize {
+		if len(c.buffer) < c.ReadBufferSize {
+			c.buffer = make([]byte, c.ReadBufferSize)
+		}
+		buffer = c.buffer[:pLengthInt]
+	} else {
+		buffer = make([]byte, pLengthInt)
 	}
-	if _, err := io.ReadFull(c.Conn, c.buffer[:pLengthInt]); err != nil {
+	if _, err := io.ReadFull(c.Conn, buffer); err != nil {
 		return 0, nil, err
 	}
-	return pType, c.buffer[:pLengthInt], nil
+	c.packetsRead.Add(1)
+	c.bytesRead.Add(uint64(len(header) + pLengthInt))
+	return pType, buffer, nil
 }
 
 // WriteAudio writes an audio packet to the connection.
 func (c *Conn) WriteAudio(format, target byte, sequence int64, final bool, data []byte, X, Y, Z *float32) error {
-	var buff [1 + varint.MaxVarintLen*2]byte
-	buff[0] = (format << 5) | target
-	n := varint.Encode(buff[1:], sequence)
-	if n == 0 {
-		return errors.New("gumble: varint out of range")
-	}
+	b := getPacketBuffer()
+	defer putPacketBuffer(b)
+
+	packet := append(*b, make([]byte, packetHeaderSize)...)
+	packet = append(packet, (format<<5)|target)
+	packet = varint.Append(packet, sequence)
 	l := int64(len(data))
 	if final {
 		l |= 0x2000
 	}
-	m := varint.Encode(buff[1+n:], l)
-	if m == 0 {
-		return errors.New("gumble: varint out of range")
-	}
-	header := buff[:1+n+m]
+	packet = varint.Append(packet, l)
+	packet = append(packet, data...)
 
-	var positionalLength int
 	if X != nil {
-		positionalLength = 3 * 4
+		packet = binary.LittleEndian.AppendUint32(packet, math.Float32bits(*X))
+		packet = binary.LittleEndian.AppendUint32(packet, math.Float32bits(*Y))
+		packet = binary.LittleEndian.AppendUint32(packet, math.Float32bits(*Z))
 	}
+	*b = packet
 
 	c.Lock()
 	defer c.Unlock()
+	return c.writePacket(PacketUDPTunnel, packet)
+}
 
-	if err := c.writeHeader(1, uint32(len(header)+len(data)+positionalLength)); err != nil {
-		return err
-	}
-	if _, err := c.Conn.Write(header); err != nil {
-		return err
-	}
-	if _, err := c.Conn.Write(data); err != nil {
-		return err
-	}
+// WritePacket writes a data packet of the given type to the connection.
+func (c *Conn) WritePacket(ptype uint16, data []byte) error {
+	b := getPacketBuffer()
+	defer putPacketBuffer(b)
+	packet := append(*b, make([]byte, packetHeaderSize)...)
+	packet = append(packet, data...)
+	*b = packet
 
-	if positionalLength > 0 {
-		if err := binary.Write(c.Conn, binary.LittleEndian, *X); err != nil {
+	c.Lock()
+	defer c.Unlock()
+	return c.writePacket(ptype, packet)
+}
+
+// packetHeaderSize is the size of the header that precedes the data of each
+// packet: the packet type, and the length of the data.
+const packetHeaderSize = 6
+
+// writePacket fills in the header of packet, whose first packetHeaderSize
+// bytes are reserved for it, and writes it to the connection with a single
+// call, so that it is sent in a single TLS record. If writes are coalesced,
+// the packet is queued instead.
+//
+// c must be locked when calling this function.
+func (c *Conn) writePacket(pType uint16, packet []byte) error {
+	binary.BigEndian.PutUint16(packet, pType)
+	binary.BigEndian.PutUint32(packet[2:], uint32(len(packet)-packetHeaderSize))
+	if c.writeErr != nil {
+		return c.writeErr
+	}
+	if c.CoalesceDelay <= 0 {
+		if _, err := c.Conn.Write(packet); err != nil {
+			c.writeFailed(err)
 			return err
 		}
-		if err := binary.Write(c.Conn, binary.LittleEndian, *Y); err != nil {
-			return err
+	} else {
+		if len(c.pending) == 0 {
+			if c.flushTimer == nil {
+				c.flushTimer = time.AfterFunc(c.CoalesceDelay, c.flushQueued)
+			} else {
+				c.flushTimer.Reset(c.CoalesceDelay)
+			}
 		}
-		if err := binary.Write(c.Conn, binary.LittleEndian, *Z); err != nil {
-			return err
+		c.pending = append(c.pending, packet...)
+		if len(c.pending) >= c.CoalesceBytes {
+			if err := c.flush(); err != nil {
+				return err
+			}
 		}
 	}
-
+	c.packetsWritten.Add(1)
+	c.bytesWritten.Add(uint64(len(packet)))
 	return nil
 }
 
-// WritePacket writes a data packet of the given type to the connection.
-func (c *Conn) WritePacket(ptype uint16, data []byte) error {
+// Flush writes the packets that have been queued by write coalescing.
+func (c *Conn) Flush() error {
 	c.Lock()
 	defer c.Unlock()
-	if err := c.writeHeader(uint16(ptype), uint32(len(data))); err != nil {
-		return err
+	return c.flush()
+}
+
+// Close flushes the queued packets, and closes the connection. The packets are
+// dropped if another write is in progress, so that Close does not block.
+func (c *Conn) Close() error {
+	if c.TryLock() {
+		c.flush()
+		c.Unlock()
 	}
-	if _, err := c.Conn.Write(data); err != nil {
-		return err
+	return c.Conn.Close()
+}
+
+// flushQueued is called when the coalescing delay of the oldest queued packet
+// has passed. A write error is returned by the next write, and reported to
+// OnWriteError.
+func (c *Conn) flushQueued() {
+	c.Flush()
+}
+
+// flush writes the queued packets.
+//
+// c must be locked when calling this function.
+func (c *Conn) flush() error {
+	if len(c.pending) == 0 {
+		return c.writeErr
 	}
-	return nil
+	if c.flushTimer != nil {
+		c.flushTimer.Stop()
+	}
+	_, err := c.Conn.Write(c.pending)
+	if cap(c.pending) > maxPooledBufferSize {
+		c.pending = nil
+	} else {
+		c.pending = c.pending[:0]
+	}
+	if err != nil {
+		c.writeFailed(err)
+	}
+	return err
 }
 
-func (c *Conn) writeHeader(pType uint16, pLength uint32) error {
-	var header [6]byte
-	binary.BigEndian.PutUint16(header[:], pType)
-	binary.BigEndian.PutUint32(header[2:], pLength)
-	if _, err := c.Conn.Write(header[:]); err != nil {
-		return err
+// writeFailed records err, which was returned by a write to the connection.
+//
+// c must be locked when calling this function.
+func (c *Conn) writeFailed(err error) {
+	if c.writeErr != nil {
+		return
 	}
-	return nil
+	c.writeErr = err
+	if c.OnWriteError != nil {
+		c.OnWriteError(err)
+	}
+}
+
+// appendMarshaler is implemented by the messages that have hand-written
+// marshalers (see MumbleProto/marshal.go).
+type appendMarshaler interface {
+	MarshalAppend(b []byte) []byte
 }
 
 // WriteProto writes a protocol buffer message to the connection.
@@ -192,9 +306,20 @@ func (c *Conn) WriteProto(message proto.Message) error {
 	default:
 		return errors.New("gumble: unknown message type")
 	}
-	data, err := proto.Marshal(message)
-	if err != nil {
+	if m, ok := message.(appendMarshaler); ok {
+		b := getPacketBuffer()
+		defer putPacketBuffer(b)
+		packet := m.MarshalAppend(append(*b, make([]byte, packetHeaderSize)...))
+		*b = packet
+
+		c.Lock()
+		defer c.Unlock()
+		return c.writePacket(protoType, packet)
+	}
+	buffer := getProtoBuffer()
+	defer putProtoBuffer(buffer)
+	if err := buffer.Marshal(message); err != nil {
 		return err
 	}
-	return c.WritePacket(protoType, data)
+	return c.WritePacket(protoType, buffer.Bytes())
 }
@@ -29,29 +29,29 @@ type ContextAction struct {
 }
 
 // Trigger will trigger the context action in the context of the server.
-func (c *ContextAction) Trigger() {
+func (c *ContextAction) Trigger()error {
 	packet := MumbleProto.ContextAction{
 		Action: &c.Name,
 	}
-	c.client.Conn.WriteProto(&packet)
+	return c.client.Conn.WriteProto(&packet)
 }
 
 // TriggerUser will trigger the context action in the context of the given
 // user.
-func (c *ContextAction) TriggerUser(user *User) {
+func (c *ContextAction) TriggerUser(user *User)error {
 	packet := MumbleProto.ContextAction{
 		Session: &user.Session,
 		Action:  &c.Name,
 	}
-	c.client.Conn.WriteProto(&packet)
+	return c.client.Conn.WriteProto(&packet)
 }
 
 // TriggerChannel will trigger the context action in the context of the given
 // channel.
-func (c *ContextAction) TriggerChannel(channel *Channel) {
+func (c *ContextAction) TriggerChannel(channel *Channel)error {
 	packet := MumbleProto.ContextAction{
 		ChannelId: &channel.ID,
 		Action:    &c.Name,
 	}
-	c.client.Conn.WriteProto(&packet)
+	return c.client.Conn.WriteProto(&packet)
 }
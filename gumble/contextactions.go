@@ -1,5 +1,12 @@
 package gumble
 
+import (
+	"errors"
+	"sync"
+
+	"layeh.com/gumble/gumble/MumbleProto"
+)
+
 // ContextActions is a map of ContextActions.
 type ContextActions map[string]*ContextAction
 
@@ -10,3 +17,130 @@ func (c ContextActions) create(action string) *ContextAction {
 	c[action] = contextAction
 	return contextAction
 }
+
+var (
+	errUnknownContextAction     = errors.New("gumble: unknown context action")
+	errInvalidContextActionType = errors.New("gumble: invalid context action type")
+)
+
+// registeredContextActions holds the context actions that the client has
+// registered with the server.
+type registeredContextActions struct {
+	mu      sync.Mutex
+	actions ContextActions
+}
+
+// RegisterContextAction registers a context action with the server, which
+// offers it to users in the given contexts (e.g. ContextActionUser |
+// ContextActionChannel). When the action is triggered, the server sends it
+// to the client, which passes it to the OnContextAction listeners.
+//
+// Murmur only accepts context actions from its server-side plugins, and
+// ignores those registered by clients.
+func (c *Client) RegisterContextAction(name, label string, context ContextActionType) (*ContextAction, error) {
+	if name == "" {
+		return nil, errors.New("gumble: context action name is empty")
+	}
+	if !validContextActionType(context) {
+		return nil, errInvalidContextActionType
+	}
+	contextAction := &ContextAction{
+		Type:   context,
+		Name:   name,
+		Label:  label,
+		client: c,
+	}
+	if err := c.modifyContextAction(MumbleProto.ContextActionModify_Add, name, label, context); err != nil {
+		return nil, err
+	}
+
+	c.registered.mu.Lock()
+	if c.registered.actions == nil {
+		c.registered.actions = make(ContextActions)
+	}
+	c.registered.actions[name] = contextAction
+	c.registered.mu.Unlock()
+	return contextAction, nil
+}
+
+// UnregisterContextAction removes a context action that was registered with
+// RegisterContextAction.
+func (c *Client) UnregisterContextAction(name string) error {
+	c.registered.mu.Lock()
+	delete(c.registered.actions, name)
+	c.registered.mu.Unlock()
+	return c.modifyContextAction(MumbleProto.ContextActionModify_Remove, name, "", 0)
+}
+
+// Remove asks the server to remove the context action with the given name,
+// so that it is no longer offered to users. An error is returned if c does
+// not contain the action.
+//
+// c is not modified; the server's removal of an action in
+// Client.ContextActions is passed to the OnContextActionChange listeners.
+func (c ContextActions) Remove(name string) error {
+	contextAction := c[name]
+	if contextAction == nil {
+		return errUnknownContextAction
+	}
+	return contextAction.Remove()
+}
+
+// Update asks the server to change the label and contexts of the context
+// action with the given name. An error is returned if c does not contain the
+// action. See Remove.
+func (c ContextActions) Update(name, text string, context ContextActionType) error {
+	contextAction := c[name]
+	if contextAction == nil {
+		return errUnknownContextAction
+	}
+	return contextAction.Update(text, context)
+}
+
+// Remove asks the server to remove the context action. See
+// ContextActions.Remove.
+func (c *ContextAction) Remove() error {
+	return c.client.UnregisterContextAction(c.Name)
+}
+
+// Update asks the server to change the context action's label and contexts.
+// See ContextActions.Update.
+func (c *ContextAction) Update(text string, context ContextActionType) error {
+	if !validContextActionType(context) {
+		return errInvalidContextActionType
+	}
+	client := c.client
+	if err := client.modifyContextAction(MumbleProto.ContextActionModify_Add, c.Name, text, context); err != nil {
+		return err
+	}
+	client.registered.mu.Lock()
+	if client.registered.actions[c.Name] == c {
+		c.Label = text
+		c.Type = context
+	}
+	client.registered.mu.Unlock()
+	return nil
+}
+
+func (c *Client) modifyContextAction(operation MumbleProto.ContextActionModify_Operation, name, text string, context ContextActionType) error {
+	packet := MumbleProto.ContextActionModify{
+		Action:    &name,
+		Operation: &operation,
+	}
+	if operation == MumbleProto.ContextActionModify_Add {
+		ctx := uint32(context)
+		packet.Text = &text
+		packet.Context = &ctx
+	}
+	return c.Conn.WriteProto(&packet)
+}
+
+func validContextActionType(context ContextActionType) bool {
+	return context != 0 && context&^(ContextActionServer|ContextActionChannel|ContextActionUser) == 0
+}
+
+func (c *Client) registeredContextAction(name string) *ContextAction {
+	c.registered.mu.Lock()
+	defer c.registered.mu.Unlock()
+	return c.registered.actions[name]
+}
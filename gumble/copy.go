@@ -0,0 +1,131 @@
+package gumble
+
+// UsersSnapshot returns a copy of c.Users that can be read from any goroutine
+// without synchronization. The copies are not updated as the server state
+// changes.
+//
+// The users' channels (and the channels' parents, children, links, and
+// users) are copies made at the same time, so the snapshot is consistent.
+//
+// The copies are shared between the callers of UsersSnapshot and
+// ChannelsSnapshot, and must not be modified. This includes the byte slices
+// of the copies (e.g. Texture), which are shared with the client.
+//
+// Methods that send a request to the server (e.g. User.Move) can be called
+// on the copies. UsersSnapshot must not be called from inside Do.
+func (c *Client) UsersSnapshot() Users {
+	return c.snapshot().users
+}
+
+// ChannelsSnapshot returns a copy of c.Channels that can be read from any
+// goroutine without synchronization. See UsersSnapshot for the guarantees
+// that the copy provides.
+func (c *Client) ChannelsSnapshot() Channels {
+	return c.snapshot().channels
+}
+
+// stateView is a consistent copy of the client's users and channels.
+//
+// Once a snapshot has been requested, the goroutine that reads from the
+// server publishes a new view after each packet that changes the state
+// (copy-on-write), so that snapshots are read without taking the client's
+// lock, and never delay the handling of packets. During the initial
+// synchronization, in which the server sends the whole state one packet at a
+// time, views are instead made on demand.
+type stateView struct {
+	users    Users
+	channels Channels
+}
+
+// snapshot returns the current view of the client's state.
+func (c *Client) snapshot() *stateView {
+	c.viewWanted.Store(true)
+	if view := c.view.Load(); view != nil {
+		return view
+	}
+
+	c.volatile.RLock()
+	defer c.volatile.RUnlock()
+	if view := c.view.Load(); view != nil {
+		return view
+	}
+	view := c.copyState()
+	c.view.Store(view)
+	return view
+}
+
+// stateChanged discards the current view of the client's state.
+//
+// c.volatile must be held when calling this function.
+func (c *Client) stateChanged() {
+	c.view.Store(nil)
+}
+
+// publishState makes a new view of the client's state if the previous one was
+// discarded while handling a packet. It is only called from readRoutine,
+// which is the only goroutine that modifies the state, so the state can be
+// read without locking.
+func (c *Client) publishState() {
+	if !c.viewWanted.Load() || c.view.Load() != nil || c.State() != StateSynced {
+		return
+	}
+	c.view.Store(c.copyState())
+}
+
+// copyState returns consistent copies of c.Users and c.Channels. c.volatile
+// must be held, or the caller must be readRoutine.
+func (c *Client) copyState() *stateView {
+	users := make(Users, len(c.Users))
+	for session, user := range c.Users {
+		userCopy := &User{}
+		*userCopy = *user
+		userCopy.decoder = nil
+		userCopy.playout = nil
+		if user.Stats != nil {
+			stats := *user.Stats
+			stats.User = userCopy
+			userCopy.Stats = &stats
+		}
+		users[session] = userCopy
+	}
+
+	channels := make(Channels, len(c.Channels))
+	for id, channel := range c.Channels {
+		channelCopy := *channel
+		channels[id] = &channelCopy
+	}
+	for _, channel := range channels {
+		if channel.Parent != nil {
+			channel.Parent = channels[channel.Parent.ID]
+		}
+		channel.Children = copyChannels(channel.Children, channels)
+		channel.Links = copyChannels(channel.Links, channels)
+		channelUsers := make(Users, len(channel.Users))
+		for session := range channel.Users {
+			if user := users[session]; user != nil {
+				channelUsers[session] = user
+			}
+		}
+		channel.Users = channelUsers
+	}
+	for _, user := range users {
+		if user.Channel != nil {
+			user.Channel = channels[user.Channel.ID]
+		}
+	}
+	return &stateView{
+		users:    users,
+		channels: channels,
+	}
+}
+
+// copyChannels returns a map of the copies of the channels in original.
+func copyChannels(original, copies Channels) Channels {
+	channels := make(Channels, len(original))
+	for id := range original {
+		if channel := copies[id]; channel != nil {
+			channels[id] = channel
+		}
+	}
+	return channels
+}
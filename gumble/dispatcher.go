@@ -0,0 +1,138 @@
+package gumble
+
+import (
+	"sync"
+)
+
+// DefaultEventQueueSize is the number of events that each event worker can
+// have queued when Config.EventQueueSize is not set.
+const DefaultEventQueueSize = 64
+
+// DispatchOrdering is the ordering guarantee kept when event listeners are
+// called from worker goroutines.
+type DispatchOrdering int
+
+// Dispatch orderings.
+const (
+	// DispatchSerial handles events one at a time, in the order in which they
+	// were received.
+	DispatchSerial DispatchOrdering = iota
+	// DispatchPerEntity handles events about the same user or channel in
+	// order, while events about different users or channels may be handled
+	// concurrently.
+	DispatchPerEntity
+)
+
+// dispatcher calls event listeners from a pool of worker goroutines.
+//
+// With DispatchPerEntity, events that are associated with a user or channel
+// are always handled by the same worker, which preserves their order. All
+// other events act as a fence: they are handled after every previously queued
+// event, and before any event queued after them.
+//
+// With DispatchSerial, a single worker is used.
+type dispatcher struct {
+	mu      sync.Mutex
+	closed  bool
+	queues  []chan func()
+	workers sync.WaitGroup
+}
+
+func newDispatcher(ordering DispatchOrdering, workers, queueSize int) *dispatcher {
+	if queueSize <= 0 {
+		queueSize = DefaultEventQueueSize
+	}
+	if ordering == DispatchSerial || workers < 1 {
+		workers = 1
+	}
+	d := &dispatcher{
+		queues: make([]chan func(), workers),
+	}
+	for i := range d.queues {
+		queue := make(chan func(), queueSize)
+		d.queues[i] = queue
+		d.workers.Add(1)
+		go func() {
+			defer d.workers.Done()
+			for f := range queue {
+				f()
+			}
+		}()
+	}
+	return d
+}
+
+// Dispatch queues f to be called by a worker. If the queue of the selected
+// worker is full, Dispatch blocks. If the dispatcher is closed, f is called
+// synchronously.
+func (d *dispatcher) Dispatch(event Event, f func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		f()
+		return
+	}
+
+	if key, ok := dispatchKey(event); ok {
+		d.queues[key%uint64(len(d.queues))] <- f
+		return
+	}
+
+	if len(d.queues) == 1 {
+		d.queues[0] <- f
+		return
+	}
+	var arrived sync.WaitGroup
+	arrived.Add(len(d.queues) - 1)
+	release := make(chan struct{})
+	d.queues[0] <- func() {
+		arrived.Wait()
+		f()
+		close(release)
+	}
+	for _, queue := range d.queues[1:] {
+		queue <- func() {
+			arrived.Done()
+			<-release
+		}
+	}
+}
+
+// Close stops the workers after all queued events have been handled.
+func (d *dispatcher) Close() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return
+	}
+	d.closed = true
+	for _, queue := range d.queues {
+		close(queue)
+	}
+}
+
+// Wait waits until the workers have stopped, after Close has been called.
+func (d *dispatcher) Wait() {
+	d.workers.Wait()
+}
+
+// dispatchKey returns the key used to select the worker for the given event.
+// false is returned if the event is not associated with a single user or
+// channel.
+func dispatchKey(event Event) (uint64, bool) {
+	switch e := event.(type) {
+	case *UserChangeEvent:
+		if e.User != nil {
+			return uint64(e.User.Session) << 1, true
+		}
+	case *TextMessageEvent:
+		if e.Sender != nil {
+			return uint64(e.Sender.Session) << 1, true
+		}
+	case *ChannelChangeEvent:
+		if e.Channel != nil {
+			return uint64(e.Channel.ID)<<1 | 1, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,141 @@
+package gumble
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func userEvent(session uint32) *UserChangeEvent {
+	return &UserChangeEvent{
+		User: &User{
+			Session: session,
+		},
+	}
+}
+
+func TestDispatchSerial(t *testing.T) {
+	d := newDispatcher(DispatchSerial, 4, 8)
+
+	const count = 1000
+	var active int32
+	var order []int
+	var wg sync.WaitGroup
+	wg.Add(count)
+	for i := 0; i < count; i++ {
+		i := i
+		d.Dispatch(userEvent(uint32(i%7)), func() {
+			defer wg.Done()
+			if atomic.AddInt32(&active, 1) != 1 {
+				t.Error("events handled concurrently")
+			}
+			order = append(order, i)
+			atomic.AddInt32(&active, -1)
+		})
+	}
+	wg.Wait()
+	d.Close()
+
+	for i, v := range order {
+		if i != v {
+			t.Fatalf("event %d handled at position %d", v, i)
+		}
+	}
+}
+
+func TestDispatchPerEntityOrder(t *testing.T) {
+	d := newDispatcher(DispatchPerEntity, 4, 8)
+
+	const count = 1000
+	const users = 7
+	var mu sync.Mutex
+	last := make(map[uint32]int)
+	var wg sync.WaitGroup
+	wg.Add(count)
+	for i := 0; i < count; i++ {
+		i := i
+		session := uint32(i % users)
+		d.Dispatch(userEvent(session), func() {
+			defer wg.Done()
+			mu.Lock()
+			defer mu.Unlock()
+			if prev, ok := last[session]; ok && prev > i {
+				t.Errorf("user %d: event %d handled after %d", session, i, prev)
+			}
+			last[session] = i
+		})
+	}
+	wg.Wait()
+	d.Close()
+}
+
+func TestDispatchPerEntityConcurrent(t *testing.T) {
+	d := newDispatcher(DispatchPerEntity, 4, 8)
+	defer d.Close()
+
+	blocked := make(chan struct{})
+	release := make(chan struct{})
+	d.Dispatch(userEvent(1), func() {
+		close(blocked)
+		<-release
+	})
+	<-blocked
+
+	done := make(chan struct{})
+	d.Dispatch(userEvent(2), func() {
+		close(done)
+	})
+	select {
+	case <-done:
+	case <-time.After(time.Second * 5):
+		t.Fatal("event for another user was blocked by a slow listener")
+	}
+	close(release)
+}
+
+func TestDispatchPerEntityFence(t *testing.T) {
+	d := newDispatcher(DispatchPerEntity, 4, 8)
+
+	const before, after = 100, 100
+	var handledBefore, handledAfter int32
+	var wg sync.WaitGroup
+	wg.Add(before + after + 1)
+	for i := 0; i < before; i++ {
+		d.Dispatch(userEvent(uint32(i)), func() {
+			defer wg.Done()
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&handledBefore, 1)
+		})
+	}
+	d.Dispatch(&ConnectEvent{}, func() {
+		defer wg.Done()
+		if n := atomic.LoadInt32(&handledBefore); n != before {
+			t.Errorf("fence handled after %d of %d earlier events", n, before)
+		}
+		if n := atomic.LoadInt32(&handledAfter); n != 0 {
+			t.Errorf("fence handled after %d later events", n)
+		}
+	})
+	for i := 0; i < after; i++ {
+		d.Dispatch(userEvent(uint32(i)), func() {
+			defer wg.Done()
+			atomic.AddInt32(&handledAfter, 1)
+		})
+	}
+	wg.Wait()
+	d.Close()
+}
+
+func TestDispatchClosed(t *testing.T) {
+	d := newDispatcher(DispatchPerEntity, 2, 1)
+	d.Close()
+
+	called := false
+	d.Dispatch(userEvent(1), func() {
+		called = true
+	})
+	if !called {
+		t.Error("closed dispatcher did not call the function synchronously")
+	}
+}
@@ -42,4 +42,53 @@
 // (Users, Channels, Config, etc.), is thread-unsafe. Accessing or modifying
 // those structures should only be done from inside of an event listener or via
 // Client.Do.
+//
+// Client.Do holds a read lock on the client's state for the duration of the
+// function, during which the goroutine that reads from the server cannot
+// apply changes. Keep the function short, and do not wait inside it for
+// events or replies from the server.
+//
+// Alternatively, Client.UsersSnapshot and Client.ChannelsSnapshot return
+// consistent copies of Users and Channels, which can be read from any
+// goroutine without holding a lock. Once they have been called, the client
+// maintains the copies copy-on-write: the state is copied once after each
+// change, rather than on each call, and reading the copies never delays the
+// handling of packets from the server.
+//
+// Programs that hang can be built with the gumbledebug tag, which checks the
+// use of the client's lock. It panics when a goroutine locks the client's
+// state while already holding it, and crashes the program with a report of
+// the goroutines (and the event listeners) that hold the lock when it cannot
+// be acquired within 10 seconds:
+//
+//  go build -tags gumbledebug
+//
+// Event ordering
+//
+// By default, event listeners are called synchronously from the goroutine that
+// reads from the server. Events are handled one at a time, in the order in
+// which they were received, and each listener is called in the order in which
+// it was attached.
+//
+// Setting Config.EventWorkers moves listener calls to a pool of worker
+// goroutines, and Config.EventOrdering selects the guarantee that is kept:
+//
+//  DispatchSerial     - events are handled one at a time, in order, by a single
+//                       worker.
+//  DispatchPerEntity  - events about the same user or channel are handled in
+//                       order, but events about different users or channels
+//                       may be handled concurrently. Events that are not about
+//                       a single user or channel (e.g. ConnectEvent) are
+//                       handled after all earlier events, and before any later
+//                       events.
+//
+// In both cases, the listeners for a single event are called in sequence.
+//
+// Write errors
+//
+// Methods that send a request to the server (e.g. User.Move, Channel.Send)
+// return the error, if any, from writing the request. If writing to the server
+// fails, the client also closes the connection, triggers a WriteErrorEvent,
+// and then disconnects with DisconnectError, so the returned error can be
+// ignored by applications that handle the event instead.
 package gumble
@@ -1,6 +1,12 @@
 package gumble
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
 	"layeh.com/gumble/gumble/MumbleProto"
 )
 
@@ -10,7 +16,13 @@ import (
 // Listener methods are executed synchronously as event happen. They also block
 // network reads from happening until all handlers for an event are called.
 // Therefore, it is not recommended to do any long processing from inside of
-// these methods.
+// these methods, unless Config.EventWorkers is set (see the package
+// documentation for the ordering guarantees in that case).
+//
+// Events that were added after EventListener are passed only to listeners
+// that also implement the event's optional interface (e.g.
+// PingUpdatedListener), or that are attached with Listeners.AttachFunc or
+// Listeners.AttachAll.
 type EventListener interface {
 	OnConnect(e *ConnectEvent)
 	OnDisconnect(e *DisconnectEvent)
@@ -25,6 +37,107 @@ type EventListener interface {
 	OnServerConfig(e *ServerConfigEvent)
 }
 
+// ListenerErrorListener is implemented by an EventListener that handles
+// ListenerErrorEvents.
+type ListenerErrorListener interface {
+	OnListenerError(e *ListenerErrorEvent)
+}
+
+// PingUpdatedListener is implemented by an EventListener that handles
+// PingUpdatedEvents.
+type PingUpdatedListener interface {
+	OnPingUpdated(e *PingUpdatedEvent)
+}
+
+// PermissionQueryListener is implemented by an EventListener that handles
+// PermissionQueryEvents.
+type PermissionQueryListener interface {
+	OnPermissionQuery(e *PermissionQueryEvent)
+}
+
+// QueryUsersListener is implemented by an EventListener that handles
+// QueryUsersEvents.
+type QueryUsersListener interface {
+	OnQueryUsers(e *QueryUsersEvent)
+}
+
+// ChannelTreeChangeListener is implemented by an EventListener that handles
+// ChannelTreeChangeEvents.
+type ChannelTreeChangeListener interface {
+	OnChannelTreeChange(e *ChannelTreeChangeEvent)
+}
+
+// ProtocolErrorListener is implemented by an EventListener that handles
+// ProtocolErrorEvents.
+type ProtocolErrorListener interface {
+	OnProtocolError(e *ProtocolErrorEvent)
+}
+
+// SlowListenerListener is implemented by an EventListener that handles
+// SlowListenerEvents.
+type SlowListenerListener interface {
+	OnSlowListener(e *SlowListenerEvent)
+}
+
+// WriteErrorListener is implemented by an EventListener that handles
+// WriteErrorEvents.
+type WriteErrorListener interface {
+	OnWriteError(e *WriteErrorEvent)
+}
+
+// AudioBandwidthListener is implemented by an EventListener that handles
+// AudioBandwidthEvents.
+type AudioBandwidthListener interface {
+	OnAudioBandwidth(e *AudioBandwidthEvent)
+}
+
+// ContextActionListener is implemented by an EventListener that handles
+// ContextActionEvents.
+type ContextActionListener interface {
+	OnContextAction(e *ContextActionEvent)
+}
+
+// ServerVersionListener is implemented by an EventListener that handles
+// ServerVersionEvents.
+type ServerVersionListener interface {
+	OnServerVersion(e *ServerVersionEvent)
+}
+
+// Event is implemented by each of the *Event types (e.g. *ConnectEvent), so
+// that all events can be handled by a single function. A type switch can be
+// used to determine the type of event:
+//
+//	switch e := event.(type) {
+//	case *gumble.TextMessageEvent:
+//	  println(e.Message)
+//	}
+type Event interface {
+	isEvent()
+}
+
+func (*ConnectEvent) isEvent()             {}
+func (*DisconnectEvent) isEvent()          {}
+func (*TextMessageEvent) isEvent()         {}
+func (*UserChangeEvent) isEvent()          {}
+func (*ChannelChangeEvent) isEvent()       {}
+func (*PermissionDeniedEvent) isEvent()    {}
+func (*UserListEvent) isEvent()            {}
+func (*ACLEvent) isEvent()                 {}
+func (*BanListEvent) isEvent()             {}
+func (*ContextActionChangeEvent) isEvent() {}
+func (*ServerConfigEvent) isEvent()        {}
+func (*ListenerErrorEvent) isEvent()       {}
+func (*PingUpdatedEvent) isEvent()         {}
+func (*PermissionQueryEvent) isEvent()     {}
+func (*QueryUsersEvent) isEvent()          {}
+func (*ChannelTreeChangeEvent) isEvent()   {}
+func (*ProtocolErrorEvent) isEvent()       {}
+func (*SlowListenerEvent) isEvent()        {}
+func (*WriteErrorEvent) isEvent()          {}
+func (*AudioBandwidthEvent) isEvent()      {}
+func (*ContextActionEvent) isEvent()       {}
+func (*ServerVersionEvent) isEvent()       {}
+
 // ConnectEvent is the event that is passed to EventListener.OnConnect.
 type ConnectEvent struct {
 	Client         *Client
@@ -32,20 +145,61 @@ type ConnectEvent struct {
 	MaximumBitrate *int
 }
 
+// ServerVersionEvent is the event that is passed to
+// ServerVersionListener.OnServerVersion. It is triggered after the
+// ConnectEvent, and again if the server sends its version while the client is
+// connected.
+type ServerVersionEvent struct {
+	Client  *Client
+	Version Version
+}
+
 // DisconnectType specifies why a Client disconnected from a server.
 type DisconnectType int
 
 // Client disconnect reasons.
 const (
+	// DisconnectError means the connection was lost due to a network error.
 	DisconnectError DisconnectType = iota + 1
+	// DisconnectKicked means the client was kicked from the server.
 	DisconnectKicked
+	// DisconnectBanned means the client was banned from the server.
 	DisconnectBanned
+	// DisconnectUser means the client disconnected by calling
+	// Client.Disconnect.
 	DisconnectUser
+	// DisconnectPingTimeout means the server stopped responding.
+	DisconnectPingTimeout
+	// DisconnectProtocolError means the server sent data that could not be
+	// read.
+	DisconnectProtocolError
 )
 
-// Has returns true if the DisconnectType has changeType part of its bitmask.
+// String returns a short description of the disconnect reason.
+func (d DisconnectType) String() string {
+	switch d {
+	case DisconnectError:
+		return "error"
+	case DisconnectKicked:
+		return "kicked"
+	case DisconnectBanned:
+		return "banned"
+	case DisconnectUser:
+		return "user"
+	case DisconnectPingTimeout:
+		return "ping timeout"
+	case DisconnectProtocolError:
+		return "protocol error"
+	}
+	return "DisconnectType(" + strconv.Itoa(int(d)) + ")"
+}
+
+// Has returns true if the DisconnectType is equal to changeType.
+//
+// Unlike the other event types, DisconnectType is not a bitmask: a client is
+// disconnected for exactly one reason.
 func (d DisconnectType) Has(changeType DisconnectType) bool {
-	return d&changeType == changeType
+	return d == changeType
 }
 
 // DisconnectEvent is the event that is passed to EventListener.OnDisconnect.
@@ -53,7 +207,14 @@ type DisconnectEvent struct {
 	Client *Client
 	Type   DisconnectType
 
+	// The reason given by the server when the client was kicked or banned.
 	String string
+	// The error that caused the disconnect, if Type is DisconnectError,
+	// DisconnectPingTimeout, or DisconnectProtocolError.
+	Err error
+	// Reconnect is true if Config.ReconnectPolicy allows reconnecting after
+	// this disconnect.
+	Reconnect bool
 }
 
 // TextMessageEvent is the event that is passed to EventListener.OnTextMessage.
@@ -81,13 +242,59 @@ const (
 	UserChangePrioritySpeaker
 	UserChangeRecording
 	UserChangeStats
+	// UserChangeMuted, UserChangeDeafened, UserChangeSuppressed,
+	// UserChangeSelfMuted, and UserChangeSelfDeafened are set along with
+	// UserChangeAudio, and specify which part of the audio state changed.
+	UserChangeMuted
+	UserChangeDeafened
+	UserChangeSuppressed
+	UserChangeSelfMuted
+	UserChangeSelfDeafened
+	// UserChangeHash is set when the user's certificate hash (User.Hash)
+	// becomes available or changes.
+	UserChangeHash
 )
 
+var userChangeNames = []string{
+	"Connected",
+	"Disconnected",
+	"Kicked",
+	"Banned",
+	"Registered",
+	"Unregistered",
+	"Name",
+	"Channel",
+	"Comment",
+	"Audio",
+	"Texture",
+	"PrioritySpeaker",
+	"Recording",
+	"Stats",
+	"Muted",
+	"Deafened",
+	"Suppressed",
+	"SelfMuted",
+	"SelfDeafened",
+	"Hash",
+}
+
 // Has returns true if the UserChangeType has changeType part of its bitmask.
 func (u UserChangeType) Has(changeType UserChangeType) bool {
 	return u&changeType == changeType
 }
 
+// HasAny returns true if the UserChangeType has any part of changeType in its
+// bitmask.
+func (u UserChangeType) HasAny(changeType UserChangeType) bool {
+	return u&changeType != 0
+}
+
+// String returns the names of the items in the bitmask, separated by "|"
+// (e.g. "Name|Comment").
+func (u UserChangeType) String() string {
+	return bitmaskString(int(u), userChangeNames)
+}
+
 // UserChangeEvent is the event that is passed to EventListener.OnUserChange.
 type UserChangeEvent struct {
 	Client *Client
@@ -96,6 +303,11 @@ type UserChangeEvent struct {
 	Actor  *User
 
 	String string
+
+	// A shallow copy of User before the change was applied. nil if the user
+	// has just connected, or if the event was not caused by a state update
+	// (e.g. UserChangeDisconnected).
+	Previous *User
 }
 
 // ChannelChangeType is a bitmask of items that changed for a channel.
@@ -114,18 +326,64 @@ const (
 	ChannelChangeMaxUsers
 )
 
+var channelChangeNames = []string{
+	"Created",
+	"Removed",
+	"Moved",
+	"Name",
+	"Links",
+	"Description",
+	"Position",
+	"Permission",
+	"MaxUsers",
+}
+
 // Has returns true if the ChannelChangeType has changeType part of its
 // bitmask.
 func (c ChannelChangeType) Has(changeType ChannelChangeType) bool {
 	return c&changeType == changeType
 }
 
+// HasAny returns true if the ChannelChangeType has any part of changeType in
+// its bitmask.
+func (c ChannelChangeType) HasAny(changeType ChannelChangeType) bool {
+	return c&changeType != 0
+}
+
+// String returns the names of the items in the bitmask, separated by "|"
+// (e.g. "Name|Links").
+func (c ChannelChangeType) String() string {
+	return bitmaskString(int(c), channelChangeNames)
+}
+
+func bitmaskString(mask int, names []string) string {
+	if mask == 0 {
+		return "0"
+	}
+	var parts []string
+	for i, name := range names {
+		if mask&(1<<uint(i)) != 0 {
+			parts = append(parts, name)
+			mask &^= 1 << uint(i)
+		}
+	}
+	if mask != 0 {
+		parts = append(parts, "0x"+strconv.FormatInt(int64(mask), 16))
+	}
+	return strings.Join(parts, "|")
+}
+
 // ChannelChangeEvent is the event that is passed to
 // EventListener.OnChannelChange.
 type ChannelChangeEvent struct {
 	Client  *Client
 	Type    ChannelChangeType
 	Channel *Channel
+
+	// A shallow copy of Channel before the change was applied. nil if the
+	// channel has just been created, or if the event was not caused by a state
+	// update (e.g. ChannelChangeRemoved).
+	Previous *Channel
 }
 
 // PermissionDeniedType specifies why a Client was denied permission to perform
@@ -171,7 +429,9 @@ type UserListEvent struct {
 	UserList RegisteredUsers
 }
 
-// ACLEvent is the event that is passed to EventListener.OnACL.
+// ACLEvent is the event that is passed to EventListener.OnACL. It is triggered
+// after Channel.RequestACL, once the names of the users in the ACL have been
+// resolved.
 type ACLEvent struct {
 	Client *Client
 	ACL    *ACL
@@ -186,7 +446,8 @@ type BanListEvent struct {
 // ContextActionChangeType specifies how a ContextAction changed.
 type ContextActionChangeType int
 
-// ContextAction change types.
+// ContextAction change types. ContextActionAdd is also used when the label
+// or contexts of an existing action change.
 const (
 	ContextActionAdd    ContextActionChangeType = ContextActionChangeType(MumbleProto.ContextActionModify_Add)
 	ContextActionRemove ContextActionChangeType = ContextActionChangeType(MumbleProto.ContextActionModify_Remove)
@@ -200,6 +461,25 @@ type ContextActionChangeEvent struct {
 	ContextAction *ContextAction
 }
 
+// ContextActionEvent is the event that is passed to
+// ContextActionListener.OnContextAction, when the server sends the client a
+// context action that was triggered (e.g. one registered with
+// Client.RegisterContextAction).
+type ContextActionEvent struct {
+	Client *Client
+	// The name of the triggered action.
+	Action string
+	// The action, if it is in Client.ContextActions or was registered by the
+	// client. Otherwise, nil.
+	ContextAction *ContextAction
+
+	// The context in which the action was triggered. User is set for
+	// ContextActionUser, and Channel for ContextActionChannel.
+	Type    ContextActionType
+	User    *User
+	Channel *Channel
+}
+
 // ServerConfigEvent is the event that is passed to
 // EventListener.OnServerConfig.
 type ServerConfigEvent struct {
@@ -221,3 +501,169 @@ type ServerConfigEvent struct {
 	SuggestPositional *bool
 	SuggestPushToTalk *bool
 }
+
+// ListenerErrorEvent is the event that is passed to
+// ListenerErrorListener.OnListenerError. It is only triggered when
+// Config.RecoverListenerPanics is set.
+type ListenerErrorEvent struct {
+	Client *Client
+
+	// The listener that panicked. nil if the listener was attached using
+	// Listeners.AttachAll.
+	Listener EventListener
+	// The event that was being handled by the listener (e.g.
+	// *TextMessageEvent).
+	Event Event
+	// The value that was passed to panic.
+	Panic interface{}
+	// The stack trace of the goroutine at the time of the panic.
+	Stack []byte
+}
+
+// Error implements error.
+func (e *ListenerErrorEvent) Error() string {
+	return fmt.Sprintf("gumble: listener panic: %v", e.Panic)
+}
+
+// PingProtocol is the transport over which a ping was sent.
+type PingProtocol int
+
+// Ping protocols.
+const (
+	PingTCP PingProtocol = iota
+	PingUDP
+)
+
+// PingUpdatedEvent is the event that is passed to
+// PingUpdatedListener.OnPingUpdated. It is triggered each time the server
+// responds to a ping sent by the client.
+type PingUpdatedEvent struct {
+	Client   *Client
+	Protocol PingProtocol
+
+	// The round-trip time of the ping.
+	Latency time.Duration
+	// The average round-trip time of recent pings.
+	Average time.Duration
+	// The variance of the round-trip time of recent pings, in milliseconds
+	// squared.
+	Variance float32
+}
+
+// PermissionQueryEvent is the event that is passed to
+// PermissionQueryListener.OnPermissionQuery. It is triggered when the server
+// sends the client's permissions, either in response to
+// Channel.RequestPermission or when the permissions change.
+type PermissionQueryEvent struct {
+	Client *Client
+	// The channel whose permissions were sent. nil if the event only flushes
+	// the cached permissions.
+	Channel *Channel
+	// The client's permissions in Channel. nil if Channel is nil.
+	Permission *Permission
+	// True if all previously cached permissions were discarded.
+	Flush bool
+}
+
+// QueryUsersEvent is the event that is passed to
+// QueryUsersListener.OnQueryUsers. It is triggered in response to
+// Client.RequestUserIDs and Client.RequestUserNames.
+type QueryUsersEvent struct {
+	Client *Client
+	// The registered users that were found, mapped from user ID to name.
+	// Users that are not registered on the server are omitted.
+	Users map[uint32]string
+}
+
+// ChannelTreeChangeEvent is the event that is passed to
+// ChannelTreeChangeListener.OnChannelTreeChange. It is triggered once a burst
+// of channel changes has settled (see Config.ChannelTreeDelay), and lists the
+// channels that were affected.
+//
+// A channel that was both created and removed during the burst is not
+// included. A channel that was created is not included in Moved or Changed.
+type ChannelTreeChangeEvent struct {
+	Client *Client
+
+	Created []*Channel
+	Removed []*Channel
+	// Channels that were moved to a new parent.
+	Moved []*Channel
+	// Channels whose other properties (e.g. name, links) were changed.
+	Changed []*Channel
+}
+
+// ProtocolErrorEvent is the event that is passed to
+// ProtocolErrorListener.OnProtocolError. It is only triggered when
+// Config.StrictProtocol is set, for each packet from the server that is
+// malformed or out of spec (e.g. it references an unknown user or channel,
+// or has a field that is longer than the server allows).
+//
+// The packet is still handled as it would be without Config.StrictProtocol.
+type ProtocolErrorEvent struct {
+	Client *Client
+
+	// The type of the packet (e.g. PacketUserState).
+	PacketType uint16
+	// The decoded packet. nil if the packet could not be decoded.
+	Message proto.Message
+	// A description of the problem.
+	Err error
+}
+
+// Error implements error.
+func (e *ProtocolErrorEvent) Error() string {
+	return fmt.Sprintf("gumble: protocol error in packet type %d: %v", e.PacketType, e.Err)
+}
+
+// SlowListenerEvent is the event that is passed to
+// SlowListenerListener.OnSlowListener. It is triggered when a listener has been
+// handling an event for longer than Config.SlowListenerTimeout.
+//
+// The event is triggered from a separate goroutine while the slow listener is
+// still running, so OnSlowListener can be called concurrently with other
+// listeners.
+type SlowListenerEvent struct {
+	Client *Client
+
+	// The slow listener. nil if the listener was attached using
+	// Listeners.AttachAll.
+	Listener EventListener
+	// The event that is being handled by the listener.
+	Event Event
+	// How long the listener had been running.
+	Duration time.Duration
+	// The stack trace of the listener's goroutine, showing where it is
+	// blocked.
+	Stack []byte
+	// True if the listener has been detached (see
+	// Config.DetachSlowListeners).
+	Detached bool
+}
+
+// WriteErrorEvent is the event that is passed to
+// WriteErrorListener.OnWriteError. It is triggered when writing to the server
+// has failed, immediately before the client disconnects. As most writes (e.g.
+// User.Move) do not return an error, this is where write failures are reported.
+type WriteErrorEvent struct {
+	Client *Client
+	Err    error
+}
+
+// AudioBandwidthEvent is the event that is passed to
+// AudioBandwidthListener.OnAudioBandwidth. It is triggered when the client's
+// audio settings have been changed to fit the server's maximum bandwidth (see
+// Config.AdjustToBandwidth).
+type AudioBandwidthEvent struct {
+	Client *Client
+
+	// The server's maximum bandwidth, in bits per second.
+	MaximumBitrate int
+	// The audio interval (see Client.AudioInterval) and data size that are
+	// now used.
+	AudioInterval  time.Duration
+	AudioDataBytes int
+	// The bitrate of the encoded audio, in bits per second, which excludes
+	// the packets' overhead.
+	Bitrate int
+}
@@ -0,0 +1,23 @@
+package gumble
+
+import (
+	"testing"
+)
+
+func TestDisconnectTypeHas(t *testing.T) {
+	types := []DisconnectType{
+		DisconnectError,
+		DisconnectKicked,
+		DisconnectBanned,
+		DisconnectUser,
+		DisconnectPingTimeout,
+		DisconnectProtocolError,
+	}
+	for _, d := range types {
+		for _, other := range types {
+			if got, want := d.Has(other), d == other; got != want {
+				t.Errorf("%v.Has(%v) = %v; want %v", d, other, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package gumble
+
+import (
+	"sync"
+)
+
+// EventOverflowPolicy specifies what happens when an event is triggered while
+// the channel returned by Client.Events is full.
+type EventOverflowPolicy int
+
+// Event overflow policies.
+const (
+	// EventOverflowBlock waits until there is room in the channel. Incoming
+	// packets are not handled while waiting.
+	EventOverflowBlock EventOverflowPolicy = iota
+	// EventOverflowDropOldest discards the oldest unreceived event to make
+	// room for the new event.
+	EventOverflowDropOldest
+	// EventOverflowDropNewest discards the new event.
+	EventOverflowDropNewest
+)
+
+// DefaultEventBufferSize is the buffer size of the channel returned by
+// Client.Events when Config.EventBufferSize is not set.
+const DefaultEventBufferSize = 64
+
+// Events returns a channel that receives the client's events as they are
+// triggered. The values sent on the channel are the same *Event values passed
+// to EventListener (e.g. *TextMessageEvent). Only events that are triggered
+// after the first call to Events are sent, and each event is sent after its
+// listeners have been called.
+//
+// The same channel is returned on each call. It is closed after the
+// DisconnectEvent has been sent.
+//
+// The buffer size of the channel and the behavior when it is full are set by
+// Config.EventBufferSize and Config.EventOverflowPolicy.
+func (c *Client) Events() <-chan Event {
+	c.volatile.Lock()
+	defer c.volatile.Unlock()
+
+	if c.events != nil {
+		return c.events.ch
+	}
+	size := c.Config.EventBufferSize
+	if size <= 0 {
+		size = DefaultEventBufferSize
+	}
+	c.events = &eventStream{
+		ch:     make(chan Event, size),
+		policy: c.Config.EventOverflowPolicy,
+	}
+	if c.State() == StateDisconnected {
+		c.events.close()
+	}
+	return c.events.ch
+}
+
+// eventStream returns the stream of events created by Events, or nil if Events
+// has not been called.
+func (c *Client) eventStream() *eventStream {
+	c.volatile.Lock()
+	defer c.volatile.Unlock()
+	return c.events
+}
+
+// eventStream is the channel returned by Client.Events.
+//
+// Events can be sent from several event workers at once, and from timers, so
+// sending and closing are done under mu, and nothing is sent once the channel
+// has been closed.
+type eventStream struct {
+	ch     chan Event
+	policy EventOverflowPolicy
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// send sends event on the channel. s can be nil.
+func (s *eventStream) send(event Event) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		sendEvent(s.ch, event, s.policy)
+	}
+}
+
+// close closes the channel. s can be nil.
+func (s *eventStream) close() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.ch)
+	}
+}
+
+func sendEvent(ch chan Event, event Event, policy EventOverflowPolicy) {
+	switch policy {
+	case EventOverflowDropOldest:
+		for {
+			select {
+			case ch <- event:
+				return
+			default:
+			}
+			select {
+			case <-ch:
+			default:
+			}
+		}
+	case EventOverflowDropNewest:
+		select {
+		case ch <- event:
+		default:
+		}
+	default:
+		ch <- event
+	}
+}
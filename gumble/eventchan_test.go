@@ -0,0 +1,105 @@
+package gumble
+
+import (
+	"sync"
+	"testing"
+)
+
+// newEventsClient returns a synced client whose events are handled by the
+// given number of event workers.
+func newEventsClient(workers int) *Client {
+	c := &Client{
+		Config:     NewConfig(),
+		state:      uint32(StateSynced),
+		end:        make(chan struct{}),
+		done:       make(chan struct{}),
+		dispatcher: newDispatcher(DispatchPerEntity, workers, 0),
+	}
+	c.disconnectEvent.Client = c
+	c.disconnectEvent.Type = DisconnectUser
+	return c
+}
+
+func (c *Client) triggerTextMessage(session uint32, message string) {
+	c.triggerAsync(func() {
+		c.Config.Listeners.onTextMessage(&TextMessageEvent{
+			Client: c,
+			TextMessage: TextMessage{
+				Sender:  &User{Session: session},
+				Message: message,
+			},
+		})
+	})
+}
+
+func TestEventsSkipsQueuedEvents(t *testing.T) {
+	c := newEventsClient(1)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	c.Config.AttachAll(func(event Event) {
+		if e, ok := event.(*TextMessageEvent); ok && e.Message == "before" {
+			close(started)
+			<-release
+		}
+	})
+
+	c.triggerTextMessage(1, "before")
+	<-started
+	c.triggerTextMessage(1, "queued")
+	events := c.Events()
+	c.triggerTextMessage(1, "after")
+	close(release)
+	go c.readEnded()
+
+	var received []string
+	for event := range events {
+		switch e := event.(type) {
+		case *TextMessageEvent:
+			received = append(received, e.Message)
+		case *DisconnectEvent:
+			received = append(received, "disconnect")
+		}
+	}
+	if len(received) != 2 || received[0] != "after" || received[1] != "disconnect" {
+		t.Errorf("received %q; want [after disconnect]", received)
+	}
+}
+
+// TestEventsInFlight subscribes and disconnects while events are being
+// triggered from several goroutines. Run it with -race.
+func TestEventsInFlight(t *testing.T) {
+	for _, policy := range []EventOverflowPolicy{EventOverflowBlock, EventOverflowDropOldest, EventOverflowDropNewest} {
+		c := newEventsClient(4)
+		c.Config.EventBufferSize = 4
+		c.Config.EventOverflowPolicy = policy
+
+		var triggers sync.WaitGroup
+		for i := uint32(0); i < 8; i++ {
+			triggers.Add(1)
+			go func(session uint32) {
+				defer triggers.Done()
+				for j := 0; j < 100; j++ {
+					c.triggerTextMessage(session, "message")
+				}
+			}(i)
+		}
+
+		events := c.Events()
+		received := make(chan bool)
+		go func() {
+			disconnected := false
+			for event := range events {
+				if _, ok := event.(*DisconnectEvent); ok {
+					disconnected = true
+				}
+			}
+			received <- disconnected
+		}()
+		c.readEnded()
+		// The DisconnectEvent can only be dropped if the policy drops events.
+		if disconnected := <-received; !disconnected && policy == EventOverflowBlock {
+			t.Errorf("policy %d: DisconnectEvent was not received", policy)
+		}
+		triggers.Wait()
+	}
+}
@@ -0,0 +1,36 @@
+package gumble
+
+import (
+	"net"
+)
+
+// NewTestClient returns a client that is connected over conn, but that has
+// not performed a handshake. The client uses codec for outgoing and incoming
+// audio.
+func NewTestClient(conn net.Conn, config *Config, codec AudioCodec) *Client {
+	client := &Client{
+		Conn:        NewConn(conn),
+		Config:      config,
+		Users:       make(Users),
+		Channels:    make(Channels),
+		permissions: make(map[uint32]*Permission),
+		state:       uint32(StateSynced),
+		connect:     make(chan *RejectError),
+		end:         make(chan struct{}),
+		logger:      newLogger(config.Logger, conn.RemoteAddr().String()),
+		clock:       SystemClock,
+	}
+	client.audioCodec = codec
+	client.AudioEncoder = client.audioCodec.NewEncoder()
+	return client
+}
+
+func (c *Client) WriteAudio(a AudioBuffer, seq int64, final bool) error {
+	return a.writeAudio(c, seq, final)
+}
+
+func (c *Client) HandlePacket(pType uint16, data []byte) error {
+	return c.handlePacket(pType, data)
+}
+
+var NewAudioPacket = audioPacket
@@ -1,8 +1,9 @@
 package gumble
 
 import (
+	"crypto/sha1"
 	"crypto/x509"
-	"encoding/binary"
+	"encoding/hex"
 	"errors"
 	"math"
 	"net"
@@ -11,7 +12,6 @@ import (
 
 	"github.com/golang/protobuf/proto"
 	"layeh.com/gumble/gumble/MumbleProto"
-	"layeh.com/gumble/gumble/varint"
 )
 
 var (
@@ -22,9 +22,11 @@ var (
 	errNoCodec              = errors.New("gumble: no audio codec")
 )
 
-var handlers = [...]func(*Client, []byte) error{
+// handlers handle the decoded control packets, indexed by packet type.
+// UDPTunnel packets are handled by handleUDPTunnel.
+var handlers = [...]func(*Client, proto.Message) error{
 	(*Client).handleVersion,
-	(*Client).handleUDPTunnel,
+	nil, // PacketUDPTunnel
 	(*Client).handleAuthenticate,
 	(*Client).handlePing,
 	(*Client).handleReject,
@@ -56,6 +58,9 @@ func parseVersion(packet *MumbleProto.Version) Version {
 	if packet.Version != nil {
 		version.Version = *packet.Version
 	}
+	if packet.VersionV2 != nil {
+		version.VersionV2 = *packet.VersionV2
+	}
 	if packet.Release != nil {
 		version.Release = *packet.Release
 	}
@@ -68,130 +73,160 @@ func parseVersion(packet *MumbleProto.Version) Version {
 	return version
 }
 
-func (c *Client) handleVersion(buffer []byte) error {
-	var packet MumbleProto.Version
-	if err := proto.Unmarshal(buffer, &packet); err != nil {
-		return err
+func (c *Client) handleVersion(message proto.Message) error {
+	packet := message.(*MumbleProto.Version)
+	version := parseVersion(packet)
+	c.volatile.Lock()
+	c.serverVersion = version
+	if packet.Version != nil {
+		c.serverConfig.Version = &version.Version
 	}
-	return nil
-}
-
-func (c *Client) handleUDPTunnel(buffer []byte) error {
-	if len(buffer) < 1 {
-		return errInvalidProtobuf
+	if packet.VersionV2 != nil {
+		c.serverConfig.VersionV2 = &version.VersionV2
 	}
-	audioType := (buffer[0] >> 5) & 0x7
-	audioTarget := buffer[0] & 0x1F
+	c.volatile.Unlock()
 
-	// Opus only
-	// TODO: add handling for other packet types
-	if audioType != audioCodecIDOpus {
-		return errUnsupportedAudio
+	if c.State() == StateSynced {
+		event := ServerVersionEvent{
+			Client:  c,
+			Version: version,
+		}
+		c.Config.Listeners.onServerVersion(&event)
 	}
+	return nil
+}
 
-	// Session
-	buffer = buffer[1:]
-	session, n := varint.Decode(buffer)
-	if n <= 0 {
-		return errInvalidProtobuf
+func (c *Client) handleUDPTunnel(buffer []byte) error {
+	packet, err := ParseAudioPacket(buffer)
+	if err != nil {
+		return err
 	}
-	buffer = buffer[n:]
-	user := c.Users[uint32(session)]
+	user := c.Users[packet.Session]
 	if user == nil {
 		return errInvalidProtobuf
 	}
+	if c.Config.SkipDecodeWhenDeafened && c.selfDeafened.Load() {
+		return nil
+	}
 	decoder := user.decoder
 	if decoder == nil {
-		// TODO: decoder pool
-		// TODO: de-reference after stream is done
 		codec := c.audioCodec
 		if codec == nil {
 			return errNoCodec
 		}
-		decoder = codec.NewDecoder()
+		decoder = c.getDecoder(codec)
 		user.decoder = decoder
 	}
 
-	// Sequence
-	// TODO: use in jitter buffer
-	_, n = varint.Decode(buffer)
-	if n <= 0 {
-		return errInvalidProtobuf
-	}
-	buffer = buffer[n:]
-
-	// Length
-	length, n := varint.Decode(buffer)
-	if n <= 0 {
-		return errInvalidProtobuf
-	}
-	buffer = buffer[n:]
-	// Opus audio packets set the 13th bit in the size field as the terminator.
-	audioLength := int(length) &^ 0x2000
-	if audioLength > len(buffer) {
-		return errInvalidProtobuf
-	}
-
-	pcm, err := decoder.Decode(buffer[:audioLength], AudioMaximumFrameSize)
-	if err != nil {
-		return err
+	var pcm []int16
+	var pcmBuffer *[]int16
+	// A terminator can be sent without audio, only to end the transmission.
+	if !packet.Terminator || len(packet.Data) > 0 {
+		if d, ok := decoder.(AudioBufferDecoder); ok {
+			pcmBuffer = getAudioBuffer()
+			pcm, err = d.DecodeBuffer(*pcmBuffer, packet.Data)
+		} else {
+			pcm, err = decoder.Decode(packet.Data, AudioMaximumFrameSize)
+		}
+		if err != nil {
+			if pcmBuffer != nil {
+				putAudioBuffer(pcmBuffer)
+			}
+			return err
+		}
+		c.audioFramesReceived.Add(1)
 	}
 
-	event := AudioPacket{
-		Client: c,
-		Sender: user,
-		Target: &VoiceTarget{
-			ID: uint32(audioTarget),
-		},
+	// The packet is pooled: a reference is held by each listener that it is
+	// passed to, and by dispatchAudio until it has been passed to all of them.
+	event := audioPackets.Get().(*AudioPacket)
+	*event = AudioPacket{
+		Client:      c,
+		Sender:      user,
 		AudioBuffer: AudioBuffer(pcm),
+		HasPosition: packet.HasPosition,
+		X:           packet.X,
+		Y:           packet.Y,
+		Z:           packet.Z,
+		IsLast:      packet.Terminator,
+
+		refs: 1,
+		pcm:  pcmBuffer,
+		target: VoiceTarget{
+			ID: uint32(packet.Target),
+		},
 	}
+	event.Target = &event.target
 
-	if len(buffer)-audioLength == 3*4 {
-		// the packet has positional audio data; 3x float32
-		buffer = buffer[audioLength:]
-
-		event.X = math.Float32frombits(binary.LittleEndian.Uint32(buffer))
-		event.Y = math.Float32frombits(binary.LittleEndian.Uint32(buffer[4:]))
-		event.Z = math.Float32frombits(binary.LittleEndian.Uint32(buffer[8:]))
-		event.HasPosition = true
+	if delay := c.playoutDelay(user); delay > 0 || user.playout != nil {
+		if user.playout == nil {
+			user.playout = newPlayoutBuffer(c, user)
+		}
+		user.playout.add(event, packet.Sequence, delay)
+		return nil
 	}
+	c.dispatchAudio(user, event)
+	return nil
+}
 
+// dispatchAudio passes the user's audio packet to the audio listeners, and
+// releases the caller's reference to it. The user's streams are closed after
+// the last packet of a transmission.
+func (c *Client) dispatchAudio(user *User, event *AudioPacket) {
+	last := event.IsLast
 	c.volatile.Lock()
 	for item := c.Config.AudioListeners.head; item != nil; item = item.next {
-		c.volatile.Unlock()
 		ch := item.streams[user]
-		if ch == nil {
+		isNew := ch == nil
+		if isNew {
 			ch = make(chan *AudioPacket)
 			item.streams[user] = ch
+		}
+		c.volatile.Unlock()
+		if isNew {
 			event := AudioStreamEvent{
 				Client: c,
 				User:   user,
 				C:      ch,
 			}
+			c.volatile.enterListener(item.listener, &event)
 			item.listener.OnAudioStream(&event)
+			c.volatile.exitListener()
 		}
-		ch <- &event
+		atomic.AddInt32(&event.refs, 1)
+		ch <- event
 		c.volatile.Lock()
+		if last {
+			close(ch)
+			delete(item.streams, user)
+		}
 	}
 	c.volatile.Unlock()
+	event.Release()
+}
 
-	return nil
+// closeAudioStreams closes the user's audio streams. c.volatile must be held,
+// and no packets can be being passed to the streams.
+func (c *Client) closeAudioStreams(user *User) {
+	for item := c.Config.AudioListeners.head; item != nil; item = item.next {
+		if ch := item.streams[user]; ch != nil {
+			close(ch)
+			delete(item.streams, user)
+		}
+	}
 }
 
-func (c *Client) handleAuthenticate(buffer []byte) error {
+func (c *Client) handleAuthenticate(message proto.Message) error {
 	return errUnimplementedHandler
 }
 
-func (c *Client) handlePing(buffer []byte) error {
-	var packet MumbleProto.Ping
-	if err := proto.Unmarshal(buffer, &packet); err != nil {
-		return err
-	}
+func (c *Client) handlePing(message proto.Message) error {
+	packet := message.(*MumbleProto.Ping)
 
 	atomic.AddUint32(&c.tcpPacketsReceived, 1)
 
 	if packet.Timestamp != nil {
-		diff := time.Since(time.Unix(0, int64(*packet.Timestamp)))
+		diff := c.clock.Now().Sub(time.Unix(0, int64(*packet.Timestamp)))
 
 		index := int(c.tcpPacketsReceived) - 1
 		if index >= len(c.tcpPingTimes) {
@@ -219,15 +254,23 @@ func (c *Client) handlePing(buffer []byte) error {
 
 		atomic.StoreUint32(&c.tcpPingAvg, math.Float32bits(avg))
 		atomic.StoreUint32(&c.tcpPingVar, math.Float32bits(variance))
+
+		if c.State() == StateSynced {
+			event := PingUpdatedEvent{
+				Client:   c,
+				Protocol: PingTCP,
+				Latency:  diff,
+				Average:  time.Duration(float64(avg) * float64(time.Millisecond)),
+				Variance: variance,
+			}
+			c.Config.Listeners.onPingUpdated(&event)
+		}
 	}
 	return nil
 }
 
-func (c *Client) handleReject(buffer []byte) error {
-	var packet MumbleProto.Reject
-	if err := proto.Unmarshal(buffer, &packet); err != nil {
-		return err
-	}
+func (c *Client) handleReject(message proto.Message) error {
+	packet := message.(*MumbleProto.Reject)
 
 	if c.State() != StateConnected {
 		return errInvalidProtobuf
@@ -246,20 +289,23 @@ func (c *Client) handleReject(buffer []byte) error {
 	return nil
 }
 
-func (c *Client) handleServerSync(buffer []byte) error {
-	var packet MumbleProto.ServerSync
-	if err := proto.Unmarshal(buffer, &packet); err != nil {
-		return err
-	}
+func (c *Client) handleServerSync(message proto.Message) error {
+	packet := message.(*MumbleProto.ServerSync)
 	event := ConnectEvent{
 		Client: c,
 	}
+	var bandwidthEvent *AudioBandwidthEvent
 
 	if packet.Session != nil {
 		{
 			c.volatile.Lock()
+			c.stateChanged()
 
 			c.Self = c.Users[*packet.Session]
+			if c.Self != nil {
+				c.selfMuted.Store(c.Self.SelfMuted)
+				c.selfDeafened.Store(c.Self.SelfDeafened)
+			}
 
 			c.volatile.Unlock()
 		}
@@ -270,18 +316,30 @@ func (c *Client) handleServerSync(buffer []byte) error {
 	if packet.MaxBandwidth != nil {
 		val := int(*packet.MaxBandwidth)
 		event.MaximumBitrate = &val
+		bandwidthEvent = c.adjustBandwidth(val)
 	}
+	c.volatile.Lock()
+	c.serverConfig.update(&ServerConfigEvent{
+		WelcomeMessage: event.WelcomeMessage,
+		MaximumBitrate: event.MaximumBitrate,
+	})
+	c.volatile.Unlock()
 	atomic.StoreUint32(&c.state, uint32(StateSynced))
+	c.connectEvent = &event
 	c.Config.Listeners.onConnect(&event)
+	c.Config.Listeners.onServerVersion(&ServerVersionEvent{
+		Client:  c,
+		Version: c.ServerVersion(),
+	})
+	if bandwidthEvent != nil {
+		c.Config.Listeners.onAudioBandwidth(bandwidthEvent)
+	}
 	close(c.connect)
 	return nil
 }
 
-func (c *Client) handleChannelRemove(buffer []byte) error {
-	var packet MumbleProto.ChannelRemove
-	if err := proto.Unmarshal(buffer, &packet); err != nil {
-		return err
-	}
+func (c *Client) handleChannelRemove(message proto.Message) error {
+	packet := message.(*MumbleProto.ChannelRemove)
 
 	if packet.ChannelId == nil {
 		return errIncompleteProtobuf
@@ -290,6 +348,7 @@ func (c *Client) handleChannelRemove(buffer []byte) error {
 	var channel *Channel
 	{
 		c.volatile.Lock()
+		c.stateChanged()
 
 		channelID := *packet.ChannelId
 		channel = c.Channels[channelID]
@@ -317,15 +376,13 @@ func (c *Client) handleChannelRemove(buffer []byte) error {
 			Channel: channel,
 		}
 		c.Config.Listeners.onChannelChange(&event)
+		c.noteChannelChange(channel, ChannelChangeRemoved)
 	}
 	return nil
 }
 
-func (c *Client) handleChannelState(buffer []byte) error {
-	var packet MumbleProto.ChannelState
-	if err := proto.Unmarshal(buffer, &packet); err != nil {
-		return err
-	}
+func (c *Client) handleChannelState(message proto.Message) error {
+	packet := message.(*MumbleProto.ChannelState)
 
 	if packet.ChannelId == nil {
 		return errIncompleteProtobuf
@@ -333,9 +390,11 @@ func (c *Client) handleChannelState(buffer []byte) error {
 	event := ChannelChangeEvent{
 		Client: c,
 	}
+	var blobEvents []Event
 
 	{
 		c.volatile.Lock()
+		c.stateChanged()
 
 		channelID := *packet.ChannelId
 		channel := c.Channels[channelID]
@@ -344,6 +403,9 @@ func (c *Client) handleChannelState(buffer []byte) error {
 			channel.client = c
 
 			event.Type |= ChannelChangeCreated
+		} else {
+			previous := *channel
+			event.Previous = &previous
 		}
 		event.Channel = channel
 		if packet.Parent != nil {
@@ -394,6 +456,7 @@ func (c *Client) handleChannelState(buffer []byte) error {
 			}
 			channel.Description = *packet.Description
 			channel.DescriptionHash = nil
+			blobEvents = c.blobReceived(blobDescription, []byte(channel.Description))
 		}
 		if packet.Temporary != nil {
 			channel.Temporary = *packet.Temporary
@@ -406,8 +469,13 @@ func (c *Client) handleChannelState(buffer []byte) error {
 		}
 		if packet.DescriptionHash != nil {
 			event.Type |= ChannelChangeDescription
-			channel.DescriptionHash = packet.DescriptionHash
-			channel.Description = ""
+			if description, ok := c.cachedBlob(packet.DescriptionHash); ok {
+				channel.Description = string(description)
+				channel.DescriptionHash = nil
+			} else {
+				channel.DescriptionHash = packet.DescriptionHash
+				channel.Description = ""
+			}
 		}
 		if packet.MaxUsers != nil {
 			event.Type |= ChannelChangeMaxUsers
@@ -419,15 +487,14 @@ func (c *Client) handleChannelState(buffer []byte) error {
 
 	if c.State() == StateSynced {
 		c.Config.Listeners.onChannelChange(&event)
+		c.noteChannelChange(event.Channel, event.Type)
 	}
+	c.triggerBlobEvents(blobEvents)
 	return nil
 }
 
-func (c *Client) handleUserRemove(buffer []byte) error {
-	var packet MumbleProto.UserRemove
-	if err := proto.Unmarshal(buffer, &packet); err != nil {
-		return err
-	}
+func (c *Client) handleUserRemove(message proto.Message) error {
+	packet := message.(*MumbleProto.UserRemove)
 
 	if packet.Session == nil {
 		return errIncompleteProtobuf
@@ -439,6 +506,7 @@ func (c *Client) handleUserRemove(buffer []byte) error {
 
 	{
 		c.volatile.Lock()
+		c.stateChanged()
 
 		session := *packet.Session
 		event.User = c.Users[session]
@@ -467,6 +535,7 @@ func (c *Client) handleUserRemove(buffer []byte) error {
 			event.Type |= UserChangeBanned
 		}
 		if event.User == c.Self {
+			c.disconnectEvent.String = event.String
 			if packet.Ban != nil && *packet.Ban {
 				c.disconnectEvent.Type = DisconnectBanned
 			} else {
@@ -474,6 +543,15 @@ func (c *Client) handleUserRemove(buffer []byte) error {
 			}
 		}
 
+		c.putDecoder(event.User)
+		if event.User.playout != nil {
+			// The playout buffer closes the user's streams once it has
+			// stopped passing packets to them.
+			event.User.playout.close()
+			event.User.playout = nil
+		} else {
+			c.closeAudioStreams(event.User)
+		}
 		c.volatile.Unlock()
 	}
 
@@ -483,11 +561,8 @@ func (c *Client) handleUserRemove(buffer []byte) error {
 	return nil
 }
 
-func (c *Client) handleUserState(buffer []byte) error {
-	var packet MumbleProto.UserState
-	if err := proto.Unmarshal(buffer, &packet); err != nil {
-		return err
-	}
+func (c *Client) handleUserState(message proto.Message) error {
+	packet := message.(*MumbleProto.UserState)
 
 	if packet.Session == nil {
 		return errIncompleteProtobuf
@@ -496,8 +571,10 @@ func (c *Client) handleUserState(buffer []byte) error {
 		Client: c,
 	}
 	var user, actor *User
+	var blobEvents []Event
 	{
 		c.volatile.Lock()
+		c.stateChanged()
 
 		session := *packet.Session
 		user = c.Users[session]
@@ -514,6 +591,9 @@ func (c *Client) handleUserState(buffer []byte) error {
 			}
 			event.Type |= UserChangeChannel
 			user.Channel.Users[session] = user
+		} else {
+			previous := *user
+			event.Previous = &previous
 		}
 
 		event.User = user
@@ -550,7 +630,7 @@ func (c *Client) handleUserState(buffer []byte) error {
 			}
 			newChannel := c.Channels[*packet.ChannelId]
 			if newChannel == nil {
-				c.volatile.Lock()
+				c.volatile.Unlock()
 				return errInvalidProtobuf
 			}
 			if newChannel != user.Channel {
@@ -561,38 +641,45 @@ func (c *Client) handleUserState(buffer []byte) error {
 		}
 		if packet.Mute != nil {
 			if *packet.Mute != user.Muted {
-				event.Type |= UserChangeAudio
+				event.Type |= UserChangeAudio | UserChangeMuted
 			}
 			user.Muted = *packet.Mute
 		}
 		if packet.Deaf != nil {
 			if *packet.Deaf != user.Deafened {
-				event.Type |= UserChangeAudio
+				event.Type |= UserChangeAudio | UserChangeDeafened
 			}
 			user.Deafened = *packet.Deaf
 		}
 		if packet.Suppress != nil {
 			if *packet.Suppress != user.Suppressed {
-				event.Type |= UserChangeAudio
+				event.Type |= UserChangeAudio | UserChangeSuppressed
 			}
 			user.Suppressed = *packet.Suppress
 		}
 		if packet.SelfMute != nil {
 			if *packet.SelfMute != user.SelfMuted {
-				event.Type |= UserChangeAudio
+				event.Type |= UserChangeAudio | UserChangeSelfMuted
 			}
 			user.SelfMuted = *packet.SelfMute
+			if user == c.Self {
+				c.selfMuted.Store(user.SelfMuted)
+			}
 		}
 		if packet.SelfDeaf != nil {
 			if *packet.SelfDeaf != user.SelfDeafened {
-				event.Type |= UserChangeAudio
+				event.Type |= UserChangeAudio | UserChangeSelfDeafened
 			}
 			user.SelfDeafened = *packet.SelfDeaf
+			if user == c.Self {
+				c.selfDeafened.Store(user.SelfDeafened)
+			}
 		}
 		if packet.Texture != nil {
 			event.Type |= UserChangeTexture
 			user.Texture = packet.Texture
 			user.TextureHash = nil
+			blobEvents = append(blobEvents, c.blobReceived(blobTexture, user.Texture)...)
 		}
 		if packet.Comment != nil {
 			if *packet.Comment != user.Comment {
@@ -600,19 +687,33 @@ func (c *Client) handleUserState(buffer []byte) error {
 			}
 			user.Comment = *packet.Comment
 			user.CommentHash = nil
+			blobEvents = append(blobEvents, c.blobReceived(blobComment, []byte(user.Comment))...)
 		}
 		if packet.Hash != nil {
+			if *packet.Hash != user.Hash {
+				event.Type |= UserChangeHash
+			}
 			user.Hash = *packet.Hash
 		}
 		if packet.CommentHash != nil {
 			event.Type |= UserChangeComment
-			user.CommentHash = packet.CommentHash
-			user.Comment = ""
+			if comment, ok := c.cachedBlob(packet.CommentHash); ok {
+				user.Comment = string(comment)
+				user.CommentHash = nil
+			} else {
+				user.CommentHash = packet.CommentHash
+				user.Comment = ""
+			}
 		}
 		if packet.TextureHash != nil {
 			event.Type |= UserChangeTexture
-			user.TextureHash = packet.TextureHash
-			user.Texture = nil
+			if texture, ok := c.cachedBlob(packet.TextureHash); ok {
+				user.Texture = texture
+				user.TextureHash = nil
+			} else {
+				user.TextureHash = packet.TextureHash
+				user.Texture = nil
+			}
 		}
 		if packet.PrioritySpeaker != nil {
 			if *packet.PrioritySpeaker != user.PrioritySpeaker {
@@ -633,14 +734,12 @@ func (c *Client) handleUserState(buffer []byte) error {
 	if c.State() == StateSynced {
 		c.Config.Listeners.onUserChange(&event)
 	}
+	c.triggerBlobEvents(blobEvents)
 	return nil
 }
 
-func (c *Client) handleBanList(buffer []byte) error {
-	var packet MumbleProto.BanList
-	if err := proto.Unmarshal(buffer, &packet); err != nil {
-		return err
-	}
+func (c *Client) handleBanList(message proto.Message) error {
+	packet := message.(*MumbleProto.BanList)
 
 	event := BanListEvent{
 		Client:  c,
@@ -680,11 +779,8 @@ func (c *Client) handleBanList(buffer []byte) error {
 	return nil
 }
 
-func (c *Client) handleTextMessage(buffer []byte) error {
-	var packet MumbleProto.TextMessage
-	if err := proto.Unmarshal(buffer, &packet); err != nil {
-		return err
-	}
+func (c *Client) handleTextMessage(message proto.Message) error {
+	packet := message.(*MumbleProto.TextMessage)
 
 	event := TextMessageEvent{
 		Client: c,
@@ -724,11 +820,8 @@ func (c *Client) handleTextMessage(buffer []byte) error {
 	return nil
 }
 
-func (c *Client) handlePermissionDenied(buffer []byte) error {
-	var packet MumbleProto.PermissionDenied
-	if err := proto.Unmarshal(buffer, &packet); err != nil {
-		return err
-	}
+func (c *Client) handlePermissionDenied(message proto.Message) error {
+	packet := message.(*MumbleProto.PermissionDenied)
 
 	if packet.Type == nil || *packet.Type == MumbleProto.PermissionDenied_H9K {
 		return errInvalidProtobuf
@@ -764,11 +857,8 @@ func (c *Client) handlePermissionDenied(buffer []byte) error {
 	return nil
 }
 
-func (c *Client) handleACL(buffer []byte) error {
-	var packet MumbleProto.ACL
-	if err := proto.Unmarshal(buffer, &packet); err != nil {
-		return err
-	}
+func (c *Client) handleACL(message proto.Message) error {
+	packet := message.(*MumbleProto.ACL)
 
 	acl := &ACL{
 		Inherits: packet.GetInheritAcls(),
@@ -849,27 +939,87 @@ func (c *Client) handleACL(buffer []byte) error {
 			acl.Rules = append(acl.Rules, aclRule)
 		}
 	}
+
+	// Resolve the names of the users in the ACL before triggering the event.
+	var userIDs []uint32
+	{
+		seen := make(map[uint32]bool)
+		add := func(user *ACLUser) {
+			if !seen[user.UserID] {
+				seen[user.UserID] = true
+				userIDs = append(userIDs, user.UserID)
+			}
+		}
+		for _, group := range acl.Groups {
+			for _, user := range group.UsersAdd {
+				add(user)
+			}
+			for _, user := range group.UsersRemove {
+				add(user)
+			}
+			for _, user := range group.UsersInherited {
+				add(user)
+			}
+		}
+		for _, rule := range acl.Rules {
+			if rule.User != nil {
+				add(rule.User)
+			}
+		}
+	}
+	if len(userIDs) == 0 {
+		event := ACLEvent{
+			Client: c,
+			ACL:    acl,
+		}
+		c.Config.Listeners.onACL(&event)
+		return nil
+	}
 	c.tmpACL = acl
+	c.tmpACLIDs = make(map[uint32]bool, len(userIDs))
+	for _, id := range userIDs {
+		c.tmpACLIDs[id] = true
+	}
+	c.RequestUserNames(userIDs...)
 	return nil
 }
 
-func (c *Client) handleQueryUsers(buffer []byte) error {
-	var packet MumbleProto.QueryUsers
-	if err := proto.Unmarshal(buffer, &packet); err != nil {
-		return err
+// isACLQueryReply returns true if the QueryUsers reply answers the name lookup
+// that handleACL sent. The server only includes the requested users that it
+// found, so the reply matches if it contains none other than them.
+func (c *Client) isACLQueryReply(packet *MumbleProto.QueryUsers) bool {
+	if c.tmpACL == nil || len(packet.Names) != len(packet.Ids) {
+		return false
 	}
-
-	acl := c.tmpACL
-	if acl == nil {
-		return errIncompleteProtobuf
+	for _, id := range packet.Ids {
+		if !c.tmpACLIDs[id] {
+			return false
+		}
 	}
-	c.tmpACL = nil
+	return true
+}
+
+func (c *Client) handleQueryUsers(message proto.Message) error {
+	packet := message.(*MumbleProto.QueryUsers)
 
 	userMap := make(map[uint32]string)
 	for i := 0; i < len(packet.Ids) && i < len(packet.Names); i++ {
 		userMap[packet.Ids[i]] = packet.Names[i]
 	}
 
+	if !c.isACLQueryReply(packet) {
+		queryEvent := QueryUsersEvent{
+			Client: c,
+			Users:  userMap,
+		}
+		c.Config.Listeners.onQueryUsers(&queryEvent)
+		return nil
+	}
+
+	acl := c.tmpACL
+	c.tmpACL = nil
+	c.tmpACLIDs = nil
+
 	for _, group := range acl.Groups {
 		for _, user := range group.UsersAdd {
 			user.Name = userMap[user.UserID]
@@ -895,15 +1045,18 @@ func (c *Client) handleQueryUsers(buffer []byte) error {
 	return nil
 }
 
-func (c *Client) handleCryptSetup(buffer []byte) error {
-	return errUnimplementedHandler
+func (c *Client) handleCryptSetup(message proto.Message) error {
+	// Audio is always tunnelled over TCP, so UDP crypt setups and resync
+	// requests are ignored.
+	packet := message.(*MumbleProto.CryptSetup)
+	if packet.Key == nil && packet.ServerNonce == nil {
+		c.logger.Debug("gumble: ignoring crypt resync request")
+	}
+	return nil
 }
 
-func (c *Client) handleContextActionModify(buffer []byte) error {
-	var packet MumbleProto.ContextActionModify
-	if err := proto.Unmarshal(buffer, &packet); err != nil {
-		return err
-	}
+func (c *Client) handleContextActionModify(message proto.Message) error {
+	packet := message.(*MumbleProto.ContextActionModify)
 
 	if packet.Action == nil || packet.Operation == nil {
 		return errInvalidProtobuf
@@ -918,12 +1071,18 @@ func (c *Client) handleContextActionModify(buffer []byte) error {
 
 		switch *packet.Operation {
 		case MumbleProto.ContextActionModify_Add:
-			if ca := c.ContextActions[*packet.Action]; ca != nil {
-				c.volatile.Unlock()
-				return nil
-			}
 			event.Type = ContextActionAdd
-			contextAction := c.ContextActions.create(*packet.Action)
+			contextAction := c.ContextActions[*packet.Action]
+			if contextAction != nil {
+				// An Add for an existing action updates it.
+				if (packet.Text == nil || *packet.Text == contextAction.Label) && (packet.Context == nil || ContextActionType(*packet.Context) == contextAction.Type) {
+					c.volatile.Unlock()
+					return nil
+				}
+			} else {
+				contextAction = c.ContextActions.create(*packet.Action)
+				contextAction.client = c
+			}
 			if packet.Text != nil {
 				contextAction.Label = *packet.Text
 			}
@@ -952,16 +1111,42 @@ func (c *Client) handleContextActionModify(buffer []byte) error {
 	return nil
 }
 
-func (c *Client) handleContextAction(buffer []byte) error {
-	return errUnimplementedHandler
-}
+func (c *Client) handleContextAction(message proto.Message) error {
+	packet := message.(*MumbleProto.ContextAction)
 
-func (c *Client) handleUserList(buffer []byte) error {
-	var packet MumbleProto.UserList
-	if err := proto.Unmarshal(buffer, &packet); err != nil {
-		return err
+	if packet.Action == nil {
+		return errInvalidProtobuf
 	}
 
+	event := ContextActionEvent{
+		Client:        c,
+		Action:        *packet.Action,
+		ContextAction: c.ContextActions[*packet.Action],
+		Type:          ContextActionServer,
+	}
+	if event.ContextAction == nil {
+		event.ContextAction = c.registeredContextAction(*packet.Action)
+	}
+	switch {
+	case packet.Session != nil:
+		event.Type = ContextActionUser
+		if event.User = c.Users[*packet.Session]; event.User == nil {
+			return nil
+		}
+	case packet.ChannelId != nil:
+		event.Type = ContextActionChannel
+		if event.Channel = c.Channels[*packet.ChannelId]; event.Channel == nil {
+			return nil
+		}
+	}
+
+	c.Config.Listeners.onContextAction(&event)
+	return nil
+}
+
+func (c *Client) handleUserList(message proto.Message) error {
+	packet := message.(*MumbleProto.UserList)
+
 	event := UserListEvent{
 		Client:   c,
 		UserList: make(RegisteredUsers, 0, len(packet.Users)),
@@ -989,15 +1174,12 @@ func (c *Client) handleUserList(buffer []byte) error {
 	return nil
 }
 
-func (c *Client) handleVoiceTarget(buffer []byte) error {
+func (c *Client) handleVoiceTarget(message proto.Message) error {
 	return errUnimplementedHandler
 }
 
-func (c *Client) handlePermissionQuery(buffer []byte) error {
-	var packet MumbleProto.PermissionQuery
-	if err := proto.Unmarshal(buffer, &packet); err != nil {
-		return err
-	}
+func (c *Client) handlePermissionQuery(message proto.Message) error {
+	packet := message.(*MumbleProto.PermissionQuery)
 
 	var singleChannel *Channel
 	if packet.ChannelId != nil && packet.Permissions != nil {
@@ -1039,14 +1221,22 @@ func (c *Client) handlePermissionQuery(buffer []byte) error {
 		c.Config.Listeners.onChannelChange(&event)
 	}
 
+	event := PermissionQueryEvent{
+		Client:  c,
+		Channel: singleChannel,
+		Flush:   packet.GetFlush(),
+	}
+	if singleChannel != nil {
+		p := Permission(*packet.Permissions)
+		event.Permission = &p
+	}
+	c.Config.Listeners.onPermissionQuery(&event)
+
 	return nil
 }
 
-func (c *Client) handleCodecVersion(buffer []byte) error {
-	var packet MumbleProto.CodecVersion
-	if err := proto.Unmarshal(buffer, &packet); err != nil {
-		return err
-	}
+func (c *Client) handleCodecVersion(message proto.Message) error {
+	packet := message.(*MumbleProto.CodecVersion)
 	event := ServerConfigEvent{
 		Client: c,
 	}
@@ -1067,12 +1257,19 @@ func (c *Client) handleCodecVersion(buffer []byte) error {
 		codec = getAudioCodec(audioCodecIDOpus)
 	}
 	if codec != nil {
+		if c.audioCodec == nil || c.audioCodec.ID() != codec.ID() {
+			c.decoders = nil
+		}
 		c.audioCodec = codec
 
 		{
 			c.volatile.Lock()
 
-			c.AudioEncoder = codec.NewEncoder()
+			// The server sends CodecVersion whenever a user connects, so the
+			// encoder is only replaced if the codec has changed.
+			if c.AudioEncoder == nil || c.AudioEncoder.ID() != codec.ID() {
+				c.AudioEncoder = codec.NewEncoder()
+			}
 
 			c.volatile.Unlock()
 		}
@@ -1082,11 +1279,8 @@ func (c *Client) handleCodecVersion(buffer []byte) error {
 	return nil
 }
 
-func (c *Client) handleUserStats(buffer []byte) error {
-	var packet MumbleProto.UserStats
-	if err := proto.Unmarshal(buffer, &packet); err != nil {
-		return err
-	}
+func (c *Client) handleUserStats(message proto.Message) error {
+	packet := message.(*MumbleProto.UserStats)
 
 	if packet.Session == nil {
 		return errIncompleteProtobuf
@@ -1096,8 +1290,10 @@ func (c *Client) handleUserStats(buffer []byte) error {
 		return errInvalidProtobuf
 	}
 
+	changeType := UserChangeStats
 	{
 		c.volatile.Lock()
+		c.stateChanged()
 
 		if user.Stats == nil {
 			user.Stats = &UserStats{}
@@ -1158,11 +1354,15 @@ func (c *Client) handleUserStats(buffer []byte) error {
 		if packet.Version != nil {
 			stats.Version = parseVersion(packet.Version)
 		}
+		now := c.clock.Now()
+		user.StatsUpdated = now
 		if packet.Onlinesecs != nil {
-			stats.Connected = time.Now().Add(time.Duration(*packet.Onlinesecs) * -time.Second)
+			user.OnlineDuration = time.Duration(*packet.Onlinesecs) * time.Second
+			stats.Connected = now.Add(-user.OnlineDuration)
 		}
 		if packet.Idlesecs != nil {
 			stats.Idle = time.Duration(*packet.Idlesecs) * time.Second
+			user.IdleDuration = stats.Idle
 		}
 		if packet.Bandwidth != nil {
 			stats.Bandwidth = int(*packet.Bandwidth)
@@ -1180,6 +1380,14 @@ func (c *Client) handleUserStats(buffer []byte) error {
 				}
 			}
 		}
+		if user.Hash == "" && len(packet.Certificates) > 0 && packet.Certificates[0] != nil {
+			// Servers only send certificate hashes to clients that are
+			// allowed to see them; the hash can also be computed from the
+			// certificate.
+			hash := sha1.Sum(packet.Certificates[0])
+			user.Hash = hex.EncodeToString(hash[:])
+			changeType |= UserChangeHash
+		}
 		stats.StrongCertificate = packet.GetStrongCertificate()
 		stats.CELTVersions = packet.GetCeltVersions()
 		if packet.Opus != nil {
@@ -1191,7 +1399,7 @@ func (c *Client) handleUserStats(buffer []byte) error {
 
 	event := UserChangeEvent{
 		Client: c,
-		Type:   UserChangeStats,
+		Type:   changeType,
 		User:   user,
 	}
 
@@ -1199,21 +1407,21 @@ func (c *Client) handleUserStats(buffer []byte) error {
 	return nil
 }
 
-func (c *Client) handleRequestBlob(buffer []byte) error {
+func (c *Client) handleRequestBlob(message proto.Message) error {
 	return errUnimplementedHandler
 }
 
-func (c *Client) handleServerConfig(buffer []byte) error {
-	var packet MumbleProto.ServerConfig
-	if err := proto.Unmarshal(buffer, &packet); err != nil {
-		return err
-	}
+func (c *Client) handleServerConfig(message proto.Message) error {
+	packet := message.(*MumbleProto.ServerConfig)
 	event := ServerConfigEvent{
 		Client: c,
 	}
 	if packet.MaxBandwidth != nil {
 		val := int(*packet.MaxBandwidth)
 		event.MaximumBitrate = &val
+		if bandwidthEvent := c.adjustBandwidth(val); bandwidthEvent != nil && c.State() == StateSynced {
+			c.Config.Listeners.onAudioBandwidth(bandwidthEvent)
+		}
 	}
 	if packet.WelcomeText != nil {
 		event.WelcomeMessage = packet.WelcomeText
@@ -1224,24 +1432,26 @@ func (c *Client) handleServerConfig(buffer []byte) error {
 	if packet.MessageLength != nil {
 		val := int(*packet.MessageLength)
 		event.MaximumMessageLength = &val
+		atomic.StoreInt32(&c.maximumMessageLength, int32(val))
 	}
 	if packet.ImageMessageLength != nil {
 		val := int(*packet.ImageMessageLength)
 		event.MaximumImageMessageLength = &val
+		atomic.StoreInt32(&c.maximumImageMessageLength, int32(val))
 	}
 	if packet.MaxUsers != nil {
 		val := int(*packet.MaxUsers)
 		event.MaximumUsers = &val
 	}
+	c.volatile.Lock()
+	c.serverConfig.update(&event)
+	c.volatile.Unlock()
 	c.Config.Listeners.onServerConfig(&event)
 	return nil
 }
 
-func (c *Client) handleSuggestConfig(buffer []byte) error {
-	var packet MumbleProto.SuggestConfig
-	if err := proto.Unmarshal(buffer, &packet); err != nil {
-		return err
-	}
+func (c *Client) handleSuggestConfig(message proto.Message) error {
+	packet := message.(*MumbleProto.SuggestConfig)
 	event := ServerConfigEvent{
 		Client: c,
 	}
@@ -0,0 +1,404 @@
+package gumble
+
+// EventFunc is a function that handles a single type of event. It can be
+// attached to Listeners using AttachFunc, which avoids having to implement
+// every method of EventListener when only one event is of interest:
+//
+//  config.AttachFunc(gumble.OnTextMessageFunc(func(e *gumble.TextMessageEvent) {
+//      fmt.Printf("Received text message: %s\n", e.Message)
+//  }))
+//
+// The following types implement EventFunc:
+//  OnConnectFunc
+//  OnDisconnectFunc
+//  OnTextMessageFunc
+//  OnUserChangeFunc
+//  OnChannelChangeFunc
+//  OnPermissionDeniedFunc
+//  OnUserListFunc
+//  OnACLFunc
+//  OnBanListFunc
+//  OnContextActionChangeFunc
+//  OnServerConfigFunc
+//  OnListenerErrorFunc
+//  OnPingUpdatedFunc
+//  OnPermissionQueryFunc
+//  OnQueryUsersFunc
+//  OnChannelTreeChangeFunc
+//  OnProtocolErrorFunc
+//  OnSlowListenerFunc
+//  OnWriteErrorFunc
+//  OnAudioBandwidthFunc
+//  OnContextActionFunc
+//  OnServerVersionFunc
+type EventFunc interface {
+	listener() *funcListener
+}
+
+// OnConnectFunc is an EventFunc that handles ConnectEvents.
+type OnConnectFunc func(e *ConnectEvent)
+
+func (f OnConnectFunc) listener() *funcListener {
+	return &funcListener{connect: f}
+}
+
+// OnDisconnectFunc is an EventFunc that handles DisconnectEvents.
+type OnDisconnectFunc func(e *DisconnectEvent)
+
+func (f OnDisconnectFunc) listener() *funcListener {
+	return &funcListener{disconnect: f}
+}
+
+// OnTextMessageFunc is an EventFunc that handles TextMessageEvents.
+type OnTextMessageFunc func(e *TextMessageEvent)
+
+func (f OnTextMessageFunc) listener() *funcListener {
+	return &funcListener{textMessage: f}
+}
+
+// OnUserChangeFunc is an EventFunc that handles UserChangeEvents.
+type OnUserChangeFunc func(e *UserChangeEvent)
+
+func (f OnUserChangeFunc) listener() *funcListener {
+	return &funcListener{userChange: f}
+}
+
+// OnChannelChangeFunc is an EventFunc that handles ChannelChangeEvents.
+type OnChannelChangeFunc func(e *ChannelChangeEvent)
+
+func (f OnChannelChangeFunc) listener() *funcListener {
+	return &funcListener{channelChange: f}
+}
+
+// OnPermissionDeniedFunc is an EventFunc that handles PermissionDeniedEvents.
+type OnPermissionDeniedFunc func(e *PermissionDeniedEvent)
+
+func (f OnPermissionDeniedFunc) listener() *funcListener {
+	return &funcListener{permissionDenied: f}
+}
+
+// OnUserListFunc is an EventFunc that handles UserListEvents.
+type OnUserListFunc func(e *UserListEvent)
+
+func (f OnUserListFunc) listener() *funcListener {
+	return &funcListener{userList: f}
+}
+
+// OnACLFunc is an EventFunc that handles ACLEvents.
+type OnACLFunc func(e *ACLEvent)
+
+func (f OnACLFunc) listener() *funcListener {
+	return &funcListener{acl: f}
+}
+
+// OnBanListFunc is an EventFunc that handles BanListEvents.
+type OnBanListFunc func(e *BanListEvent)
+
+func (f OnBanListFunc) listener() *funcListener {
+	return &funcListener{banList: f}
+}
+
+// OnContextActionChangeFunc is an EventFunc that handles
+// ContextActionChangeEvents.
+type OnContextActionChangeFunc func(e *ContextActionChangeEvent)
+
+func (f OnContextActionChangeFunc) listener() *funcListener {
+	return &funcListener{contextActionChange: f}
+}
+
+// OnServerConfigFunc is an EventFunc that handles ServerConfigEvents.
+type OnServerConfigFunc func(e *ServerConfigEvent)
+
+func (f OnServerConfigFunc) listener() *funcListener {
+	return &funcListener{serverConfig: f}
+}
+
+// OnListenerErrorFunc is an EventFunc that handles ListenerErrorEvents.
+type OnListenerErrorFunc func(e *ListenerErrorEvent)
+
+func (f OnListenerErrorFunc) listener() *funcListener {
+	return &funcListener{listenerError: f}
+}
+
+// OnPingUpdatedFunc is an EventFunc that handles PingUpdatedEvents.
+type OnPingUpdatedFunc func(e *PingUpdatedEvent)
+
+func (f OnPingUpdatedFunc) listener() *funcListener {
+	return &funcListener{pingUpdated: f}
+}
+
+// OnPermissionQueryFunc is an EventFunc that handles PermissionQueryEvents.
+type OnPermissionQueryFunc func(e *PermissionQueryEvent)
+
+func (f OnPermissionQueryFunc) listener() *funcListener {
+	return &funcListener{permissionQuery: f}
+}
+
+// OnQueryUsersFunc is an EventFunc that handles QueryUsersEvents.
+type OnQueryUsersFunc func(e *QueryUsersEvent)
+
+func (f OnQueryUsersFunc) listener() *funcListener {
+	return &funcListener{queryUsers: f}
+}
+
+// OnChannelTreeChangeFunc is an EventFunc that handles ChannelTreeChangeEvents.
+type OnChannelTreeChangeFunc func(e *ChannelTreeChangeEvent)
+
+func (f OnChannelTreeChangeFunc) listener() *funcListener {
+	return &funcListener{channelTreeChange: f}
+}
+
+// OnProtocolErrorFunc is an EventFunc that handles ProtocolErrorEvents.
+type OnProtocolErrorFunc func(e *ProtocolErrorEvent)
+
+func (f OnProtocolErrorFunc) listener() *funcListener {
+	return &funcListener{protocolError: f}
+}
+
+// OnSlowListenerFunc is an EventFunc that handles SlowListenerEvents.
+type OnSlowListenerFunc func(e *SlowListenerEvent)
+
+func (f OnSlowListenerFunc) listener() *funcListener {
+	return &funcListener{slowListener: f}
+}
+
+// OnWriteErrorFunc is an EventFunc that handles WriteErrorEvents.
+type OnWriteErrorFunc func(e *WriteErrorEvent)
+
+func (f OnWriteErrorFunc) listener() *funcListener {
+	return &funcListener{writeError: f}
+}
+
+// OnAudioBandwidthFunc is an EventFunc that handles AudioBandwidthEvents.
+type OnAudioBandwidthFunc func(e *AudioBandwidthEvent)
+
+func (f OnAudioBandwidthFunc) listener() *funcListener {
+	return &funcListener{audioBandwidth: f}
+}
+
+// OnContextActionFunc is an EventFunc that handles ContextActionEvents.
+type OnContextActionFunc func(e *ContextActionEvent)
+
+func (f OnContextActionFunc) listener() *funcListener {
+	return &funcListener{contextAction: f}
+}
+
+// OnServerVersionFunc is an EventFunc that handles ServerVersionEvents.
+type OnServerVersionFunc func(e *ServerVersionEvent)
+
+func (f OnServerVersionFunc) listener() *funcListener {
+	return &funcListener{serverVersion: f}
+}
+
+// funcListener is an EventListener that forwards events to the non-nil
+// functions it contains.
+type funcListener struct {
+	connect             OnConnectFunc
+	disconnect          OnDisconnectFunc
+	textMessage         OnTextMessageFunc
+	userChange          OnUserChangeFunc
+	channelChange       OnChannelChangeFunc
+	permissionDenied    OnPermissionDeniedFunc
+	userList            OnUserListFunc
+	acl                 OnACLFunc
+	banList             OnBanListFunc
+	contextActionChange OnContextActionChangeFunc
+	serverConfig        OnServerConfigFunc
+	listenerError       OnListenerErrorFunc
+	pingUpdated         OnPingUpdatedFunc
+	permissionQuery     OnPermissionQueryFunc
+	queryUsers          OnQueryUsersFunc
+	channelTreeChange   OnChannelTreeChangeFunc
+	protocolError       OnProtocolErrorFunc
+	slowListener        OnSlowListenerFunc
+	writeError          OnWriteErrorFunc
+	audioBandwidth      OnAudioBandwidthFunc
+	contextAction       OnContextActionFunc
+	serverVersion       OnServerVersionFunc
+}
+
+var _ EventListener = (*funcListener)(nil)
+
+// handles returns true if the listener contains a function for the given
+// event's type.
+func (l *funcListener) handles(event Event) bool {
+	switch event.(type) {
+	case *ConnectEvent:
+		return l.connect != nil
+	case *DisconnectEvent:
+		return l.disconnect != nil
+	case *TextMessageEvent:
+		return l.textMessage != nil
+	case *UserChangeEvent:
+		return l.userChange != nil
+	case *ChannelChangeEvent:
+		return l.channelChange != nil
+	case *PermissionDeniedEvent:
+		return l.permissionDenied != nil
+	case *UserListEvent:
+		return l.userList != nil
+	case *ACLEvent:
+		return l.acl != nil
+	case *BanListEvent:
+		return l.banList != nil
+	case *ContextActionChangeEvent:
+		return l.contextActionChange != nil
+	case *ServerConfigEvent:
+		return l.serverConfig != nil
+	case *ListenerErrorEvent:
+		return l.listenerError != nil
+	case *PingUpdatedEvent:
+		return l.pingUpdated != nil
+	case *PermissionQueryEvent:
+		return l.permissionQuery != nil
+	case *QueryUsersEvent:
+		return l.queryUsers != nil
+	case *ChannelTreeChangeEvent:
+		return l.channelTreeChange != nil
+	case *ProtocolErrorEvent:
+		return l.protocolError != nil
+	case *SlowListenerEvent:
+		return l.slowListener != nil
+	case *WriteErrorEvent:
+		return l.writeError != nil
+	case *AudioBandwidthEvent:
+		return l.audioBandwidth != nil
+	case *ContextActionEvent:
+		return l.contextAction != nil
+	case *ServerVersionEvent:
+		return l.serverVersion != nil
+	}
+	return false
+}
+
+func (l *funcListener) OnConnect(e *ConnectEvent) {
+	if l.connect != nil {
+		l.connect(e)
+	}
+}
+
+func (l *funcListener) OnDisconnect(e *DisconnectEvent) {
+	if l.disconnect != nil {
+		l.disconnect(e)
+	}
+}
+
+func (l *funcListener) OnTextMessage(e *TextMessageEvent) {
+	if l.textMessage != nil {
+		l.textMessage(e)
+	}
+}
+
+func (l *funcListener) OnUserChange(e *UserChangeEvent) {
+	if l.userChange != nil {
+		l.userChange(e)
+	}
+}
+
+func (l *funcListener) OnChannelChange(e *ChannelChangeEvent) {
+	if l.channelChange != nil {
+		l.channelChange(e)
+	}
+}
+
+func (l *funcListener) OnPermissionDenied(e *PermissionDeniedEvent) {
+	if l.permissionDenied != nil {
+		l.permissionDenied(e)
+	}
+}
+
+func (l *funcListener) OnUserList(e *UserListEvent) {
+	if l.userList != nil {
+		l.userList(e)
+	}
+}
+
+func (l *funcListener) OnACL(e *ACLEvent) {
+	if l.acl != nil {
+		l.acl(e)
+	}
+}
+
+func (l *funcListener) OnBanList(e *BanListEvent) {
+	if l.banList != nil {
+		l.banList(e)
+	}
+}
+
+func (l *funcListener) OnContextActionChange(e *ContextActionChangeEvent) {
+	if l.contextActionChange != nil {
+		l.contextActionChange(e)
+	}
+}
+
+func (l *funcListener) OnServerConfig(e *ServerConfigEvent) {
+	if l.serverConfig != nil {
+		l.serverConfig(e)
+	}
+}
+
+func (l *funcListener) OnListenerError(e *ListenerErrorEvent) {
+	if l.listenerError != nil {
+		l.listenerError(e)
+	}
+}
+
+func (l *funcListener) OnPingUpdated(e *PingUpdatedEvent) {
+	if l.pingUpdated != nil {
+		l.pingUpdated(e)
+	}
+}
+
+func (l *funcListener) OnPermissionQuery(e *PermissionQueryEvent) {
+	if l.permissionQuery != nil {
+		l.permissionQuery(e)
+	}
+}
+
+func (l *funcListener) OnQueryUsers(e *QueryUsersEvent) {
+	if l.queryUsers != nil {
+		l.queryUsers(e)
+	}
+}
+
+func (l *funcListener) OnChannelTreeChange(e *ChannelTreeChangeEvent) {
+	if l.channelTreeChange != nil {
+		l.channelTreeChange(e)
+	}
+}
+
+func (l *funcListener) OnProtocolError(e *ProtocolErrorEvent) {
+	if l.protocolError != nil {
+		l.protocolError(e)
+	}
+}
+
+func (l *funcListener) OnSlowListener(e *SlowListenerEvent) {
+	if l.slowListener != nil {
+		l.slowListener(e)
+	}
+}
+
+func (l *funcListener) OnWriteError(e *WriteErrorEvent) {
+	if l.writeError != nil {
+		l.writeError(e)
+	}
+}
+
+func (l *funcListener) OnAudioBandwidth(e *AudioBandwidthEvent) {
+	if l.audioBandwidth != nil {
+		l.audioBandwidth(e)
+	}
+}
+
+func (l *funcListener) OnContextAction(e *ContextActionEvent) {
+	if l.contextAction != nil {
+		l.contextAction(e)
+	}
+}
+
+func (l *funcListener) OnServerVersion(e *ServerVersionEvent) {
+	if l.serverVersion != nil {
+		l.serverVersion(e)
+	}
+}
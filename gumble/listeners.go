@@ -1,12 +1,29 @@
 package gumble
 
+import (
+	"runtime/debug"
+	"time"
+)
+
 type eventItem struct {
 	parent     *Listeners
 	prev, next *eventItem
 	listener   EventListener
+	// If non-nil, all is called with every event instead of listener.
+	all func(event Event)
+	// If non-nil, the item is detached after handling an event for which
+	// until returns true.
+	until func(event Event) bool
+	// While replaying is true, events are appended to backlog rather than
+	// being passed to the listener.
+	replaying bool
+	backlog   []Event
 }
 
 func (e *eventItem) Detach() {
+	if e.parent == nil {
+		return
+	}
 	if e.prev == nil {
 		e.parent.head = e.next
 	} else {
@@ -17,12 +34,15 @@ func (e *eventItem) Detach() {
 	} else {
 		e.next.prev = e.prev
 	}
+	e.parent = nil
 }
 
 // Listeners is a list of event listeners. Each attached listener is called in
 // sequence when a Client event is triggered.
 type Listeners struct {
 	head, tail *eventItem
+
+	middlewareHead, middlewareTail *middlewareItem
 }
 
 // Attach adds a new event listener to the end of the current list of listeners.
@@ -42,112 +62,411 @@ func (e *Listeners) Attach(listener EventListener) Detacher {
 	return item
 }
 
+// AttachAll adds a function to the end of the current list of listeners that
+// is called with every event, regardless of its type.
+func (e *Listeners) AttachAll(f func(event Event)) Detacher {
+	item := e.Attach(nil).(*eventItem)
+	item.all = f
+	return item
+}
+
+// AttachUntil adds a new event listener to the end of the current list of
+// listeners. The listener is detached after it has been called with an event
+// for which done returns true.
+func (e *Listeners) AttachUntil(listener EventListener, done func(event Event) bool) Detacher {
+	item := e.Attach(listener).(*eventItem)
+	item.until = done
+	return item
+}
+
+// Once adds a listener for a single type of event to the end of the current
+// list of listeners. The listener is detached after handling the first event
+// of its type.
+func (e *Listeners) Once(f EventFunc) Detacher {
+	l := f.listener()
+	return e.AttachUntil(l, l.handles)
+}
+
+// AttachFunc adds a listener for a single type of event to the end of the
+// current list of listeners.
+func (e *Listeners) AttachFunc(f EventFunc) Detacher {
+	return e.Attach(f.listener())
+}
+
 func (e *Listeners) onConnect(event *ConnectEvent) {
-	event.Client.volatile.Lock()
-	for item := e.head; item != nil; item = item.next {
-		event.Client.volatile.Unlock()
-		item.listener.OnConnect(event)
-		event.Client.volatile.Lock()
-	}
-	event.Client.volatile.Unlock()
+	e.dispatch(event.Client, event, func(l EventListener) {
+		l.OnConnect(event)
+	})
 }
 
 func (e *Listeners) onDisconnect(event *DisconnectEvent) {
-	event.Client.volatile.Lock()
-	for item := e.head; item != nil; item = item.next {
-		event.Client.volatile.Unlock()
-		item.listener.OnDisconnect(event)
-		event.Client.volatile.Lock()
-	}
-	event.Client.volatile.Unlock()
+	e.dispatch(event.Client, event, func(l EventListener) {
+		l.OnDisconnect(event)
+	})
 }
 
 func (e *Listeners) onTextMessage(event *TextMessageEvent) {
-	event.Client.volatile.Lock()
-	for item := e.head; item != nil; item = item.next {
-		event.Client.volatile.Unlock()
-		item.listener.OnTextMessage(event)
-		event.Client.volatile.Lock()
-	}
-	event.Client.volatile.Unlock()
+	e.dispatch(event.Client, event, func(l EventListener) {
+		l.OnTextMessage(event)
+	})
 }
 
 func (e *Listeners) onUserChange(event *UserChangeEvent) {
-	event.Client.volatile.Lock()
-	for item := e.head; item != nil; item = item.next {
-		event.Client.volatile.Unlock()
-		item.listener.OnUserChange(event)
-		event.Client.volatile.Lock()
-	}
-	event.Client.volatile.Unlock()
+	e.dispatch(event.Client, event, func(l EventListener) {
+		l.OnUserChange(event)
+	})
 }
 
 func (e *Listeners) onChannelChange(event *ChannelChangeEvent) {
-	event.Client.volatile.Lock()
-	for item := e.head; item != nil; item = item.next {
-		event.Client.volatile.Unlock()
-		item.listener.OnChannelChange(event)
-		event.Client.volatile.Lock()
-	}
-	event.Client.volatile.Unlock()
+	e.dispatch(event.Client, event, func(l EventListener) {
+		l.OnChannelChange(event)
+	})
 }
 
 func (e *Listeners) onPermissionDenied(event *PermissionDeniedEvent) {
-	event.Client.volatile.Lock()
-	for item := e.head; item != nil; item = item.next {
-		event.Client.volatile.Unlock()
-		item.listener.OnPermissionDenied(event)
-		event.Client.volatile.Lock()
-	}
-	event.Client.volatile.Unlock()
+	e.dispatch(event.Client, event, func(l EventListener) {
+		l.OnPermissionDenied(event)
+	})
 }
 
 func (e *Listeners) onUserList(event *UserListEvent) {
-	event.Client.volatile.Lock()
-	for item := e.head; item != nil; item = item.next {
-		event.Client.volatile.Unlock()
-		item.listener.OnUserList(event)
-		event.Client.volatile.Lock()
-	}
-	event.Client.volatile.Unlock()
+	e.dispatch(event.Client, event, func(l EventListener) {
+		l.OnUserList(event)
+	})
 }
 
 func (e *Listeners) onACL(event *ACLEvent) {
-	event.Client.volatile.Lock()
-	for item := e.head; item != nil; item = item.next {
-		event.Client.volatile.Unlock()
-		item.listener.OnACL(event)
-		event.Client.volatile.Lock()
-	}
-	event.Client.volatile.Unlock()
+	e.dispatch(event.Client, event, func(l EventListener) {
+		l.OnACL(event)
+	})
 }
 
 func (e *Listeners) onBanList(event *BanListEvent) {
-	event.Client.volatile.Lock()
-	for item := e.head; item != nil; item = item.next {
-		event.Client.volatile.Unlock()
-		item.listener.OnBanList(event)
-		event.Client.volatile.Lock()
-	}
-	event.Client.volatile.Unlock()
+	e.dispatch(event.Client, event, func(l EventListener) {
+		l.OnBanList(event)
+	})
 }
 
 func (e *Listeners) onContextActionChange(event *ContextActionChangeEvent) {
-	event.Client.volatile.Lock()
-	for item := e.head; item != nil; item = item.next {
-		event.Client.volatile.Unlock()
-		item.listener.OnContextActionChange(event)
-		event.Client.volatile.Lock()
-	}
-	event.Client.volatile.Unlock()
+	e.dispatch(event.Client, event, func(l EventListener) {
+		l.OnContextActionChange(event)
+	})
 }
 
 func (e *Listeners) onServerConfig(event *ServerConfigEvent) {
-	event.Client.volatile.Lock()
-	for item := e.head; item != nil; item = item.next {
-		event.Client.volatile.Unlock()
-		item.listener.OnServerConfig(event)
-		event.Client.volatile.Lock()
+	e.dispatch(event.Client, event, func(l EventListener) {
+		l.OnServerConfig(event)
+	})
+}
+
+func (e *Listeners) onPingUpdated(event *PingUpdatedEvent) {
+	e.dispatch(event.Client, event, func(l EventListener) {
+		if l, ok := l.(PingUpdatedListener); ok {
+			l.OnPingUpdated(event)
+		}
+	})
+}
+
+func (e *Listeners) onPermissionQuery(event *PermissionQueryEvent) {
+	e.dispatch(event.Client, event, func(l EventListener) {
+		if l, ok := l.(PermissionQueryListener); ok {
+			l.OnPermissionQuery(event)
+		}
+	})
+}
+
+func (e *Listeners) onQueryUsers(event *QueryUsersEvent) {
+	e.dispatch(event.Client, event, func(l EventListener) {
+		if l, ok := l.(QueryUsersListener); ok {
+			l.OnQueryUsers(event)
+		}
+	})
+}
+
+func (e *Listeners) onChannelTreeChange(event *ChannelTreeChangeEvent) {
+	e.dispatch(event.Client, event, func(l EventListener) {
+		if l, ok := l.(ChannelTreeChangeListener); ok {
+			l.OnChannelTreeChange(event)
+		}
+	})
+}
+
+func (e *Listeners) onProtocolError(event *ProtocolErrorEvent) {
+	e.dispatch(event.Client, event, func(l EventListener) {
+		if l, ok := l.(ProtocolErrorListener); ok {
+			l.OnProtocolError(event)
+		}
+	})
+}
+
+func (e *Listeners) onSlowListener(event *SlowListenerEvent) {
+	e.dispatch(event.Client, event, func(l EventListener) {
+		if l, ok := l.(SlowListenerListener); ok {
+			l.OnSlowListener(event)
+		}
+	})
+}
+
+func (e *Listeners) onWriteError(event *WriteErrorEvent) {
+	e.dispatch(event.Client, event, func(l EventListener) {
+		if l, ok := l.(WriteErrorListener); ok {
+			l.OnWriteError(event)
+		}
+	})
+}
+
+func (e *Listeners) onAudioBandwidth(event *AudioBandwidthEvent) {
+	e.dispatch(event.Client, event, func(l EventListener) {
+		if l, ok := l.(AudioBandwidthListener); ok {
+			l.OnAudioBandwidth(event)
+		}
+	})
+}
+
+func (e *Listeners) onContextAction(event *ContextActionEvent) {
+	e.dispatch(event.Client, event, func(l EventListener) {
+		if l, ok := l.(ContextActionListener); ok {
+			l.OnContextAction(event)
+		}
+	})
+}
+
+func (e *Listeners) onServerVersion(event *ServerVersionEvent) {
+	e.dispatch(event.Client, event, func(l EventListener) {
+		if l, ok := l.(ServerVersionListener); ok {
+			l.OnServerVersion(event)
+		}
+	})
+}
+
+// dispatch passes event through the middleware chain and then calls call for
+// each of the attached listeners. If the client has an event dispatcher, this
+// is done from one of its workers.
+func (e *Listeners) dispatch(client *Client, event Event, call func(l EventListener)) {
+	client.recordEvent(event)
+	client.auditEvent(event)
+	// The stream is read when the event is triggered, rather than when it is
+	// handled, so that events that were queued before Client.Events was
+	// called are not sent.
+	events := client.eventStream()
+	if client.dispatcher != nil {
+		client.dispatcher.Dispatch(event, func() {
+			e.dispatchSync(client, event, events, call)
+		})
+		return
+	}
+	client.dispatchMutex.Lock()
+	defer client.dispatchMutex.Unlock()
+	e.dispatchSync(client, event, events, call)
+}
+
+// dispatchSync passes event through the middleware chain, calls call for each
+// of the attached listeners, and then sends event to events, which can be nil.
+func (e *Listeners) dispatchSync(client *Client, event Event, events *eventStream, call func(l EventListener)) {
+	client.volatile.Lock()
+	handle := e.chain(event, func() {
+		client.volatile.Lock()
+		for item := e.head; item != nil; item = item.next {
+			if item.replaying {
+				item.backlog = append(item.backlog, event)
+				continue
+			}
+			client.volatile.Unlock()
+			e.call(client, event, item, call)
+			client.volatile.Lock()
+			if item.until != nil && item.until(event) {
+				item.Detach()
+			}
+		}
+		client.volatile.Unlock()
+		events.send(event)
+	})
+	client.volatile.Unlock()
+	handle()
+}
+
+// call passes event to the given item's listener. If
+// Config.RecoverListenerPanics is set, a panic raised by the listener is
+// recovered and passed to the OnListenerError listeners.
+func (e *Listeners) call(client *Client, event Event, item *eventItem, call func(l EventListener)) {
+	invoke := func() {
+		client.volatile.enterListener(item.listener, event)
+		defer client.volatile.exitListener()
+		if item.all != nil {
+			item.all(event)
+		} else {
+			call(item.listener)
+		}
+	}
+	if timeout := client.Config.SlowListenerTimeout; timeout > 0 {
+		if _, ok := event.(*SlowListenerEvent); !ok {
+			id, start := goid(), time.Now()
+			timer := time.AfterFunc(timeout, func() {
+				client.triggerAsync(func() {
+					e.slowListener(client, event, item, id, start)
+				})
+			})
+			defer timer.Stop()
+		}
+	}
+	if !client.Config.RecoverListenerPanics {
+		invoke()
+		return
+	}
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		if _, ok := event.(*ListenerErrorEvent); ok {
+			// Do not report panics raised by OnListenerError.
+			return
+		}
+		errEvent := ListenerErrorEvent{
+			Client:   client,
+			Listener: item.listener,
+			Event:    event,
+			Panic:    r,
+			Stack:    debug.Stack(),
+		}
+		// Called directly, rather than through dispatch, as this may already
+		// be running on an event worker.
+		e.dispatchSync(client, &errEvent, client.eventStream(), func(l EventListener) {
+			if l, ok := l.(ListenerErrorListener); ok {
+				l.OnListenerError(&errEvent)
+			}
+		})
+	}()
+	invoke()
+}
+
+// slowListener triggers a SlowListenerEvent for the given item's listener,
+// which has been handling event, on the goroutine with the given ID, since
+// start.
+func (e *Listeners) slowListener(client *Client, event Event, item *eventItem, id uint64, start time.Time) {
+	slowEvent := SlowListenerEvent{
+		Client:   client,
+		Listener: item.listener,
+		Event:    event,
+		Duration: time.Since(start),
+		Stack:    goroutineStack(id),
+	}
+	if client.Config.DetachSlowListeners {
+		client.volatile.Lock()
+		item.Detach()
+		client.volatile.Unlock()
+		slowEvent.Detached = true
+	}
+	// Called directly, rather than through dispatch, as the slow listener may
+	// be holding the dispatcher.
+	e.dispatchSync(client, &slowEvent, client.eventStream(), func(l EventListener) {
+		if l, ok := l.(SlowListenerListener); ok {
+			l.OnSlowListener(&slowEvent)
+		}
+	})
+}
+
+// attachReplay attaches listener in the replaying state, so that it does not
+// receive events until replay has been called.
+//
+// client.volatile must be held when calling this function.
+func (e *Listeners) attachReplay(listener EventListener) *eventItem {
+	item := e.Attach(listener).(*eventItem)
+	item.replaying = true
+	return item
+}
+
+// replay passes events to the given item's listener, followed by the events
+// that were triggered while doing so.
+func (e *Listeners) replay(client *Client, item *eventItem, events []Event) {
+	for {
+		for _, event := range events {
+			event := event
+			e.call(client, event, item, func(l EventListener) {
+				callListener(l, event)
+			})
+		}
+		client.volatile.Lock()
+		events = item.backlog
+		item.backlog = nil
+		if len(events) == 0 {
+			item.replaying = false
+			client.volatile.Unlock()
+			return
+		}
+		client.volatile.Unlock()
+	}
+}
+
+// callListener calls the method of listener that corresponds to the type of
+// event.
+func callListener(listener EventListener, event Event) {
+	switch e := event.(type) {
+	case *ConnectEvent:
+		listener.OnConnect(e)
+	case *DisconnectEvent:
+		listener.OnDisconnect(e)
+	case *TextMessageEvent:
+		listener.OnTextMessage(e)
+	case *UserChangeEvent:
+		listener.OnUserChange(e)
+	case *ChannelChangeEvent:
+		listener.OnChannelChange(e)
+	case *PermissionDeniedEvent:
+		listener.OnPermissionDenied(e)
+	case *UserListEvent:
+		listener.OnUserList(e)
+	case *ACLEvent:
+		listener.OnACL(e)
+	case *BanListEvent:
+		listener.OnBanList(e)
+	case *ContextActionChangeEvent:
+		listener.OnContextActionChange(e)
+	case *ServerConfigEvent:
+		listener.OnServerConfig(e)
+	case *ListenerErrorEvent:
+		if l, ok := listener.(ListenerErrorListener); ok {
+			l.OnListenerError(e)
+		}
+	case *PingUpdatedEvent:
+		if l, ok := listener.(PingUpdatedListener); ok {
+			l.OnPingUpdated(e)
+		}
+	case *PermissionQueryEvent:
+		if l, ok := listener.(PermissionQueryListener); ok {
+			l.OnPermissionQuery(e)
+		}
+	case *QueryUsersEvent:
+		if l, ok := listener.(QueryUsersListener); ok {
+			l.OnQueryUsers(e)
+		}
+	case *ChannelTreeChangeEvent:
+		if l, ok := listener.(ChannelTreeChangeListener); ok {
+			l.OnChannelTreeChange(e)
+		}
+	case *ProtocolErrorEvent:
+		if l, ok := listener.(ProtocolErrorListener); ok {
+			l.OnProtocolError(e)
+		}
+	case *SlowListenerEvent:
+		if l, ok := listener.(SlowListenerListener); ok {
+			l.OnSlowListener(e)
+		}
+	case *WriteErrorEvent:
+		if l, ok := listener.(WriteErrorListener); ok {
+			l.OnWriteError(e)
+		}
+	case *AudioBandwidthEvent:
+		if l, ok := listener.(AudioBandwidthListener); ok {
+			l.OnAudioBandwidth(e)
+		}
+	case *ContextActionEvent:
+		if l, ok := listener.(ContextActionListener); ok {
+			l.OnContextAction(e)
+		}
+	case *ServerVersionEvent:
+		if l, ok := listener.(ServerVersionListener); ok {
+			l.OnServerVersion(e)
+		}
 	}
-	event.Client.volatile.Unlock()
 }
@@ -0,0 +1,22 @@
+package gumble
+
+import (
+	"context"
+	"log/slog"
+)
+
+// discardHandler is the slog.Handler used when Config.Logger is nil.
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (d discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return d }
+func (d discardHandler) WithGroup(string) slog.Handler           { return d }
+
+// newLogger returns the logger of a client connected to addr.
+func newLogger(handler slog.Handler, addr string) *slog.Logger {
+	if handler == nil {
+		handler = discardHandler{}
+	}
+	return slog.New(handler).With(slog.String("server", addr))
+}
@@ -0,0 +1,94 @@
+package gumble
+
+import (
+	"time"
+)
+
+// readResult is the result of reading a packet from the server.
+type readResult struct {
+	pType uint16
+	data  []byte
+	err   error
+}
+
+// loopRoutine is used instead of readRoutine, pingRoutine, and the
+// goroutines of AudioOutgoing when Config.EventLoop is set. It handles the
+// packets from the server, sends pings, and sends outgoing audio, one at a
+// time, in the order in which they become ready.
+//
+// Reading from the connection blocks, so it is done by readLoop, which passes
+// each packet to loopRoutine and waits for it to be handled before reading the
+// next.
+func (c *Client) loopRoutine() {
+	c.disconnectEvent = DisconnectEvent{
+		Client: c,
+		Type:   DisconnectError,
+	}
+
+	packets := make(chan readResult)
+	next := make(chan struct{})
+	go c.readLoop(packets, next)
+
+	ticker := c.clock.NewTicker(time.Second * 5)
+	defer ticker.Stop()
+	ping := newPinger(c)
+	ping.send(c.clock.Now())
+
+	// The outgoing audio channels are sent from one after another.
+	var audio []*outgoingAudio
+	for {
+		var frames chan AudioBuffer
+		if len(audio) > 0 {
+			frames = audio[0].ch
+		}
+		select {
+		case r := <-packets:
+			if !c.handleRead(r.pType, r.data, r.err) {
+				c.readEnded()
+				// Keep consuming the outgoing audio channels, so that writes
+				// to them do not block.
+				c.loopMutex.Lock()
+				audio = append(audio, c.loopAudio...)
+				c.loopAudio = nil
+				c.loopEnded = true
+				c.loopMutex.Unlock()
+				for _, stream := range audio {
+					go stream.run(c)
+				}
+				return
+			}
+			next <- struct{}{}
+		case t := <-ticker.C():
+			ping.send(t)
+		case <-c.loopWake:
+			c.loopMutex.Lock()
+			audio = append(audio, c.loopAudio...)
+			c.loopAudio = nil
+			c.loopMutex.Unlock()
+		case frame, ok := <-frames:
+			if ok {
+				audio[0].write(c, frame)
+			} else {
+				audio[0].close(c)
+				audio = audio[1:]
+			}
+		}
+	}
+}
+
+// readLoop reads packets from the server for loopRoutine. It returns after a
+// read fails.
+func (c *Client) readLoop(packets chan<- readResult, next <-chan struct{}) {
+	for {
+		pType, data, err := c.Conn.ReadPacket()
+		packets <- readResult{
+			pType: pType,
+			data:  data,
+			err:   err,
+		}
+		if err != nil {
+			return
+		}
+		<-next
+	}
+}
@@ -0,0 +1,69 @@
+package gumble
+
+// Middleware wraps the dispatch of an event to the attached event listeners.
+//
+// next calls the remaining middleware and then the event listeners. Code
+// placed before or after the call to next runs before or after the event is
+// handled. If next is not called, the event is not passed on to any further
+// middleware or listeners.
+type Middleware func(event Event, next func())
+
+type middlewareItem struct {
+	parent     *Listeners
+	prev, next *middlewareItem
+	middleware Middleware
+}
+
+func (m *middlewareItem) Detach() {
+	if m.parent == nil {
+		return
+	}
+	if m.prev == nil {
+		m.parent.middlewareHead = m.next
+	} else {
+		m.prev.next = m.next
+	}
+	if m.next == nil {
+		m.parent.middlewareTail = m.prev
+	} else {
+		m.next.prev = m.prev
+	}
+	m.parent = nil
+}
+
+// Use adds a new middleware to the end of the current middleware chain. The
+// first middleware added is the outermost, and is called first when an event
+// is triggered.
+func (e *Listeners) Use(middleware Middleware) Detacher {
+	item := &middlewareItem{
+		parent:     e,
+		prev:       e.middlewareTail,
+		middleware: middleware,
+	}
+	if e.middlewareHead == nil {
+		e.middlewareHead = item
+	}
+	if e.middlewareTail != nil {
+		e.middlewareTail.next = item
+	}
+	e.middlewareTail = item
+	return item
+}
+
+// chain returns a function that passes event through the middleware chain,
+// ending with handle.
+//
+// client.volatile must be held when calling this function.
+func (e *Listeners) chain(event Event, handle func()) func() {
+	if e.middlewareTail == nil {
+		return handle
+	}
+	next := handle
+	for item := e.middlewareTail; item != nil; item = item.prev {
+		middleware, inner := item.middleware, next
+		next = func() {
+			middleware(event, inner)
+		}
+	}
+	return next
+}
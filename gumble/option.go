@@ -0,0 +1,60 @@
+package gumble
+
+import (
+	"log/slog"
+	"time"
+)
+
+// Option sets a field of a Config. Options are passed to NewConfig.
+type Option func(config *Config)
+
+// WithUsername sets Config.Username.
+func WithUsername(username string) Option {
+	return func(config *Config) {
+		config.Username = username
+	}
+}
+
+// WithPassword sets Config.Password.
+func WithPassword(password string) Option {
+	return func(config *Config) {
+		config.Password = password
+	}
+}
+
+// WithTokens appends tokens to Config.Tokens.
+func WithTokens(tokens ...string) Option {
+	return func(config *Config) {
+		config.Tokens = append(config.Tokens, tokens...)
+	}
+}
+
+// WithAudioInterval sets Config.AudioInterval, and Config.AudioDataBytes to
+// the number of bytes that the default data rate allows for the interval.
+func WithAudioInterval(interval time.Duration) Option {
+	return func(config *Config) {
+		config.AudioInterval = interval
+		config.AudioDataBytes = int(int64(AudioDefaultDataBytes) * int64(interval) / int64(AudioDefaultInterval))
+	}
+}
+
+// WithReconnect sets Config.ReconnectPolicy.
+func WithReconnect(policy *ReconnectPolicy) Option {
+	return func(config *Config) {
+		config.ReconnectPolicy = policy
+	}
+}
+
+// WithLogger sets Config.Logger.
+func WithLogger(handler slog.Handler) Option {
+	return func(config *Config) {
+		config.Logger = handler
+	}
+}
+
+// WithListener attaches listener to Config.Listeners.
+func WithListener(listener EventListener) Option {
+	return func(config *Config) {
+		config.Listeners.Attach(listener)
+	}
+}
@@ -0,0 +1,217 @@
+package gumble
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+
+	"github.com/golang/protobuf/proto"
+	"layeh.com/gumble/gumble/MumbleProto"
+	"layeh.com/gumble/gumble/varint"
+)
+
+// Control packet types.
+const (
+	PacketVersion uint16 = iota
+	PacketUDPTunnel
+	PacketAuthenticate
+	PacketPing
+	PacketReject
+	PacketServerSync
+	PacketChannelRemove
+	PacketChannelState
+	PacketUserRemove
+	PacketUserState
+	PacketBanList
+	PacketTextMessage
+	PacketPermissionDenied
+	PacketACL
+	PacketQueryUsers
+	PacketCryptSetup
+	PacketContextActionModify
+	PacketContextAction
+	PacketUserList
+	PacketVoiceTarget
+	PacketPermissionQuery
+	PacketCodecVersion
+	PacketUserStats
+	PacketRequestBlob
+	PacketServerConfig
+	PacketSuggestConfig
+)
+
+var errUnknownPacket = errors.New("gumble: unknown packet type")
+
+// NewControlMessage returns a new, empty protocol buffer message for the
+// given control packet type. nil is returned for unknown types, and for
+// PacketUDPTunnel, whose packets are not protocol buffer messages (see
+// ParseAudioPacket).
+func NewControlMessage(pType uint16) proto.Message {
+	switch pType {
+	case PacketVersion:
+		return &MumbleProto.Version{}
+	case PacketAuthenticate:
+		return &MumbleProto.Authenticate{}
+	case PacketPing:
+		return &MumbleProto.Ping{}
+	case PacketReject:
+		return &MumbleProto.Reject{}
+	case PacketServerSync:
+		return &MumbleProto.ServerSync{}
+	case PacketChannelRemove:
+		return &MumbleProto.ChannelRemove{}
+	case PacketChannelState:
+		return &MumbleProto.ChannelState{}
+	case PacketUserRemove:
+		return &MumbleProto.UserRemove{}
+	case PacketUserState:
+		return &MumbleProto.UserState{}
+	case PacketBanList:
+		return &MumbleProto.BanList{}
+	case PacketTextMessage:
+		return &MumbleProto.TextMessage{}
+	case PacketPermissionDenied:
+		return &MumbleProto.PermissionDenied{}
+	case PacketACL:
+		return &MumbleProto.ACL{}
+	case PacketQueryUsers:
+		return &MumbleProto.QueryUsers{}
+	case PacketCryptSetup:
+		return &MumbleProto.CryptSetup{}
+	case PacketContextActionModify:
+		return &MumbleProto.ContextActionModify{}
+	case PacketContextAction:
+		return &MumbleProto.ContextAction{}
+	case PacketUserList:
+		return &MumbleProto.UserList{}
+	case PacketVoiceTarget:
+		return &MumbleProto.VoiceTarget{}
+	case PacketPermissionQuery:
+		return &MumbleProto.PermissionQuery{}
+	case PacketCodecVersion:
+		return &MumbleProto.CodecVersion{}
+	case PacketUserStats:
+		return &MumbleProto.UserStats{}
+	case PacketRequestBlob:
+		return &MumbleProto.RequestBlob{}
+	case PacketServerConfig:
+		return &MumbleProto.ServerConfig{}
+	case PacketSuggestConfig:
+		return &MumbleProto.SuggestConfig{}
+	}
+	return nil
+}
+
+// ParseControlPacket decodes the data of a control packet (as returned by
+// Conn.ReadPacket) into its protocol buffer message. The message does not
+// reference data.
+func ParseControlPacket(pType uint16, data []byte) (proto.Message, error) {
+	message := NewControlMessage(pType)
+	if message == nil {
+		return nil, errUnknownPacket
+	}
+	if err := proto.Unmarshal(data, message); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// EncodedAudioPacket is an audio packet, as tunnelled through the control
+// connection, whose audio has not been decoded.
+type EncodedAudioPacket struct {
+	// The audio codec ID (e.g. 4 for Opus).
+	Codec byte
+	// The voice target ID.
+	Target byte
+	// The session of the user who sent the audio.
+	Session  uint32
+	Sequence int64
+	// Is this the last packet of the transmission?
+	Terminator bool
+	// The encoded audio. It references the data passed to ParseAudioPacket.
+	Data []byte
+
+	HasPosition bool
+	X, Y, Z     float32
+}
+
+// ParseAudioPacket decodes the header of an audio packet that has been sent
+// by a server (i.e. the data of a UDPTunnel packet). Only Opus packets are
+// supported.
+func ParseAudioPacket(data []byte) (*EncodedAudioPacket, error) {
+	if len(data) < 1 {
+		return nil, errInvalidProtobuf
+	}
+	packet := EncodedAudioPacket{
+		Codec:  (data[0] >> 5) & 0x7,
+		Target: data[0] & 0x1F,
+	}
+
+	// Opus only
+	// TODO: add handling for other packet types
+	if packet.Codec != audioCodecIDOpus {
+		return nil, errUnsupportedAudio
+	}
+
+	// Session
+	data = data[1:]
+	session, n := varint.Decode(data)
+	if n <= 0 {
+		return nil, errInvalidProtobuf
+	}
+	data = data[n:]
+	packet.Session = uint32(session)
+
+	// Sequence
+	packet.Sequence, n = varint.Decode(data)
+	if n <= 0 {
+		return nil, errInvalidProtobuf
+	}
+	data = data[n:]
+
+	// Length
+	length, n := varint.Decode(data)
+	if n <= 0 {
+		return nil, errInvalidProtobuf
+	}
+	data = data[n:]
+	// Opus audio packets set the 13th bit in the size field as the terminator.
+	packet.Terminator = length&0x2000 != 0
+	audioLength := int(length) &^ 0x2000
+	if audioLength < 0 || audioLength > len(data) {
+		return nil, errInvalidProtobuf
+	}
+	packet.Data = data[:audioLength]
+
+	if len(data)-audioLength == 3*4 {
+		// the packet has positional audio data; 3x float32
+		data = data[audioLength:]
+
+		packet.X = math.Float32frombits(binary.LittleEndian.Uint32(data))
+		packet.Y = math.Float32frombits(binary.LittleEndian.Uint32(data[4:]))
+		packet.Z = math.Float32frombits(binary.LittleEndian.Uint32(data[8:]))
+		packet.HasPosition = true
+	}
+	return &packet, nil
+}
+
+// Append appends the packet to b, in the format in which servers send it (and
+// that ParseAudioPacket decodes), and returns the extended buffer. It does not
+// allocate if b has enough spare capacity.
+func (p *EncodedAudioPacket) Append(b []byte) []byte {
+	b = append(b, (p.Codec&0x7)<<5|p.Target&0x1F)
+	b = varint.Append(b, int64(p.Session))
+	b = varint.Append(b, p.Sequence)
+	length := int64(len(p.Data))
+	if p.Terminator {
+		length |= 0x2000
+	}
+	b = varint.Append(b, length)
+	b = append(b, p.Data...)
+	if p.HasPosition {
+		b = binary.LittleEndian.AppendUint32(b, math.Float32bits(p.X))
+		b = binary.LittleEndian.AppendUint32(b, math.Float32bits(p.Y))
+		b = binary.LittleEndian.AppendUint32(b, math.Float32bits(p.Z))
+	}
+	return b
+}
@@ -0,0 +1,84 @@
+package gumble
+
+import (
+	"bytes"
+	"testing"
+
+	"layeh.com/gumble/gumble/varint"
+)
+
+func audioPacket(session, sequence int64, payload []byte, terminator bool) []byte {
+	var b [varint.MaxVarintLen]byte
+	packet := []byte{audioCodecIDOpus << 5}
+	packet = append(packet, b[:varint.Encode(b[:], session)]...)
+	packet = append(packet, b[:varint.Encode(b[:], sequence)]...)
+	length := int64(len(payload))
+	if terminator {
+		length |= 0x2000
+	}
+	packet = append(packet, b[:varint.Encode(b[:], length)]...)
+	return append(packet, payload...)
+}
+
+func TestParseAudioPacket(t *testing.T) {
+	payload := []byte{1, 2, 3, 4, 5}
+	packet, err := ParseAudioPacket(audioPacket(42, 1000, payload, true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if packet.Session != 42 || packet.Sequence != 1000 || !packet.Terminator || !bytes.Equal(packet.Data, payload) {
+		t.Fatalf("unexpected packet %+v", packet)
+	}
+	if packet.HasPosition {
+		t.Fatal("packet should not have a position")
+	}
+}
+
+func TestAppendAudioPacket(t *testing.T) {
+	packet := EncodedAudioPacket{
+		Codec:       audioCodecIDOpus,
+		Target:      31,
+		Session:     70000,
+		Sequence:    -3,
+		Data:        []byte{1, 2, 3},
+		HasPosition: true,
+		X:           1,
+		Y:           -2,
+		Z:           3.5,
+	}
+	parsed, err := ParseAudioPacket(packet.Append(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Target != packet.Target || parsed.Session != packet.Session || parsed.Sequence != packet.Sequence ||
+		!bytes.Equal(parsed.Data, packet.Data) || !parsed.HasPosition || parsed.X != 1 || parsed.Y != -2 || parsed.Z != 3.5 {
+		t.Fatalf("unexpected packet %+v", parsed)
+	}
+}
+
+func FuzzParseAudioPacket(f *testing.F) {
+	f.Add(audioPacket(1, 0, []byte{0xFC, 0xFF, 0xFE}, false))
+	f.Add(audioPacket(300, 70000, make([]byte, 12), true))
+	f.Add(append(audioPacket(5, 1, []byte{1}, false), make([]byte, 12)...))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		packet, err := ParseAudioPacket(data)
+		if err != nil {
+			return
+		}
+		if len(packet.Data) > len(data) {
+			t.Fatalf("audio data is longer than the packet")
+		}
+	})
+}
+
+func FuzzParseControlPacket(f *testing.F) {
+	f.Add(uint16(PacketTextMessage), []byte{0x2A, 0x02, 'h', 'i'})
+	f.Add(uint16(PacketUserState), []byte{0x08, 0x01})
+	f.Add(uint16(PacketUDPTunnel), []byte{})
+	f.Fuzz(func(t *testing.T, pType uint16, data []byte) {
+		message, err := ParseControlPacket(pType, data)
+		if err == nil && message == nil {
+			t.Fatal("nil message without an error")
+		}
+	})
+}
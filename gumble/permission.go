@@ -1,5 +1,10 @@
 package gumble
 
+import (
+	"strconv"
+	"strings"
+)
+
 // Permission is a bitmask of permissions given to a certain user.
 type Permission int
 
@@ -31,3 +36,47 @@ const (
 func (p Permission) Has(o Permission) bool {
 	return p&o == o
 }
+
+// PermissionDefault is the set of permissions that every user has before any
+// ACL rules are applied.
+const PermissionDefault = PermissionTraverse | PermissionEnter | PermissionSpeak | PermissionWhisper | PermissionTextMessage
+
+var permissionNames = []struct {
+	Permission Permission
+	Name       string
+}{
+	{PermissionWrite, "Write"},
+	{PermissionTraverse, "Traverse"},
+	{PermissionEnter, "Enter"},
+	{PermissionSpeak, "Speak"},
+	{PermissionMuteDeafen, "MuteDeafen"},
+	{PermissionMove, "Move"},
+	{PermissionMakeChannel, "MakeChannel"},
+	{PermissionLinkChannel, "LinkChannel"},
+	{PermissionWhisper, "Whisper"},
+	{PermissionTextMessage, "TextMessage"},
+	{PermissionMakeTemporaryChannel, "MakeTemporaryChannel"},
+	{PermissionKick, "Kick"},
+	{PermissionBan, "Ban"},
+	{PermissionRegister, "Register"},
+	{PermissionRegisterSelf, "RegisterSelf"},
+}
+
+// String returns the names of the permissions in the bitmask, separated by
+// "|" (e.g. "Enter|Speak"). "None" is returned if the bitmask is empty.
+func (p Permission) String() string {
+	if p == 0 {
+		return "None"
+	}
+	var parts []string
+	for _, n := range permissionNames {
+		if p&n.Permission != 0 {
+			parts = append(parts, n.Name)
+			p &^= n.Permission
+		}
+	}
+	if p != 0 {
+		parts = append(parts, "0x"+strconv.FormatInt(int64(p), 16))
+	}
+	return strings.Join(parts, "|")
+}
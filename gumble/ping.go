@@ -6,6 +6,7 @@ import (
 	"errors"
 	"io"
 	"net"
+	"strconv"
 	"sync"
 	"time"
 )
@@ -17,8 +18,10 @@ type PingResponse struct {
 	Address *net.UDPAddr
 	// The round-trip time from the client to the server.
 	Ping time.Duration
-	// The server's version. Only the Version field and SemanticVersion method of
-	// the value will be valid.
+	// The server's version. Only the Version and VersionV2 fields, and the
+	// SemanticVersion and FullVersion methods, of the value will be valid. The
+	// ping protocol only carries the Version format, so VersionV2 is derived
+	// from it, and patch versions are limited to 255.
 	Version Version
 	// The number users currently connected to the server.
 	ConnectedUsers int
@@ -98,7 +101,8 @@ func Ping(address string, interval, timeout time.Duration) (*PingResponse, error
 			Address: conn.RemoteAddr().(*net.UDPAddr),
 			Ping:    time.Since(sendTime),
 			Version: Version{
-				Version: binary.BigEndian.Uint32(incoming[0:]),
+				Version:   binary.BigEndian.Uint32(incoming[0:]),
+				VersionV2: versionV1ToV2(binary.BigEndian.Uint32(incoming[0:])),
 			},
 			ConnectedUsers: int(binary.BigEndian.Uint32(incoming[12:])),
 			MaximumUsers:   int(binary.BigEndian.Uint32(incoming[16:])),
@@ -106,3 +110,24 @@ func Ping(address string, interval, timeout time.Duration) (*PingResponse, error
 		}, nil
 	}
 }
+
+// Default retransmission interval and timeout used by QueryServer.
+const (
+	DefaultQueryInterval = time.Second
+	DefaultQueryTimeout  = time.Second * 5
+)
+
+// QueryServer returns information about the server at the given address
+// (e.g. the number of connected users), using the connectionless UDP ping
+// protocol that server lists use. No TLS connection is made, and the server
+// does not see the query as a client. If the address does not contain a port,
+// DefaultPort is used.
+//
+// QueryServer is an alias of Ping(address, DefaultQueryInterval,
+// DefaultQueryTimeout).
+func QueryServer(address string) (*PingResponse, error) {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, strconv.Itoa(DefaultPort))
+	}
+	return Ping(address, DefaultQueryInterval, DefaultQueryTimeout)
+}
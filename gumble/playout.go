@@ -0,0 +1,169 @@
+package gumble
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// playoutTick is how often a playout buffer checks for packets that are due
+// to be passed to the audio listeners.
+const playoutTick = 5 * time.Millisecond
+
+// SetPlayoutDelay sets how long the user's incoming audio is buffered before
+// it is passed to the audio listeners, overriding Config.AudioPlayoutDelay.
+// If delay is negative, Config.AudioPlayoutDelay is used again.
+func (u *User) SetPlayoutDelay(delay time.Duration) {
+	c := u.client
+	c.playoutDelays.mu.Lock()
+	defer c.playoutDelays.mu.Unlock()
+	if delay < 0 {
+		delete(c.playoutDelays.delays, u.Session)
+		return
+	}
+	if c.playoutDelays.delays == nil {
+		c.playoutDelays.delays = make(map[uint32]time.Duration)
+	}
+	c.playoutDelays.delays[u.Session] = delay
+}
+
+// PlayoutDelay returns how long the user's incoming audio is buffered before
+// it is passed to the audio listeners. See SetPlayoutDelay.
+func (u *User) PlayoutDelay() time.Duration {
+	return u.client.playoutDelay(u)
+}
+
+func (c *Client) playoutDelay(user *User) time.Duration {
+	c.playoutDelays.mu.Lock()
+	defer c.playoutDelays.mu.Unlock()
+	if delay, ok := c.playoutDelays.delays[user.Session]; ok {
+		return delay
+	}
+	return c.Config.AudioPlayoutDelay
+}
+
+// playoutDelays are the delays set with User.SetPlayoutDelay, by session.
+type playoutDelays struct {
+	mu     sync.Mutex
+	delays map[uint32]time.Duration
+}
+
+// playoutBuffer holds a user's incoming audio packets until they are due to
+// be passed to the audio listeners. Packets are passed in sequence order,
+// each one after the previous packet's audio has played, beginning the
+// user's playout delay after the first packet of a transmission arrived.
+type playoutBuffer struct {
+	client *Client
+	user   *User
+	in     chan playoutPacket
+	stop   chan struct{}
+}
+
+type playoutPacket struct {
+	packet   *AudioPacket
+	sequence int64
+	delay    time.Duration
+}
+
+func newPlayoutBuffer(client *Client, user *User) *playoutBuffer {
+	b := &playoutBuffer{
+		client: client,
+		user:   user,
+		in:     make(chan playoutPacket),
+		stop:   make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+// add queues the packet. It must only be called from readRoutine.
+func (b *playoutBuffer) add(packet *AudioPacket, sequence int64, delay time.Duration) {
+	select {
+	case b.in <- playoutPacket{packet, sequence, delay}:
+	case <-b.stop:
+		packet.Release()
+	}
+}
+
+// close stops the buffer, discarding the packets that it holds, and closes
+// the user's audio streams.
+func (b *playoutBuffer) close() {
+	close(b.stop)
+}
+
+func (b *playoutBuffer) run() {
+	c := b.client
+	var (
+		queue []playoutPacket
+		// When the packet at the head of the queue is due.
+		next time.Time
+		// The sequence number of the last packet that was passed on.
+		last     int64
+		released bool
+
+		ticker Ticker
+		tick   <-chan time.Time
+	)
+	defer func() {
+		if ticker != nil {
+			ticker.Stop()
+		}
+		for _, p := range queue {
+			p.packet.Release()
+		}
+	}()
+
+	for {
+		select {
+		case p := <-b.in:
+			now := c.clock.Now()
+			if len(queue) == 0 {
+				if !released || next.Before(now) || p.sequence <= last {
+					// The start of a transmission, or the buffer ran dry:
+					// buffer the packet for the full delay.
+					next = now.Add(p.delay)
+					released = false
+				}
+			} else if released && p.sequence <= last {
+				// Too late to be played in order.
+				p.packet.Release()
+				continue
+			}
+			i := sort.Search(len(queue), func(i int) bool {
+				return queue[i].sequence > p.sequence
+			})
+			queue = append(queue, playoutPacket{})
+			copy(queue[i+1:], queue[i:])
+			queue[i] = p
+			if ticker == nil {
+				ticker = c.clock.NewTicker(playoutTick)
+				tick = ticker.C()
+			}
+		case now := <-tick:
+			for len(queue) > 0 && !next.After(now) {
+				p := queue[0]
+				queue = queue[1:]
+				last = p.sequence
+				// The next transmission is buffered for the full delay.
+				released = !p.packet.IsLast
+				next = next.Add(time.Duration(len(p.packet.AudioBuffer)/AudioChannels) * time.Second / AudioSampleRate)
+				c.dispatchAudio(b.user, p.packet)
+			}
+			if len(queue) == 0 {
+				ticker.Stop()
+				ticker = nil
+				tick = nil
+			}
+		case <-b.stop:
+			c.volatile.Lock()
+			c.closeAudioStreams(b.user)
+			c.volatile.Unlock()
+			return
+		case <-c.end:
+			c.volatile.Lock()
+			c.closeAudioStreams(b.user)
+			c.volatile.Unlock()
+			return
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package gumble
+
+import (
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// maxPooledBufferSize is the capacity above which buffers are not returned to
+// their pools, so that a single large packet (e.g. a user texture) is not kept
+// in memory.
+const maxPooledBufferSize = 64 * 1024
+
+// packetBuffers holds the buffers in which outgoing packets are assembled.
+var packetBuffers = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 0, 1024)
+		return &b
+	},
+}
+
+// protoBuffers holds the buffers into which outgoing protocol buffer messages
+// are marshalled.
+var protoBuffers = sync.Pool{
+	New: func() interface{} {
+		return proto.NewBuffer(make([]byte, 0, 1024))
+	},
+}
+
+// audioPackets holds the AudioPackets that have been released by all of their
+// listeners.
+var audioPackets = sync.Pool{
+	New: func() interface{} {
+		return new(AudioPacket)
+	},
+}
+
+// audioBuffers holds the buffers into which incoming audio is decoded, if the
+// decoder is an AudioBufferDecoder.
+var audioBuffers = sync.Pool{
+	New: func() interface{} {
+		b := make([]int16, AudioMaximumFrameSize*AudioChannels)
+		return &b
+	},
+}
+
+func getAudioBuffer() *[]int16 {
+	b := audioBuffers.Get().(*[]int16)
+	*b = (*b)[:cap(*b)]
+	return b
+}
+
+func putAudioBuffer(b *[]int16) {
+	audioBuffers.Put(b)
+}
+
+// maxPooledDecoders is the number of decoders that a client keeps for reuse.
+const maxPooledDecoders = 16
+
+// getDecoder returns a decoder for codec, reusing one of a user who has
+// disconnected if possible, to avoid the cost of initializing a new one.
+func (c *Client) getDecoder(codec AudioCodec) AudioDecoder {
+	if n := len(c.decoders); n > 0 {
+		decoder := c.decoders[n-1]
+		c.decoders[n-1] = nil
+		c.decoders = c.decoders[:n-1]
+		return decoder
+	}
+	return codec.NewDecoder()
+}
+
+// putDecoder resets the decoder of the user, who has disconnected, and keeps
+// it for reuse.
+func (c *Client) putDecoder(user *User) {
+	decoder := user.decoder
+	if decoder == nil {
+		return
+	}
+	user.decoder = nil
+	if len(c.decoders) < maxPooledDecoders && c.audioCodec != nil && decoder.ID() == c.audioCodec.ID() {
+		decoder.Reset()
+		c.decoders = append(c.decoders, decoder)
+	}
+}
+
+func getPacketBuffer() *[]byte {
+	b := packetBuffers.Get().(*[]byte)
+	*b = (*b)[:0]
+	return b
+}
+
+func putPacketBuffer(b *[]byte) {
+	if cap(*b) <= maxPooledBufferSize {
+		packetBuffers.Put(b)
+	}
+}
+
+func getProtoBuffer() *proto.Buffer {
+	b := protoBuffers.Get().(*proto.Buffer)
+	b.Reset()
+	return b
+}
+
+func putProtoBuffer(b *proto.Buffer) {
+	if cap(b.Bytes()) <= maxPooledBufferSize {
+		protoBuffers.Put(b)
+	}
+}
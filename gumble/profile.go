@@ -0,0 +1,23 @@
+package gumble
+
+import (
+	"context"
+	"runtime/pprof"
+)
+
+// withProfileLabels calls f, with the pprof labels of the given audio
+// goroutine role applied if Config.ProfileLabels is set.
+func (c *Client) withProfileLabels(role string, f func()) {
+	if !c.Config.ProfileLabels {
+		f()
+		return
+	}
+	var server string
+	if c.auditInfo != nil {
+		server = c.auditInfo.server
+	}
+	labels := pprof.Labels("gumble", role, "server", server)
+	pprof.Do(context.Background(), labels, func(context.Context) {
+		f()
+	})
+}
@@ -0,0 +1,76 @@
+package gumble
+
+import (
+	"math/rand"
+	"time"
+)
+
+// ReconnectPolicy describes when and how often a disconnected client should
+// reconnect to the server.
+//
+// gumble does not reconnect by itself; the policy is consulted by code that
+// supervises a Client (for example, gumbleutil's auto-reconnect listener),
+// and its decision is reported in DisconnectEvent.Reconnect.
+type ReconnectPolicy struct {
+	// The delay before the first reconnect attempt.
+	MinDelay time.Duration
+	// The maximum delay between reconnect attempts.
+	MaxDelay time.Duration
+	// The factor by which the delay increases after each failed attempt.
+	// Values less than 1 are treated as 1.
+	Multiplier float64
+	// The fraction of the delay that is randomized, between 0 and 1.
+	Jitter float64
+	// The maximum number of consecutive reconnect attempts. If zero, there is
+	// no limit.
+	MaxAttempts int
+
+	// Reconnect after being kicked from the server.
+	AfterKick bool
+}
+
+// NewReconnectPolicy returns a new ReconnectPolicy with default values set.
+func NewReconnectPolicy() *ReconnectPolicy {
+	return &ReconnectPolicy{
+		MinDelay:   time.Second,
+		MaxDelay:   time.Minute * 2,
+		Multiplier: 2,
+		Jitter:     0.2,
+	}
+}
+
+// ShouldReconnect returns true if the policy allows reconnecting after a
+// disconnect of the given type. The client never reconnects after
+// disconnecting by request or after being banned.
+func (p *ReconnectPolicy) ShouldReconnect(t DisconnectType) bool {
+	switch t {
+	case DisconnectUser, DisconnectBanned:
+		return false
+	case DisconnectKicked:
+		return p.AfterKick
+	}
+	return true
+}
+
+// Delay returns how long to wait before the given reconnect attempt, starting
+// from zero. false is returned if MaxAttempts has been reached.
+func (p *ReconnectPolicy) Delay(attempt int) (time.Duration, bool) {
+	if p.MaxAttempts > 0 && attempt >= p.MaxAttempts {
+		return 0, false
+	}
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	delay := float64(p.MinDelay)
+	for i := 0; i < attempt && (p.MaxDelay <= 0 || delay < float64(p.MaxDelay)); i++ {
+		delay *= multiplier
+	}
+	if p.MaxDelay > 0 && delay > float64(p.MaxDelay) {
+		delay = float64(p.MaxDelay)
+	}
+	if p.Jitter > 0 {
+		delay += delay * p.Jitter * (rand.Float64()*2 - 1)
+	}
+	return time.Duration(delay), true
+}
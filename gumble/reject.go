@@ -29,33 +29,66 @@ type RejectError struct {
 	Reason string
 }
 
-// Error implements error.
-func (e RejectError) Error() string {
-	var msg string
-	switch e.Type {
+// String returns a description of the reject type (e.g. "server full").
+func (t RejectType) String() string {
+	switch t {
 	case RejectNone:
-		msg = "none"
+		return "none"
 	case RejectVersion:
-		msg = "wrong client version"
+		return "wrong client version"
 	case RejectUserName:
-		msg = "invalid username"
+		return "invalid username"
 	case RejectUserCredentials:
-		msg = "incorrect user credentials"
+		return "incorrect user credentials"
 	case RejectServerPassword:
-		msg = "incorrect server password"
+		return "incorrect server password"
 	case RejectUsernameInUse:
-		msg = "username in use"
+		return "username in use"
 	case RejectServerFull:
-		msg = "server full"
+		return "server full"
 	case RejectNoCertificate:
-		msg = "no certificate"
+		return "no certificate"
 	case RejectAuthenticatorFail:
-		msg = "authenticator fail"
-	default:
-		msg = "unknown type " + strconv.Itoa(int(e.Type))
+		return "authenticator fail"
+	}
+	return "unknown type " + strconv.Itoa(int(t))
+}
+
+// IsAuthFailure returns true if the client was rejected because it could not
+// be authenticated: its password or certificate was rejected, it did not
+// provide a certificate, or the server's authenticator failed.
+func (t RejectType) IsAuthFailure() bool {
+	switch t {
+	case RejectUserCredentials, RejectServerPassword, RejectNoCertificate, RejectAuthenticatorFail:
+		return true
 	}
+	return false
+}
+
+// IsServerFull returns true if the client was rejected because the server
+// has reached its maximum number of users.
+func (t RejectType) IsServerFull() bool {
+	return t == RejectServerFull
+}
+
+// IsVersionMismatch returns true if the client was rejected because the
+// server does not support its version.
+func (t RejectType) IsVersionMismatch() bool {
+	return t == RejectVersion
+}
+
+// Error implements error.
+func (e RejectError) Error() string {
+	msg := e.Type.String()
 	if e.Reason != "" {
 		msg += ": " + e.Reason
 	}
 	return msg
 }
+
+// Credentials are the secrets used by a client to authenticate with the
+// server. See Config.CredentialsProvider.
+type Credentials struct {
+	Password string
+	Tokens   AccessTokens
+}
@@ -0,0 +1,86 @@
+package gumble
+
+// recordEvent adds event to the client's event history, if enabled by
+// Config.EventHistory.
+func (c *Client) recordEvent(event Event) {
+	size := c.Config.EventHistory
+	if size <= 0 {
+		return
+	}
+	c.volatile.Lock()
+	defer c.volatile.Unlock()
+	if len(c.history) < size {
+		c.history = append(c.history, event)
+		return
+	}
+	c.history[c.historyNext] = event
+	c.historyNext = (c.historyNext + 1) % len(c.history)
+}
+
+// AttachWithHistory attaches listener to c.Config.Listeners, and then passes it
+// the recent events kept by the client, oldest first. Events that are
+// triggered while the history is being replayed are passed to the listener
+// afterwards, so that no event is missed or received out of order.
+//
+// The number of events kept is set by Config.EventHistory. Replayed events do
+// not pass through the middleware chain.
+func (c *Client) AttachWithHistory(listener EventListener) Detacher {
+	c.volatile.Lock()
+	events := make([]Event, 0, len(c.history))
+	events = append(events, c.history[c.historyNext:]...)
+	events = append(events, c.history[:c.historyNext]...)
+	item := c.Config.Listeners.attachReplay(listener)
+	c.volatile.Unlock()
+
+	c.Config.Listeners.replay(c, item, events)
+	return item
+}
+
+// AttachWithState attaches listener to c.Config.Listeners, and then passes it
+// events that describe the client's current state: a ConnectEvent, a
+// ChannelChangeEvent with ChannelChangeCreated for each channel (parents before
+// children), and a UserChangeEvent with UserChangeConnected for each user.
+//
+// This allows a listener that is attached after the client has connected to
+// build its state the same way as a listener that was attached before Dial.
+// Events that are triggered while the state is being replayed are passed to
+// the listener afterwards.
+func (c *Client) AttachWithState(listener EventListener) Detacher {
+	c.volatile.Lock()
+	var events []Event
+	if c.State() == StateSynced {
+		connect := c.connectEvent
+		if connect == nil {
+			connect = &ConnectEvent{
+				Client: c,
+			}
+		}
+		events = append(events, connect)
+		var addChannel func(channel *Channel)
+		addChannel = func(channel *Channel) {
+			events = append(events, &ChannelChangeEvent{
+				Client:  c,
+				Type:    ChannelChangeCreated,
+				Channel: channel,
+			})
+			for _, child := range channel.Children {
+				addChannel(child)
+			}
+		}
+		if root := c.Channels[0]; root != nil {
+			addChannel(root)
+		}
+		for _, user := range c.Users {
+			events = append(events, &UserChangeEvent{
+				Client: c,
+				Type:   UserChangeConnected,
+				User:   user,
+			})
+		}
+	}
+	item := c.Config.Listeners.attachReplay(listener)
+	c.volatile.Unlock()
+
+	c.Config.Listeners.replay(c, item, events)
+	return item
+}
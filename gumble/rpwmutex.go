@@ -1,3 +1,6 @@
+//go:build !gumbledebug
+// +build !gumbledebug
+
 package gumble
 
 import "sync"
@@ -34,3 +37,9 @@ func (m *rpwMutex) RUnlock() {
 	}
 	m.r.Unlock()
 }
+
+// enterListener and exitListener bracket calls to event listeners. They are
+// only used by the gumbledebug build.
+func (m *rpwMutex) enterListener(listener, event interface{}) {}
+
+func (m *rpwMutex) exitListener() {}
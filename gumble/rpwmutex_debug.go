@@ -0,0 +1,204 @@
+//go:build gumbledebug
+// +build gumbledebug
+
+package gumble
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// lockTimeout is how long a goroutine can wait to acquire a client's volatile
+// lock before the gumbledebug build reports a deadlock.
+var lockTimeout = 10 * time.Second
+
+// rpwMutex is a reader-preferred RWMutex.
+//
+// When built with the gumbledebug tag, the mutex tracks the goroutines that
+// hold it. It panics when it is misused (e.g. unlocked by a goroutine that
+// does not hold it, or locked by a goroutine that already holds it), and
+// crashes the program with a report of its holders, and the event listeners
+// that they were running, if it cannot be acquired within lockTimeout.
+type rpwMutex struct {
+	w sync.Mutex
+	r sync.Mutex
+	n int
+
+	debug sync.Mutex
+	// The goroutines that hold the mutex.
+	holders map[uint64]*lockHolder
+	// The event listeners that each goroutine is running, innermost last.
+	listeners map[uint64][]string
+}
+
+type lockHolder struct {
+	write    bool
+	count    int
+	since    time.Time
+	listener string
+	stack    []byte
+}
+
+func (m *rpwMutex) Lock() {
+	g := goid()
+	m.checkLock(g, true)
+	timer := time.AfterFunc(lockTimeout, func() {
+		m.deadlock(g, "Lock")
+	})
+	m.w.Lock()
+	timer.Stop()
+	m.acquired(g, true)
+}
+
+func (m *rpwMutex) Unlock() {
+	m.released(goid(), true)
+	m.w.Unlock()
+}
+
+func (m *rpwMutex) RLock() {
+	g := goid()
+	m.checkLock(g, false)
+	timer := time.AfterFunc(lockTimeout, func() {
+		m.deadlock(g, "RLock")
+	})
+	m.r.Lock()
+	m.n++
+	if m.n == 1 {
+		m.w.Lock()
+	}
+	m.r.Unlock()
+	timer.Stop()
+	m.acquired(g, false)
+}
+
+func (m *rpwMutex) RUnlock() {
+	m.released(goid(), false)
+	m.r.Lock()
+	m.n--
+	if m.n == 0 {
+		m.w.Unlock()
+	}
+	m.r.Unlock()
+}
+
+func (m *rpwMutex) enterListener(listener, event interface{}) {
+	var name string
+	if listener != nil {
+		name = fmt.Sprintf("%T handling %T", listener, event)
+	} else {
+		name = fmt.Sprintf("listener func handling %T", event)
+	}
+	g := goid()
+	m.debug.Lock()
+	defer m.debug.Unlock()
+	if m.listeners == nil {
+		m.listeners = make(map[uint64][]string)
+	}
+	m.listeners[g] = append(m.listeners[g], name)
+}
+
+func (m *rpwMutex) exitListener() {
+	g := goid()
+	m.debug.Lock()
+	defer m.debug.Unlock()
+	if n := len(m.listeners[g]); n > 1 {
+		m.listeners[g] = m.listeners[g][:n-1]
+	} else {
+		delete(m.listeners, g)
+	}
+}
+
+// checkLock panics if goroutine g would deadlock by acquiring the mutex.
+// Read locks can be acquired recursively, as the mutex prefers readers.
+func (m *rpwMutex) checkLock(g uint64, write bool) {
+	m.debug.Lock()
+	defer m.debug.Unlock()
+	holder := m.holders[g]
+	if holder == nil || (!write && !holder.write) {
+		return
+	}
+	panic("gumble: goroutine " + strconv.FormatUint(g, 10) + " locks the client's state, which it already holds\n\n" + m.describe(g, holder))
+}
+
+func (m *rpwMutex) acquired(g uint64, write bool) {
+	m.debug.Lock()
+	defer m.debug.Unlock()
+	if holder := m.holders[g]; holder != nil {
+		holder.count++
+		return
+	}
+	if m.holders == nil {
+		m.holders = make(map[uint64]*lockHolder)
+	}
+	holder := &lockHolder{
+		write: write,
+		count: 1,
+		since: time.Now(),
+		stack: stack(false),
+	}
+	if listeners := m.listeners[g]; len(listeners) > 0 {
+		holder.listener = listeners[len(listeners)-1]
+	}
+	m.holders[g] = holder
+}
+
+func (m *rpwMutex) released(g uint64, write bool) {
+	m.debug.Lock()
+	defer m.debug.Unlock()
+	holder := m.holders[g]
+	if holder == nil || holder.write != write {
+		op := "RUnlock"
+		if write {
+			op = "Unlock"
+		}
+		panic("gumble: goroutine " + strconv.FormatUint(g, 10) + " calls " + op + " on the client's state, which it does not hold")
+	}
+	holder.count--
+	if holder.count == 0 {
+		delete(m.holders, g)
+	}
+}
+
+// deadlock reports that goroutine g has not acquired the mutex within
+// lockTimeout, and crashes the program.
+func (m *rpwMutex) deadlock(g uint64, op string) {
+	m.debug.Lock()
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "gumble: goroutine %d has waited %v to %s the client's state\n\n", g, lockTimeout, op)
+	if len(m.holders) == 0 {
+		b.WriteString("no goroutine holds the lock\n\n")
+	}
+	ids := make([]uint64, 0, len(m.holders))
+	for id := range m.holders {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		b.WriteString(m.describe(id, m.holders[id]))
+	}
+	m.debug.Unlock()
+
+	b.WriteString("all goroutines:\n\n")
+	b.Write(stack(true))
+	os.Stderr.Write(b.Bytes())
+	panic("gumble: deadlock on the client's state")
+}
+
+func (m *rpwMutex) describe(g uint64, holder *lockHolder) string {
+	mode := "read"
+	if holder.write {
+		mode = "write"
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "goroutine %d has held a %s lock for %v", g, mode, time.Since(holder.since))
+	if holder.listener != "" {
+		fmt.Fprintf(&b, ", acquired in %s", holder.listener)
+	}
+	fmt.Fprintf(&b, ":\n%s\n", holder.stack)
+	return b.String()
+}
@@ -0,0 +1,172 @@
+package gumble
+
+import (
+	"math"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// Snapshot is a copy of a client's state at a point in time, as returned by
+// Client.Snapshot. It does not reference the client's live objects, and can
+// be encoded as JSON.
+type Snapshot struct {
+	Time  time.Time `json:"time"`
+	State string    `json:"state"`
+
+	// The session of the client's user, or zero if the client has not been
+	// synced with the server.
+	Self uint32 `json:"self,omitempty"`
+	// The users and channels of the server, sorted by session and ID.
+	Users    []SnapshotUser    `json:"users"`
+	Channels []SnapshotChannel `json:"channels"`
+
+	Server SnapshotServer `json:"server"`
+	Stats  ClientStats    `json:"stats"`
+	// The average TCP ping latency to the server.
+	Ping time.Duration `json:"ping"`
+}
+
+// SnapshotUser is a user in a Snapshot.
+type SnapshotUser struct {
+	Session         uint32 `json:"session"`
+	UserID          uint32 `json:"user_id,omitempty"`
+	Name            string `json:"name"`
+	Hash            string `json:"hash,omitempty"`
+	Channel         uint32 `json:"channel"`
+	Muted           bool   `json:"muted,omitempty"`
+	Deafened        bool   `json:"deafened,omitempty"`
+	Suppressed      bool   `json:"suppressed,omitempty"`
+	SelfMuted       bool   `json:"self_muted,omitempty"`
+	SelfDeafened    bool   `json:"self_deafened,omitempty"`
+	PrioritySpeaker bool   `json:"priority_speaker,omitempty"`
+	Recording       bool   `json:"recording,omitempty"`
+	Comment         string `json:"comment,omitempty"`
+}
+
+// SnapshotChannel is a channel in a Snapshot. The channel's parent, links,
+// and users are referred to by ID and session.
+type SnapshotChannel struct {
+	ID          uint32   `json:"id"`
+	Name        string   `json:"name"`
+	Parent      *uint32  `json:"parent,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Position    int32    `json:"position"`
+	MaxUsers    uint32   `json:"max_users,omitempty"`
+	Temporary   bool     `json:"temporary,omitempty"`
+	Links       []uint32 `json:"links,omitempty"`
+	Users       []uint32 `json:"users,omitempty"`
+}
+
+// SnapshotServer is the server configuration in a Snapshot. Fields that the
+// server has not sent are nil.
+type SnapshotServer struct {
+	Address                   string  `json:"address,omitempty"`
+	Version                   *uint32 `json:"version,omitempty"`
+	VersionV2                 *uint64 `json:"version_v2,omitempty"`
+	WelcomeMessage            *string `json:"welcome_message,omitempty"`
+	MaximumBitrate            *int    `json:"maximum_bitrate,omitempty"`
+	AllowHTML                 *bool   `json:"allow_html,omitempty"`
+	MaximumMessageLength      *int    `json:"maximum_message_length,omitempty"`
+	MaximumImageMessageLength *int    `json:"maximum_image_message_length,omitempty"`
+	MaximumUsers              *int    `json:"maximum_users,omitempty"`
+}
+
+// update sets the fields of s that are set in the event.
+func (s *SnapshotServer) update(e *ServerConfigEvent) {
+	if e.WelcomeMessage != nil {
+		s.WelcomeMessage = e.WelcomeMessage
+	}
+	if e.MaximumBitrate != nil {
+		s.MaximumBitrate = e.MaximumBitrate
+	}
+	if e.AllowHTML != nil {
+		s.AllowHTML = e.AllowHTML
+	}
+	if e.MaximumMessageLength != nil {
+		s.MaximumMessageLength = e.MaximumMessageLength
+	}
+	if e.MaximumImageMessageLength != nil {
+		s.MaximumImageMessageLength = e.MaximumImageMessageLength
+	}
+	if e.MaximumUsers != nil {
+		s.MaximumUsers = e.MaximumUsers
+	}
+}
+
+// Snapshot returns a copy of the client's current state. The client's data
+// structures are locked while the copy is made, so Snapshot can be called
+// from any goroutine, but must not be called from inside Do.
+func (c *Client) Snapshot() *Snapshot {
+	s := &Snapshot{
+		Time:  c.clock.Now(),
+		State: c.State().String(),
+		Stats: c.Stats(),
+		Ping:  time.Duration(float64(math.Float32frombits(atomic.LoadUint32(&c.tcpPingAvg))) * float64(time.Millisecond)),
+	}
+
+	c.Do(func() {
+		s.Server = c.serverConfig
+		if c.auditInfo != nil {
+			s.Server.Address = c.auditInfo.server
+		}
+		if c.Self != nil {
+			s.Self = c.Self.Session
+		}
+
+		s.Users = make([]SnapshotUser, 0, len(c.Users))
+		for _, u := range c.Users {
+			user := SnapshotUser{
+				Session:         u.Session,
+				UserID:          u.UserID,
+				Name:            u.Name,
+				Hash:            u.Hash,
+				Muted:           u.Muted,
+				Deafened:        u.Deafened,
+				Suppressed:      u.Suppressed,
+				SelfMuted:       u.SelfMuted,
+				SelfDeafened:    u.SelfDeafened,
+				PrioritySpeaker: u.PrioritySpeaker,
+				Recording:       u.Recording,
+				Comment:         u.Comment,
+			}
+			if u.Channel != nil {
+				user.Channel = u.Channel.ID
+			}
+			s.Users = append(s.Users, user)
+		}
+
+		s.Channels = make([]SnapshotChannel, 0, len(c.Channels))
+		for _, ch := range c.Channels {
+			channel := SnapshotChannel{
+				ID:          ch.ID,
+				Name:        ch.Name,
+				Description: ch.Description,
+				Position:    ch.Position,
+				MaxUsers:    ch.MaxUsers,
+				Temporary:   ch.Temporary,
+			}
+			if ch.Parent != nil {
+				parent := ch.Parent.ID
+				channel.Parent = &parent
+			}
+			for id := range ch.Links {
+				channel.Links = append(channel.Links, id)
+			}
+			for session := range ch.Users {
+				channel.Users = append(channel.Users, session)
+			}
+			sortUint32s(channel.Links)
+			sortUint32s(channel.Users)
+			s.Channels = append(s.Channels, channel)
+		}
+	})
+
+	sort.Slice(s.Users, func(i, j int) bool { return s.Users[i].Session < s.Users[j].Session })
+	sort.Slice(s.Channels, func(i, j int) bool { return s.Channels[i].ID < s.Channels[j].ID })
+	return s
+}
+
+func sortUint32s(s []uint32) {
+	sort.Slice(s, func(i, j int) bool { return s[i] < s[j] })
+}
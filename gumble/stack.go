@@ -0,0 +1,42 @@
+package gumble
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+)
+
+// goid returns the ID of the calling goroutine.
+func goid() uint64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i >= 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseUint(string(b), 10, 64)
+	return id
+}
+
+func stack(all bool) []byte {
+	buf := make([]byte, 4096)
+	for {
+		n := runtime.Stack(buf, all)
+		if n < len(buf) {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// goroutineStack returns the stack trace of the goroutine with the given ID,
+// or nil if it has exited.
+func goroutineStack(id uint64) []byte {
+	prefix := []byte("goroutine " + strconv.FormatUint(id, 10) + " [")
+	for _, trace := range bytes.Split(stack(true), []byte("\n\n")) {
+		if bytes.HasPrefix(trace, prefix) {
+			return trace
+		}
+	}
+	return nil
+}
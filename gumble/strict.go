@@ -0,0 +1,94 @@
+package gumble
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/golang/protobuf/proto"
+	"layeh.com/gumble/gumble/MumbleProto"
+)
+
+// protocolError triggers a ProtocolErrorEvent, if Config.StrictProtocol is
+// set.
+func (c *Client) protocolError(pType uint16, message proto.Message, err error) {
+	if !c.Config.StrictProtocol {
+		return
+	}
+	event := ProtocolErrorEvent{
+		Client:     c,
+		PacketType: pType,
+		Message:    message,
+		Err:        err,
+	}
+	c.Config.Listeners.onProtocolError(&event)
+}
+
+// validate checks message for the problems that its handler tolerates (the
+// problems that its handler rejects are reported by handlePacket). It is
+// called from readRoutine, the only goroutine that modifies the client's
+// state, so the state can be read without locking.
+func (c *Client) validate(message proto.Message) error {
+	switch packet := message.(type) {
+	case *MumbleProto.ChannelState:
+		if packet.Parent != nil && c.Channels[*packet.Parent] == nil {
+			return fmt.Errorf("unknown parent channel %d", *packet.Parent)
+		}
+		if packet.ChannelId != nil && packet.Parent != nil && *packet.Parent == *packet.ChannelId {
+			return fmt.Errorf("channel %d is its own parent", *packet.ChannelId)
+		}
+		for _, ids := range [][]uint32{packet.Links, packet.LinksAdd, packet.LinksRemove} {
+			if err := c.validateChannels("linked", ids); err != nil {
+				return err
+			}
+		}
+		if packet.Description != nil {
+			return c.validateLength("channel description", *packet.Description)
+		}
+	case *MumbleProto.UserState:
+		if packet.Comment != nil {
+			return c.validateLength("user comment", *packet.Comment)
+		}
+	case *MumbleProto.TextMessage:
+		if packet.Actor != nil && c.Users[*packet.Actor] == nil {
+			return fmt.Errorf("unknown sender session %d", *packet.Actor)
+		}
+		for _, session := range packet.Session {
+			if c.Users[session] == nil {
+				return fmt.Errorf("unknown recipient session %d", session)
+			}
+		}
+		if err := c.validateChannels("recipient", packet.ChannelId); err != nil {
+			return err
+		}
+		if err := c.validateChannels("recipient tree", packet.TreeId); err != nil {
+			return err
+		}
+		if packet.Message != nil {
+			return c.validateLength("text message", *packet.Message)
+		}
+	}
+	return nil
+}
+
+func (c *Client) validateChannels(kind string, ids []uint32) error {
+	for _, id := range ids {
+		if c.Channels[id] == nil {
+			return fmt.Errorf("unknown %s channel %d", kind, id)
+		}
+	}
+	return nil
+}
+
+// validateLength checks that s is not longer than the server's message
+// length limits. As messages that contain images can be longer, the larger of
+// the two limits is used.
+func (c *Client) validateLength(field, s string) error {
+	limit := atomic.LoadInt32(&c.maximumMessageLength)
+	if image := atomic.LoadInt32(&c.maximumImageMessageLength); image > limit {
+		limit = image
+	}
+	if limit > 0 && len(s) > int(limit) {
+		return fmt.Errorf("%s is %d bytes, longer than the server's limit of %d", field, len(s), limit)
+	}
+	return nil
+}
@@ -1,9 +1,18 @@
 package gumble
 
 import (
+	"errors"
+	"strings"
+	"sync/atomic"
+
 	"layeh.com/gumble/gumble/MumbleProto"
 )
 
+// ErrMessageTooLong is returned by Client.Send, Channel.Send and User.Send
+// when a message is longer than the server's message length limit, and
+// Config.SplitMessage is not set. Servers reject such messages.
+var ErrMessageTooLong = errors.New("gumble: message is longer than the server allows")
+
 // TextMessage is a chat message that can be received from and sent to the
 // server.
 type TextMessage struct {
@@ -20,8 +29,40 @@ type TextMessage struct {
 }
 
 func (t *TextMessage) writeMessage(client *Client) error {
+	limit := int(atomic.LoadInt32(&client.maximumMessageLength))
+	imageLimit := int(atomic.LoadInt32(&client.maximumImageMessageLength))
+	if exceedsMessageLimit(t.Message, limit, imageLimit) {
+		split := client.Config.SplitMessage
+		if split == nil {
+			return ErrMessageTooLong
+		}
+		for _, message := range split(t.Message, limit, imageLimit) {
+			if err := t.write(client, message); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return t.write(client, t.Message)
+}
+
+// exceedsMessageLimit returns true if the server would reject message. As
+// the server does, the image limit is applied to messages that contain an
+// image, and a limit of zero means that there is no limit.
+func exceedsMessageLimit(message string, limit, imageLimit int) bool {
+	if limit <= 0 || len(message) <= limit {
+		return false
+	}
+	if !strings.Contains(message, "<img") {
+		return true
+	}
+	return imageLimit > 0 && len(message) > imageLimit
+}
+
+// write sends message to the recipients of t.
+func (t *TextMessage) write(client *Client, message string) error {
 	packet := MumbleProto.TextMessage{
-		Message: &t.Message,
+		Message: &message,
 	}
 	if t.Users != nil {
 		packet.Session = make([]uint32, len(t.Users))
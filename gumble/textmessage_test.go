@@ -0,0 +1,67 @@
+package gumble_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"layeh.com/gumble/gumble"
+	"layeh.com/gumble/gumbletest"
+	"layeh.com/gumble/gumbleutil"
+)
+
+func TestChannelSendTooLong(t *testing.T) {
+	server := gumbletest.NewServer()
+	defer server.Close()
+
+	configured := make(chan struct{})
+	config := gumble.NewConfig()
+	config.Attach(gumbleutil.Listener{
+		ServerConfig: func(e *gumble.ServerConfigEvent) {
+			if e.MaximumMessageLength != nil {
+				close(configured)
+			}
+		},
+	})
+	client, err := server.Dial(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Disconnect()
+	select {
+	case <-configured:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the server config")
+	}
+
+	message := strings.Repeat("a", client.MaximumMessageLength()+1)
+	send := func() (err error) {
+		client.Do(func() {
+			err = client.Self.Channel.Send(message, false)
+		})
+		return
+	}
+
+	if err := send(); err != gumble.ErrMessageTooLong {
+		t.Fatalf("Send error = %v; want %v", err, gumble.ErrMessageTooLong)
+	}
+
+	config.SplitMessage = gumbleutil.SplitMessage
+	if err := send(); err != nil {
+		t.Fatalf("Send error = %v; want nil", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(server.Messages()) < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("server received %d messages; want 2", len(server.Messages()))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	var received string
+	for _, m := range server.Messages() {
+		received += m.Message
+	}
+	if received != message {
+		t.Errorf("server received %d bytes; want %d", len(received), len(message))
+	}
+}
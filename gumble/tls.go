@@ -0,0 +1,61 @@
+package gumble
+
+import (
+	"crypto/tls"
+)
+
+// DefaultTLSSessionCacheSize is the size of the TLS session cache that is
+// shared by configs returned by NewConfig.
+const DefaultTLSSessionCacheSize = 64
+
+// defaultTLSSessionCache is shared by all configs returned by NewConfig, so
+// that clients that reconnect, or that connect to the same server many times,
+// can resume their previous TLS sessions.
+var defaultTLSSessionCache = tls.NewLRUClientSessionCache(DefaultTLSSessionCacheSize)
+
+// MumbleCipherSuites are the TLS 1.2 cipher suites supported by Mumble
+// servers, in order of preference. Modern servers use the ECDHE suites; the
+// RSA key exchange suites are only needed by servers older than 1.2.4. The
+// TLS 1.3 cipher suites are not configurable and are always enabled.
+var MumbleCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
+	tls.TLS_RSA_WITH_AES_256_CBC_SHA,
+	tls.TLS_RSA_WITH_AES_128_CBC_SHA,
+}
+
+// NewTLSConfig returns a new tls.Config that can connect to any Mumble server
+// that supports TLS 1.2 or newer, using MumbleCipherSuites. It does not skip
+// certificate verification.
+func NewTLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		CipherSuites: append([]uint16(nil), MumbleCipherSuites...),
+	}
+}
+
+// clientTLSConfig returns the tls.Config used to connect to the server. If the
+// given config does not have a session cache, a copy with the client config's
+// TLSSessionCache is returned.
+func clientTLSConfig(config *Config, tlsConfig *tls.Config) *tls.Config {
+	if config.TLSSessionCache == nil {
+		return tlsConfig
+	}
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{}
+	} else if tlsConfig.ClientSessionCache != nil || tlsConfig.SessionTicketsDisabled {
+		return tlsConfig
+	} else {
+		tlsConfig = tlsConfig.Clone()
+	}
+	tlsConfig.ClientSessionCache = config.TLSSessionCache
+	return tlsConfig
+}
@@ -0,0 +1,108 @@
+package gumble
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// URL is a parsed mumble:// URL, of the form:
+//  mumble://[username[:password]@]host[:port][/channel/sub-channel][?version=1.2.0]
+type URL struct {
+	Username string
+	Password string
+	// The host and port of the server. If the URL does not contain a port,
+	// DefaultPort is used.
+	Host string
+	// The path of names from the root channel to the channel that the client
+	// should join. Empty if the URL does not contain a channel.
+	Channel []string
+	// The protocol version of the server, if it is given by the URL.
+	Version string
+}
+
+// ParseURL parses a mumble:// URL.
+func ParseURL(rawurl string) (*URL, error) {
+	parsed, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	if parsed.Scheme != "mumble" {
+		return nil, errors.New("gumble: URL scheme must be mumble")
+	}
+	if parsed.Hostname() == "" {
+		return nil, errors.New("gumble: URL is missing a host")
+	}
+	u := &URL{
+		Host:    parsed.Host,
+		Version: parsed.Query().Get("version"),
+	}
+	if parsed.Port() == "" {
+		u.Host = net.JoinHostPort(parsed.Hostname(), strconv.Itoa(DefaultPort))
+	}
+	if parsed.User != nil {
+		u.Username = parsed.User.Username()
+		u.Password, _ = parsed.User.Password()
+	}
+	for _, name := range strings.Split(parsed.Path, "/") {
+		if name != "" {
+			u.Channel = append(u.Channel, name)
+		}
+	}
+	return u, nil
+}
+
+// String returns the URL in its mumble:// form.
+func (u *URL) String() string {
+	out := url.URL{
+		Scheme: "mumble",
+		Host:   u.Host,
+	}
+	if len(u.Channel) > 0 {
+		out.Path = "/" + strings.Join(u.Channel, "/")
+	}
+	if u.Username != "" {
+		if u.Password != "" {
+			out.User = url.UserPassword(u.Username, u.Password)
+		} else {
+			out.User = url.User(u.Username)
+		}
+	}
+	if u.Version != "" {
+		out.RawQuery = url.Values{"version": {u.Version}}.Encode()
+	}
+	return out.String()
+}
+
+// DialURL connects to the server of the given mumble:// URL. The URL's
+// username and password, if present, override those of config.
+//
+// Once connected, the client moves itself to the URL's channel. The move is
+// not waited for, and the channel is silently ignored if it does not exist.
+func DialURL(dialer *net.Dialer, rawurl string, config *Config, tlsConfig *tls.Config) (*Client, error) {
+	u, err := ParseURL(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	if u.Username != "" {
+		config.Username = u.Username
+	}
+	if u.Password != "" {
+		config.Password = u.Password
+	}
+	client, err := DialWithDialer(dialer, u.Host, config, tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+	if len(u.Channel) > 0 {
+		client.Do(func() {
+			if channel := client.Channels.Find(u.Channel...); channel != nil && client.Self != nil && client.Self.Channel != channel {
+				client.Self.Move(channel)
+			}
+		})
+	}
+	return client, nil
+}
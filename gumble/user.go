@@ -1,6 +1,8 @@
 package gumble
 
 import (
+	"time"
+
 	"github.com/golang/protobuf/proto"
 	"layeh.com/gumble/gumble/MumbleProto"
 )
@@ -36,7 +38,10 @@ type User struct {
 	Comment string
 	// The user's comment hash. nil if User.Comment has been populated.
 	CommentHash []byte
-	// The hash of the user's certificate (can be empty).
+	// The hash of the user's certificate: the hex encoded SHA-1 hash of the
+	// certificate. Can be empty if the user does not have a certificate, or if
+	// the server has not sent it. A UserChangeEvent with UserChangeHash is
+	// triggered when the hash becomes available.
 	Hash string
 	// The user's texture (avatar). nil if the user does not have a
 	// texture, or if the texture needs to be requested.
@@ -46,36 +51,44 @@ type User struct {
 
 	// The user's stats. Contains nil if the stats have not yet been requested.
 	Stats *UserStats
+	// How long the user had been connected to the server, and how long the
+	// user had been idle, when the user's stats were last received (see
+	// RefreshStats). StatsUpdated is when they were received, and is zero if
+	// the stats have not yet been received.
+	OnlineDuration time.Duration
+	IdleDuration   time.Duration
+	StatsUpdated   time.Time
 
 	client  *Client
 	decoder AudioDecoder
+	playout *playoutBuffer
 }
 
 // SetTexture sets the user's texture.
-func (u *User) SetTexture(texture []byte) {
+func (u *User) SetTexture(texture []byte)error {
 	packet := MumbleProto.UserState{
 		Session: &u.Session,
 		Texture: texture,
 	}
-	u.client.Conn.WriteProto(&packet)
+	return u.client.Conn.WriteProto(&packet)
 }
 
 // SetPrioritySpeaker sets if the user is a priority speaker in the channel.
-func (u *User) SetPrioritySpeaker(prioritySpeaker bool) {
+func (u *User) SetPrioritySpeaker(prioritySpeaker bool)error {
 	packet := MumbleProto.UserState{
 		Session:         &u.Session,
 		PrioritySpeaker: &prioritySpeaker,
 	}
-	u.client.Conn.WriteProto(&packet)
+	return u.client.Conn.WriteProto(&packet)
 }
 
 // SetRecording sets if the user is recording audio.
-func (u *User) SetRecording(recording bool) {
+func (u *User) SetRecording(recording bool)error {
 	packet := MumbleProto.UserState{
 		Session:   &u.Session,
 		Recording: &recording,
 	}
-	u.client.Conn.WriteProto(&packet)
+	return u.client.Conn.WriteProto(&packet)
 }
 
 // IsRegistered returns true if the user's certificate has been registered with
@@ -86,134 +99,166 @@ func (u *User) IsRegistered() bool {
 
 // Register will register the user with the server. If the client has
 // permission to do so, the user will shortly be given a UserID.
-func (u *User) Register() {
+func (u *User) Register()error {
 	packet := MumbleProto.UserState{
 		Session: &u.Session,
 		UserId:  proto.Uint32(0),
 	}
-	u.client.Conn.WriteProto(&packet)
+	return u.client.Conn.WriteProto(&packet)
 }
 
 // SetComment will set the user's comment to the given string. The user's
 // comment will be erased if the comment is set to the empty string.
-func (u *User) SetComment(comment string) {
+func (u *User) SetComment(comment string)error {
 	packet := MumbleProto.UserState{
 		Session: &u.Session,
 		Comment: &comment,
 	}
-	u.client.Conn.WriteProto(&packet)
+	return u.client.Conn.WriteProto(&packet)
 }
 
 // Move will move the user to the given channel.
-func (u *User) Move(channel *Channel) {
+func (u *User) Move(channel *Channel)error {
 	packet := MumbleProto.UserState{
 		Session:   &u.Session,
 		ChannelId: &channel.ID,
 	}
-	u.client.Conn.WriteProto(&packet)
+	return u.client.Conn.WriteProto(&packet)
 }
 
 // Kick will kick the user from the server.
-func (u *User) Kick(reason string) {
+func (u *User) Kick(reason string)error {
 	packet := MumbleProto.UserRemove{
 		Session: &u.Session,
 		Reason:  &reason,
 	}
-	u.client.Conn.WriteProto(&packet)
+	return u.client.Conn.WriteProto(&packet)
 }
 
 // Ban will ban the user from the server.
-func (u *User) Ban(reason string) {
+func (u *User) Ban(reason string)error {
 	packet := MumbleProto.UserRemove{
 		Session: &u.Session,
 		Reason:  &reason,
 		Ban:     proto.Bool(true),
 	}
-	u.client.Conn.WriteProto(&packet)
+	return u.client.Conn.WriteProto(&packet)
 }
 
 // SetMuted sets whether the user can transmit audio or not.
-func (u *User) SetMuted(muted bool) {
+func (u *User) SetMuted(muted bool)error {
 	packet := MumbleProto.UserState{
 		Session: &u.Session,
 		Mute:    &muted,
 	}
-	u.client.Conn.WriteProto(&packet)
+	return u.client.Conn.WriteProto(&packet)
 }
 
 // SetSuppressed sets whether the user is suppressed by the server or not.
-func (u *User) SetSuppressed(supressed bool) {
+func (u *User) SetSuppressed(supressed bool)error {
 	packet := MumbleProto.UserState{
 		Session:  &u.Session,
 		Suppress: &supressed,
 	}
-	u.client.Conn.WriteProto(&packet)
+	return u.client.Conn.WriteProto(&packet)
 }
 
 // SetDeafened sets whether the user can receive audio or not.
-func (u *User) SetDeafened(muted bool) {
+func (u *User) SetDeafened(muted bool)error {
 	packet := MumbleProto.UserState{
 		Session: &u.Session,
 		Deaf:    &muted,
 	}
-	u.client.Conn.WriteProto(&packet)
+	return u.client.Conn.WriteProto(&packet)
 }
 
 // SetSelfMuted sets whether the user can transmit audio or not.
 //
-// This method should only be called on Client.Self().
-func (u *User) SetSelfMuted(muted bool) {
+// This method should only be called on Client.Self(). If
+// Config.SkipEncodeWhenMuted is set, outgoing audio is discarded from when it
+// is called.
+func (u *User) SetSelfMuted(muted bool)error {
+	if u == u.client.Self {
+		u.client.selfMuted.Store(muted)
+	}
 	packet := MumbleProto.UserState{
 		Session:  &u.Session,
 		SelfMute: &muted,
 	}
-	u.client.Conn.WriteProto(&packet)
+	return u.client.Conn.WriteProto(&packet)
 }
 
 // SetSelfDeafened sets whether the user can receive audio or not.
 //
-// This method should only be called on Client.Self().
-func (u *User) SetSelfDeafened(muted bool) {
+// This method should only be called on Client.Self(). If
+// Config.SkipDecodeWhenDeafened is set, incoming audio is discarded from when
+// it is called.
+func (u *User) SetSelfDeafened(muted bool)error {
+	if u == u.client.Self {
+		u.client.selfDeafened.Store(muted)
+	}
 	packet := MumbleProto.UserState{
 		Session:  &u.Session,
 		SelfDeaf: &muted,
 	}
-	u.client.Conn.WriteProto(&packet)
+	return u.client.Conn.WriteProto(&packet)
 }
 
 // RequestStats requests that the user's stats be sent to the client.
-func (u *User) RequestStats() {
+func (u *User) RequestStats()error {
 	packet := MumbleProto.UserStats{
 		Session: &u.Session,
 	}
-	u.client.Conn.WriteProto(&packet)
+	return u.client.Conn.WriteProto(&packet)
+}
+
+// RefreshStats requests the user's stats if they have not been received
+// within maxAge, and returns whether the request was sent. OnlineDuration,
+// IdleDuration, and StatsUpdated are updated when the stats are received.
+func (u *User) RefreshStats(maxAge time.Duration) bool {
+	if !u.StatsUpdated.IsZero() && u.client.clock.Now().Sub(u.StatsUpdated) < maxAge {
+		return false
+	}
+	u.RequestStats()
+	return true
 }
 
 // RequestTexture requests that the user's actual texture (i.e. non-hashed) be
-// sent to the client.
-func (u *User) RequestTexture() {
+// sent to the client. The request is not sent if the same texture has already
+// been requested, for this or another user, and has not yet been received.
+func (u *User) RequestTexture()error {
+	if !u.client.blobs.request(blobTexture, u.TextureHash, u.client.clock.Now()) {
+		return nil
+	}
 	packet := MumbleProto.RequestBlob{
 		SessionTexture: []uint32{u.Session},
 	}
-	u.client.Conn.WriteProto(&packet)
+	return u.client.Conn.WriteProto(&packet)
 }
 
 // RequestComment requests that the user's actual comment (i.e. non-hashed) be
-// sent to the client.
-func (u *User) RequestComment() {
+// sent to the client. The request is not sent if the same comment has already
+// been requested, for this or another user, and has not yet been received.
+func (u *User) RequestComment()error {
+	if !u.client.blobs.request(blobComment, u.CommentHash, u.client.clock.Now()) {
+		return nil
+	}
 	packet := MumbleProto.RequestBlob{
 		SessionComment: []uint32{u.Session},
 	}
-	u.client.Conn.WriteProto(&packet)
+	return u.client.Conn.WriteProto(&packet)
 }
 
 // Send will send a text message to the user.
-func (u *User) Send(message string) {
+//
+// If the message is too long and Config.SplitMessage is not set, the message
+// is not sent and ErrMessageTooLong is returned.
+func (u *User) Send(message string) error {
 	textMessage := TextMessage{
 		Users:   []*User{u},
 		Message: message,
 	}
-	u.client.Send(&textMessage)
+	return u.client.Send(&textMessage)
 }
 
 // SetPlugin sets the user's plugin data.
@@ -223,11 +268,11 @@ func (u *User) Send(message string) {
 // same. The official Mumble client sets the context to:
 //
 //  PluginShortName + "\x00" + AdditionalContextInformation
-func (u *User) SetPlugin(context []byte, identity string) {
+func (u *User) SetPlugin(context []byte, identity string)error {
 	packet := MumbleProto.UserState{
 		Session:        &u.Session,
 		PluginContext:  context,
 		PluginIdentity: &identity,
 	}
-	u.client.Conn.WriteProto(&packet)
+	return u.client.Conn.WriteProto(&packet)
 }
@@ -1,6 +1,8 @@
 package gumble
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"layeh.com/gumble/gumble/MumbleProto"
@@ -52,6 +54,29 @@ func (r *RegisteredUser) ACLUser() *ACLUser {
 // the registered user list is sent back to the server.
 type RegisteredUsers []*RegisteredUser
 
+// Find returns the registered user whose name is equal to name
+// case-insensitively, as the server compares names. nil is returned if no
+// user matches.
+func (r RegisteredUsers) Find(name string) *RegisteredUser {
+	for _, user := range r {
+		if strings.EqualFold(user.Name, name) {
+			return user
+		}
+	}
+	return nil
+}
+
+// resolve returns the registered users with the given names (see Find).
+func (r RegisteredUsers) resolve(names []string) ([]*RegisteredUser, error) {
+	users := make([]*RegisteredUser, len(names))
+	for i, name := range names {
+		if users[i] = r.Find(name); users[i] == nil {
+			return nil, fmt.Errorf("gumble: %q is not a registered user", name)
+		}
+	}
+	return users, nil
+}
+
 func (r RegisteredUsers) writeMessage(client *Client) error {
 	packet := MumbleProto.UserList{}
 
@@ -1,5 +1,9 @@
 package gumble
 
+import (
+	"strings"
+)
+
 // Users is a map of server users.
 //
 // When accessed through client.Users, it contains all users currently on the
@@ -28,3 +32,56 @@ func (u Users) Find(name string) *User {
 	}
 	return nil
 }
+
+// FindByName returns the user whose name is equal to name or, if there is
+// none, a user whose name is equal to name case-insensitively. nil is
+// returned if no user matches.
+func (u Users) FindByName(name string) *User {
+	var found *User
+	for _, user := range u {
+		if user.Name == name {
+			return user
+		}
+		if strings.EqualFold(user.Name, name) && (found == nil || user.Session < found.Session) {
+			found = user
+		}
+	}
+	return found
+}
+
+// FindBySession returns the user with the given session. nil is returned if no
+// user has the session.
+func (u Users) FindBySession(session uint32) *User {
+	return u[session]
+}
+
+// FindByUserID returns the registered user with the given user ID (see
+// User.IsRegistered). nil is returned if no such user is connected.
+func (u Users) FindByUserID(id uint32) *User {
+	if id == 0 {
+		return nil
+	}
+	for _, user := range u {
+		if user.UserID == id {
+			return user
+		}
+	}
+	return nil
+}
+
+// FindByHash returns the user whose certificate hash (User.Hash) is equal to
+// hash. nil is returned if no user exists with the given hash.
+//
+// Unlike names, certificate hashes cannot be impersonated, and also identify
+// users who are not registered.
+func (u Users) FindByHash(hash string) *User {
+	if hash == "" {
+		return nil
+	}
+	for _, user := range u {
+		if strings.EqualFold(user.Hash, hash) {
+			return user
+		}
+	}
+	return nil
+}
@@ -1,6 +1,9 @@
 package varint
 
-import "testing"
+import (
+	"math"
+	"testing"
+)
 
 func TestRange(t *testing.T) {
 
@@ -11,6 +14,9 @@ func TestRange(t *testing.T) {
 			t.Error("Encode returned size 0\n")
 		}
 		s := b[:size]
+		if size != Size(i) {
+			t.Errorf("Size(%d) = %d, encoded in %d bytes\n", i, Size(i), size)
+		}
 
 		val, size := Decode(s)
 		if size == 0 {
@@ -30,3 +36,28 @@ func TestRange(t *testing.T) {
 	fn(10282934828342)
 	fn(1028293482834200000)
 }
+
+func TestEncodeInvalid(t *testing.T) {
+	var b [MaxVarintLen]byte
+	if n := Encode(b[:], math.MinInt64); n != 0 {
+		t.Errorf("Encode(math.MinInt64) = %d\n", n)
+	}
+	if n := Encode(b[:2], 1<<20); n != 0 {
+		t.Errorf("Encode into a short buffer = %d\n", n)
+	}
+}
+
+func TestAppendAllocs(t *testing.T) {
+	b := make([]byte, 0, MaxVarintLen*3)
+	allocs := testing.AllocsPerRun(100, func() {
+		b = Append(b[:0], 1028293482834200000)
+		b = Append(b, -300)
+		b = Append(b, 7)
+	})
+	if allocs != 0 {
+		t.Errorf("Append allocated %v times\n", allocs)
+	}
+	if v, n := Decode(b); v != 1028293482834200000 || n != 9 {
+		t.Errorf("Decode(Append) = %d, %d\n", v, n)
+	}
+}
@@ -9,8 +9,47 @@ import (
 // number.
 const MaxVarintLen = 10
 
-// Encode encodes the given value to varint format.
+// Size returns the number of bytes required to encode the given value, or 0 if
+// it cannot be encoded (math.MinInt64, whose negation overflows).
+func Size(value int64) int {
+	switch {
+	case value <= -1 && value >= -4:
+		return 1
+	case value == math.MinInt64:
+		return 0
+	case value < 0:
+		return 1 + Size(-value)
+	case value <= 0x7F:
+		return 1
+	case value <= 0x3FFF:
+		return 2
+	case value <= 0x1FFFFF:
+		return 3
+	case value <= 0xFFFFFFF:
+		return 4
+	case value <= math.MaxInt32:
+		return 5
+	}
+	return 9
+}
+
+// Append appends the varint encoding of the given value to b, and returns the
+// extended buffer. It does not allocate if b has enough spare capacity.
+//
+// b is returned unchanged if the value cannot be encoded.
+func Append(b []byte, value int64) []byte {
+	var buf [MaxVarintLen]byte
+	n := Encode(buf[:], value)
+	return append(b, buf[:n]...)
+}
+
+// Encode encodes the given value to varint format, and returns the number of
+// bytes written to b. 0 is returned if b is too short, or if the value cannot
+// be encoded (see Size).
 func Encode(b []byte, value int64) int {
+	if n := Size(value); n == 0 || len(b) < n {
+		return 0
+	}
 	// 111111xx Byte-inverted negative two bit number (~xx)
 	if value <= -1 && value >= -4 {
 		b[0] = 0xFC | byte(^value&0xFF)
@@ -54,11 +93,7 @@ func Encode(b []byte, value int64) int {
 		return 5
 	}
 	// 111101__ + long (64-bit) 64-bit number
-	if value <= math.MaxInt64 {
-		b[0] = 0xF4
-		binary.BigEndian.PutUint64(b[1:], uint64(value))
-		return 9
-	}
-
-	return 0
+	b[0] = 0xF4
+	binary.BigEndian.PutUint64(b[1:], uint64(value))
+	return 9
 }
@@ -7,6 +7,13 @@ type Version struct {
 	// Bits 0-15 are the major version, bits 16-23 are the minor version, and
 	// bits 24-31 are the patch version.
 	Version uint32
+	// The semantic version information in the format introduced in Mumble
+	// 1.5, which allows patch versions greater than 255. Zero if it was not
+	// sent (i.e. by versions before 1.5).
+	//
+	// Bits 48-63 are the major version, bits 32-47 are the minor version, and
+	// bits 16-31 are the patch version.
+	VersionV2 uint64
 	// The name of the client.
 	Release string
 	// The operating system name.
@@ -22,3 +29,43 @@ func (v *Version) SemanticVersion() (major uint16, minor, patch uint8) {
 	patch = uint8(v.Version) & 0xFF
 	return
 }
+
+// FullVersion returns the version's semantic version components from
+// VersionV2, or from Version if VersionV2 is not set.
+func (v *Version) FullVersion() (major, minor, patch uint16) {
+	versionV2 := v.VersionV2
+	if versionV2 == 0 {
+		versionV2 = versionV1ToV2(v.Version)
+	}
+	major = uint16(versionV2 >> 48)
+	minor = uint16(versionV2 >> 32)
+	patch = uint16(versionV2 >> 16)
+	return
+}
+
+// AtLeast returns true if the version (see FullVersion) is at least
+// major.minor.patch, e.g. to check whether a server supports a protocol
+// feature.
+func (v *Version) AtLeast(major, minor, patch uint16) bool {
+	vMajor, vMinor, vPatch := v.FullVersion()
+	if vMajor != major {
+		return vMajor > major
+	}
+	if vMinor != minor {
+		return vMinor > minor
+	}
+	return vPatch >= patch
+}
+
+// versionV1ToV2 converts a packed Version to the VersionV2 format.
+func versionV1ToV2(version uint32) uint64 {
+	return uint64(version>>16)<<48 | uint64(version>>8&0xFF)<<32 | uint64(version&0xFF)<<16
+}
+
+// versionV2ToV1 converts a packed VersionV2 to the Version format. Minor and
+// patch versions greater than 255 are limited to 255.
+func versionV2ToV1(version uint64) uint32 {
+	minor := min(version>>32&0xFFFF, 0xFF)
+	patch := min(version>>16&0xFFFF, 0xFF)
+	return uint32(version>>48)<<16 | uint32(minor)<<8 | uint32(patch)
+}
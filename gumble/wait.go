@@ -0,0 +1,95 @@
+package gumble
+
+import (
+	"context"
+	"errors"
+)
+
+var errWaitDisconnected = errors.New("gumble: client disconnected while waiting")
+
+// WaitFor blocks until cond returns true, ctx is done, or the client
+// disconnects. cond is called with the client's state locked (see Do): once
+// when WaitFor is called, and again after each event. ctx.Err() is returned if
+// ctx is done first.
+//
+// WaitFor must not be called from an event listener, as the events that it
+// waits for would not be triggered until the listener returns.
+func (c *Client) WaitFor(ctx context.Context, cond func() bool) error {
+	wake := make(chan struct{}, 1)
+
+	c.volatile.Lock()
+	if cond() {
+		c.volatile.Unlock()
+		return nil
+	}
+	detacher := c.Config.Listeners.AttachAll(func(event Event) {
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	})
+	c.volatile.Unlock()
+
+	defer func() {
+		c.volatile.Lock()
+		detacher.Detach()
+		c.volatile.Unlock()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-c.end:
+		case <-wake:
+		}
+		var ok bool
+		c.Do(func() {
+			ok = cond()
+		})
+		if ok {
+			return nil
+		}
+		if c.State() == StateDisconnected {
+			return errWaitDisconnected
+		}
+	}
+}
+
+// WaitForSynced blocks until the client has received the server's state (see
+// StateSynced). See WaitFor.
+func (c *Client) WaitForSynced(ctx context.Context) error {
+	return c.WaitFor(ctx, func() bool {
+		return c.State() == StateSynced
+	})
+}
+
+// WaitForUser blocks until a user named name is connected to the server, and
+// returns the user. See WaitFor.
+func (c *Client) WaitForUser(ctx context.Context, name string) (*User, error) {
+	var user *User
+	err := c.WaitFor(ctx, func() bool {
+		user = c.Users.Find(name)
+		return user != nil
+	})
+	return user, err
+}
+
+// WaitForChannel blocks until the channel at the given path (see
+// Channels.Find) exists, and returns the channel. It is typically used after
+// creating a channel with Channel.Add:
+//
+//  client.Do(func() {
+//    client.Channels[0].Add("Games", false)
+//  })
+//  games, err := client.WaitForChannel(ctx, "Games")
+//
+// See WaitFor.
+func (c *Client) WaitForChannel(ctx context.Context, names ...string) (*Channel, error) {
+	var channel *Channel
+	err := c.WaitFor(ctx, func() bool {
+		channel = c.Channels.Find(names...)
+		return channel != nil
+	})
+	return channel, err
+}
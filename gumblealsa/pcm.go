@@ -0,0 +1,91 @@
+//go:build linux
+// +build linux
+
+package gumblealsa
+
+/*
+#cgo pkg-config: alsa
+#include <stdlib.h>
+#include <alsa/asoundlib.h>
+*/
+import "C"
+
+import (
+	"errors"
+	"unsafe"
+
+	"layeh.com/gumble/gumble"
+)
+
+// pcm is an open ALSA PCM device configured for gumble's audio format.
+type pcm struct {
+	handle *C.snd_pcm_t
+}
+
+func alsaError(ret C.int) error {
+	return errors.New("gumblealsa: " + C.GoString(C.snd_strerror(ret)))
+}
+
+func openPCM(device string, capture bool, latency int) (*pcm, error) {
+	cDevice := C.CString(device)
+	defer C.free(unsafe.Pointer(cDevice))
+
+	stream := C.snd_pcm_stream_t(C.SND_PCM_STREAM_PLAYBACK)
+	if capture {
+		stream = C.SND_PCM_STREAM_CAPTURE
+	}
+
+	p := &pcm{}
+	if ret := C.snd_pcm_open(&p.handle, cDevice, stream, 0); ret < 0 {
+		return nil, alsaError(ret)
+	}
+	ret := C.snd_pcm_set_params(p.handle, C.SND_PCM_FORMAT_S16_LE, C.SND_PCM_ACCESS_RW_INTERLEAVED,
+		C.uint(gumble.AudioChannels), C.uint(gumble.AudioSampleRate), 1, C.uint(latency))
+	if ret < 0 {
+		C.snd_pcm_close(p.handle)
+		return nil, alsaError(ret)
+	}
+	return p, nil
+}
+
+// write plays the given samples, blocking until they have been queued. Buffer
+// underruns are recovered from automatically.
+func (p *pcm) write(samples []int16) error {
+	for len(samples) > 0 {
+		n := C.snd_pcm_writei(p.handle, unsafe.Pointer(&samples[0]), C.snd_pcm_uframes_t(len(samples)))
+		if n < 0 {
+			if ret := C.snd_pcm_recover(p.handle, C.int(n), 1); ret < 0 {
+				return alsaError(ret)
+			}
+			continue
+		}
+		samples = samples[n:]
+	}
+	return nil
+}
+
+// read fills samples with captured audio, blocking until enough audio is
+// available. Buffer overruns are recovered from automatically.
+func (p *pcm) read(samples []int16) error {
+	for len(samples) > 0 {
+		n := C.snd_pcm_readi(p.handle, unsafe.Pointer(&samples[0]), C.snd_pcm_uframes_t(len(samples)))
+		if n < 0 {
+			if ret := C.snd_pcm_recover(p.handle, C.int(n), 1); ret < 0 {
+				return alsaError(ret)
+			}
+			continue
+		}
+		samples = samples[n:]
+	}
+	return nil
+}
+
+// drop stops the device, discarding any pending samples.
+func (p *pcm) drop() {
+	C.snd_pcm_drop(p.handle)
+	C.snd_pcm_prepare(p.handle)
+}
+
+func (p *pcm) close() {
+	C.snd_pcm_close(p.handle)
+}
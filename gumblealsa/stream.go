@@ -0,0 +1,186 @@
+//go:build linux
+// +build linux
+
+// Package gumblealsa is a minimal ALSA audio system for gumble.
+//
+// It talks to ALSA directly and does not require OpenAL or a sound server,
+// which makes it suitable for headless devices such as a Raspberry Pi running
+// as an intercom. Incoming audio streams are mixed together and played on a
+// single playback device.
+package gumblealsa
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"layeh.com/gumble/gumble"
+	"layeh.com/gumble/internal/mix"
+)
+
+var (
+	ErrState = errors.New("gumblealsa: invalid state")
+)
+
+// DefaultDevice is the name of the ALSA device that is used when an empty
+// device name is given to New.
+const DefaultDevice = "default"
+
+// Latency is the requested device latency.
+var Latency = 60 * time.Millisecond
+
+// Stream plays incoming audio and captures outgoing audio using ALSA PCM
+// devices.
+type Stream struct {
+	client *gumble.Client
+	link   gumble.Detacher
+
+	mixer mix.Mixer
+
+	sink     *pcm
+	sinkStop chan bool
+	sinkWg   sync.WaitGroup
+
+	source     *pcm
+	sourceStop chan bool
+	sourceWg   sync.WaitGroup
+	sourceLock sync.Mutex
+}
+
+// New opens the given playback and capture devices (e.g. "default" or
+// "plughw:1,0") and attaches the stream to the client as an audio listener.
+// Playback starts immediately; capture is started with StartSource.
+func New(client *gumble.Client, playbackDevice, captureDevice string) (*Stream, error) {
+	if playbackDevice == "" {
+		playbackDevice = DefaultDevice
+	}
+	if captureDevice == "" {
+		captureDevice = DefaultDevice
+	}
+	latency := int(Latency / time.Microsecond)
+
+	sink, err := openPCM(playbackDevice, false, latency)
+	if err != nil {
+		return nil, err
+	}
+	source, err := openPCM(captureDevice, true, latency)
+	if err != nil {
+		sink.close()
+		return nil, err
+	}
+
+	s := &Stream{
+		client:   client,
+		sink:     sink,
+		sinkStop: make(chan bool),
+		source:   source,
+	}
+	s.sinkWg.Add(1)
+	go s.sinkRoutine()
+
+	s.link = client.Config.AttachAudio(s)
+
+	return s, nil
+}
+
+// Destroy stops capture and playback and closes the devices.
+func (s *Stream) Destroy() {
+	s.link.Detach()
+	s.StopSource()
+	close(s.sinkStop)
+	s.sinkWg.Wait()
+	s.sink.close()
+	s.source.close()
+}
+
+// StartSource begins capturing audio and sending it to the server.
+func (s *Stream) StartSource() error {
+	s.sourceLock.Lock()
+	defer s.sourceLock.Unlock()
+	if s.sourceStop != nil {
+		return ErrState
+	}
+	s.sourceStop = make(chan bool)
+	s.sourceWg.Add(1)
+	go s.sourceRoutine(s.sourceStop)
+	return nil
+}
+
+// StopSource stops capturing audio.
+func (s *Stream) StopSource() error {
+	s.sourceLock.Lock()
+	if s.sourceStop == nil {
+		s.sourceLock.Unlock()
+		return ErrState
+	}
+	close(s.sourceStop)
+	s.sourceStop = nil
+	s.sourceLock.Unlock()
+	s.sourceWg.Wait()
+	s.source.drop()
+	return nil
+}
+
+// OnAudioStream implements gumble.AudioListener.
+func (s *Stream) OnAudioStream(e *gumble.AudioStreamEvent) {
+	go func() {
+		for packet := range e.C {
+			s.mixer.Write(e.User, packet.AudioBuffer)
+			packet.Release()
+		}
+	}()
+}
+
+func (s *Stream) sinkRoutine() {
+	defer s.sinkWg.Done()
+
+	frame := make([]int16, gumble.AudioDefaultFrameSize)
+	for {
+		select {
+		case <-s.sinkStop:
+			return
+		default:
+		}
+		s.mixer.Read(frame)
+		// write blocks while the device buffer is full, pacing the loop.
+		if err := s.sink.write(frame); err != nil {
+			return
+		}
+	}
+}
+
+func (s *Stream) sourceRoutine(stop chan bool) {
+	defer s.sourceWg.Done()
+
+	frameSize := s.client.AudioFrameSize()
+
+	outgoing := s.client.AudioOutgoing()
+	defer close(outgoing)
+
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		int16Buffer := make([]int16, frameSize)
+		if err := s.source.read(int16Buffer); err != nil {
+			s.sourceEnded(stop)
+			return
+		}
+		outgoing <- gumble.AudioBuffer(int16Buffer)
+	}
+}
+
+// sourceEnded drops the captured samples after capture has ended by itself,
+// so that it can be started again with StartSource. Nothing is done if
+// StopSource has already been called.
+func (s *Stream) sourceEnded(stop chan bool) {
+	s.sourceLock.Lock()
+	defer s.sourceLock.Unlock()
+	if s.sourceStop != stop {
+		return
+	}
+	s.sourceStop = nil
+	s.source.drop()
+}
@@ -0,0 +1,209 @@
+// Package gumblebridge connects gumble clients to other voice systems (e.g.
+// Discord, Matrix, or TeamSpeak) through a PCM audio interface, so that an
+// integration only has to implement the far side of the bridge.
+//
+//  link := gumblebridge.Attach(config, myBridge)
+//  client, err := gumble.Dial("example.com:64738", config)
+//
+// All audio is 48 kHz, mono, 16-bit PCM (see gumble.AudioSampleRate and
+// gumble.AudioChannels).
+package gumblebridge
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"layeh.com/gumble/gumble"
+	"layeh.com/gumble/gumbleutil"
+	"layeh.com/gumble/internal/mix"
+)
+
+// Frame is a frame of PCM audio, labelled with the user who is speaking.
+type Frame struct {
+	// The name of the speaking user. Frames with different labels are mixed
+	// together.
+	User string
+	PCM  []int16
+}
+
+// Bridge is the far side of a bridge.
+type Bridge interface {
+	// WriteAudio is called with each frame of audio that is received from a
+	// Mumble user. It is called from multiple goroutines (one per user), and
+	// should not block.
+	WriteAudio(frame Frame) error
+	// ReadAudio returns the next frame of audio from the far side, which is
+	// transmitted to the Mumble server. It blocks until a frame is available.
+	// io.EOF is returned once the bridge has been closed.
+	ReadAudio() (Frame, error)
+}
+
+// Link wires the clients that use a gumble.Config to a Bridge: audio received
+// from Mumble users is passed to the bridge, and audio read from the bridge
+// is mixed and transmitted by the client.
+type Link struct {
+	// If non-nil, called with the errors returned by the bridge (other than
+	// io.EOF).
+	OnError func(err error)
+
+	bridge    Bridge
+	mixer     mix.Mixer
+	detachers []gumble.Detacher
+
+	mu     sync.Mutex
+	client *gumble.Client
+	closed bool
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// Attach links the clients that use config to bridge, and starts reading
+// audio from bridge.
+func Attach(config *gumble.Config, bridge Bridge) *Link {
+	l := &Link{
+		bridge: bridge,
+		done:   make(chan struct{}),
+	}
+	l.detachers = []gumble.Detacher{
+		config.AttachAudio(l),
+		config.Attach(gumbleutil.Listener{
+			Connect: func(e *gumble.ConnectEvent) {
+				l.mu.Lock()
+				l.client = e.Client
+				l.mu.Unlock()
+			},
+			Disconnect: func(e *gumble.DisconnectEvent) {
+				l.mu.Lock()
+				if l.client == e.Client {
+					l.client = nil
+				}
+				l.mu.Unlock()
+			},
+		}),
+	}
+	l.wg.Add(2)
+	go l.readRoutine()
+	go l.transmitRoutine()
+	return l
+}
+
+// OnAudioStream implements gumble.AudioListener.
+func (l *Link) OnAudioStream(e *gumble.AudioStreamEvent) {
+	go func() {
+		for packet := range e.C {
+			err := l.bridge.WriteAudio(Frame{
+				User: e.User.Name,
+				PCM:  packet.AudioBuffer,
+			})
+			if err != nil {
+				l.error(err)
+			}
+		}
+	}()
+}
+
+func (l *Link) error(err error) {
+	if err != nil && err != io.EOF && l.OnError != nil {
+		l.OnError(err)
+	}
+}
+
+// readRoutine reads frames from the bridge into the mixer.
+func (l *Link) readRoutine() {
+	defer l.wg.Done()
+	for {
+		select {
+		case <-l.done:
+			return
+		default:
+		}
+		frame, err := l.bridge.ReadAudio()
+		if err != nil {
+			l.error(err)
+			if err == io.EOF {
+				return
+			}
+			select {
+			case <-l.done:
+				return
+			case <-time.After(gumble.AudioDefaultInterval):
+			}
+			continue
+		}
+		l.mixer.Write(frame.User, frame.PCM)
+	}
+}
+
+// transmitRoutine sends the mixed audio to the server. A transmission is
+// started when audio is first available, and ended once the mixer is empty.
+func (l *Link) transmitRoutine() {
+	defer l.wg.Done()
+
+	var (
+		outgoing chan<- gumble.AudioBuffer
+		client   *gumble.Client
+	)
+	end := func() {
+		if outgoing != nil {
+			close(outgoing)
+			outgoing = nil
+		}
+	}
+	defer end()
+
+	ticker := time.NewTicker(gumble.AudioDefaultInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.done:
+			return
+		case <-ticker.C:
+		}
+
+		l.mu.Lock()
+		current := l.client
+		l.mu.Unlock()
+		if current != client {
+			// The client reconnected or disconnected.
+			end()
+			client = current
+		}
+		if client == nil {
+			l.mixer.Read(make([]int16, gumble.AudioDefaultFrameSize))
+			continue
+		}
+
+		frame := make([]int16, gumble.AudioDefaultFrameSize)
+		if l.mixer.Read(frame) == 0 {
+			end()
+			continue
+		}
+		if outgoing == nil {
+			outgoing = client.AudioOutgoing()
+		}
+		outgoing <- gumble.AudioBuffer(frame)
+	}
+}
+
+// ErrClosed is returned by Link.Close if the link has already been closed.
+var ErrClosed = errors.New("gumblebridge: link is closed")
+
+// Close detaches the link from the config and stops transmitting. The bridge
+// is not closed; if ReadAudio is blocked, Close waits until it returns.
+func (l *Link) Close() error {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return ErrClosed
+	}
+	l.closed = true
+	l.mu.Unlock()
+	for _, d := range l.detachers {
+		d.Detach()
+	}
+	close(l.done)
+	l.wg.Wait()
+	return nil
+}
@@ -37,6 +37,10 @@ type Stream struct {
 	Source Source
 	// Starting offset.
 	Offset time.Duration
+	// Opens the channel that audio is written to. Defaults to
+	// Client.AudioOutgoing. This can be used to route the stream through a
+	// mixer, such as the one provided by gumblesoundboard.
+	Outgoing func() chan<- gumble.AudioBuffer
 
 	client  *gumble.Client
 	cmd     *exec.Cmd
@@ -53,12 +57,13 @@ type Stream struct {
 // New returns a new Stream for the given gumble Client and Source.
 func New(client *gumble.Client, source Source) *Stream {
 	return &Stream{
-		client:  client,
-		Volume:  1.0,
-		Source:  source,
-		Command: "ffmpeg",
-		pause:   make(chan struct{}),
-		state:   StateInitial,
+		client:   client,
+		Volume:   1.0,
+		Source:   source,
+		Command:  "ffmpeg",
+		Outgoing: client.AudioOutgoing,
+		pause:    make(chan struct{}),
+		state:    StateInitial,
 	}
 }
 
@@ -154,22 +159,22 @@ func (s *Stream) Elapsed() time.Duration {
 func (s *Stream) process() {
 	// s.state has been set to StatePlaying
 
-	interval := s.client.Config.AudioInterval
-	frameSize := s.client.Config.AudioFrameSize()
+	interval := s.client.AudioInterval()
+	frameSize := s.client.AudioFrameSize()
 
 	byteBuffer := make([]byte, frameSize*2)
 
-	outgoing := s.client.AudioOutgoing()
+	outgoing := s.Outgoing()
 	defer close(outgoing)
 
-	ticker := time.NewTicker(interval)
+	ticker := s.client.Clock().NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-s.pause:
 			return
-		case <-ticker.C:
+		case <-ticker.C():
 			if _, err := io.ReadFull(s.pipe, byteBuffer); err != nil {
 				s.l.Lock()
 				s.cleanup()
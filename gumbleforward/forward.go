@@ -0,0 +1,262 @@
+// Package gumbleforward publishes gumble events as JSON to HTTP webhooks or
+// MQTT topics, for home automation and chat-ops integrations.
+//
+//  f := gumbleforward.New(config, &gumbleforward.Webhook{
+//    URL: "https://example.com/hooks/mumble",
+//  })
+//  defer f.Close()
+//
+// Events are batched, and publishing is retried with exponential backoff.
+package gumbleforward
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"layeh.com/gumble/gumble"
+	"layeh.com/gumble/gumbleutil"
+)
+
+// Record types.
+const (
+	TypeUserJoined  = "user_joined"
+	TypeUserLeft    = "user_left"
+	TypeUserKicked  = "user_kicked"
+	TypeUserBanned  = "user_banned"
+	TypeTextMessage = "text_message"
+)
+
+// Record is a forwarded event.
+type Record struct {
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+	// The user that the event is about, or the sender of a text message.
+	User string `json:"user,omitempty"`
+	// The user that kicked or banned User.
+	Actor string `json:"actor,omitempty"`
+	// The path of the user's channel (see gumbleutil.ChannelPathString).
+	Channel string `json:"channel,omitempty"`
+	// The text message, or the kick or ban reason.
+	Message string `json:"message,omitempty"`
+}
+
+var errQueueFull = errors.New("gumbleforward: queue is full")
+
+// Publisher publishes a batch of records.
+type Publisher interface {
+	Publish(ctx context.Context, records []Record) error
+}
+
+// Defaults of Forwarder.
+const (
+	DefaultBatchSize     = 20
+	DefaultBatchInterval = time.Second
+	DefaultMaxRetries    = 5
+	DefaultRetryInterval = time.Second
+	DefaultQueueSize     = 1024
+	// The timeout of each publish attempt.
+	DefaultPublishTimeout = 10 * time.Second
+)
+
+// Forwarder passes the events of the clients that use a gumble.Config to a
+// Publisher.
+//
+// The fields must not be changed after the first event has been received.
+type Forwarder struct {
+	// If non-empty, only records of these types are forwarded.
+	Types []string
+	// A batch is published once it contains BatchSize records, or
+	// BatchInterval after its first record was queued.
+	BatchSize     int
+	BatchInterval time.Duration
+	// A batch that fails to publish is retried up to MaxRetries times,
+	// waiting RetryInterval before the first retry and doubling the wait
+	// after each one.
+	MaxRetries    int
+	RetryInterval time.Duration
+	// If non-nil, called when a batch is dropped after its last retry fails,
+	// or when a record is dropped because the queue is full.
+	OnError func(err error, records []Record)
+
+	publisher Publisher
+	detacher  gumble.Detacher
+	queue     chan Record
+	done      chan struct{}
+	finished  chan struct{}
+	closeOnce sync.Once
+}
+
+// New returns a new Forwarder that publishes the events of the clients that
+// use config to publisher.
+func New(config *gumble.Config, publisher Publisher) *Forwarder {
+	f := &Forwarder{
+		BatchSize:     DefaultBatchSize,
+		BatchInterval: DefaultBatchInterval,
+		MaxRetries:    DefaultMaxRetries,
+		RetryInterval: DefaultRetryInterval,
+
+		publisher: publisher,
+		queue:     make(chan Record, DefaultQueueSize),
+		done:      make(chan struct{}),
+		finished:  make(chan struct{}),
+	}
+	f.detacher = config.Attach(gumbleutil.Listener{
+		UserChange:  f.onUserChange,
+		TextMessage: f.onTextMessage,
+	})
+	go f.publishRoutine()
+	return f
+}
+
+func (f *Forwarder) onUserChange(e *gumble.UserChangeEvent) {
+	if e.User == nil {
+		return
+	}
+	r := Record{
+		User:    e.User.Name,
+		Message: e.String,
+	}
+	switch {
+	case e.Type.Has(gumble.UserChangeBanned):
+		r.Type = TypeUserBanned
+	case e.Type.Has(gumble.UserChangeKicked):
+		r.Type = TypeUserKicked
+	case e.Type.Has(gumble.UserChangeConnected):
+		r.Type = TypeUserJoined
+	case e.Type.Has(gumble.UserChangeDisconnected):
+		r.Type = TypeUserLeft
+	default:
+		return
+	}
+	if e.Actor != nil {
+		r.Actor = e.Actor.Name
+	}
+	if e.User.Channel != nil {
+		r.Channel = gumbleutil.ChannelPathString(e.User.Channel)
+	}
+	f.enqueue(r)
+}
+
+func (f *Forwarder) onTextMessage(e *gumble.TextMessageEvent) {
+	r := Record{
+		Type:    TypeTextMessage,
+		Message: e.Message,
+	}
+	if e.Sender != nil {
+		r.User = e.Sender.Name
+	}
+	if len(e.Channels) > 0 {
+		r.Channel = gumbleutil.ChannelPathString(e.Channels[0])
+	}
+	f.enqueue(r)
+}
+
+func (f *Forwarder) enqueue(r Record) {
+	if len(f.Types) > 0 {
+		found := false
+		for _, t := range f.Types {
+			if t == r.Type {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return
+		}
+	}
+	r.Time = time.Now()
+	select {
+	case <-f.done:
+	case f.queue <- r:
+	default:
+		if f.OnError != nil {
+			f.OnError(errQueueFull, []Record{r})
+		}
+	}
+}
+
+func (f *Forwarder) publishRoutine() {
+	var (
+		batch []Record
+		timer *time.Timer
+		timeC <-chan time.Time
+	)
+	flush := func() {
+		if timer != nil {
+			timer.Stop()
+			timer, timeC = nil, nil
+		}
+		if len(batch) > 0 {
+			f.publish(batch)
+			batch = nil
+		}
+	}
+	for {
+		select {
+		case r := <-f.queue:
+			batch = append(batch, r)
+			if len(batch) >= f.BatchSize {
+				flush()
+			} else if timer == nil {
+				timer = time.NewTimer(f.BatchInterval)
+				timeC = timer.C
+			}
+		case <-timeC:
+			timer, timeC = nil, nil
+			flush()
+		case <-f.done:
+			// Publish the records that are still queued.
+			for len(f.queue) > 0 {
+				batch = append(batch, <-f.queue)
+				if len(batch) >= f.BatchSize {
+					flush()
+				}
+			}
+			flush()
+			close(f.finished)
+			return
+		}
+	}
+}
+
+// publish publishes the batch, retrying on failure. Once the forwarder is
+// closed, failed batches are dropped instead of retried.
+func (f *Forwarder) publish(batch []Record) {
+	wait := f.RetryInterval
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), DefaultPublishTimeout)
+		err := f.publisher.Publish(ctx, batch)
+		cancel()
+		if err == nil {
+			return
+		}
+		if attempt >= f.MaxRetries {
+			if f.OnError != nil {
+				f.OnError(err, batch)
+			}
+			return
+		}
+		select {
+		case <-time.After(wait):
+		case <-f.done:
+			// Closing; the batch is not retried.
+			if f.OnError != nil {
+				f.OnError(err, batch)
+			}
+			return
+		}
+		wait *= 2
+	}
+}
+
+// Close detaches the forwarder from the config, and publishes the records
+// that are queued. It returns once they have been published or dropped.
+func (f *Forwarder) Close() {
+	f.closeOnce.Do(func() {
+		f.detacher.Detach()
+		close(f.done)
+	})
+	<-f.finished
+}
@@ -0,0 +1,49 @@
+package gumbleforward
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// MQTT is a Publisher that publishes each record as a JSON message to an MQTT
+// topic.
+type MQTT struct {
+	// A connected client.
+	Client mqtt.Client
+	// The topic of the messages. "{type}" is replaced with the record's type
+	// (e.g. "mumble/{type}" becomes "mumble/user_joined").
+	Topic    string
+	QoS      byte
+	Retained bool
+}
+
+var errMQTTNotConnected = errors.New("gumbleforward: MQTT client is not connected")
+
+// Publish implements Publisher. If publishing one of the records fails, the
+// whole batch is retried, so subscribers may receive duplicate messages.
+func (m *MQTT) Publish(ctx context.Context, records []Record) error {
+	if !m.Client.IsConnected() {
+		return errMQTTNotConnected
+	}
+	for _, r := range records {
+		payload, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		topic := strings.Replace(m.Topic, "{type}", r.Type, -1)
+		token := m.Client.Publish(topic, m.QoS, m.Retained, payload)
+		select {
+		case <-token.Done():
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if err := token.Error(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
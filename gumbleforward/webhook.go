@@ -0,0 +1,53 @@
+package gumbleforward
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Webhook is a Publisher that POSTs each batch of records to a URL, as a JSON
+// array.
+type Webhook struct {
+	URL string
+	// Additional headers of the requests (e.g. Authorization).
+	Header http.Header
+	// The client used to send the requests. If nil, http.DefaultClient is
+	// used.
+	Client *http.Client
+}
+
+// Publish implements Publisher. Responses with a status code other than 2xx
+// are errors.
+func (w *Webhook) Publish(ctx context.Context, records []Record) error {
+	body, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for key, values := range w.Header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("gumbleforward: webhook responded with %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,2 @@
+//go:generate protoc --go_out=plugins=grpc:. gumble.proto
+package gumblepb
@@ -0,0 +1,348 @@
+// Package gumblegrpc exposes a gRPC service that remote-controls a gumble
+// client, so that bots can be driven by tooling written in other languages.
+//
+//  config := gumble.NewConfig()
+//  service := gumblegrpc.New(config)
+//  server := grpc.NewServer()
+//  service.Register(server)
+//  go server.Serve(listener)
+//  client, err := gumble.Dial("example.com:64738", config)
+//
+// The service is defined in gumblepb/gumble.proto. Clients for other
+// languages can be generated from it.
+package gumblegrpc
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"layeh.com/gumble/gumble"
+	"layeh.com/gumble/gumbleffmpeg"
+	"layeh.com/gumble/gumblegrpc/gumblepb"
+	"layeh.com/gumble/gumbleutil"
+)
+
+// DefaultSubscriberBufferSize is the number of events that are buffered for
+// each Subscribe call. Events are dropped for subscribers whose buffer is
+// full, so that a slow subscriber does not block the client.
+const DefaultSubscriberBufferSize = 64
+
+var errNotConnected = status.Error(codes.Unavailable, "gumblegrpc: client is not connected")
+
+// Server implements gumblepb.GumbleServer for the clients that use a
+// gumble.Config.
+type Server struct {
+	gumblepb.UnimplementedGumbleServer
+
+	detacher gumble.Detacher
+
+	mu     sync.Mutex
+	client *gumble.Client
+	stream *gumbleffmpeg.Stream
+
+	subscribersMu sync.Mutex
+	subscribers   map[*subscriber]struct{}
+}
+
+type subscriber struct {
+	types  map[string]bool
+	events chan *gumblepb.Event
+}
+
+// New returns a new Server that controls the clients that use config.
+func New(config *gumble.Config) *Server {
+	s := &Server{
+		subscribers: make(map[*subscriber]struct{}),
+	}
+	s.detacher = config.Attach(gumbleutil.Listener{
+		Connect:       s.onConnect,
+		Disconnect:    s.onDisconnect,
+		TextMessage:   s.onTextMessage,
+		UserChange:    s.onUserChange,
+		ChannelChange: s.onChannelChange,
+	})
+	return s
+}
+
+// Register registers the service with the given gRPC server.
+func (s *Server) Register(server *grpc.Server) {
+	gumblepb.RegisterGumbleServer(server, s)
+}
+
+// Detach stops the server from receiving the client's events. Calls made
+// after Detach fail as if the client were not connected.
+func (s *Server) Detach() {
+	s.detacher.Detach()
+	s.mu.Lock()
+	s.client = nil
+	s.mu.Unlock()
+}
+
+func (s *Server) currentClient() (*gumble.Client, error) {
+	s.mu.Lock()
+	client := s.client
+	s.mu.Unlock()
+	if client == nil || client.State() != gumble.StateSynced {
+		return nil, errNotConnected
+	}
+	return client, nil
+}
+
+// JoinChannel implements gumblepb.GumbleServer.
+func (s *Server) JoinChannel(ctx context.Context, req *gumblepb.JoinChannelRequest) (*gumblepb.JoinChannelResponse, error) {
+	client, err := s.currentClient()
+	if err != nil {
+		return nil, err
+	}
+	var path string
+	client.Do(func() {
+		channel := gumbleutil.FindChannelByPath(client, req.Path)
+		if channel == nil {
+			err = status.Errorf(codes.NotFound, "gumblegrpc: channel %q does not exist", req.Path)
+			return
+		}
+		path = gumbleutil.ChannelPathString(channel)
+		if client.Self != nil && client.Self.Channel != channel {
+			client.Self.Move(channel)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &gumblepb.JoinChannelResponse{Path: path}, nil
+}
+
+// SendMessage implements gumblepb.GumbleServer.
+func (s *Server) SendMessage(ctx context.Context, req *gumblepb.SendMessageRequest) (*gumblepb.SendMessageResponse, error) {
+	if len(req.Channels) == 0 && len(req.Users) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "gumblegrpc: message has no recipients")
+	}
+	client, err := s.currentClient()
+	if err != nil {
+		return nil, err
+	}
+	message := gumble.TextMessage{
+		Message: req.Message,
+	}
+	client.Do(func() {
+		for _, path := range req.Channels {
+			channel := gumbleutil.FindChannelByPath(client, path)
+			if channel == nil {
+				err = status.Errorf(codes.NotFound, "gumblegrpc: channel %q does not exist", path)
+				return
+			}
+			if req.Tree {
+				message.Trees = append(message.Trees, channel)
+			} else {
+				message.Channels = append(message.Channels, channel)
+			}
+		}
+		for _, name := range req.Users {
+			user := client.Users.Find(name)
+			if user == nil {
+				err = status.Errorf(codes.NotFound, "gumblegrpc: user %q is not connected", name)
+				return
+			}
+			message.Users = append(message.Users, user)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	client.Send(&message)
+	return &gumblepb.SendMessageResponse{}, nil
+}
+
+// Play implements gumblepb.GumbleServer.
+func (s *Server) Play(ctx context.Context, req *gumblepb.PlayRequest) (*gumblepb.PlayResponse, error) {
+	if req.Source == "" {
+		return nil, status.Error(codes.InvalidArgument, "gumblegrpc: source is empty")
+	}
+	client, err := s.currentClient()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stream != nil {
+		s.stream.Stop()
+	}
+	stream := gumbleffmpeg.New(client, gumbleffmpeg.SourceFile(req.Source))
+	if req.Volume > 0 {
+		stream.Volume = req.Volume
+	}
+	if err := stream.Play(); err != nil {
+		s.stream = nil
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	s.stream = stream
+	return &gumblepb.PlayResponse{}, nil
+}
+
+// Stop implements gumblepb.GumbleServer.
+func (s *Server) Stop(ctx context.Context, req *gumblepb.StopRequest) (*gumblepb.StopResponse, error) {
+	s.mu.Lock()
+	stream := s.stream
+	s.stream = nil
+	s.mu.Unlock()
+	if stream == nil {
+		return &gumblepb.StopResponse{}, nil
+	}
+	return &gumblepb.StopResponse{Stopped: stream.Stop() == nil}, nil
+}
+
+// ListUsers implements gumblepb.GumbleServer.
+func (s *Server) ListUsers(ctx context.Context, req *gumblepb.ListUsersRequest) (*gumblepb.ListUsersResponse, error) {
+	client, err := s.currentClient()
+	if err != nil {
+		return nil, err
+	}
+	resp := &gumblepb.ListUsersResponse{}
+	client.Do(func() {
+		for _, user := range client.Users {
+			resp.Users = append(resp.Users, convertUser(user))
+		}
+	})
+	sort.Slice(resp.Users, func(i, j int) bool {
+		return resp.Users[i].Session < resp.Users[j].Session
+	})
+	return resp, nil
+}
+
+// Subscribe implements gumblepb.GumbleServer.
+func (s *Server) Subscribe(req *gumblepb.SubscribeRequest, stream gumblepb.Gumble_SubscribeServer) error {
+	sub := &subscriber{
+		events: make(chan *gumblepb.Event, DefaultSubscriberBufferSize),
+	}
+	if len(req.Types) > 0 {
+		sub.types = make(map[string]bool, len(req.Types))
+		for _, t := range req.Types {
+			sub.types[t] = true
+		}
+	}
+	s.subscribersMu.Lock()
+	s.subscribers[sub] = struct{}{}
+	s.subscribersMu.Unlock()
+	defer func() {
+		s.subscribersMu.Lock()
+		delete(s.subscribers, sub)
+		s.subscribersMu.Unlock()
+	}()
+
+	ctx := stream.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event := <-sub.events:
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// publish sends the event to the subscribers that want it.
+func (s *Server) publish(event *gumblepb.Event) {
+	event.Time = time.Now().UnixNano()
+	s.subscribersMu.Lock()
+	defer s.subscribersMu.Unlock()
+	for sub := range s.subscribers {
+		if sub.types != nil && !sub.types[event.Type] {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+		}
+	}
+}
+
+func (s *Server) onConnect(e *gumble.ConnectEvent) {
+	s.mu.Lock()
+	s.client = e.Client
+	s.mu.Unlock()
+	event := &gumblepb.Event{
+		Type: "connect",
+	}
+	if e.WelcomeMessage != nil {
+		event.Message = *e.WelcomeMessage
+	}
+	s.publish(event)
+}
+
+func (s *Server) onDisconnect(e *gumble.DisconnectEvent) {
+	s.mu.Lock()
+	if s.client == e.Client {
+		s.client = nil
+		s.stream = nil
+	}
+	s.mu.Unlock()
+	message := e.String
+	if message == "" {
+		message = e.Type.String()
+	}
+	s.publish(&gumblepb.Event{
+		Type:    "disconnect",
+		Message: message,
+	})
+}
+
+func (s *Server) onTextMessage(e *gumble.TextMessageEvent) {
+	event := &gumblepb.Event{
+		Type:    "text_message",
+		Message: e.Message,
+	}
+	if e.Sender != nil {
+		event.User = convertUser(e.Sender)
+	}
+	if len(e.Channels) > 0 {
+		event.Channel = gumbleutil.ChannelPathString(e.Channels[0])
+	}
+	s.publish(event)
+}
+
+func (s *Server) onUserChange(e *gumble.UserChangeEvent) {
+	event := &gumblepb.Event{
+		Type:    "user_change",
+		Message: e.Type.String(),
+	}
+	if e.User != nil {
+		event.User = convertUser(e.User)
+		event.Channel = event.User.Channel
+	}
+	s.publish(event)
+}
+
+func (s *Server) onChannelChange(e *gumble.ChannelChangeEvent) {
+	event := &gumblepb.Event{
+		Type:    "channel_change",
+		Message: e.Type.String(),
+	}
+	if e.Channel != nil {
+		event.Channel = gumbleutil.ChannelPathString(e.Channel)
+	}
+	s.publish(event)
+}
+
+func convertUser(user *gumble.User) *gumblepb.User {
+	u := &gumblepb.User{
+		Session:      user.Session,
+		UserId:       user.UserID,
+		Name:         user.Name,
+		Muted:        user.Muted,
+		Deafened:     user.Deafened,
+		SelfMuted:    user.SelfMuted,
+		SelfDeafened: user.SelfDeafened,
+	}
+	if user.Channel != nil {
+		u.Channel = gumbleutil.ChannelPathString(user.Channel)
+	}
+	return u
+}
@@ -0,0 +1,358 @@
+// Package gumblelua exposes a gumble client to Lua scripts, so that bot
+// behavior can be changed without recompiling.
+//
+// Scripts are run by an embedded interpreter (gopher-lua), and access the
+// client through the global gumble table:
+//  gumble.on(event, function)         -- subscribe to an event (see below)
+//  gumble.send(text)                  -- message the client's channel
+//  gumble.send_user(name, text)       -- message a user
+//  gumble.send_channel(path, text)    -- message a channel, by path
+//  gumble.move(name, path)            -- move a user to a channel
+//  gumble.play(filename)              -- play an audio file (using ffmpeg)
+//  gumble.stop()                      -- stop playing audio
+//  gumble.self()                      -- the client's user
+//  gumble.users()                     -- the connected users
+//
+// Users are passed to scripts as tables with the name, session, user_id,
+// registered, and channel (a path, see gumbleutil.ChannelPathString) fields.
+//
+// The following events can be subscribed to. Handlers are passed a table
+// with the listed fields:
+//  connect
+//  disconnect      type, reason
+//  text_message    sender, message, text (the message without HTML)
+//  user_change     user, type, actor, reason
+//  channel_change  channel, type
+//
+// For example:
+//  gumble.on("text_message", function(e)
+//    if e.text == "!ping" then
+//      gumble.send_user(e.sender.name, "pong")
+//    end
+//  end)
+package gumblelua
+
+import (
+	"sync"
+
+	lua "github.com/yuin/gopher-lua"
+	"layeh.com/gumble/gumble"
+	"layeh.com/gumble/gumbleffmpeg"
+	"layeh.com/gumble/gumbleutil"
+)
+
+// Script is an embedded Lua interpreter bound to a gumble client.
+//
+// All Lua code runs with the script locked, so scripts do not need to worry
+// about concurrency.
+type Script struct {
+	// OnError, if non-nil, is called when an event handler raises an error.
+	OnError func(event string, err error)
+
+	mu       sync.Mutex
+	state    *lua.LState
+	client   *gumble.Client
+	handlers map[string][]*lua.LFunction
+	stream   *gumbleffmpeg.Stream
+}
+
+// New returns a new Script with the gumble table defined.
+func New() *Script {
+	s := &Script{
+		state:    lua.NewState(),
+		handlers: make(map[string][]*lua.LFunction),
+	}
+	module := s.state.NewTable()
+	s.state.SetFuncs(module, map[string]lua.LGFunction{
+		"on":           s.luaOn,
+		"send":         s.luaSend,
+		"send_user":    s.luaSendUser,
+		"send_channel": s.luaSendChannel,
+		"move":         s.luaMove,
+		"play":         s.luaPlay,
+		"stop":         s.luaStop,
+		"self":         s.luaSelf,
+		"users":        s.luaUsers,
+	})
+	s.state.SetGlobal("gumble", module)
+	return s
+}
+
+// DoFile runs the Lua file with the given name.
+func (s *Script) DoFile(filename string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state.DoFile(filename)
+}
+
+// DoString runs the given Lua source.
+func (s *Script) DoString(source string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state.DoString(source)
+}
+
+// Close stops any audio that is playing and closes the interpreter.
+func (s *Script) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stream != nil {
+		s.stream.Stop()
+		s.stream = nil
+	}
+	s.state.Close()
+}
+
+// Attach attaches the listener that passes the client's events to the
+// script's handlers.
+func (s *Script) Attach(config *gumble.Config) gumble.Detacher {
+	return config.Attach(gumbleutil.Listener{
+		Connect: func(e *gumble.ConnectEvent) {
+			s.mu.Lock()
+			s.client = e.Client
+			s.mu.Unlock()
+			s.emit("connect", func(t *lua.LTable) {})
+		},
+		Disconnect: func(e *gumble.DisconnectEvent) {
+			s.emit("disconnect", func(t *lua.LTable) {
+				t.RawSetString("type", lua.LString(e.Type.String()))
+				t.RawSetString("reason", lua.LString(e.String))
+			})
+			s.mu.Lock()
+			s.client = nil
+			s.mu.Unlock()
+		},
+		TextMessage: func(e *gumble.TextMessageEvent) {
+			s.emit("text_message", func(t *lua.LTable) {
+				t.RawSetString("sender", s.userTable(e.Sender))
+				t.RawSetString("message", lua.LString(e.Message))
+				t.RawSetString("text", lua.LString(gumbleutil.PlainText(&e.TextMessage)))
+			})
+		},
+		UserChange: func(e *gumble.UserChangeEvent) {
+			s.emit("user_change", func(t *lua.LTable) {
+				t.RawSetString("user", s.userTable(e.User))
+				t.RawSetString("type", lua.LString(e.Type.String()))
+				t.RawSetString("actor", s.userTable(e.Actor))
+				t.RawSetString("reason", lua.LString(e.String))
+			})
+		},
+		ChannelChange: func(e *gumble.ChannelChangeEvent) {
+			s.emit("channel_change", func(t *lua.LTable) {
+				t.RawSetString("channel", lua.LString(gumbleutil.ChannelPathString(e.Channel)))
+				t.RawSetString("type", lua.LString(e.Type.String()))
+			})
+		},
+	})
+}
+
+// emit calls the handlers of the given event with a table filled by fill.
+func (s *Script) emit(event string, fill func(t *lua.LTable)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	handlers := s.handlers[event]
+	if len(handlers) == 0 {
+		return
+	}
+	t := s.state.NewTable()
+	fill(t)
+	for _, fn := range handlers {
+		err := s.state.CallByParam(lua.P{
+			Fn:      fn,
+			NRet:    0,
+			Protect: true,
+		}, t)
+		if err != nil && s.OnError != nil {
+			s.OnError(event, err)
+		}
+	}
+}
+
+// userTable returns the Lua representation of the user.
+//
+// s.mu must be held when calling this function.
+func (s *Script) userTable(user *gumble.User) lua.LValue {
+	if user == nil {
+		return lua.LNil
+	}
+	t := s.state.NewTable()
+	t.RawSetString("name", lua.LString(user.Name))
+	t.RawSetString("session", lua.LNumber(user.Session))
+	t.RawSetString("user_id", lua.LNumber(user.UserID))
+	t.RawSetString("registered", lua.LBool(user.IsRegistered()))
+	if user.Channel != nil {
+		t.RawSetString("channel", lua.LString(gumbleutil.ChannelPathString(user.Channel)))
+	}
+	return t
+}
+
+// The following functions are called from Lua, with s.mu held.
+
+// failure pushes the nil, error message return values.
+func failure(L *lua.LState, message string) int {
+	L.Push(lua.LNil)
+	L.Push(lua.LString(message))
+	return 2
+}
+
+func success(L *lua.LState) int {
+	L.Push(lua.LTrue)
+	return 1
+}
+
+func (s *Script) luaOn(L *lua.LState) int {
+	event := L.CheckString(1)
+	fn := L.CheckFunction(2)
+	switch event {
+	case "connect", "disconnect", "text_message", "user_change", "channel_change":
+	default:
+		L.ArgError(1, "unknown event "+event)
+		return 0
+	}
+	s.handlers[event] = append(s.handlers[event], fn)
+	return 0
+}
+
+func (s *Script) luaSend(L *lua.LState) int {
+	text := L.CheckString(1)
+	if s.client == nil {
+		return failure(L, "not connected")
+	}
+	var ok bool
+	var err error
+	s.client.Do(func() {
+		if self := s.client.Self; self != nil && self.Channel != nil {
+			err = self.Channel.Send(text, false)
+			ok = true
+		}
+	})
+	if !ok {
+		return failure(L, "not in a channel")
+	}
+	if err != nil {
+		return failure(L, err.Error())
+	}
+	return success(L)
+}
+
+func (s *Script) luaSendUser(L *lua.LState) int {
+	name := L.CheckString(1)
+	text := L.CheckString(2)
+	if s.client == nil {
+		return failure(L, "not connected")
+	}
+	var ok bool
+	var err error
+	s.client.Do(func() {
+		if user := s.client.Users.Find(name); user != nil {
+			err = user.Send(text)
+			ok = true
+		}
+	})
+	if !ok {
+		return failure(L, "unknown user "+name)
+	}
+	if err != nil {
+		return failure(L, err.Error())
+	}
+	return success(L)
+}
+
+func (s *Script) luaSendChannel(L *lua.LState) int {
+	path := L.CheckString(1)
+	text := L.CheckString(2)
+	if s.client == nil {
+		return failure(L, "not connected")
+	}
+	var ok bool
+	var err error
+	s.client.Do(func() {
+		if channel := gumbleutil.FindChannelByPath(s.client, path); channel != nil {
+			err = channel.Send(text, false)
+			ok = true
+		}
+	})
+	if !ok {
+		return failure(L, "unknown channel "+path)
+	}
+	if err != nil {
+		return failure(L, err.Error())
+	}
+	return success(L)
+}
+
+func (s *Script) luaMove(L *lua.LState) int {
+	name := L.CheckString(1)
+	path := L.CheckString(2)
+	if s.client == nil {
+		return failure(L, "not connected")
+	}
+	var message string
+	s.client.Do(func() {
+		user := s.client.Users.Find(name)
+		if user == nil {
+			message = "unknown user " + name
+			return
+		}
+		channel := gumbleutil.FindChannelByPath(s.client, path)
+		if channel == nil {
+			message = "unknown channel " + path
+			return
+		}
+		user.Move(channel)
+	})
+	if message != "" {
+		return failure(L, message)
+	}
+	return success(L)
+}
+
+func (s *Script) luaPlay(L *lua.LState) int {
+	filename := L.CheckString(1)
+	if s.client == nil {
+		return failure(L, "not connected")
+	}
+	if s.stream != nil {
+		s.stream.Stop()
+	}
+	s.stream = gumbleffmpeg.New(s.client, gumbleffmpeg.SourceFile(filename))
+	if err := s.stream.Play(); err != nil {
+		s.stream = nil
+		return failure(L, err.Error())
+	}
+	return success(L)
+}
+
+func (s *Script) luaStop(L *lua.LState) int {
+	if s.stream != nil {
+		s.stream.Stop()
+		s.stream = nil
+	}
+	return 0
+}
+
+func (s *Script) luaSelf(L *lua.LState) int {
+	if s.client == nil {
+		L.Push(lua.LNil)
+		return 1
+	}
+	var self lua.LValue = lua.LNil
+	s.client.Do(func() {
+		self = s.userTable(s.client.Self)
+	})
+	L.Push(self)
+	return 1
+}
+
+func (s *Script) luaUsers(L *lua.LState) int {
+	users := L.NewTable()
+	if s.client != nil {
+		s.client.Do(func() {
+			for _, user := range s.client.Users {
+				users.Append(s.userTable(user))
+			}
+		})
+	}
+	L.Push(users)
+	return 1
+}
@@ -0,0 +1,179 @@
+// Package gumblemalgo is a cross-platform audio system for gumble based on
+// miniaudio (https://miniaud.io/).
+//
+// miniaudio selects the native audio API of the platform (WASAPI, Core Audio,
+// PulseAudio, ALSA, etc.) at runtime, which makes this package a reasonable
+// default for simple clients that do not want to deal with per-platform
+// backends.
+package gumblemalgo
+
+import (
+	"encoding/binary"
+	"errors"
+	"sync"
+
+	"github.com/gen2brain/malgo"
+	"layeh.com/gumble/gumble"
+	"layeh.com/gumble/internal/mix"
+)
+
+var (
+	ErrState = errors.New("gumblemalgo: invalid state")
+)
+
+// Stream plays incoming audio and captures outgoing audio using the default
+// devices of the platform's native audio API.
+type Stream struct {
+	client *gumble.Client
+	link   gumble.Detacher
+
+	context *malgo.AllocatedContext
+	mixer   mix.Mixer
+
+	sink       *malgo.Device
+	sinkBuffer []int16
+
+	sourceLock      sync.Mutex
+	source          *malgo.Device
+	sourceFrameSize int
+	sourceBuffer    []int16
+	sourceOutgoing  chan<- gumble.AudioBuffer
+}
+
+// New initializes miniaudio, starts playback on the default output device and
+// attaches the stream to the client as an audio listener. Capture is started
+// with StartSource.
+func New(client *gumble.Client) (*Stream, error) {
+	context, err := malgo.InitContext(nil, malgo.ContextConfig{}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Stream{
+		client:  client,
+		context: context,
+	}
+
+	config := malgo.DefaultDeviceConfig(malgo.Playback)
+	config.Playback.Format = malgo.FormatS16
+	config.Playback.Channels = gumble.AudioChannels
+	config.SampleRate = gumble.AudioSampleRate
+	s.sink, err = malgo.InitDevice(context.Context, config, malgo.DeviceCallbacks{
+		Data: s.playback,
+	})
+	if err != nil {
+		s.freeContext()
+		return nil, err
+	}
+	if err := s.sink.Start(); err != nil {
+		s.sink.Uninit()
+		s.freeContext()
+		return nil, err
+	}
+
+	s.link = client.Config.AttachAudio(s)
+
+	return s, nil
+}
+
+func (s *Stream) freeContext() {
+	s.context.Uninit()
+	s.context.Free()
+}
+
+// Destroy stops capture and playback and releases the devices.
+func (s *Stream) Destroy() {
+	s.link.Detach()
+	s.StopSource()
+	s.sink.Uninit()
+	s.freeContext()
+}
+
+// StartSource begins capturing audio from the default input device and
+// sending it to the server.
+func (s *Stream) StartSource() error {
+	s.sourceLock.Lock()
+	defer s.sourceLock.Unlock()
+	if s.source != nil {
+		return ErrState
+	}
+
+	config := malgo.DefaultDeviceConfig(malgo.Capture)
+	config.Capture.Format = malgo.FormatS16
+	config.Capture.Channels = gumble.AudioChannels
+	config.SampleRate = gumble.AudioSampleRate
+	source, err := malgo.InitDevice(s.context.Context, config, malgo.DeviceCallbacks{
+		Data: s.capture,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.sourceFrameSize = s.client.AudioFrameSize()
+	s.sourceBuffer = make([]int16, 0, s.sourceFrameSize)
+	s.sourceOutgoing = s.client.AudioOutgoing()
+	if err := source.Start(); err != nil {
+		source.Uninit()
+		close(s.sourceOutgoing)
+		s.sourceOutgoing = nil
+		return err
+	}
+	s.source = source
+	return nil
+}
+
+// StopSource stops capturing audio.
+func (s *Stream) StopSource() error {
+	s.sourceLock.Lock()
+	source := s.source
+	s.sourceLock.Unlock()
+	if source == nil {
+		return ErrState
+	}
+	// Uninit waits for any running data callback, which acquires sourceLock,
+	// so the lock cannot be held here.
+	source.Uninit()
+
+	s.sourceLock.Lock()
+	s.source = nil
+	close(s.sourceOutgoing)
+	s.sourceOutgoing = nil
+	s.sourceLock.Unlock()
+	return nil
+}
+
+// OnAudioStream implements gumble.AudioListener.
+func (s *Stream) OnAudioStream(e *gumble.AudioStreamEvent) {
+	go func() {
+		for packet := range e.C {
+			s.mixer.Write(e.User, packet.AudioBuffer)
+			packet.Release()
+		}
+	}()
+}
+
+func (s *Stream) playback(output, input []byte, frameCount uint32) {
+	if cap(s.sinkBuffer) < int(frameCount) {
+		s.sinkBuffer = make([]int16, frameCount)
+	}
+	samples := s.sinkBuffer[:frameCount]
+	s.mixer.Read(samples)
+	for i, sample := range samples {
+		binary.LittleEndian.PutUint16(output[i*2:], uint16(sample))
+	}
+}
+
+func (s *Stream) capture(output, input []byte, frameCount uint32) {
+	s.sourceLock.Lock()
+	defer s.sourceLock.Unlock()
+	if s.sourceOutgoing == nil {
+		return
+	}
+	for i := 0; i < int(frameCount); i++ {
+		s.sourceBuffer = append(s.sourceBuffer, int16(binary.LittleEndian.Uint16(input[i*2:])))
+		if len(s.sourceBuffer) == s.sourceFrameSize {
+			s.sourceOutgoing <- gumble.AudioBuffer(s.sourceBuffer)
+			s.sourceBuffer = make([]int16, 0, s.sourceFrameSize)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+// Package gumblemetrics exports metrics of a gumble client to Prometheus.
+//
+//  collector := gumblemetrics.New(config, nil)
+//  prometheus.MustRegister(collector)
+//  client, err := gumble.Dial("example.com:64738", config)
+//
+// The following metrics are exported:
+//  gumble_connected                        1 if the client is connected
+//  gumble_ping_seconds{protocol}           latest ping latency
+//  gumble_ping_average_seconds{protocol}   average ping latency
+//  gumble_users                            connected users
+//  gumble_packets_received_total           control packets received
+//  gumble_packets_sent_total               control packets sent
+//  gumble_bytes_received_total             control bytes received
+//  gumble_bytes_sent_total                 control bytes sent
+//  gumble_audio_frames_received_total      audio frames decoded
+//  gumble_audio_frames_sent_total          audio frames sent
+//  gumble_connects_total                   successful connections
+//  gumble_reconnects_total                 connections after the first
+//  gumble_disconnects_total{type}          disconnects, by type
+//  gumble_event_dispatch_seconds           time taken to dispatch events
+//
+// The counters are kept across reconnects, as long as the same
+// gumble.Config is used.
+package gumblemetrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"layeh.com/gumble/gumble"
+	"layeh.com/gumble/gumbleutil"
+)
+
+const namespace = "gumble"
+
+// Collector is a prometheus.Collector of a client's metrics.
+type Collector struct {
+	connected       *prometheus.Desc
+	ping            *prometheus.Desc
+	pingAverage     *prometheus.Desc
+	users           *prometheus.Desc
+	packetsReceived *prometheus.Desc
+	packetsSent     *prometheus.Desc
+	bytesReceived   *prometheus.Desc
+	bytesSent       *prometheus.Desc
+	framesReceived  *prometheus.Desc
+	framesSent      *prometheus.Desc
+	connects        *prometheus.Desc
+	reconnects      *prometheus.Desc
+	disconnects     *prometheus.Desc
+	dispatch        prometheus.Histogram
+
+	detachers []gumble.Detacher
+
+	mu     sync.Mutex
+	client *gumble.Client
+	// The sum of the traffic counters of previous clients.
+	previous        gumble.ClientStats
+	pings           map[gumble.PingProtocol][2]time.Duration
+	connectCount    uint64
+	disconnectCount map[gumble.DisconnectType]uint64
+}
+
+// New returns a new Collector of the metrics of the clients that use config.
+// constLabels are added to every metric (e.g. to identify the server when
+// there are multiple clients).
+func New(config *gumble.Config, constLabels prometheus.Labels) *Collector {
+	desc := func(name, help string, labels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "", name), help, labels, constLabels)
+	}
+	c := &Collector{
+		connected:       desc("connected", "Whether the client is connected to the server."),
+		ping:            desc("ping_seconds", "Latest ping latency to the server.", "protocol"),
+		pingAverage:     desc("ping_average_seconds", "Average ping latency to the server.", "protocol"),
+		users:           desc("users", "Number of users connected to the server."),
+		packetsReceived: desc("packets_received_total", "Control protocol packets received."),
+		packetsSent:     desc("packets_sent_total", "Control protocol packets sent."),
+		bytesReceived:   desc("bytes_received_total", "Control protocol bytes received."),
+		bytesSent:       desc("bytes_sent_total", "Control protocol bytes sent."),
+		framesReceived:  desc("audio_frames_received_total", "Audio frames decoded from other users."),
+		framesSent:      desc("audio_frames_sent_total", "Audio frames sent."),
+		connects:        desc("connects_total", "Successful connections to the server."),
+		reconnects:      desc("reconnects_total", "Successful connections to the server after the first."),
+		disconnects:     desc("disconnects_total", "Disconnects from the server.", "type"),
+		dispatch: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace:   namespace,
+			Name:        "event_dispatch_seconds",
+			Help:        "Time taken to pass events to the event listeners.",
+			ConstLabels: constLabels,
+			Buckets:     prometheus.ExponentialBuckets(0.0001, 4, 10),
+		}),
+
+		pings:           make(map[gumble.PingProtocol][2]time.Duration),
+		disconnectCount: make(map[gumble.DisconnectType]uint64),
+	}
+	c.detachers = []gumble.Detacher{
+		config.Use(func(event gumble.Event, next func()) {
+			start := time.Now()
+			next()
+			c.dispatch.Observe(time.Since(start).Seconds())
+		}),
+		config.Attach(gumbleutil.Listener{
+			Connect:     c.onConnect,
+			Disconnect:  c.onDisconnect,
+			PingUpdated: c.onPingUpdated,
+		}),
+	}
+	return c
+}
+
+// Detach stops the collector from receiving the client's events. Metrics of
+// the current client are still collected.
+func (c *Collector) Detach() {
+	for _, d := range c.detachers {
+		d.Detach()
+	}
+}
+
+func (c *Collector) onConnect(e *gumble.ConnectEvent) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.client = e.Client
+	c.connectCount++
+}
+
+func (c *Collector) onDisconnect(e *gumble.DisconnectEvent) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.client == e.Client {
+		stats := e.Client.Stats()
+		c.previous.PacketsReceived += stats.PacketsReceived
+		c.previous.PacketsSent += stats.PacketsSent
+		c.previous.BytesReceived += stats.BytesReceived
+		c.previous.BytesSent += stats.BytesSent
+		c.previous.AudioFramesReceived += stats.AudioFramesReceived
+		c.previous.AudioFramesSent += stats.AudioFramesSent
+		c.client = nil
+	}
+	c.disconnectCount[e.Type]++
+	c.pings = make(map[gumble.PingProtocol][2]time.Duration)
+}
+
+func (c *Collector) onPingUpdated(e *gumble.PingUpdatedEvent) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pings[e.Protocol] = [2]time.Duration{e.Latency, e.Average}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		c.connected, c.ping, c.pingAverage, c.users,
+		c.packetsReceived, c.packetsSent, c.bytesReceived, c.bytesSent,
+		c.framesReceived, c.framesSent,
+		c.connects, c.reconnects, c.disconnects,
+	} {
+		ch <- d
+	}
+	c.dispatch.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	client := c.client
+	stats := c.previous
+	connects := c.connectCount
+	ping := make(map[gumble.PingProtocol][2]time.Duration, len(c.pings))
+	for k, v := range c.pings {
+		ping[k] = v
+	}
+	disconnects := make(map[gumble.DisconnectType]uint64, len(c.disconnectCount))
+	for k, v := range c.disconnectCount {
+		disconnects[k] = v
+	}
+	c.mu.Unlock()
+
+	var connected, users float64
+	if client != nil {
+		current := client.Stats()
+		stats.PacketsReceived += current.PacketsReceived
+		stats.PacketsSent += current.PacketsSent
+		stats.BytesReceived += current.BytesReceived
+		stats.BytesSent += current.BytesSent
+		stats.AudioFramesReceived += current.AudioFramesReceived
+		stats.AudioFramesSent += current.AudioFramesSent
+		if client.State() == gumble.StateSynced {
+			connected = 1
+		}
+		client.Do(func() {
+			users = float64(len(client.Users))
+		})
+	}
+
+	gauge := func(desc *prometheus.Desc, value float64, labels ...string) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, labels...)
+	}
+	counter := func(desc *prometheus.Desc, value uint64, labels ...string) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(value), labels...)
+	}
+
+	gauge(c.connected, connected)
+	gauge(c.users, users)
+	for protocol, p := range ping {
+		name := "tcp"
+		if protocol == gumble.PingUDP {
+			name = "udp"
+		}
+		gauge(c.ping, p[0].Seconds(), name)
+		gauge(c.pingAverage, p[1].Seconds(), name)
+	}
+	counter(c.packetsReceived, stats.PacketsReceived)
+	counter(c.packetsSent, stats.PacketsSent)
+	counter(c.bytesReceived, stats.BytesReceived)
+	counter(c.bytesSent, stats.BytesSent)
+	counter(c.framesReceived, stats.AudioFramesReceived)
+	counter(c.framesSent, stats.AudioFramesSent)
+	counter(c.connects, connects)
+	if connects > 0 {
+		counter(c.reconnects, connects-1)
+	} else {
+		counter(c.reconnects, 0)
+	}
+	for t, n := range disconnects {
+		counter(c.disconnects, n, t.String())
+	}
+	c.dispatch.Collect(ch)
+}
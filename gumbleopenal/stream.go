@@ -28,7 +28,7 @@ type Stream struct {
 func New(client *gumble.Client) (*Stream, error) {
 	s := &Stream{
 		client:          client,
-		sourceFrameSize: client.Config.AudioFrameSize(),
+		sourceFrameSize: client.AudioFrameSize(),
 	}
 
 	s.deviceSource = openal.CaptureOpenDevice("", gumble.AudioSampleRate, openal.FormatMono16, uint32(s.sourceFrameSize))
@@ -92,11 +92,13 @@ func (s *Stream) OnAudioStream(e *gumble.AudioStreamEvent) {
 		for packet := range e.C {
 			samples := len(packet.AudioBuffer)
 			if samples > cap(raw) {
+				packet.Release()
 				continue
 			}
 			for i, value := range packet.AudioBuffer {
 				binary.LittleEndian.PutUint16(raw[i*2:], uint16(value))
 			}
+			packet.Release()
 			reclaim()
 			if len(emptyBufs) == 0 {
 				continue
@@ -117,8 +119,8 @@ func (s *Stream) OnAudioStream(e *gumble.AudioStreamEvent) {
 }
 
 func (s *Stream) sourceRoutine() {
-	interval := s.client.Config.AudioInterval
-	frameSize := s.client.Config.AudioFrameSize()
+	interval := s.client.AudioInterval()
+	frameSize := s.client.AudioFrameSize()
 
 	if frameSize != s.sourceFrameSize {
 		s.deviceSource.CaptureCloseDevice()
@@ -0,0 +1,285 @@
+// Package gumbleotel instruments gumble clients with OpenTelemetry tracing.
+//
+//  config := gumble.NewConfig()
+//  tracer := gumbleotel.New(config, otel.Tracer("mybot"))
+//  client, err := tracer.Dial(ctx, nil, "example.com:64738", config, nil)
+//
+// The following spans are recorded:
+//  gumble.Dial                   connecting, authenticating, and syncing
+//  gumble.RequestACL             Tracer.RequestACL until the ACL is received
+//  gumble.RequestBanList         Tracer.RequestBanList until the list is received
+//  gumble.RequestStats           Tracer.RequestStats until the stats are received
+//  gumble.dispatch <event>       passing an event to the event listeners
+//
+// Requests that are still waiting for a response when the client disconnects
+// are ended with an error.
+package gumbleotel
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"layeh.com/gumble/gumble"
+	"layeh.com/gumble/gumbleutil"
+)
+
+// Span attribute keys.
+const (
+	AttrServerAddress = "mumble.server.address"
+	AttrUsername      = "mumble.username"
+	AttrSession       = "mumble.session"
+	AttrUserName      = "mumble.user.name"
+	AttrChannelID     = "mumble.channel.id"
+	AttrChannelName   = "mumble.channel.name"
+	AttrEvent         = "mumble.event"
+	AttrRejectType    = "mumble.reject.type"
+	AttrDisconnect    = "mumble.disconnect.type"
+)
+
+var errDisconnected = errors.New("gumbleotel: client disconnected before the response was received")
+
+// Tracer records spans of the clients that use a gumble.Config.
+type Tracer struct {
+	tracer    trace.Tracer
+	detachers []gumble.Detacher
+
+	mu      sync.Mutex
+	acl     map[uint32][]trace.Span
+	banList []trace.Span
+	stats   map[uint32][]trace.Span
+}
+
+// New returns a new Tracer that records spans with tracer. Event dispatch
+// spans are recorded for every client that uses config.
+func New(config *gumble.Config, tracer trace.Tracer) *Tracer {
+	t := &Tracer{
+		tracer: tracer,
+		acl:    make(map[uint32][]trace.Span),
+		stats:  make(map[uint32][]trace.Span),
+	}
+	t.detachers = []gumble.Detacher{
+		config.Use(t.dispatch),
+		config.Attach(gumbleutil.Listener{
+			Disconnect: t.onDisconnect,
+			UserChange: t.onUserChange,
+			ACL:        t.onACL,
+			BanList:    t.onBanList,
+		}),
+	}
+	return t
+}
+
+// Detach stops the tracer from receiving the client's events. Pending request
+// spans are ended with an error.
+func (t *Tracer) Detach() {
+	for _, d := range t.detachers {
+		d.Detach()
+	}
+	t.endAll(errors.New("gumbleotel: tracer detached"))
+}
+
+// Dial is gumble.DialWithDialer, recorded as a gumble.Dial span. If dialer is
+// nil, a zero net.Dialer is used.
+func (t *Tracer) Dial(ctx context.Context, dialer *net.Dialer, addr string, config *gumble.Config, tlsConfig *tls.Config) (*gumble.Client, error) {
+	_, span := t.tracer.Start(ctx, "gumble.Dial",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String(AttrServerAddress, addr),
+			attribute.String(AttrUsername, config.Username),
+		),
+	)
+	defer span.End()
+
+	if dialer == nil {
+		dialer = new(net.Dialer)
+	}
+	client, err := gumble.DialWithDialer(dialer, addr, config, tlsConfig)
+	if err != nil {
+		var reject *gumble.RejectError
+		if errors.As(err, &reject) {
+			span.SetAttributes(attribute.Int(AttrRejectType, int(reject.Type)))
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	client.Do(func() {
+		if client.Self != nil {
+			span.SetAttributes(attribute.Int64(AttrSession, int64(client.Self.Session)))
+		}
+	})
+	return client, nil
+}
+
+// RequestACL calls channel.RequestACL, and records a span that ends once the
+// channel's ACL is received.
+func (t *Tracer) RequestACL(ctx context.Context, channel *gumble.Channel) {
+	_, span := t.tracer.Start(ctx, "gumble.RequestACL",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(channelAttributes(channel)...),
+	)
+	t.mu.Lock()
+	t.acl[channel.ID] = append(t.acl[channel.ID], span)
+	t.mu.Unlock()
+	channel.RequestACL()
+}
+
+// RequestBanList calls client.RequestBanList, and records a span that ends
+// once the ban list is received.
+func (t *Tracer) RequestBanList(ctx context.Context, client *gumble.Client) {
+	_, span := t.tracer.Start(ctx, "gumble.RequestBanList",
+		trace.WithSpanKind(trace.SpanKindClient),
+	)
+	t.mu.Lock()
+	t.banList = append(t.banList, span)
+	t.mu.Unlock()
+	client.RequestBanList()
+}
+
+// RequestStats calls user.RequestStats, and records a span that ends once the
+// user's stats are received.
+func (t *Tracer) RequestStats(ctx context.Context, user *gumble.User) {
+	_, span := t.tracer.Start(ctx, "gumble.RequestStats",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(userAttributes(user)...),
+	)
+	t.mu.Lock()
+	t.stats[user.Session] = append(t.stats[user.Session], span)
+	t.mu.Unlock()
+	user.RequestStats()
+}
+
+func (t *Tracer) onACL(e *gumble.ACLEvent) {
+	if e.ACL == nil || e.ACL.Channel == nil {
+		return
+	}
+	t.mu.Lock()
+	spans := t.acl[e.ACL.Channel.ID]
+	delete(t.acl, e.ACL.Channel.ID)
+	t.mu.Unlock()
+	endSpans(spans, nil)
+}
+
+func (t *Tracer) onBanList(e *gumble.BanListEvent) {
+	t.mu.Lock()
+	spans := t.banList
+	t.banList = nil
+	t.mu.Unlock()
+	for _, span := range spans {
+		span.SetAttributes(attribute.Int("mumble.banlist.size", len(e.BanList)))
+	}
+	endSpans(spans, nil)
+}
+
+func (t *Tracer) onUserChange(e *gumble.UserChangeEvent) {
+	if e.User == nil {
+		return
+	}
+	var err error
+	switch {
+	case e.Type.Has(gumble.UserChangeStats):
+	case e.Type.Has(gumble.UserChangeDisconnected):
+		err = errors.New("gumbleotel: user disconnected before the stats were received")
+	default:
+		return
+	}
+	t.mu.Lock()
+	spans := t.stats[e.User.Session]
+	delete(t.stats, e.User.Session)
+	t.mu.Unlock()
+	endSpans(spans, err)
+}
+
+func (t *Tracer) onDisconnect(e *gumble.DisconnectEvent) {
+	t.endAll(errDisconnected)
+}
+
+// endAll ends all pending request spans with err.
+func (t *Tracer) endAll(err error) {
+	t.mu.Lock()
+	var spans []trace.Span
+	for _, s := range t.acl {
+		spans = append(spans, s...)
+	}
+	for _, s := range t.stats {
+		spans = append(spans, s...)
+	}
+	spans = append(spans, t.banList...)
+	t.acl = make(map[uint32][]trace.Span)
+	t.stats = make(map[uint32][]trace.Span)
+	t.banList = nil
+	t.mu.Unlock()
+	endSpans(spans, err)
+}
+
+func endSpans(spans []trace.Span, err error) {
+	for _, span := range spans {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
+
+// dispatch is the middleware that records event dispatch spans.
+func (t *Tracer) dispatch(event gumble.Event, next func()) {
+	name := eventName(event)
+	attrs := []attribute.KeyValue{
+		attribute.String(AttrEvent, name),
+	}
+	switch e := event.(type) {
+	case *gumble.DisconnectEvent:
+		attrs = append(attrs, attribute.String(AttrDisconnect, e.Type.String()))
+	case *gumble.TextMessageEvent:
+		if e.Sender != nil {
+			attrs = append(attrs, userAttributes(e.Sender)...)
+		}
+	case *gumble.UserChangeEvent:
+		if e.User != nil {
+			attrs = append(attrs, userAttributes(e.User)...)
+		}
+	case *gumble.ChannelChangeEvent:
+		if e.Channel != nil {
+			attrs = append(attrs, channelAttributes(e.Channel)...)
+		}
+	}
+	_, span := t.tracer.Start(context.Background(), "gumble.dispatch "+name,
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(attrs...),
+	)
+	defer span.End()
+	next()
+}
+
+// eventName returns the name of the event's type, without the package name
+// and Event suffix (e.g. "TextMessage").
+func eventName(event gumble.Event) string {
+	name := fmt.Sprintf("%T", event)
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		name = name[i+1:]
+	}
+	return strings.TrimSuffix(name, "Event")
+}
+
+func userAttributes(user *gumble.User) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.Int64(AttrSession, int64(user.Session)),
+		attribute.String(AttrUserName, user.Name),
+	}
+}
+
+func channelAttributes(channel *gumble.Channel) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.Int64(AttrChannelID, int64(channel.ID)),
+		attribute.String(AttrChannelName, channel.Name),
+	}
+}
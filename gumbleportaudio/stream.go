@@ -0,0 +1,192 @@
+// Package gumbleportaudio is a PortAudio audio system for gumble.
+//
+// It is an alternative to gumbleopenal for platforms where OpenAL is not
+// available. Incoming audio streams are mixed together and played through the
+// default output device; audio captured from the default input device is sent
+// to the server.
+package gumbleportaudio
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/gordonklaus/portaudio"
+	"layeh.com/gumble/gumble"
+	"layeh.com/gumble/internal/mix"
+)
+
+var (
+	ErrState = errors.New("gumbleportaudio: invalid state")
+)
+
+// Stream plays incoming audio and captures outgoing audio using the default
+// PortAudio devices.
+type Stream struct {
+	client *gumble.Client
+	link   gumble.Detacher
+
+	mixer mix.Mixer
+
+	sourceStream    *portaudio.Stream
+	sourceBuffer    []int16
+	sourceFrameSize int
+	sourceStop      chan bool
+	sourceWg        sync.WaitGroup
+	sourceLock      sync.Mutex
+
+	sinkStream *portaudio.Stream
+}
+
+// New initializes PortAudio, opens the default input and output devices and
+// attaches the stream to the client as an audio listener.
+func New(client *gumble.Client) (*Stream, error) {
+	if err := portaudio.Initialize(); err != nil {
+		return nil, err
+	}
+
+	s := &Stream{
+		client:          client,
+		sourceFrameSize: client.AudioFrameSize(),
+	}
+
+	if err := s.openSource(); err != nil {
+		portaudio.Terminate()
+		return nil, err
+	}
+
+	sink, err := portaudio.OpenDefaultStream(0, gumble.AudioChannels, gumble.AudioSampleRate, s.sourceFrameSize, s.sinkCallback)
+	if err != nil {
+		s.sourceStream.Close()
+		portaudio.Terminate()
+		return nil, err
+	}
+	if err := sink.Start(); err != nil {
+		sink.Close()
+		s.sourceStream.Close()
+		portaudio.Terminate()
+		return nil, err
+	}
+	s.sinkStream = sink
+
+	s.link = client.Config.AttachAudio(s)
+
+	return s, nil
+}
+
+func (s *Stream) openSource() error {
+	s.sourceBuffer = make([]int16, s.sourceFrameSize)
+	source, err := portaudio.OpenDefaultStream(gumble.AudioChannels, 0, gumble.AudioSampleRate, len(s.sourceBuffer), s.sourceBuffer)
+	if err != nil {
+		return err
+	}
+	s.sourceStream = source
+	return nil
+}
+
+// Destroy stops capture and playback, closes the devices and terminates
+// PortAudio.
+func (s *Stream) Destroy() {
+	s.link.Detach()
+	if s.sourceStream != nil {
+		s.StopSource()
+		s.sourceStream.Close()
+		s.sourceStream = nil
+	}
+	if s.sinkStream != nil {
+		s.sinkStream.Stop()
+		s.sinkStream.Close()
+		s.sinkStream = nil
+	}
+	portaudio.Terminate()
+}
+
+// StartSource begins capturing audio from the input device and sending it to
+// the server.
+func (s *Stream) StartSource() error {
+	s.sourceLock.Lock()
+	defer s.sourceLock.Unlock()
+	if s.sourceStop != nil {
+		return ErrState
+	}
+	if frameSize := s.client.AudioFrameSize(); frameSize != s.sourceFrameSize {
+		s.sourceStream.Close()
+		s.sourceFrameSize = frameSize
+		if err := s.openSource(); err != nil {
+			return err
+		}
+	}
+	if err := s.sourceStream.Start(); err != nil {
+		return err
+	}
+	s.sourceStop = make(chan bool)
+	s.sourceWg.Add(1)
+	go s.sourceRoutine(s.sourceStop)
+	return nil
+}
+
+// StopSource stops capturing audio.
+func (s *Stream) StopSource() error {
+	s.sourceLock.Lock()
+	if s.sourceStop == nil {
+		s.sourceLock.Unlock()
+		return ErrState
+	}
+	close(s.sourceStop)
+	s.sourceStop = nil
+	s.sourceLock.Unlock()
+	s.sourceWg.Wait()
+	return s.sourceStream.Stop()
+}
+
+// OnAudioStream implements gumble.AudioListener.
+func (s *Stream) OnAudioStream(e *gumble.AudioStreamEvent) {
+	go func() {
+		for packet := range e.C {
+			s.mixer.Write(e.User, packet.AudioBuffer)
+			packet.Release()
+		}
+	}()
+}
+
+func (s *Stream) sinkCallback(out []int16) {
+	s.mixer.Read(out)
+}
+
+func (s *Stream) sourceRoutine(stop chan bool) {
+	defer s.sourceWg.Done()
+
+	outgoing := s.client.AudioOutgoing()
+	defer close(outgoing)
+
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		// Read blocks until a full frame has been captured, which paces the
+		// loop at the configured audio interval. An overflow only means that
+		// samples were dropped; other errors (e.g. the device was unplugged)
+		// end capture.
+		if err := s.sourceStream.Read(); err != nil && err != portaudio.InputOverflowed {
+			s.sourceEnded(stop)
+			return
+		}
+		int16Buffer := make([]int16, len(s.sourceBuffer))
+		copy(int16Buffer, s.sourceBuffer)
+		outgoing <- gumble.AudioBuffer(int16Buffer)
+	}
+}
+
+// sourceEnded stops the input stream after capture has ended by itself, so
+// that it can be started again with StartSource. Nothing is done if StopSource
+// has already been called.
+func (s *Stream) sourceEnded(stop chan bool) {
+	s.sourceLock.Lock()
+	defer s.sourceLock.Unlock()
+	if s.sourceStop != stop {
+		return
+	}
+	s.sourceStop = nil
+	s.sourceStream.Stop()
+}
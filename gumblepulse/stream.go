@@ -0,0 +1,265 @@
+// Package gumblepulse is a native PulseAudio audio system for gumble. It also
+// works with PipeWire through its PulseAudio compatibility server.
+//
+// Streams are registered with the sound server under the configured
+// application and stream names, so they can be identified and controlled from
+// the desktop's volume mixer. Unless a specific sink or source is requested,
+// streams are not pinned to a device and follow the user's default device when
+// it changes.
+package gumblepulse
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"sync/atomic"
+
+	"github.com/jfreymuth/pulse"
+	"layeh.com/gumble/gumble"
+	"layeh.com/gumble/internal/mix"
+)
+
+var (
+	ErrState = errors.New("gumblepulse: invalid state")
+)
+
+// Options configures how the streams are presented to the sound server.
+type Options struct {
+	// Name of the application. Defaults to "gumble".
+	ApplicationName string
+	// Name of the playback stream. Defaults to "Mumble playback".
+	PlaybackName string
+	// Name of the capture stream. Defaults to "Mumble capture".
+	CaptureName string
+
+	// Name of the sink that incoming audio is played on. If empty, the
+	// default sink is used and followed.
+	Sink string
+	// Name of the source that outgoing audio is captured from. If empty, the
+	// default source is used and followed.
+	Source string
+}
+
+// Stream plays incoming audio and captures outgoing audio through PulseAudio.
+type Stream struct {
+	client *gumble.Client
+	link   gumble.Detacher
+
+	pulse *pulse.Client
+	mixer mix.Mixer
+
+	playbackVolume uint32
+	captureVolume  uint32
+
+	sink *pulse.PlaybackStream
+
+	source          *pulse.RecordStream
+	sourceFrameSize int
+	sourceBuffer    []int16
+	sourceOutgoing  chan<- gumble.AudioBuffer
+	sourceStop      chan struct{}
+	sourceWrites    sync.WaitGroup
+	sourceLock      sync.Mutex
+	// sourceControl serializes StartSource and StopSource. Unlike sourceLock,
+	// it is held while waiting for the sound server, which recordWrite must
+	// never wait for.
+	sourceControl sync.Mutex
+}
+
+// New connects to the sound server and attaches the stream to the client as
+// an audio listener. Playback starts immediately; capture is started with
+// StartSource. options can be nil.
+func New(client *gumble.Client, options *Options) (*Stream, error) {
+	var opts Options
+	if options != nil {
+		opts = *options
+	}
+	if opts.ApplicationName == "" {
+		opts.ApplicationName = "gumble"
+	}
+	if opts.PlaybackName == "" {
+		opts.PlaybackName = "Mumble playback"
+	}
+	if opts.CaptureName == "" {
+		opts.CaptureName = "Mumble capture"
+	}
+
+	c, err := pulse.NewClient(pulse.ClientApplicationName(opts.ApplicationName))
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Stream{
+		client:          client,
+		pulse:           c,
+		sourceFrameSize: client.AudioFrameSize(),
+	}
+	s.SetPlaybackVolume(1)
+	s.SetCaptureVolume(1)
+
+	playbackOptions := []pulse.PlaybackOption{
+		pulse.PlaybackMono,
+		pulse.PlaybackSampleRate(gumble.AudioSampleRate),
+		pulse.PlaybackBufferSize(s.sourceFrameSize),
+		pulse.PlaybackMediaName(opts.PlaybackName),
+	}
+	if opts.Sink != "" {
+		sink, err := c.SinkByID(opts.Sink)
+		if err != nil {
+			c.Close()
+			return nil, err
+		}
+		playbackOptions = append(playbackOptions, pulse.PlaybackSink(sink))
+	}
+	s.sink, err = c.NewPlayback(pulse.Int16Reader(s.playbackRead), playbackOptions...)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	recordOptions := []pulse.RecordOption{
+		pulse.RecordMono,
+		pulse.RecordSampleRate(gumble.AudioSampleRate),
+		pulse.RecordBufferFragmentSize(uint32(s.sourceFrameSize * 2)),
+		pulse.RecordMediaName(opts.CaptureName),
+	}
+	if opts.Source != "" {
+		source, err := c.SourceByID(opts.Source)
+		if err != nil {
+			s.sink.Close()
+			c.Close()
+			return nil, err
+		}
+		recordOptions = append(recordOptions, pulse.RecordSource(source))
+	}
+	s.source, err = c.NewRecord(pulse.Int16Writer(s.recordWrite), recordOptions...)
+	if err != nil {
+		s.sink.Close()
+		c.Close()
+		return nil, err
+	}
+
+	s.sink.Start()
+	s.link = client.Config.AttachAudio(s)
+
+	return s, nil
+}
+
+// Destroy stops capture and playback and disconnects from the sound server.
+func (s *Stream) Destroy() {
+	s.link.Detach()
+	s.StopSource()
+	s.source.Close()
+	s.sink.Stop()
+	s.sink.Close()
+	s.pulse.Close()
+}
+
+// SetPlaybackVolume sets the gain applied to incoming audio. 1 is the
+// original volume.
+func (s *Stream) SetPlaybackVolume(volume float32) {
+	atomic.StoreUint32(&s.playbackVolume, math.Float32bits(volume))
+}
+
+// SetCaptureVolume sets the gain applied to outgoing audio. 1 is the original
+// volume.
+func (s *Stream) SetCaptureVolume(volume float32) {
+	atomic.StoreUint32(&s.captureVolume, math.Float32bits(volume))
+}
+
+// StartSource begins capturing audio and sending it to the server.
+func (s *Stream) StartSource() error {
+	s.sourceControl.Lock()
+	defer s.sourceControl.Unlock()
+	s.sourceLock.Lock()
+	if s.sourceOutgoing != nil {
+		s.sourceLock.Unlock()
+		return ErrState
+	}
+	s.sourceFrameSize = s.client.AudioFrameSize()
+	s.sourceBuffer = make([]int16, 0, s.sourceFrameSize)
+	s.sourceOutgoing = s.client.AudioOutgoing()
+	s.sourceStop = make(chan struct{})
+	s.sourceLock.Unlock()
+	s.source.Start()
+	return nil
+}
+
+// StopSource stops capturing audio.
+func (s *Stream) StopSource() error {
+	s.sourceControl.Lock()
+	defer s.sourceControl.Unlock()
+	s.sourceLock.Lock()
+	if s.sourceOutgoing == nil {
+		s.sourceLock.Unlock()
+		return ErrState
+	}
+	outgoing := s.sourceOutgoing
+	s.sourceOutgoing = nil
+	// Unblock recordWrite if it is waiting to send a frame: the sound
+	// server's reply to Stop is not read until it returns.
+	close(s.sourceStop)
+	s.sourceLock.Unlock()
+	s.source.Stop()
+	s.sourceWrites.Wait()
+	close(outgoing)
+	return nil
+}
+
+// OnAudioStream implements gumble.AudioListener.
+func (s *Stream) OnAudioStream(e *gumble.AudioStreamEvent) {
+	go func() {
+		for packet := range e.C {
+			s.mixer.Write(e.User, packet.AudioBuffer)
+			packet.Release()
+		}
+	}()
+}
+
+func (s *Stream) playbackRead(out []int16) (int, error) {
+	s.mixer.Read(out)
+	applyVolume(out, math.Float32frombits(atomic.LoadUint32(&s.playbackVolume)))
+	return len(out), nil
+}
+
+func (s *Stream) recordWrite(in []int16) (int, error) {
+	s.sourceLock.Lock()
+	if s.sourceOutgoing == nil {
+		s.sourceLock.Unlock()
+		return len(in), nil
+	}
+	volume := math.Float32frombits(atomic.LoadUint32(&s.captureVolume))
+	var frames []gumble.AudioBuffer
+	for _, sample := range in {
+		s.sourceBuffer = append(s.sourceBuffer, sample)
+		if len(s.sourceBuffer) == s.sourceFrameSize {
+			applyVolume(s.sourceBuffer, volume)
+			frames = append(frames, s.sourceBuffer)
+			s.sourceBuffer = make([]int16, 0, s.sourceFrameSize)
+		}
+	}
+	outgoing, stop := s.sourceOutgoing, s.sourceStop
+	s.sourceWrites.Add(1)
+	s.sourceLock.Unlock()
+	defer s.sourceWrites.Done()
+
+	// The frames are sent without holding sourceLock, so that StopSource is
+	// not blocked by a full outgoing channel.
+	for _, frame := range frames {
+		select {
+		case outgoing <- frame:
+		case <-stop:
+			return len(in), nil
+		}
+	}
+	return len(in), nil
+}
+
+func applyVolume(samples []int16, volume float32) {
+	if volume == 1 {
+		return
+	}
+	for i, sample := range samples {
+		samples[i] = mix.Clamp(int32(float32(sample) * volume))
+	}
+}
@@ -0,0 +1,147 @@
+// Package gumblertp bridges gumble audio to and from RTP sessions with an
+// Opus payload (RFC 7587), for connecting Mumble channels to SIP and WebRTC
+// infrastructure.
+//
+// Egress sends the audio of each user that the client hears as a separate
+// RTP stream (SSRC):
+//
+//  egress, err := gumblertp.NewEgress(config, "10.0.0.2:5004")
+//
+// Ingress receives an RTP stream and transmits it as the client's audio:
+//
+//  ingress, err := gumblertp.NewIngress(client, ":5006")
+package gumblertp
+
+import (
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"layeh.com/gumble/gumble"
+	"layeh.com/gumble/opus"
+)
+
+// DefaultPayloadType is the dynamic RTP payload type used for Opus.
+const DefaultPayloadType = 111
+
+// talkspurtGap is the silence after which the next packet of a user starts a
+// new talkspurt, and has its marker bit set.
+const talkspurtGap = 200 * time.Millisecond
+
+// Egress sends the audio received by the clients that use a gumble.Config to
+// an RTP destination, one SSRC per user.
+type Egress struct {
+	// The RTP payload type of the packets. Defaults to DefaultPayloadType.
+	PayloadType uint8
+	// If non-nil, called when a user's RTP stream is started.
+	OnStream func(user *gumble.User, ssrc uint32)
+
+	conn     net.Conn
+	detacher gumble.Detacher
+
+	mu      sync.Mutex
+	streams map[*gumble.User]*egressStream
+	closed  bool
+}
+
+type egressStream struct {
+	ssrc      uint32
+	sequence  uint16
+	timestamp uint32
+	last      time.Time
+	encoder   gumble.AudioEncoder
+}
+
+// NewEgress returns a new Egress that sends the audio received by the clients
+// that use config to the UDP address dst.
+func NewEgress(config *gumble.Config, dst string) (*Egress, error) {
+	conn, err := net.Dial("udp", dst)
+	if err != nil {
+		return nil, err
+	}
+	e := &Egress{
+		PayloadType: DefaultPayloadType,
+		conn:        conn,
+		streams:     make(map[*gumble.User]*egressStream),
+	}
+	e.detacher = config.AttachAudio(e)
+	return e, nil
+}
+
+// SSRC returns the SSRC of the user's RTP stream, and whether the user has a
+// stream.
+func (e *Egress) SSRC(user *gumble.User) (uint32, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if s := e.streams[user]; s != nil {
+		return s.ssrc, true
+	}
+	return 0, false
+}
+
+// OnAudioStream implements gumble.AudioListener.
+func (e *Egress) OnAudioStream(event *gumble.AudioStreamEvent) {
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		return
+	}
+	s := &egressStream{
+		ssrc:      rand.Uint32(),
+		sequence:  uint16(rand.Uint32()),
+		timestamp: rand.Uint32(),
+		encoder:   opus.Codec.NewEncoder(),
+	}
+	e.streams[event.User] = s
+	onStream := e.OnStream
+	e.mu.Unlock()
+	if onStream != nil {
+		onStream(event.User, s.ssrc)
+	}
+
+	go func() {
+		for packet := range event.C {
+			e.send(s, packet.AudioBuffer)
+		}
+		e.mu.Lock()
+		delete(e.streams, event.User)
+		e.mu.Unlock()
+	}()
+}
+
+func (e *Egress) send(s *egressStream, pcm gumble.AudioBuffer) {
+	now := time.Now()
+	marker := s.last.IsZero() || now.Sub(s.last) > talkspurtGap
+	if !s.last.IsZero() && marker {
+		// Advance the timestamp by the length of the silence, so that the
+		// receiver's playout is not shifted.
+		s.timestamp += uint32(now.Sub(s.last).Seconds() * gumble.AudioSampleRate)
+	}
+	s.last = now
+
+	data, err := s.encoder.Encode(pcm, len(pcm), gumble.AudioMaximumFrameSize)
+	if err != nil {
+		return
+	}
+	packet := Packet{
+		Marker:         marker,
+		PayloadType:    e.PayloadType,
+		SequenceNumber: s.sequence,
+		Timestamp:      s.timestamp,
+		SSRC:           s.ssrc,
+		Payload:        data,
+	}
+	e.conn.Write(packet.Marshal())
+	s.sequence++
+	s.timestamp += uint32(len(pcm))
+}
+
+// Close stops sending audio and closes the connection.
+func (e *Egress) Close() error {
+	e.mu.Lock()
+	e.closed = true
+	e.mu.Unlock()
+	e.detacher.Detach()
+	return e.conn.Close()
+}
@@ -0,0 +1,107 @@
+package gumblertp
+
+import (
+	"net"
+	"time"
+
+	"layeh.com/gumble/gumble"
+	"layeh.com/gumble/opus"
+)
+
+// Ingress receives an RTP stream with an Opus payload, and transmits it as a
+// client's audio.
+//
+// Only one RTP stream is transmitted at a time: packets of other SSRCs are
+// ignored until the current stream has been silent for a moment.
+type Ingress struct {
+	client *gumble.Client
+	conn   net.PacketConn
+	done   chan struct{}
+}
+
+// NewIngress returns a new Ingress that listens for RTP packets on the UDP
+// address addr (e.g. ":5006"), and transmits them as client's audio.
+func NewIngress(client *gumble.Client, addr string) (*Ingress, error) {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	i := &Ingress{
+		client: client,
+		conn:   conn,
+		done:   make(chan struct{}),
+	}
+	go i.readRoutine()
+	return i, nil
+}
+
+// Addr returns the address on which the ingress listens.
+func (i *Ingress) Addr() net.Addr {
+	return i.conn.LocalAddr()
+}
+
+func (i *Ingress) readRoutine() {
+	defer close(i.done)
+
+	var (
+		outgoing chan<- gumble.AudioBuffer
+		decoder  gumble.AudioDecoder
+		ssrc     uint32
+		sequence uint16
+	)
+	endTalkspurt := func() {
+		if outgoing != nil {
+			close(outgoing)
+			outgoing = nil
+		}
+	}
+	defer endTalkspurt()
+
+	buf := make([]byte, 1500)
+	for {
+		if outgoing != nil {
+			i.conn.SetReadDeadline(time.Now().Add(talkspurtGap))
+		} else {
+			i.conn.SetReadDeadline(time.Time{})
+		}
+		n, _, err := i.conn.ReadFrom(buf)
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				endTalkspurt()
+				continue
+			}
+			return
+		}
+
+		var packet Packet
+		if err := packet.Unmarshal(buf[:n]); err != nil {
+			continue
+		}
+		if outgoing == nil {
+			ssrc = packet.SSRC
+			sequence = packet.SequenceNumber - 1
+			decoder = opus.Codec.NewDecoder()
+			outgoing = i.client.AudioOutgoing()
+		} else if packet.SSRC != ssrc {
+			continue
+		}
+		// Drop duplicate and late packets.
+		if int16(packet.SequenceNumber-sequence) <= 0 {
+			continue
+		}
+		sequence = packet.SequenceNumber
+
+		pcm, err := decoder.Decode(packet.Payload, gumble.AudioMaximumFrameSize)
+		if err != nil || len(pcm) == 0 {
+			continue
+		}
+		outgoing <- gumble.AudioBuffer(pcm)
+	}
+}
+
+// Close stops receiving packets, and ends the client's current transmission.
+func (i *Ingress) Close() error {
+	err := i.conn.Close()
+	<-i.done
+	return err
+}
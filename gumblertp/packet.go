@@ -0,0 +1,72 @@
+package gumblertp
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+const headerSize = 12
+
+// Packet is an RTP packet (RFC 3550). CSRCs and header extensions are skipped
+// when unmarshaling, and are never written.
+type Packet struct {
+	Marker         bool
+	PayloadType    uint8
+	SequenceNumber uint16
+	Timestamp      uint32
+	SSRC           uint32
+	Payload        []byte
+}
+
+var errInvalidPacket = errors.New("gumblertp: invalid RTP packet")
+
+// Marshal returns the encoded packet.
+func (p *Packet) Marshal() []byte {
+	buf := make([]byte, headerSize+len(p.Payload))
+	buf[0] = 2 << 6
+	buf[1] = p.PayloadType & 0x7F
+	if p.Marker {
+		buf[1] |= 0x80
+	}
+	binary.BigEndian.PutUint16(buf[2:], p.SequenceNumber)
+	binary.BigEndian.PutUint32(buf[4:], p.Timestamp)
+	binary.BigEndian.PutUint32(buf[8:], p.SSRC)
+	copy(buf[headerSize:], p.Payload)
+	return buf
+}
+
+// Unmarshal decodes data into p. p.Payload references data.
+func (p *Packet) Unmarshal(data []byte) error {
+	if len(data) < headerSize || data[0]>>6 != 2 {
+		return errInvalidPacket
+	}
+	padding := data[0]&0x20 != 0
+	extension := data[0]&0x10 != 0
+	csrcs := int(data[0] & 0x0F)
+
+	p.Marker = data[1]&0x80 != 0
+	p.PayloadType = data[1] & 0x7F
+	p.SequenceNumber = binary.BigEndian.Uint16(data[2:])
+	p.Timestamp = binary.BigEndian.Uint32(data[4:])
+	p.SSRC = binary.BigEndian.Uint32(data[8:])
+
+	offset := headerSize + csrcs*4
+	if extension {
+		if len(data) < offset+4 {
+			return errInvalidPacket
+		}
+		offset += 4 + int(binary.BigEndian.Uint16(data[offset+2:]))*4
+	}
+	end := len(data)
+	if padding {
+		if end == 0 {
+			return errInvalidPacket
+		}
+		end -= int(data[end-1])
+	}
+	if offset > end {
+		return errInvalidPacket
+	}
+	p.Payload = data[offset:end]
+	return nil
+}
@@ -0,0 +1,75 @@
+package gumblesoundboard
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"layeh.com/gumble/gumble"
+)
+
+// Clip is a short, preloaded sound.
+//
+// Clips are kept as decoded PCM so that any number of them can be mixed
+// together, and with music, when played.
+type Clip struct {
+	// The clip's name.
+	Name string
+	// The clip's samples.
+	PCM []int16
+	// Playback volume of the clip.
+	Volume float32
+}
+
+// NewClip returns a new clip containing the given samples. pcm must be mono
+// audio sampled at gumble.AudioSampleRate.
+func NewClip(name string, pcm []int16) *Clip {
+	return &Clip{
+		Name:   name,
+		PCM:    pcm,
+		Volume: 1.0,
+	}
+}
+
+// LoadClip decodes the given media file into a clip using ffmpeg. command is
+// the ffmpeg executable to run; if it is empty, "ffmpeg" is used.
+func LoadClip(command, name, filename string) (*Clip, error) {
+	if command == "" {
+		command = "ffmpeg"
+	}
+	cmd := exec.Command(command, "-i", filename, "-ac", strconv.Itoa(gumble.AudioChannels), "-ar", strconv.Itoa(gumble.AudioSampleRate), "-f", "s16le", "-")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	raw, err := cmd.Output()
+	if err != nil {
+		if stderr.Len() > 0 {
+			return nil, &LoadError{Filename: filename, Err: err, Output: stderr.String()}
+		}
+		return nil, &LoadError{Filename: filename, Err: err}
+	}
+	pcm := make([]int16, len(raw)/2)
+	for i := range pcm {
+		pcm[i] = int16(binary.LittleEndian.Uint16(raw[i*2:]))
+	}
+	return NewClip(name, pcm), nil
+}
+
+// Duration returns the length of the clip.
+func (c *Clip) Duration() time.Duration {
+	return time.Duration(len(c.PCM)) * time.Second / gumble.AudioSampleRate
+}
+
+// LoadError is returned by LoadClip when a file cannot be decoded.
+type LoadError struct {
+	Filename string
+	Err      error
+	// ffmpeg's diagnostic output, if any.
+	Output string
+}
+
+// Error implements error.
+func (e *LoadError) Error() string {
+	return "gumblesoundboard: could not load " + e.Filename + ": " + e.Err.Error()
+}
@@ -0,0 +1,225 @@
+// Package gumblesoundboard plays preloaded sound clips on demand.
+//
+// Any number of clips can play at the same time; they are mixed together
+// before being sent to the server. Music (e.g. a gumbleffmpeg.Stream) can be
+// routed through the soundboard so that it is mixed with the clips and ducked
+// while clips are playing:
+//
+//  board := gumblesoundboard.New(client)
+//  board.Add(clip)
+//
+//  music := gumbleffmpeg.New(client, gumbleffmpeg.SourceFile("song.mp3"))
+//  music.Outgoing = board.MusicOutgoing
+//  music.Play()
+//
+//  board.Play("airhorn")
+package gumblesoundboard
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"layeh.com/gumble/gumble"
+	"layeh.com/gumble/internal/mix"
+)
+
+var (
+	// ErrUnknownClip is returned by Play when no clip with the given name
+	// has been added to the soundboard.
+	ErrUnknownClip = errors.New("gumblesoundboard: unknown clip")
+)
+
+// DefaultDuckVolume is the volume that music is reduced to while clips are
+// playing.
+const DefaultDuckVolume = 0.3
+
+// Soundboard holds a set of clips and mixes the ones that are playing, along
+// with any music routed through it, into the client's outgoing audio.
+type Soundboard struct {
+	// Volume of the music while at least one clip is playing.
+	DuckVolume float32
+
+	client *gumble.Client
+
+	mu      sync.Mutex
+	clips   map[string]*Clip
+	playing []*playback
+	music   mix.Mixer
+	inputs  int
+	running bool
+}
+
+type playback struct {
+	clip     *Clip
+	position int
+	stop     bool
+}
+
+// New returns a new Soundboard for the given client.
+func New(client *gumble.Client) *Soundboard {
+	return &Soundboard{
+		DuckVolume: DefaultDuckVolume,
+		client:     client,
+		clips:      make(map[string]*Clip),
+	}
+}
+
+// Add adds the clip to the soundboard, replacing any clip with the same name.
+func (s *Soundboard) Add(clip *Clip) {
+	s.mu.Lock()
+	s.clips[clip.Name] = clip
+	s.mu.Unlock()
+}
+
+// Remove removes the clip with the given name from the soundboard.
+func (s *Soundboard) Remove(name string) {
+	s.mu.Lock()
+	delete(s.clips, name)
+	s.mu.Unlock()
+}
+
+// Clip returns the clip with the given name, or nil if it does not exist.
+func (s *Soundboard) Clip(name string) *Clip {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.clips[name]
+}
+
+// Clips returns the names of the clips on the soundboard.
+func (s *Soundboard) Clips() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.clips))
+	for name := range s.clips {
+		names = append(names, name)
+	}
+	return names
+}
+
+// Play starts playing the clip with the given name. If other clips are
+// already playing, the clip is mixed with them.
+func (s *Soundboard) Play(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clip := s.clips[name]
+	if clip == nil {
+		return ErrUnknownClip
+	}
+	s.playing = append(s.playing, &playback{clip: clip})
+	s.start()
+	return nil
+}
+
+// Stop stops all playing instances of the clip with the given name. If name
+// is empty, all clips are stopped.
+func (s *Soundboard) Stop(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range s.playing {
+		if name == "" || p.clip.Name == name {
+			p.stop = true
+		}
+	}
+}
+
+// Playing returns the number of clips currently playing.
+func (s *Soundboard) Playing() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.playing)
+}
+
+// MusicOutgoing has the same semantics as Client.AudioOutgoing, but the audio
+// written to the returned channel is mixed with the soundboard's clips and
+// ducked while clips are playing. It can be assigned to
+// gumbleffmpeg.Stream.Outgoing.
+func (s *Soundboard) MusicOutgoing() chan<- gumble.AudioBuffer {
+	ch := make(chan gumble.AudioBuffer)
+	key := new(int)
+
+	s.mu.Lock()
+	s.inputs++
+	s.start()
+	s.mu.Unlock()
+
+	go func() {
+		for buffer := range ch {
+			s.music.Write(key, buffer)
+		}
+		s.mu.Lock()
+		s.inputs--
+		s.mu.Unlock()
+	}()
+	return ch
+}
+
+// start launches the output routine if it is not already running. s.mu must
+// be held.
+func (s *Soundboard) start() {
+	if s.running {
+		return
+	}
+	s.running = true
+	go s.process()
+}
+
+func (s *Soundboard) process() {
+	interval := s.client.AudioInterval()
+	frameSize := s.client.AudioFrameSize()
+
+	outgoing := s.client.AudioOutgoing()
+	defer close(outgoing)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	music := make([]int16, frameSize)
+	acc := make([]int32, frameSize)
+	for range ticker.C {
+		s.mu.Lock()
+		hasMusic := s.music.Read(music) > 0
+		if len(s.playing) == 0 && !hasMusic && s.inputs == 0 {
+			s.running = false
+			s.mu.Unlock()
+			return
+		}
+
+		musicVolume := float32(1)
+		if len(s.playing) > 0 {
+			musicVolume = s.DuckVolume
+		}
+		for i, sample := range music {
+			acc[i] = int32(float32(sample) * musicVolume)
+		}
+
+		active := s.playing[:0]
+		for _, p := range s.playing {
+			if p.stop {
+				continue
+			}
+			samples := p.clip.PCM[p.position:]
+			if len(samples) > frameSize {
+				samples = samples[:frameSize]
+			}
+			for i, sample := range samples {
+				acc[i] += int32(float32(sample) * p.clip.Volume)
+			}
+			p.position += len(samples)
+			if p.position < len(p.clip.PCM) {
+				active = append(active, p)
+			}
+		}
+		for i := len(active); i < len(s.playing); i++ {
+			s.playing[i] = nil
+		}
+		s.playing = active
+		s.mu.Unlock()
+
+		frame := make(gumble.AudioBuffer, frameSize)
+		for i, v := range acc {
+			frame[i] = mix.Clamp(v)
+		}
+		outgoing <- frame
+	}
+}
@@ -0,0 +1,105 @@
+package gumblestream
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"layeh.com/gumble/gumble"
+)
+
+// oggBOS is the header type of the first page of an Ogg stream.
+const oggBOS = 0x02
+
+// opusPreSkip is the number of samples that the decoder discards at the start
+// of the stream, as recommended by RFC 7845.
+const opusPreSkip = 312
+
+var oggCRCTable = func() (table [256]uint32) {
+	for i := range table {
+		r := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if r&0x80000000 != 0 {
+				r = r<<1 ^ 0x04c11db7
+			} else {
+				r <<= 1
+			}
+		}
+		table[i] = r
+	}
+	return
+}()
+
+func oggCRC(data []byte) uint32 {
+	var crc uint32
+	for _, b := range data {
+		crc = crc<<8 ^ oggCRCTable[byte(crc>>24)^b]
+	}
+	return crc
+}
+
+// oggWriter encapsulates Opus packets in an Ogg stream, one packet per page
+// (RFC 7845).
+type oggWriter struct {
+	serial   uint32
+	sequence uint32
+	granule  uint64
+}
+
+// page returns an Ogg page containing packet.
+func (o *oggWriter) page(headerType byte, granule uint64, packet []byte) []byte {
+	var segments []byte
+	for n := len(packet); ; n -= 255 {
+		if n < 255 {
+			segments = append(segments, byte(n))
+			break
+		}
+		segments = append(segments, 255)
+	}
+
+	var buf bytes.Buffer
+	buf.Grow(27 + len(segments) + len(packet))
+	buf.WriteString("OggS")
+	buf.WriteByte(0)
+	buf.WriteByte(headerType)
+	binary.Write(&buf, binary.LittleEndian, granule)
+	binary.Write(&buf, binary.LittleEndian, o.serial)
+	binary.Write(&buf, binary.LittleEndian, o.sequence)
+	binary.Write(&buf, binary.LittleEndian, uint32(0))
+	buf.WriteByte(byte(len(segments)))
+	buf.Write(segments)
+	buf.Write(packet)
+	o.sequence++
+
+	page := buf.Bytes()
+	binary.LittleEndian.PutUint32(page[22:], oggCRC(page))
+	return page
+}
+
+// headers returns the pages of the OpusHead and OpusTags packets, which start
+// the stream.
+func (o *oggWriter) headers() []byte {
+	var head bytes.Buffer
+	head.WriteString("OpusHead")
+	head.WriteByte(1)
+	head.WriteByte(gumble.AudioChannels)
+	binary.Write(&head, binary.LittleEndian, uint16(opusPreSkip))
+	binary.Write(&head, binary.LittleEndian, uint32(gumble.AudioSampleRate))
+	binary.Write(&head, binary.LittleEndian, int16(0))
+	head.WriteByte(0)
+
+	const vendor = "gumble"
+	var tags bytes.Buffer
+	tags.WriteString("OpusTags")
+	binary.Write(&tags, binary.LittleEndian, uint32(len(vendor)))
+	tags.WriteString(vendor)
+	binary.Write(&tags, binary.LittleEndian, uint32(0))
+
+	out := o.page(oggBOS, 0, head.Bytes())
+	return append(out, o.page(0, 0, tags.Bytes())...)
+}
+
+// packet returns the page of an audio packet of the given number of samples.
+func (o *oggWriter) packet(data []byte, samples int) []byte {
+	o.granule += uint64(samples)
+	return o.page(0, o.granule, data)
+}
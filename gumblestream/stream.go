@@ -0,0 +1,325 @@
+// Package gumblestream serves the audio that a gumble client receives over
+// HTTP, so that a channel can be listened to in a web browser or relayed by
+// an Icecast server.
+//
+//  config := gumble.NewConfig()
+//  stream, err := gumblestream.New(config, gumblestream.FormatOpus)
+//  http.Handle("/listen.ogg", stream)
+//  client, err := gumble.Dial("example.com:64738", config)
+//
+// The audio of all of the users that the client hears is mixed into a single
+// stream. Silence is streamed while no one is speaking.
+package gumblestream
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+
+	"layeh.com/gumble/gumble"
+	"layeh.com/gumble/internal/mix"
+	"layeh.com/gumble/opus"
+)
+
+// Format is the encoding of a Stream.
+type Format int
+
+// Stream formats.
+const (
+	// Ogg encapsulated Opus (audio/ogg), encoded natively.
+	FormatOpus Format = iota
+	// MP3 (audio/mpeg), encoded by ffmpeg.
+	FormatMP3
+)
+
+// ContentType returns the MIME type of the format.
+func (f Format) ContentType() string {
+	if f == FormatMP3 {
+		return "audio/mpeg"
+	}
+	return "audio/ogg"
+}
+
+// Defaults of Stream.
+const (
+	// The length of each encoded frame.
+	DefaultFrameDuration = 20 * time.Millisecond
+	// The MP3 bitrate, in bits per second.
+	DefaultMP3Bitrate = 128000
+	// The number of encoded chunks that are buffered for each listener. A
+	// listener that falls further behind is disconnected.
+	DefaultListenerBufferSize = 256
+)
+
+// ErrClosed is returned when a Stream is used after it has been closed.
+var ErrClosed = errors.New("gumblestream: stream is closed")
+
+const frameSize = gumble.AudioSampleRate * int(DefaultFrameDuration/time.Millisecond) / 1000
+
+// Stream encodes the audio received by the clients that use a gumble.Config,
+// and sends it to its listeners. It implements http.Handler.
+type Stream struct {
+	// The stream's name, sent to Icecast and HTTP listeners.
+	Name string
+
+	format   Format
+	mixer    mix.Mixer
+	detacher gumble.Detacher
+
+	// FormatOpus
+	encoder gumble.AudioEncoder
+	// FormatMP3
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+
+	mu        sync.Mutex
+	listeners map[*listener]struct{}
+	closed    bool
+	stop      chan struct{}
+	wg        sync.WaitGroup
+}
+
+type listener struct {
+	chunks chan []byte
+	// Closed when the listener is removed.
+	done chan struct{}
+}
+
+// New returns a new Stream of the audio received by the clients that use
+// config, and starts encoding.
+//
+// For FormatMP3, ffmpeg must be installed; it is started with the default
+// bitrate. NewMP3 can be used to pick the command and bitrate.
+func New(config *gumble.Config, format Format) (*Stream, error) {
+	if format == FormatMP3 {
+		return NewMP3(config, "ffmpeg", DefaultMP3Bitrate)
+	}
+	s := newStream(config, format)
+	s.encoder = opus.Codec.NewEncoder()
+	s.start(s.encodeOpus)
+	return s, nil
+}
+
+// NewMP3 returns a new FormatMP3 Stream that is encoded by the given ffmpeg
+// command at the given bitrate (in bits per second).
+func NewMP3(config *gumble.Config, command string, bitrate int) (*Stream, error) {
+	s := newStream(config, FormatMP3)
+	s.cmd = exec.Command(command,
+		"-f", "s16le", "-ar", strconv.Itoa(gumble.AudioSampleRate), "-ac", strconv.Itoa(gumble.AudioChannels), "-i", "-",
+		"-f", "mp3", "-b:a", strconv.Itoa(bitrate), "-",
+	)
+	stdin, err := s.cmd.StdinPipe()
+	if err != nil {
+		s.detacher.Detach()
+		return nil, err
+	}
+	stdout, err := s.cmd.StdoutPipe()
+	if err != nil {
+		s.detacher.Detach()
+		return nil, err
+	}
+	if err := s.cmd.Start(); err != nil {
+		s.detacher.Detach()
+		return nil, err
+	}
+	s.stdin = stdin
+	s.wg.Add(1)
+	go s.readMP3(stdout)
+	s.start(s.encodeMP3)
+	return s, nil
+}
+
+func newStream(config *gumble.Config, format Format) *Stream {
+	s := &Stream{
+		format:    format,
+		listeners: make(map[*listener]struct{}),
+		stop:      make(chan struct{}),
+	}
+	s.detacher = config.AttachAudio(s)
+	return s
+}
+
+// Format returns the stream's format.
+func (s *Stream) Format() Format {
+	return s.format
+}
+
+// OnAudioStream implements gumble.AudioListener.
+func (s *Stream) OnAudioStream(e *gumble.AudioStreamEvent) {
+	go func() {
+		for packet := range e.C {
+			s.mixer.Write(e.User, packet.AudioBuffer)
+			packet.Release()
+		}
+	}()
+}
+
+// start starts the routine that mixes a frame every DefaultFrameDuration and
+// passes it to encode.
+func (s *Stream) start(encode func(pcm []int16)) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(DefaultFrameDuration)
+		defer ticker.Stop()
+		frame := make([]int16, frameSize)
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+			}
+			s.mixer.Read(frame)
+			encode(frame)
+		}
+	}()
+}
+
+func (s *Stream) encodeOpus(pcm []int16) {
+	if s.Listeners() == 0 {
+		return
+	}
+	data, err := s.encoder.Encode(pcm, len(pcm), gumble.AudioMaximumFrameSize)
+	if err != nil {
+		return
+	}
+	s.broadcast(data)
+}
+
+func (s *Stream) encodeMP3(pcm []int16) {
+	buf := make([]byte, len(pcm)*2)
+	for i, v := range pcm {
+		buf[i*2] = byte(v)
+		buf[i*2+1] = byte(v >> 8)
+	}
+	s.stdin.Write(buf)
+}
+
+func (s *Stream) readMP3(r io.Reader) {
+	defer s.wg.Done()
+	for {
+		buf := make([]byte, 4096)
+		n, err := r.Read(buf)
+		if n > 0 {
+			s.broadcast(buf[:n])
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// broadcast sends the chunk to all listeners. Listeners whose buffer is full
+// are removed.
+func (s *Stream) broadcast(chunk []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for l := range s.listeners {
+		select {
+		case l.chunks <- chunk:
+		default:
+			delete(s.listeners, l)
+			close(l.done)
+		}
+	}
+}
+
+// Listeners returns the number of connected listeners, including Icecast
+// servers.
+func (s *Stream) Listeners() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.listeners)
+}
+
+func (s *Stream) addListener() (*listener, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, ErrClosed
+	}
+	l := &listener{
+		chunks: make(chan []byte, DefaultListenerBufferSize),
+		done:   make(chan struct{}),
+	}
+	s.listeners[l] = struct{}{}
+	return l, nil
+}
+
+func (s *Stream) removeListener(l *listener) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.listeners[l]; ok {
+		delete(s.listeners, l)
+		close(l.done)
+	}
+}
+
+// copyTo writes the stream to w until stop is closed, the listener falls
+// behind, the stream is closed, or a write fails. flush, if non-nil, is
+// called after each write.
+func (s *Stream) copyTo(w io.Writer, flush func(), stop <-chan struct{}) error {
+	l, err := s.addListener()
+	if err != nil {
+		return err
+	}
+	defer s.removeListener(l)
+
+	var ogg *oggWriter
+	if s.format == FormatOpus {
+		ogg = &oggWriter{
+			serial: rand.Uint32(),
+		}
+		if _, err := w.Write(ogg.headers()); err != nil {
+			return err
+		}
+	}
+	for {
+		var chunk []byte
+		select {
+		case <-stop:
+			return nil
+		case <-l.done:
+			return nil
+		case chunk = <-l.chunks:
+		}
+		if ogg != nil {
+			chunk = ogg.packet(chunk, frameSize)
+		}
+		if _, err := w.Write(chunk); err != nil {
+			return err
+		}
+		if flush != nil {
+			flush()
+		}
+	}
+}
+
+// Close stops encoding, detaches the stream from the config, and disconnects
+// all listeners.
+func (s *Stream) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrClosed
+	}
+	s.closed = true
+	for l := range s.listeners {
+		delete(s.listeners, l)
+		close(l.done)
+	}
+	s.mu.Unlock()
+
+	s.detacher.Detach()
+	close(s.stop)
+	if s.cmd != nil {
+		s.stdin.Close()
+		s.cmd.Wait()
+	}
+	s.wg.Wait()
+	return nil
+}
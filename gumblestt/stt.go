@@ -0,0 +1,208 @@
+// Package gumblestt transcribes what users say using a pluggable speech
+// recognizer.
+//
+// A Transcriber is attached to a client as an audio listener. Each user's
+// audio is split into utterances at pauses in speech, and every utterance is
+// passed to the Recognizer (e.g. a Whisper or Vosk binding, or a cloud
+// speech-to-text API). The resulting text is delivered to the transcript
+// handler along with the user who spoke it:
+//
+//  t := gumblestt.New(recognizer, func(e *gumblestt.TranscriptEvent) {
+//      fmt.Printf("%s: %s\n", e.User.Name, e.Text)
+//  })
+//  config.AttachAudio(t)
+package gumblestt
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"layeh.com/gumble/gumble"
+)
+
+// Recognizer converts speech to text.
+type Recognizer interface {
+	// Recognize returns the text spoken in the given mono PCM samples, which
+	// are sampled at gumble.AudioSampleRate.
+	Recognize(ctx context.Context, pcm []int16) (string, error)
+}
+
+// RecognizerFunc is a function that implements Recognizer.
+type RecognizerFunc func(ctx context.Context, pcm []int16) (string, error)
+
+// Recognize implements Recognizer.
+func (f RecognizerFunc) Recognize(ctx context.Context, pcm []int16) (string, error) {
+	return f(ctx, pcm)
+}
+
+// TranscriptEvent is the event that is passed to the transcript handler.
+type TranscriptEvent struct {
+	Client *gumble.Client
+	// The user who spoke.
+	User *gumble.User
+	// The recognized text. Empty if Err is non-nil.
+	Text string
+	// The error returned by the recognizer, if any.
+	Err error
+
+	// When the utterance started and ended.
+	Start, End time.Time
+	// The audio that was recognized.
+	PCM []int16
+}
+
+// Default segmentation parameters.
+const (
+	DefaultSilenceThreshold = 500
+	DefaultSilenceDuration  = 700 * time.Millisecond
+	DefaultMinDuration      = 300 * time.Millisecond
+	DefaultMaxDuration      = 30 * time.Second
+)
+
+// Transcriber is a gumble.AudioListener that segments incoming audio into
+// utterances and transcribes them.
+type Transcriber struct {
+	// Recognizer used to transcribe utterances.
+	Recognizer Recognizer
+	// Called with each transcript. Transcripts for a single user are delivered
+	// in order; transcripts for different users may be delivered concurrently.
+	OnTranscript func(e *TranscriptEvent)
+
+	// Frames with a root mean square amplitude below the threshold are
+	// considered silent.
+	SilenceThreshold float64
+	// How long silence (or the absence of audio packets) must last to end an
+	// utterance.
+	SilenceDuration time.Duration
+	// Utterances shorter than this are discarded.
+	MinDuration time.Duration
+	// Utterances are split when they reach this length.
+	MaxDuration time.Duration
+	// Context passed to the recognizer.
+	Context context.Context
+}
+
+var _ gumble.AudioListener = (*Transcriber)(nil)
+
+// New returns a new Transcriber with default segmentation parameters.
+func New(recognizer Recognizer, onTranscript func(e *TranscriptEvent)) *Transcriber {
+	return &Transcriber{
+		Recognizer:       recognizer,
+		OnTranscript:     onTranscript,
+		SilenceThreshold: DefaultSilenceThreshold,
+		SilenceDuration:  DefaultSilenceDuration,
+		MinDuration:      DefaultMinDuration,
+		MaxDuration:      DefaultMaxDuration,
+		Context:          context.Background(),
+	}
+}
+
+type segment struct {
+	pcm        []int16
+	start, end time.Time
+}
+
+// OnAudioStream implements gumble.AudioListener.
+func (t *Transcriber) OnAudioStream(e *gumble.AudioStreamEvent) {
+	segments := make(chan *segment, 8)
+	go t.recognize(e, segments)
+	go t.segment(e, segments)
+}
+
+func (t *Transcriber) segment(e *gumble.AudioStreamEvent, segments chan<- *segment) {
+	defer close(segments)
+
+	timer := time.NewTimer(t.SilenceDuration)
+	timer.Stop()
+	defer timer.Stop()
+
+	var (
+		current *segment
+		silence time.Duration
+	)
+	flush := func() {
+		if current == nil {
+			return
+		}
+		if speech := samplesDuration(len(current.pcm)) - silence; speech >= t.MinDuration {
+			segments <- current
+		}
+		current = nil
+		silence = 0
+	}
+
+	for {
+		select {
+		case packet, ok := <-e.C:
+			if !ok {
+				flush()
+				return
+			}
+			now := time.Now()
+			duration := samplesDuration(len(packet.AudioBuffer))
+			speaking := rms(packet.AudioBuffer) >= t.SilenceThreshold
+			if current == nil {
+				if !speaking {
+					continue
+				}
+				current = &segment{
+					start: now.Add(-duration),
+				}
+			}
+			current.pcm = append(current.pcm, packet.AudioBuffer...)
+			current.end = now
+			if speaking {
+				silence = 0
+			} else {
+				silence += duration
+			}
+			if silence >= t.SilenceDuration || (t.MaxDuration > 0 && samplesDuration(len(current.pcm)) >= t.MaxDuration) {
+				flush()
+				continue
+			}
+			// Clients stop sending packets when they stop talking, so a gap
+			// in packets also ends the utterance.
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			timer.Reset(t.SilenceDuration)
+		case <-timer.C:
+			flush()
+		}
+	}
+}
+
+func (t *Transcriber) recognize(e *gumble.AudioStreamEvent, segments <-chan *segment) {
+	for s := range segments {
+		event := TranscriptEvent{
+			Client: e.Client,
+			User:   e.User,
+			Start:  s.start,
+			End:    s.end,
+			PCM:    s.pcm,
+		}
+		event.Text, event.Err = t.Recognizer.Recognize(t.Context, s.pcm)
+		if t.OnTranscript != nil {
+			t.OnTranscript(&event)
+		}
+	}
+}
+
+func samplesDuration(n int) time.Duration {
+	return time.Duration(n) * time.Second / gumble.AudioSampleRate
+}
+
+func rms(pcm []int16) float64 {
+	if len(pcm) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, sample := range pcm {
+		sum += float64(sample) * float64(sample)
+	}
+	return math.Sqrt(sum / float64(len(pcm)))
+}
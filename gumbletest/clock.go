@@ -0,0 +1,96 @@
+package gumbletest
+
+import (
+	"sync"
+	"time"
+
+	"layeh.com/gumble/gumble"
+)
+
+// Clock is a gumble.Clock whose time only changes when it is advanced, so
+// that tests do not have to sleep:
+//
+//  clock := gumbletest.NewClock(time.Unix(0, 0))
+//  config.Clock = clock
+//  // ...
+//  clock.Advance(5 * time.Second) // the client sends a ping
+type Clock struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers map[*ticker]struct{}
+}
+
+// NewClock returns a new Clock whose current time is now.
+func NewClock(now time.Time) *Clock {
+	return &Clock{
+		now:     now,
+		tickers: make(map[*ticker]struct{}),
+	}
+}
+
+// Now implements gumble.Clock.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// NewTicker implements gumble.Clock.
+func (c *Clock) NewTicker(d time.Duration) gumble.Ticker {
+	if d <= 0 {
+		panic("gumbletest: non-positive interval for NewTicker")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &ticker{
+		clock:  c,
+		period: d,
+		next:   c.now.Add(d),
+		c:      make(chan time.Time, 1),
+	}
+	c.tickers[t] = struct{}{}
+	return t
+}
+
+// Advance moves the clock forward by d, and fires the tickers that are due.
+// Like a time.Ticker, a ticker drops ticks if its receiver falls behind.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	for t := range c.tickers {
+		for !t.next.After(c.now) {
+			select {
+			case t.c <- t.next:
+			default:
+			}
+			t.next = t.next.Add(t.period)
+		}
+	}
+}
+
+// Tickers returns the number of tickers that have not been stopped. It can
+// be used to wait for a goroutine to create its ticker before advancing the
+// clock.
+func (c *Clock) Tickers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.tickers)
+}
+
+type ticker struct {
+	clock  *Clock
+	period time.Duration
+	next   time.Time
+	c      chan time.Time
+}
+
+func (t *ticker) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *ticker) Stop() {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	delete(t.clock.tickers, t)
+}
@@ -0,0 +1,185 @@
+package gumbletest
+
+import (
+	"crypto/tls"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"layeh.com/gumble/gumble"
+)
+
+// ErrReset is returned by the reads and writes of a FaultConn that has been
+// reset.
+var ErrReset = errors.New("gumbletest: connection reset")
+
+// Faults are the faults injected by a FaultConn.
+type Faults struct {
+	// The delay added to each write.
+	Latency time.Duration
+	// The probability, between 0 and 1, that a write is discarded, while
+	// reporting that it succeeded.
+	DropRate float64
+	// The probability, between 0 and 1, that only part of a write is sent,
+	// after which the write returns io.ErrShortWrite.
+	PartialWriteRate float64
+	// If positive, the connection is reset after this many bytes have been
+	// read and written.
+	ResetAfter int64
+	// The seed of the random faults. Connections with the same seed and the
+	// same traffic inject the same faults.
+	Seed int64
+}
+
+// FaultConn is a net.Conn that injects faults into the connection that it
+// wraps. It is usually placed below TLS (see gumble.DialWithConn), so that
+// the faults look like those of a bad network to the client.
+type FaultConn struct {
+	net.Conn
+
+	mu     sync.Mutex
+	faults Faults
+	rand   *rand.Rand
+	bytes  int64
+	reset  bool
+}
+
+// NewFaultConn returns a new FaultConn that injects faults into conn.
+func NewFaultConn(conn net.Conn, faults Faults) *FaultConn {
+	return &FaultConn{
+		Conn:   conn,
+		faults: faults,
+		rand:   rand.New(rand.NewSource(faults.Seed)),
+	}
+}
+
+// DialFaulty connects a client to the server at addr (e.g. Server.Addr) over
+// a FaultConn, using config. The server's certificate is not verified.
+func DialFaulty(addr string, config *gumble.Config, faults Faults) (*gumble.Client, *FaultConn, error) {
+	if config.Username == "" {
+		config.Username = "gumble"
+	}
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		return nil, nil, err
+	}
+	fc := NewFaultConn(conn, faults)
+	// DialWithConn does not time out by itself; the faults can cause the
+	// handshake to stall.
+	timer := time.AfterFunc(5*time.Second, func() {
+		fc.Close()
+	})
+	client, err := gumble.DialWithConn(fc, config, &tls.Config{
+		InsecureSkipVerify: true,
+	})
+	timer.Stop()
+	if err != nil {
+		return nil, nil, err
+	}
+	return client, fc, nil
+}
+
+// SetFaults changes the faults that are injected.
+func (c *FaultConn) SetFaults(faults Faults) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.faults = faults
+}
+
+// Reset resets the connection: TCP connections are closed without lingering,
+// so that the peer receives a RST.
+func (c *FaultConn) Reset() error {
+	c.mu.Lock()
+	c.reset = true
+	c.mu.Unlock()
+	if tcp, ok := c.Conn.(*net.TCPConn); ok {
+		tcp.SetLinger(0)
+	}
+	return c.Conn.Close()
+}
+
+// take counts n bytes of traffic. It returns the number of bytes that can be
+// passed through before the connection is reset, and whether it has to be
+// reset.
+func (c *FaultConn) take(n int) (int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.faults.ResetAfter > 0 {
+		if remaining := c.faults.ResetAfter - c.bytes; int64(n) >= remaining {
+			c.bytes += remaining
+			return int(remaining), true
+		}
+	}
+	c.bytes += int64(n)
+	return n, false
+}
+
+// Read implements net.Conn.
+func (c *FaultConn) Read(b []byte) (int, error) {
+	c.mu.Lock()
+	reset := c.reset
+	c.mu.Unlock()
+	if reset {
+		return 0, ErrReset
+	}
+
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		var reset bool
+		if n, reset = c.take(n); reset {
+			c.Reset()
+			if n == 0 {
+				return 0, ErrReset
+			}
+			return n, nil
+		}
+	}
+	return n, err
+}
+
+// Write implements net.Conn.
+func (c *FaultConn) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	if c.reset {
+		c.mu.Unlock()
+		return 0, ErrReset
+	}
+	faults := c.faults
+	drop := faults.DropRate > 0 && c.rand.Float64() < faults.DropRate
+	partial := -1
+	if !drop && len(b) > 0 && faults.PartialWriteRate > 0 && c.rand.Float64() < faults.PartialWriteRate {
+		partial = c.rand.Intn(len(b))
+	}
+	c.mu.Unlock()
+
+	if faults.Latency > 0 {
+		time.Sleep(faults.Latency)
+	}
+
+	if partial >= 0 {
+		b = b[:partial]
+	}
+	allowed, reset := c.take(len(b))
+	var n int
+	var err error
+	if drop {
+		n = allowed
+	} else {
+		n, err = c.Conn.Write(b[:allowed])
+	}
+	switch {
+	case reset:
+		c.Reset()
+		return n, ErrReset
+	case err != nil:
+		return n, err
+	case drop:
+		return len(b), nil
+	case partial >= 0:
+		return n, io.ErrShortWrite
+	}
+	return n, nil
+}
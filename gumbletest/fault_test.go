@@ -0,0 +1,37 @@
+package gumbletest_test
+
+import (
+	"testing"
+	"time"
+
+	"layeh.com/gumble/gumble"
+	"layeh.com/gumble/gumbletest"
+)
+
+func TestFaultConnReset(t *testing.T) {
+	server := gumbletest.NewServer()
+	defer server.Close()
+
+	config := gumble.NewConfig()
+	watcher := gumbletest.Watch(config)
+	defer watcher.Detach()
+	client, conn, err := gumbletest.DialFaulty(server.Addr(), config, gumbletest.Faults{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Disconnect()
+
+	if err := conn.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	e, err := watcher.WaitDisconnect(5 * time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Type != gumble.DisconnectError {
+		t.Errorf("DisconnectEvent.Type = %v; want DisconnectError", e.Type)
+	}
+	if _, err := conn.Write([]byte{0}); err != gumbletest.ErrReset {
+		t.Errorf("Write after Reset = %v; want ErrReset", err)
+	}
+}
@@ -0,0 +1,294 @@
+package gumbletest
+
+import (
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"layeh.com/gumble/gumble"
+	"layeh.com/gumble/gumble/MumbleProto"
+	"layeh.com/gumble/gumble/varint"
+)
+
+// The functions in this file must be called with s.mu held.
+
+func (c *channel) state() *MumbleProto.ChannelState {
+	packet := &MumbleProto.ChannelState{
+		ChannelId: proto.Uint32(c.id),
+		Name:      proto.String(c.name),
+	}
+	if c.id != 0 {
+		packet.Parent = proto.Uint32(c.parent)
+	}
+	if c.temporary {
+		packet.Temporary = proto.Bool(true)
+	}
+	return packet
+}
+
+func (u *user) state() *MumbleProto.UserState {
+	return &MumbleProto.UserState{
+		Session:   proto.Uint32(u.session),
+		Name:      proto.String(u.name),
+		ChannelId: proto.Uint32(u.channel),
+		Mute:      proto.Bool(u.muted),
+		Deaf:      proto.Bool(u.deafened),
+		SelfMute:  proto.Bool(u.selfMuted),
+		SelfDeaf:  proto.Bool(u.selfDeafened),
+		Comment:   proto.String(u.comment),
+	}
+}
+
+// broadcast sends the packet to every connected user.
+func (s *Server) broadcast(packet proto.Message) {
+	for _, u := range s.users {
+		u.conn.WriteProto(packet)
+	}
+}
+
+func (s *Server) addChannel(parent uint32, name string, temporary bool) *channel {
+	if s.channels[parent] == nil {
+		return nil
+	}
+	c := &channel{
+		id:        s.nextChannel,
+		parent:    parent,
+		name:      name,
+		temporary: temporary,
+	}
+	s.nextChannel++
+	s.channels[c.id] = c
+	s.broadcast(c.state())
+	return c
+}
+
+// removeChannel removes the channel and its sub-channels. Users in the
+// removed channels are moved to the root channel.
+func (s *Server) removeChannel(c *channel) {
+	for _, child := range s.channels {
+		if child.id != 0 && child.parent == c.id {
+			s.removeChannel(child)
+		}
+	}
+	for _, u := range s.users {
+		if u.channel == c.id {
+			u.channel = 0
+			s.broadcast(&MumbleProto.UserState{
+				Session:   proto.Uint32(u.session),
+				ChannelId: proto.Uint32(0),
+			})
+		}
+	}
+	delete(s.channels, c.id)
+	s.broadcast(&MumbleProto.ChannelRemove{
+		ChannelId: proto.Uint32(c.id),
+	})
+}
+
+// removeUser disconnects the user, and tells the other users. actor is nil
+// if the user was not kicked by another user.
+func (s *Server) removeUser(u *user, actor *user, reason string, ban bool) {
+	packet := MumbleProto.UserRemove{
+		Session: proto.Uint32(u.session),
+	}
+	if actor != nil {
+		packet.Actor = proto.Uint32(actor.session)
+	}
+	if reason != "" {
+		packet.Reason = proto.String(reason)
+	}
+	if ban {
+		packet.Ban = proto.Bool(true)
+		s.bans[u.name] = true
+	}
+	s.broadcast(&packet)
+	delete(s.users, u.session)
+	u.conn.Close()
+}
+
+// handle handles a packet from the user.
+func (s *Server) handle(u *user, pType uint16, data []byte) {
+	switch pType {
+	case gumble.PacketUDPTunnel:
+		s.handleUDPTunnel(u, data)
+	case gumble.PacketPing:
+		var packet MumbleProto.Ping
+		if proto.Unmarshal(data, &packet) == nil {
+			u.conn.WriteProto(&MumbleProto.Ping{
+				Timestamp: packet.Timestamp,
+			})
+		}
+	case gumble.PacketChannelState:
+		var packet MumbleProto.ChannelState
+		if proto.Unmarshal(data, &packet) == nil {
+			s.handleChannelState(u, &packet)
+		}
+	case gumble.PacketChannelRemove:
+		var packet MumbleProto.ChannelRemove
+		if proto.Unmarshal(data, &packet) == nil {
+			if c := s.channels[packet.GetChannelId()]; c != nil && c.id != 0 {
+				s.removeChannel(c)
+			}
+		}
+	case gumble.PacketUserState:
+		var packet MumbleProto.UserState
+		if proto.Unmarshal(data, &packet) == nil {
+			s.handleUserState(u, &packet)
+		}
+	case gumble.PacketUserRemove:
+		var packet MumbleProto.UserRemove
+		if proto.Unmarshal(data, &packet) == nil {
+			if target := s.users[packet.GetSession()]; target != nil {
+				s.removeUser(target, u, packet.GetReason(), packet.GetBan())
+			}
+		}
+	case gumble.PacketTextMessage:
+		var packet MumbleProto.TextMessage
+		if proto.Unmarshal(data, &packet) == nil {
+			s.handleTextMessage(u, &packet)
+		}
+	case gumble.PacketPermissionQuery:
+		var packet MumbleProto.PermissionQuery
+		if proto.Unmarshal(data, &packet) == nil {
+			u.conn.WriteProto(&MumbleProto.PermissionQuery{
+				ChannelId:   packet.ChannelId,
+				Permissions: proto.Uint32(^uint32(0)),
+			})
+		}
+	case gumble.PacketUserStats:
+		var packet MumbleProto.UserStats
+		if proto.Unmarshal(data, &packet) == nil {
+			if target := s.users[packet.GetSession()]; target != nil {
+				u.conn.WriteProto(&MumbleProto.UserStats{
+					Session:    proto.Uint32(target.session),
+					Onlinesecs: proto.Uint32(uint32(time.Since(target.connected) / time.Second)),
+					Idlesecs:   proto.Uint32(0),
+					Opus:       proto.Bool(true),
+				})
+			}
+		}
+	}
+}
+
+func (s *Server) handleChannelState(u *user, packet *MumbleProto.ChannelState) {
+	if packet.ChannelId == nil {
+		s.addChannel(packet.GetParent(), packet.GetName(), packet.GetTemporary())
+		return
+	}
+	c := s.channels[packet.GetChannelId()]
+	if c == nil {
+		return
+	}
+	if packet.Name != nil {
+		c.name = packet.GetName()
+	}
+	if packet.Parent != nil && c.id != 0 && s.channels[packet.GetParent()] != nil {
+		c.parent = packet.GetParent()
+	}
+	s.broadcast(c.state())
+}
+
+func (s *Server) handleUserState(u *user, packet *MumbleProto.UserState) {
+	target := u
+	if packet.Session != nil {
+		target = s.users[packet.GetSession()]
+		if target == nil {
+			return
+		}
+	}
+	out := MumbleProto.UserState{
+		Session: proto.Uint32(target.session),
+	}
+	if target != u {
+		out.Actor = proto.Uint32(u.session)
+	}
+	if packet.ChannelId != nil && s.channels[packet.GetChannelId()] != nil {
+		target.channel = packet.GetChannelId()
+		out.ChannelId = packet.ChannelId
+	}
+	if packet.Mute != nil {
+		target.muted = packet.GetMute()
+		out.Mute = packet.Mute
+	}
+	if packet.Deaf != nil {
+		target.deafened = packet.GetDeaf()
+		out.Deaf = packet.Deaf
+	}
+	if packet.SelfMute != nil && target == u {
+		target.selfMuted = packet.GetSelfMute()
+		out.SelfMute = packet.SelfMute
+	}
+	if packet.SelfDeaf != nil && target == u {
+		target.selfDeafened = packet.GetSelfDeaf()
+		out.SelfDeaf = packet.SelfDeaf
+	}
+	if packet.Comment != nil {
+		target.comment = packet.GetComment()
+		out.Comment = packet.Comment
+	}
+	s.broadcast(&out)
+}
+
+func (s *Server) handleTextMessage(u *user, packet *MumbleProto.TextMessage) {
+	s.messages = append(s.messages, Message{
+		Sender:   u.name,
+		Users:    packet.Session,
+		Channels: packet.ChannelId,
+		Trees:    packet.TreeId,
+		Message:  packet.GetMessage(),
+	})
+
+	recipients := make(map[*user]bool)
+	for _, session := range packet.Session {
+		if target := s.users[session]; target != nil {
+			recipients[target] = true
+		}
+	}
+	channels := make(map[uint32]bool)
+	for _, id := range packet.ChannelId {
+		channels[id] = true
+	}
+	for _, id := range packet.TreeId {
+		s.addTree(channels, id)
+	}
+	for _, target := range s.users {
+		if channels[target.channel] {
+			recipients[target] = true
+		}
+	}
+	delete(recipients, u)
+
+	packet.Actor = proto.Uint32(u.session)
+	for target := range recipients {
+		target.conn.WriteProto(packet)
+	}
+}
+
+// addTree adds the channel and its sub-channels to ids.
+func (s *Server) addTree(ids map[uint32]bool, id uint32) {
+	if s.channels[id] == nil || ids[id] {
+		return
+	}
+	ids[id] = true
+	for _, c := range s.channels {
+		if c.id != 0 && c.parent == id {
+			s.addTree(ids, c.id)
+		}
+	}
+}
+
+// handleUDPTunnel forwards the user's audio to the other users in the same
+// channel, adding the user's session to the packet.
+func (s *Server) handleUDPTunnel(u *user, data []byte) {
+	if len(data) < 1 {
+		return
+	}
+	packet := make([]byte, 0, len(data)+varint.Size(int64(u.session)))
+	packet = append(packet, data[0])
+	packet = varint.Append(packet, int64(u.session))
+	packet = append(packet, data[1:]...)
+	for _, target := range s.users {
+		if target != u && target.channel == u.channel && !target.deafened && !target.selfDeafened {
+			target.conn.WritePacket(gumble.PacketUDPTunnel, packet)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+// Package murmur runs a real Mumble server (Murmur) for integration tests of
+// gumble and of the bots that are built on it.
+//
+//  func TestBot(t *testing.T) {
+//    server := murmur.Require(t)
+//    client := server.Connect(t, "bot")
+//    // ...
+//  }
+//
+// The server is started in Docker using Image. If the GUMBLE_MURMUR_ADDR
+// environment variable is set, the server at that address is used instead,
+// with the SuperUser password in GUMBLE_MURMUR_SUPERUSER_PASSWORD.
+package murmur
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"layeh.com/gumble/gumble"
+)
+
+// Image is the Docker image of the Mumble server.
+var Image = "mumblevoip/mumble-server:latest"
+
+// Environment variables that select an existing server.
+const (
+	EnvAddr              = "GUMBLE_MURMUR_ADDR"
+	EnvSuperUserPassword = "GUMBLE_MURMUR_SUPERUSER_PASSWORD"
+)
+
+// StartTimeout is how long Start waits for the server to accept connections.
+var StartTimeout = 60 * time.Second
+
+// superUserPassword is the SuperUser password of the servers that are started
+// in Docker.
+const superUserPassword = "gumbletest"
+
+// Server is a Mumble server used by tests.
+type Server struct {
+	// The address of the server.
+	Addr string
+	// The password of the server's SuperUser account, which has every
+	// permission.
+	SuperUserPassword string
+
+	container string
+}
+
+// ErrNoServer is returned by Start when there is no server address set, and
+// Docker is not available.
+var ErrNoServer = errors.New("murmur: " + EnvAddr + " is not set and docker is not available")
+
+// Start starts a new server in Docker, or returns the server set in the
+// environment.
+func Start() (*Server, error) {
+	if addr := os.Getenv(EnvAddr); addr != "" {
+		return &Server{
+			Addr:              addr,
+			SuperUserPassword: os.Getenv(EnvSuperUserPassword),
+		}, nil
+	}
+	if _, err := exec.LookPath("docker"); err != nil {
+		return nil, ErrNoServer
+	}
+
+	out, err := docker("run", "--detach", "--rm",
+		"--publish", "127.0.0.1::64738/tcp",
+		"--env", "MUMBLE_SUPERUSER_PASSWORD="+superUserPassword,
+		Image)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		SuperUserPassword: superUserPassword,
+		container:         out,
+	}
+	out, err = docker("port", s.container, "64738/tcp")
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+	// The first line is the IPv4 binding, e.g. "127.0.0.1:49153".
+	s.Addr = strings.SplitN(out, "\n", 2)[0]
+	if err := s.wait(StartTimeout); err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Require returns a server for the test, skipping the test if one cannot be
+// started. A server that is started is closed when the test completes.
+func Require(tb testing.TB) *Server {
+	tb.Helper()
+	s, err := Start()
+	if err == ErrNoServer {
+		tb.Skip(err)
+	}
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() {
+		s.Close()
+	})
+	return s
+}
+
+// wait waits for the server to complete a TLS handshake.
+func (s *Server) wait(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		dialer := &net.Dialer{
+			Timeout: time.Second,
+		}
+		conn, err := tls.DialWithDialer(dialer, "tcp", s.Addr, &tls.Config{
+			InsecureSkipVerify: true,
+		})
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("murmur: server did not start: %v", err)
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+}
+
+// Dial connects a client to the server, using config. The server's
+// certificate is not verified.
+func (s *Server) Dial(config *gumble.Config) (*gumble.Client, error) {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+	}
+	return gumble.DialWithDialer(dialer, s.Addr, config, &tls.Config{
+		InsecureSkipVerify: true,
+	})
+}
+
+// Connect connects a client with the given username to the server, failing
+// the test if it cannot. The client is disconnected when the test completes.
+func (s *Server) Connect(tb testing.TB, username string) *gumble.Client {
+	tb.Helper()
+	config := gumble.NewConfig()
+	config.Username = username
+	return s.ConnectWithConfig(tb, config)
+}
+
+// ConnectSuperUser connects a client as the server's SuperUser.
+func (s *Server) ConnectSuperUser(tb testing.TB) *gumble.Client {
+	tb.Helper()
+	if s.SuperUserPassword == "" {
+		tb.Skip("murmur: " + EnvSuperUserPassword + " is not set")
+	}
+	config := gumble.NewConfig()
+	config.Username = "SuperUser"
+	config.Password = s.SuperUserPassword
+	return s.ConnectWithConfig(tb, config)
+}
+
+// ConnectWithConfig connects a client to the server using config, failing the
+// test if it cannot. The client is disconnected when the test completes.
+func (s *Server) ConnectWithConfig(tb testing.TB, config *gumble.Config) *gumble.Client {
+	tb.Helper()
+	client, err := s.Dial(config)
+	if err != nil {
+		tb.Fatalf("murmur: connecting as %q: %v", config.Username, err)
+	}
+	tb.Cleanup(func() {
+		client.Disconnect()
+	})
+	return client
+}
+
+// Close stops the server, if it was started by Start.
+func (s *Server) Close() error {
+	if s.container == "" {
+		return nil
+	}
+	_, err := docker("rm", "--force", s.container)
+	s.container = ""
+	return err
+}
+
+// Eventually waits until cond, called from inside client.Do, returns true,
+// failing the test if it does not within timeout. It is used to wait for the
+// client's state to reflect a change made on the server. See
+// gumble.Client.WaitFor.
+func Eventually(tb testing.TB, client *gumble.Client, timeout time.Duration, cond func() bool) {
+	tb.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := client.WaitFor(ctx, cond); err != nil {
+		tb.Fatal("murmur: condition not met within ", timeout, ": ", err)
+	}
+}
+
+func docker(args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("docker", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("murmur: docker %s: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
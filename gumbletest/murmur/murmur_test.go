@@ -0,0 +1,200 @@
+//go:build integration
+// +build integration
+
+package murmur
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"layeh.com/gumble/gumble"
+	"layeh.com/gumble/gumbleutil"
+	_ "layeh.com/gumble/opus"
+)
+
+// The scenarios are run with:
+//
+//  go test -tags integration ./gumbletest/murmur/
+
+const timeout = 10 * time.Second
+
+func TestConnect(t *testing.T) {
+	server := Require(t)
+	client := server.Connect(t, "gumble-connect")
+	Eventually(t, client, timeout, func() bool {
+		return client.Self != nil && client.Channels[0] != nil
+	})
+	if state := client.State(); state != gumble.StateSynced {
+		t.Fatalf("state = %v, expected %v", state, gumble.StateSynced)
+	}
+}
+
+func TestMove(t *testing.T) {
+	server := Require(t)
+	admin := server.ConnectSuperUser(t)
+	client := server.Connect(t, "gumble-move")
+
+	admin.Do(func() {
+		admin.Channels[0].Add("gumble-move", true)
+	})
+	var channel *gumble.Channel
+	Eventually(t, client, timeout, func() bool {
+		channel = client.Channels[0].Find("gumble-move")
+		return channel != nil
+	})
+	client.Do(func() {
+		client.Self.Move(channel)
+	})
+	Eventually(t, client, timeout, func() bool {
+		return client.Self.Channel == channel
+	})
+}
+
+func TestMessage(t *testing.T) {
+	server := Require(t)
+
+	received := make(chan string, 1)
+	config := gumble.NewConfig()
+	config.Username = "gumble-receiver"
+	config.Attach(gumbleutil.Listener{
+		TextMessage: func(e *gumble.TextMessageEvent) {
+			select {
+			case received <- e.Message:
+			default:
+			}
+		},
+	})
+	server.ConnectWithConfig(t, config)
+	sender := server.Connect(t, "gumble-sender")
+
+	sender.Do(func() {
+		sender.Self.Channel.Send("hello from gumble", false)
+	})
+	select {
+	case message := <-received:
+		if message != "hello from gumble" {
+			t.Fatalf("received %q", message)
+		}
+	case <-time.After(timeout):
+		t.Fatal("message not received")
+	}
+}
+
+type audioListener chan *gumble.AudioStreamEvent
+
+func (l audioListener) OnAudioStream(e *gumble.AudioStreamEvent) {
+	select {
+	case l <- e:
+	default:
+	}
+}
+
+func TestAudio(t *testing.T) {
+	server := Require(t)
+
+	streams := make(audioListener, 1)
+	config := gumble.NewConfig()
+	config.Username = "gumble-listener"
+	config.AttachAudio(streams)
+	server.ConnectWithConfig(t, config)
+	speaker := server.Connect(t, "gumble-speaker")
+
+	// One second of a 440 Hz tone.
+	outgoing := speaker.AudioOutgoing()
+	go func() {
+		defer close(outgoing)
+		var n int
+		for i := 0; i < 100; i++ {
+			frame := make(gumble.AudioBuffer, gumble.AudioDefaultFrameSize)
+			for j := range frame {
+				frame[j] = int16(8000 * math.Sin(2*math.Pi*440*float64(n)/gumble.AudioSampleRate))
+				n++
+			}
+			outgoing <- frame
+			time.Sleep(gumble.AudioDefaultInterval)
+		}
+	}()
+
+	select {
+	case e := <-streams:
+		if e.User.Name != "gumble-speaker" {
+			t.Fatalf("audio from %q", e.User.Name)
+		}
+		select {
+		case packet := <-e.C:
+			if len(packet.AudioBuffer) == 0 {
+				t.Fatal("empty audio packet")
+			}
+		case <-time.After(timeout):
+			t.Fatal("no audio packets")
+		}
+	case <-time.After(timeout):
+		t.Fatal("audio not received")
+	}
+}
+
+func TestACL(t *testing.T) {
+	server := Require(t)
+
+	acls := make(chan *gumble.ACL, 1)
+	config := gumble.NewConfig()
+	config.Username = "SuperUser"
+	config.Password = server.SuperUserPassword
+	config.Attach(gumbleutil.Listener{
+		ACL: func(e *gumble.ACLEvent) {
+			acls <- e.ACL
+		},
+	})
+	if config.Password == "" {
+		t.Skip("murmur: " + EnvSuperUserPassword + " is not set")
+	}
+	admin := server.ConnectWithConfig(t, config)
+
+	admin.Do(func() {
+		admin.Channels[0].Add("gumble-acl", false)
+	})
+	var channel *gumble.Channel
+	Eventually(t, admin, timeout, func() bool {
+		channel = admin.Channels[0].Find("gumble-acl")
+		return channel != nil
+	})
+	defer admin.Do(func() {
+		channel.Remove()
+	})
+
+	requestACL := func() *gumble.ACL {
+		admin.Do(func() {
+			channel.RequestACL()
+		})
+		select {
+		case acl := <-acls:
+			return acl
+		case <-time.After(timeout):
+			t.Fatal("ACL not received")
+			return nil
+		}
+	}
+
+	acl := requestACL()
+	before := len(acl.Rules)
+	acl.Rules = append(acl.Rules, &gumble.ACLRule{
+		AppliesCurrent: true,
+		Denied:         gumble.PermissionEnter,
+		Group: &gumble.ACLGroup{
+			Name: gumble.ACLGroupEveryone,
+		},
+	})
+	admin.Send(acl)
+
+	acl = requestACL()
+	var found bool
+	for _, rule := range acl.Rules {
+		if !rule.Inherited && rule.Group != nil && rule.Group.Name == gumble.ACLGroupEveryone && rule.Denied&gumble.PermissionEnter != 0 {
+			found = true
+		}
+	}
+	if !found || len(acl.Rules) <= before {
+		t.Fatal("ACL rule was not saved")
+	}
+}
@@ -0,0 +1,332 @@
+package gumbletest
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/json"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"layeh.com/gumble/gumble"
+	"layeh.com/gumble/gumble/MumbleProto"
+)
+
+// Packet is a control packet of a recorded session.
+type Packet struct {
+	// The time at which the packet was received, relative to the start of the
+	// session.
+	Time time.Duration `json:"time"`
+	// true if the packet was sent by the client, false if it was sent by the
+	// server.
+	FromClient bool   `json:"from_client,omitempty"`
+	Type       uint16 `json:"type"`
+	Data       []byte `json:"data"`
+}
+
+// ReadRecording reads the packets that were written by a Recorder.
+func ReadRecording(r io.Reader) ([]Packet, error) {
+	var packets []Packet
+	decoder := json.NewDecoder(bufio.NewReader(r))
+	for {
+		var packet Packet
+		if err := decoder.Decode(&packet); err != nil {
+			if err == io.EOF {
+				return packets, nil
+			}
+			return nil, err
+		}
+		packets = append(packets, packet)
+	}
+}
+
+// Recorder is a proxy that records the control packets of a session with a
+// real Mumble server. Each packet is written to the recording as a line of
+// JSON.
+//
+//  f, _ := os.Create("testdata/session.jsonl")
+//  recorder, err := gumbletest.NewRecorder(f, "example.com:64738", nil)
+//  client, err := recorder.Dial(config)
+//  // ...
+//  client.Disconnect()
+//  recorder.Close()
+//
+// A recorder proxies a single session; it stops listening once a client has
+// connected.
+type Recorder struct {
+	addr      string
+	tlsConfig *tls.Config
+	listener  net.Listener
+	wg        sync.WaitGroup
+
+	mu      sync.Mutex
+	encoder *json.Encoder
+	start   time.Time
+	conns   []net.Conn
+	closed  bool
+	err     error
+}
+
+// NewRecorder returns a new Recorder that writes to w the session of a client
+// with the server at addr.
+//
+// tlsConfig is used to connect to the server, and can be nil. As the recorder
+// terminates the client's TLS connection, a client certificate must be set in
+// tlsConfig rather than in the client's configuration.
+func NewRecorder(w io.Writer, addr string, tlsConfig *tls.Config) (*Recorder, error) {
+	listener, err := listen()
+	if err != nil {
+		return nil, err
+	}
+	r := &Recorder{
+		addr:      addr,
+		tlsConfig: tlsConfig,
+		listener:  listener,
+		encoder:   json.NewEncoder(w),
+	}
+	r.wg.Add(1)
+	go r.acceptRoutine()
+	return r, nil
+}
+
+// Addr returns the address that the recorder is listening on.
+func (r *Recorder) Addr() string {
+	return r.listener.Addr().String()
+}
+
+// Dial connects a client to the server through the recorder, using config.
+func (r *Recorder) Dial(config *gumble.Config) (*gumble.Client, error) {
+	return dial(r.Addr(), config)
+}
+
+func (r *Recorder) acceptRoutine() {
+	defer r.wg.Done()
+	conn, err := r.listener.Accept()
+	r.listener.Close()
+	if err != nil {
+		return
+	}
+	server, err := tls.Dial("tcp", r.addr, r.tlsConfig)
+	if err != nil {
+		conn.Close()
+		r.fail(err)
+		return
+	}
+
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		conn.Close()
+		server.Close()
+		return
+	}
+	r.start = time.Now()
+	r.conns = []net.Conn{conn, server}
+	r.mu.Unlock()
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		r.proxy(gumble.NewConn(server), gumble.NewConn(conn), false)
+	}()
+	r.proxy(gumble.NewConn(conn), gumble.NewConn(server), true)
+}
+
+// proxy copies the packets read from src to dst, recording them, until
+// either connection is closed.
+func (r *Recorder) proxy(src, dst *gumble.Conn, fromClient bool) {
+	defer src.Close()
+	defer dst.Close()
+	for {
+		pType, data, err := src.ReadPacket()
+		if err != nil {
+			return
+		}
+		r.mu.Lock()
+		err = r.encoder.Encode(&Packet{
+			Time:       time.Since(r.start),
+			FromClient: fromClient,
+			Type:       pType,
+			Data:       data,
+		})
+		r.mu.Unlock()
+		if err != nil {
+			r.fail(err)
+			return
+		}
+		if err := dst.WritePacket(pType, data); err != nil {
+			return
+		}
+	}
+}
+
+func (r *Recorder) fail(err error) {
+	r.mu.Lock()
+	if r.err == nil {
+		r.err = err
+	}
+	r.mu.Unlock()
+}
+
+// Close closes the recorder's connections. The error that occurred while
+// connecting to the server or writing the recording, if any, is returned.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	r.closed = true
+	for _, conn := range r.conns {
+		conn.Close()
+	}
+	r.mu.Unlock()
+	r.listener.Close()
+	r.wg.Wait()
+	return r.err
+}
+
+// Replayer is a fake Mumble server that sends recorded packets to the clients
+// that connect to it, for regression testing against the behavior of real
+// servers.
+//
+//  f, _ := os.Open("testdata/session.jsonl")
+//  packets, err := gumbletest.ReadRecording(f)
+//  replayer := gumbletest.NewReplayer(packets)
+//  defer replayer.Close()
+//  client, err := replayer.Dial(config)
+//
+// Each client receives all of the packets that were sent by the server, once
+// it has authenticated. The packets sent by the client are not compared
+// against the recording; Pings are answered, and everything else is ignored.
+type Replayer struct {
+	// If true, the packets are sent with the delays between them in the
+	// recording. Otherwise, they are sent as fast as possible.
+	Realtime bool
+
+	packets  []Packet
+	listener net.Listener
+	wg       sync.WaitGroup
+
+	mu     sync.Mutex
+	conns  map[*gumble.Conn]struct{}
+	closed bool
+}
+
+// NewReplayer returns a new started Replayer that replays packets, listening
+// on a local address.
+func NewReplayer(packets []Packet) *Replayer {
+	listener, err := listen()
+	if err != nil {
+		panic("gumbletest: " + err.Error())
+	}
+	r := &Replayer{
+		packets:  packets,
+		listener: listener,
+		conns:    make(map[*gumble.Conn]struct{}),
+	}
+	r.wg.Add(1)
+	go r.acceptRoutine()
+	return r
+}
+
+// Addr returns the address that the replayer is listening on.
+func (r *Replayer) Addr() string {
+	return r.listener.Addr().String()
+}
+
+// Dial connects a client to the replayer, using config.
+func (r *Replayer) Dial(config *gumble.Config) (*gumble.Client, error) {
+	return dial(r.Addr(), config)
+}
+
+func (r *Replayer) acceptRoutine() {
+	defer r.wg.Done()
+	for {
+		conn, err := r.listener.Accept()
+		if err != nil {
+			return
+		}
+		c := gumble.NewConn(conn)
+		r.mu.Lock()
+		if r.closed {
+			r.mu.Unlock()
+			conn.Close()
+			return
+		}
+		r.conns[c] = struct{}{}
+		r.mu.Unlock()
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			r.replay(c)
+			r.mu.Lock()
+			delete(r.conns, c)
+			r.mu.Unlock()
+		}()
+	}
+}
+
+func (r *Replayer) replay(conn *gumble.Conn) {
+	defer conn.Close()
+	if _, err := handshake(conn); err != nil {
+		return
+	}
+
+	// Pings are answered as they are read, so that the client does not time
+	// out after the recording has been sent.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			pType, data, err := conn.ReadPacket()
+			if err != nil {
+				return
+			}
+			if pType != gumble.PacketPing {
+				continue
+			}
+			var packet MumbleProto.Ping
+			if proto.Unmarshal(data, &packet) == nil {
+				conn.WriteProto(&MumbleProto.Ping{
+					Timestamp: packet.Timestamp,
+				})
+			}
+		}
+	}()
+
+	start := time.Now()
+	for _, packet := range r.packets {
+		// The recorded Pings were replies to the recorded client.
+		if packet.FromClient || packet.Type == gumble.PacketPing {
+			continue
+		}
+		if r.Realtime {
+			if d := packet.Time - time.Since(start); d > 0 {
+				select {
+				case <-done:
+					return
+				case <-time.After(d):
+				}
+			}
+		}
+		if err := conn.WritePacket(packet.Type, packet.Data); err != nil {
+			return
+		}
+	}
+	<-done
+}
+
+// Close disconnects all clients and stops the replayer.
+func (r *Replayer) Close() {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return
+	}
+	r.closed = true
+	for conn := range r.conns {
+		conn.Close()
+	}
+	r.mu.Unlock()
+	r.listener.Close()
+	r.wg.Wait()
+}
@@ -0,0 +1,64 @@
+package gumbletest_test
+
+import (
+	"bytes"
+	"crypto/tls"
+	"testing"
+
+	"layeh.com/gumble/gumble"
+	"layeh.com/gumble/gumbletest"
+)
+
+func TestRecorderReplayer(t *testing.T) {
+	server := gumbletest.NewServer()
+	defer server.Close()
+	lobby := server.AddChannel(0, "Lobby")
+
+	var recording bytes.Buffer
+	recorder, err := gumbletest.NewRecorder(&recording, server.Addr(), &tls.Config{
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := recorder.Dial(gumble.NewConfig())
+	if err != nil {
+		recorder.Close()
+		t.Fatal(err)
+	}
+	client.Disconnect()
+	if err := recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	packets, err := gumbletest.ReadRecording(&recording)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromClient, fromServer bool
+	for _, packet := range packets {
+		if packet.FromClient {
+			fromClient = true
+		} else {
+			fromServer = true
+		}
+	}
+	if !fromClient || !fromServer {
+		t.Fatalf("recording has packets from the client: %v, from the server: %v; want both", fromClient, fromServer)
+	}
+
+	replayer := gumbletest.NewReplayer(packets)
+	defer replayer.Close()
+	client, err = replayer.Dial(gumble.NewConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Disconnect()
+	var channel *gumble.Channel
+	client.Do(func() {
+		channel = client.Channels.Find("Lobby")
+	})
+	if channel == nil || channel.ID != lobby {
+		t.Errorf("Find(\"Lobby\") = %v; want channel %d", channel, lobby)
+	}
+}
@@ -0,0 +1,397 @@
+// Package gumbletest provides an in-memory Mumble server for testing gumble
+// clients, without a real Murmur server.
+//
+//  server := gumbletest.NewServer()
+//  defer server.Close()
+//  lobby := server.AddChannel(0, "Lobby")
+//  client, err := server.Dial(gumble.NewConfig())
+//
+// The server implements the parts of the protocol that most bots use: the
+// version and authentication handshake, channel and user state, text
+// messages, kicking and banning, pings, user stats, and tunneled audio. There
+// are no ACLs; every user is allowed to do everything.
+//
+// For behavior that the in-memory server does not implement, a session with
+// a real server can be captured with a Recorder, and played back to clients
+// with a Replayer.
+package gumbletest
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"layeh.com/gumble/gumble"
+	"layeh.com/gumble/gumble/MumbleProto"
+	"layeh.com/gumble/gumbleutil"
+)
+
+// serverVersion is the protocol version of the server (1.3.0).
+const serverVersion = 1<<16 | 3<<8 | 0
+
+// Message is a text message that was sent to the server by a client.
+type Message struct {
+	// The name of the sender.
+	Sender string
+	// The sessions and channel IDs of the recipients.
+	Users    []uint32
+	Channels []uint32
+	Trees    []uint32
+	Message  string
+}
+
+// Server is an in-memory Mumble server.
+type Server struct {
+	// If non-empty, the password that clients must connect with.
+	Password string
+	// The welcome message sent to clients when they connect.
+	WelcomeText string
+	// The maximum bitrate sent to clients when they connect.
+	MaxBandwidth uint32
+
+	listener net.Listener
+	wg       sync.WaitGroup
+
+	mu          sync.Mutex
+	conns       map[*gumble.Conn]struct{}
+	channels    map[uint32]*channel
+	users       map[uint32]*user
+	bans        map[string]bool
+	messages    []Message
+	nextSession uint32
+	nextChannel uint32
+	closed      bool
+}
+
+type channel struct {
+	id        uint32
+	parent    uint32
+	name      string
+	temporary bool
+}
+
+type user struct {
+	session   uint32
+	name      string
+	channel   uint32
+	conn      *gumble.Conn
+	connected time.Time
+
+	muted, deafened, selfMuted, selfDeafened bool
+	comment                                  string
+}
+
+// NewServer returns a new started Server, listening on a local address.
+func NewServer() *Server {
+	s := NewUnstartedServer()
+	if err := s.Start(); err != nil {
+		panic("gumbletest: " + err.Error())
+	}
+	return s
+}
+
+// NewUnstartedServer returns a new Server that has not been started. Its
+// fields can be changed before Start is called.
+func NewUnstartedServer() *Server {
+	return &Server{
+		channels: map[uint32]*channel{
+			0: {id: 0, name: "Root"},
+		},
+		conns:       make(map[*gumble.Conn]struct{}),
+		users:       make(map[uint32]*user),
+		bans:        make(map[string]bool),
+		nextSession: 1,
+		nextChannel: 1,
+	}
+}
+
+// Start starts listening on a local address.
+func (s *Server) Start() error {
+	listener, err := listen()
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+	s.wg.Add(1)
+	go s.acceptRoutine()
+	return nil
+}
+
+// Addr returns the address that the server is listening on.
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Dial connects a client to the server, using config. config.Username is set
+// to "gumble" if it is empty.
+func (s *Server) Dial(config *gumble.Config) (*gumble.Client, error) {
+	return dial(s.Addr(), config)
+}
+
+// Close disconnects all clients and stops the server.
+func (s *Server) Close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.listener.Close()
+	s.wg.Wait()
+}
+
+// AddChannel adds a channel to the server, and returns its ID.
+func (s *Server) AddChannel(parent uint32, name string) uint32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.addChannel(parent, name, false)
+	if c == nil {
+		panic("gumbletest: parent channel does not exist")
+	}
+	return c.id
+}
+
+// Messages returns the text messages that have been sent by clients.
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.messages...)
+}
+
+// Users returns the names of the connected users, sorted.
+func (s *Server) Users() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.users))
+	for _, u := range s.users {
+		names = append(names, u.name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SendMessage sends a text message from the server (i.e. without a sender) to
+// every connected user.
+func (s *Server) SendMessage(message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	packet := MumbleProto.TextMessage{
+		Message: &message,
+	}
+	for _, u := range s.users {
+		packet.Session = append(packet.Session, u.session)
+	}
+	s.broadcast(&packet)
+}
+
+// Kick disconnects the user with the given name. false is returned if the
+// user is not connected.
+func (s *Server) Kick(name, reason string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, u := range s.users {
+		if u.name == name {
+			s.removeUser(u, nil, reason, false)
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) acceptRoutine() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		c := gumble.NewConn(conn)
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[c] = struct{}{}
+		s.mu.Unlock()
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.serve(c)
+			s.mu.Lock()
+			delete(s.conns, c)
+			s.mu.Unlock()
+		}()
+	}
+}
+
+func (s *Server) reject(conn *gumble.Conn, t MumbleProto.Reject_RejectType, reason string) {
+	conn.WriteProto(&MumbleProto.Reject{
+		Type:   t.Enum(),
+		Reason: &reason,
+	})
+	conn.Close()
+}
+
+func (s *Server) serve(conn *gumble.Conn) {
+	auth, err := handshake(conn)
+	if err != nil {
+		conn.Close()
+		return
+	}
+
+	s.mu.Lock()
+	name := auth.GetUsername()
+	switch {
+	case s.closed:
+		s.mu.Unlock()
+		conn.Close()
+		return
+	case name == "":
+		s.mu.Unlock()
+		s.reject(conn, MumbleProto.Reject_InvalidUsername, "invalid username")
+		return
+	case s.Password != "" && auth.GetPassword() != s.Password:
+		s.mu.Unlock()
+		s.reject(conn, MumbleProto.Reject_WrongServerPW, "wrong server password")
+		return
+	case s.bans[name]:
+		s.mu.Unlock()
+		s.reject(conn, MumbleProto.Reject_None, "you are banned")
+		return
+	}
+	for _, other := range s.users {
+		if other.name == name {
+			s.mu.Unlock()
+			s.reject(conn, MumbleProto.Reject_UsernameInUse, "username in use")
+			return
+		}
+	}
+
+	u := &user{
+		session:   s.nextSession,
+		name:      name,
+		conn:      conn,
+		connected: time.Now(),
+	}
+	s.nextSession++
+
+	conn.WriteProto(&MumbleProto.Version{
+		Version: proto.Uint32(serverVersion),
+		Release: proto.String("gumbletest"),
+	})
+	conn.WriteProto(&MumbleProto.CodecVersion{
+		Alpha:       proto.Int32(-2147483637),
+		Beta:        proto.Int32(0),
+		PreferAlpha: proto.Bool(true),
+		Opus:        proto.Bool(true),
+	})
+	ids := make([]uint32, 0, len(s.channels))
+	for id := range s.channels {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		conn.WriteProto(s.channels[id].state())
+	}
+	for _, other := range s.users {
+		conn.WriteProto(other.state())
+	}
+	s.users[u.session] = u
+	s.broadcast(u.state())
+	serverSync := MumbleProto.ServerSync{
+		Session:     &u.session,
+		Permissions: proto.Uint64(uint64(^uint32(0))),
+	}
+	if s.WelcomeText != "" {
+		serverSync.WelcomeText = proto.String(s.WelcomeText)
+	}
+	if s.MaxBandwidth > 0 {
+		serverSync.MaxBandwidth = proto.Uint32(s.MaxBandwidth)
+	}
+	conn.WriteProto(&serverSync)
+	conn.WriteProto(&MumbleProto.ServerConfig{
+		AllowHtml:          proto.Bool(true),
+		MessageLength:      proto.Uint32(5000),
+		ImageMessageLength: proto.Uint32(131072),
+	})
+	s.mu.Unlock()
+
+	for {
+		pType, data, err := conn.ReadPacket()
+		if err != nil {
+			break
+		}
+		s.mu.Lock()
+		if s.users[u.session] != u {
+			// The user has been kicked.
+			s.mu.Unlock()
+			break
+		}
+		s.handle(u, pType, data)
+		s.mu.Unlock()
+	}
+
+	s.mu.Lock()
+	if s.users[u.session] == u {
+		s.removeUser(u, nil, "", false)
+	}
+	s.mu.Unlock()
+	conn.Close()
+}
+
+// listen returns a TLS listener on a local address, with a self-signed
+// certificate.
+func listen() (net.Listener, error) {
+	cert, _, err := gumbleutil.GenerateCertificate("gumbletest")
+	if err != nil {
+		return nil, err
+	}
+	return tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{cert},
+	})
+}
+
+// dial connects a client to the listener at addr.
+func dial(addr string, config *gumble.Config) (*gumble.Client, error) {
+	if config.Username == "" {
+		config.Username = "gumble"
+	}
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+	}
+	return gumble.DialWithDialer(dialer, addr, config, &tls.Config{
+		InsecureSkipVerify: true,
+	})
+}
+
+var errUnexpectedPacket = errors.New("gumbletest: unexpected packet")
+
+// handshake reads the client's Version and Authenticate packets.
+func handshake(conn *gumble.Conn) (*MumbleProto.Authenticate, error) {
+	for {
+		pType, data, err := conn.ReadPacket()
+		if err != nil {
+			return nil, err
+		}
+		switch pType {
+		case gumble.PacketVersion:
+		case gumble.PacketAuthenticate:
+			var packet MumbleProto.Authenticate
+			if err := proto.Unmarshal(data, &packet); err != nil {
+				return nil, err
+			}
+			return &packet, nil
+		default:
+			return nil, errUnexpectedPacket
+		}
+	}
+}
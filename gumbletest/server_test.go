@@ -0,0 +1,129 @@
+package gumbletest_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"layeh.com/gumble/gumble"
+	"layeh.com/gumble/gumbletest"
+)
+
+// waitMessages waits until server has received n text messages.
+func waitMessages(t *testing.T, server *gumbletest.Server, n int) []gumbletest.Message {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		messages := server.Messages()
+		if len(messages) >= n {
+			return messages
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("server received %d messages; want %d", len(messages), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestServerDial(t *testing.T) {
+	server := gumbletest.NewServer()
+	defer server.Close()
+	lobby := server.AddChannel(0, "Lobby")
+
+	received := make(chan *gumble.TextMessageEvent, 1)
+	config := gumble.NewConfig()
+	config.AttachAll(func(event gumble.Event) {
+		if e, ok := event.(*gumble.TextMessageEvent); ok {
+			received <- e
+		}
+	})
+	client, err := server.Dial(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Disconnect()
+
+	if got, want := server.Users(), []string{"gumble"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Users() = %q; want %q", got, want)
+	}
+	var channel *gumble.Channel
+	client.Do(func() {
+		if client.Self == nil || client.Self.Name != "gumble" {
+			t.Errorf("Self = %v; want the gumble user", client.Self)
+		}
+		channel = client.Channels.Find("Lobby")
+	})
+	if channel == nil || channel.ID != lobby {
+		t.Fatalf("Find(\"Lobby\") = %v; want channel %d", channel, lobby)
+	}
+
+	if err := channel.Send("hello", false); err != nil {
+		t.Fatal(err)
+	}
+	messages := waitMessages(t, server, 1)
+	want := gumbletest.Message{
+		Sender:   "gumble",
+		Channels: []uint32{lobby},
+		Message:  "hello",
+	}
+	if !reflect.DeepEqual(messages[0], want) {
+		t.Errorf("Messages()[0] = %+v; want %+v", messages[0], want)
+	}
+
+	server.SendMessage("welcome")
+	select {
+	case e := <-received:
+		if e.Message != "welcome" || e.Sender != nil {
+			t.Errorf("TextMessageEvent = %+v; want a message from the server", e.TextMessage)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the TextMessageEvent")
+	}
+}
+
+func TestServerPassword(t *testing.T) {
+	server := gumbletest.NewUnstartedServer()
+	server.Password = "secret"
+	if err := server.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	if client, err := server.Dial(gumble.NewConfig()); err == nil {
+		client.Disconnect()
+		t.Fatal("Dial with the wrong password succeeded")
+	}
+
+	config := gumble.NewConfig()
+	config.Password = "secret"
+	client, err := server.Dial(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Disconnect()
+}
+
+func TestServerKick(t *testing.T) {
+	server := gumbletest.NewServer()
+	defer server.Close()
+
+	config := gumble.NewConfig()
+	watcher := gumbletest.Watch(config)
+	defer watcher.Detach()
+	if _, err := server.Dial(config); err != nil {
+		t.Fatal(err)
+	}
+
+	if !server.Kick("gumble", "bye") {
+		t.Fatal("Kick returned false for a connected user")
+	}
+	e, err := watcher.WaitDisconnect(5 * time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Type != gumble.DisconnectKicked || e.String != "bye" {
+		t.Errorf("DisconnectEvent = %v, %q; want DisconnectKicked, %q", e.Type, e.String, "bye")
+	}
+	if server.Kick("gumble", "") {
+		t.Error("Kick returned true for a disconnected user")
+	}
+}
@@ -0,0 +1,99 @@
+package gumbletest
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"layeh.com/gumble/gumble"
+	"layeh.com/gumble/gumbleutil"
+)
+
+var errWatchTimeout = errors.New("gumbletest: timed out waiting for event")
+
+// Watcher records the connects and disconnects of the clients that use a
+// gumble.Config, to assert how they recover from faults:
+//
+//  watcher := gumbletest.Watch(config)
+//  conn.Reset()
+//  if _, err := watcher.WaitDisconnect(time.Second); err != nil {
+//    t.Fatal(err)
+//  }
+//  if _, err := watcher.WaitConnect(5 * time.Second); err != nil {
+//    t.Fatal("client did not reconnect")
+//  }
+type Watcher struct {
+	detacher    gumble.Detacher
+	connects    chan *gumble.ConnectEvent
+	disconnects chan *gumble.DisconnectEvent
+
+	mu                            sync.Mutex
+	connectCount, disconnectCount int
+}
+
+// Watch returns a new Watcher that watches the clients that use config.
+func Watch(config *gumble.Config) *Watcher {
+	w := &Watcher{
+		connects:    make(chan *gumble.ConnectEvent, 64),
+		disconnects: make(chan *gumble.DisconnectEvent, 64),
+	}
+	w.detacher = config.Attach(gumbleutil.Listener{
+		Connect: func(e *gumble.ConnectEvent) {
+			w.mu.Lock()
+			w.connectCount++
+			w.mu.Unlock()
+			select {
+			case w.connects <- e:
+			default:
+			}
+		},
+		Disconnect: func(e *gumble.DisconnectEvent) {
+			w.mu.Lock()
+			w.disconnectCount++
+			w.mu.Unlock()
+			select {
+			case w.disconnects <- e:
+			default:
+			}
+		},
+	})
+	return w
+}
+
+// WaitConnect returns the next connect event, waiting up to timeout for it.
+func (w *Watcher) WaitConnect(timeout time.Duration) (*gumble.ConnectEvent, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case e := <-w.connects:
+		return e, nil
+	case <-timer.C:
+		return nil, errWatchTimeout
+	}
+}
+
+// WaitDisconnect returns the next disconnect event, waiting up to timeout for
+// it.
+func (w *Watcher) WaitDisconnect(timeout time.Duration) (*gumble.DisconnectEvent, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case e := <-w.disconnects:
+		return e, nil
+	case <-timer.C:
+		return nil, errWatchTimeout
+	}
+}
+
+// Counts returns the number of connects and disconnects that have been
+// watched.
+func (w *Watcher) Counts() (connects, disconnects int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.connectCount, w.disconnectCount
+}
+
+// Detach stops watching the config.
+func (w *Watcher) Detach() {
+	w.detacher.Detach()
+}
@@ -0,0 +1,54 @@
+package gumbletts
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os/exec"
+	"strconv"
+)
+
+// Espeak is a Synthesizer that runs espeak-ng (or espeak) as a subprocess.
+type Espeak struct {
+	// Command to execute. Defaults to "espeak-ng".
+	Command string
+	// Voice name (e.g. "en-us"). If empty, the default voice is used.
+	Voice string
+	// Speaking rate in words per minute. If zero, the default rate is used.
+	Speed int
+	// Pitch adjustment, 0 to 99. If zero, the default pitch is used.
+	Pitch int
+}
+
+var _ Synthesizer = (*Espeak)(nil)
+
+// Synthesize implements Synthesizer.
+func (e *Espeak) Synthesize(ctx context.Context, text string) ([]int16, error) {
+	command := e.Command
+	if command == "" {
+		command = "espeak-ng"
+	}
+	args := []string{"--stdout"}
+	if e.Voice != "" {
+		args = append(args, "-v", e.Voice)
+	}
+	if e.Speed > 0 {
+		args = append(args, "-s", strconv.Itoa(e.Speed))
+	}
+	if e.Pitch > 0 {
+		args = append(args, "-p", strconv.Itoa(e.Pitch))
+	}
+	// Read the text from stdin so that it cannot be interpreted as a flag.
+	cmd := exec.CommandContext(ctx, command, args...)
+	cmd.Stdin = bytes.NewBufferString(text)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	wav, err := cmd.Output()
+	if err != nil {
+		if stderr.Len() > 0 {
+			return nil, errors.New("gumbletts: " + command + ": " + stderr.String())
+		}
+		return nil, err
+	}
+	return DecodeWAV(wav)
+}
@@ -0,0 +1,98 @@
+package gumbletts
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+)
+
+// Format is the audio format of a synthesizer's response.
+type Format int
+
+// Supported response formats.
+const (
+	// FormatWAV is a 16-bit PCM WAV file.
+	FormatWAV Format = iota
+	// FormatPCM is raw little-endian 16-bit mono PCM at HTTP.SampleRate.
+	FormatPCM
+)
+
+// HTTP is a Synthesizer that requests speech from an HTTP API, such as a
+// cloud text-to-speech service.
+//
+// Because every service has its own request format, the request is built by
+// NewRequest. For example:
+//
+//  synth := &gumbletts.HTTP{
+//      NewRequest: func(ctx context.Context, text string) (*http.Request, error) {
+//          body := strings.NewReader(`{"input":` + strconv.Quote(text) + `,"response_format":"wav"}`)
+//          req, err := http.NewRequestWithContext(ctx, "POST", endpoint, body)
+//          if err != nil {
+//              return nil, err
+//          }
+//          req.Header.Set("Authorization", "Bearer "+token)
+//          req.Header.Set("Content-Type", "application/json")
+//          return req, nil
+//      },
+//      Format: gumbletts.FormatWAV,
+//  }
+type HTTP struct {
+	// Builds the request that synthesizes the given text.
+	NewRequest func(ctx context.Context, text string) (*http.Request, error)
+	// Format of the response body.
+	Format Format
+	// Sample rate of the response body, if Format is FormatPCM.
+	SampleRate int
+	// Client used to make requests. If nil, http.DefaultClient is used.
+	Client *http.Client
+}
+
+var _ Synthesizer = (*HTTP)(nil)
+
+// StatusError is returned by HTTP.Synthesize when the service responds with a
+// non-2xx status code.
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+// Error implements error.
+func (e *StatusError) Error() string {
+	msg := "gumbletts: unexpected HTTP status " + strconv.Itoa(e.StatusCode)
+	if e.Body != "" {
+		msg += ": " + e.Body
+	}
+	return msg
+}
+
+// Synthesize implements Synthesizer.
+func (h *HTTP) Synthesize(ctx context.Context, text string) ([]int16, error) {
+	req, err := h.NewRequest(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+	client := h.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		if len(body) > 512 {
+			body = body[:512]
+		}
+		return nil, &StatusError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	if h.Format == FormatPCM {
+		return DecodePCM(body, 1, h.SampleRate), nil
+	}
+	return DecodeWAV(body)
+}
@@ -0,0 +1,143 @@
+// Package gumbletts speaks text to a Mumble server using a pluggable speech
+// synthesizer.
+//
+// Two synthesizers are provided: Espeak, which runs espeak-ng locally, and
+// HTTP, which can be adapted to most cloud text-to-speech APIs. Any other
+// engine can be used by implementing Synthesizer.
+//
+//  speaker := gumbletts.New(client, &gumbletts.Espeak{Voice: "en-us"})
+//  speaker.Say(context.Background(), "Welcome to the server!")
+package gumbletts
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"layeh.com/gumble/gumble"
+)
+
+// Synthesizer converts text to speech.
+type Synthesizer interface {
+	// Synthesize returns the spoken text as mono PCM samples at
+	// gumble.AudioSampleRate.
+	Synthesize(ctx context.Context, text string) ([]int16, error)
+}
+
+// Speaker plays synthesized speech through a client. Utterances are queued
+// and spoken one after another.
+type Speaker struct {
+	// Synthesizer used to convert text to speech.
+	Synthesizer Synthesizer
+	// Playback volume.
+	Volume float32
+	// Opens the channel that audio is written to. Defaults to
+	// Client.AudioOutgoing.
+	Outgoing func() chan<- gumble.AudioBuffer
+
+	client *gumble.Client
+
+	mu      sync.Mutex
+	queue   [][]int16
+	running bool
+	stop    bool
+	idle    *sync.Cond
+}
+
+// New returns a new Speaker for the given client and synthesizer.
+func New(client *gumble.Client, synthesizer Synthesizer) *Speaker {
+	s := &Speaker{
+		Synthesizer: synthesizer,
+		Volume:      1.0,
+		Outgoing:    client.AudioOutgoing,
+		client:      client,
+	}
+	s.idle = sync.NewCond(&s.mu)
+	return s
+}
+
+// Say synthesizes the given text and queues it to be spoken. The function
+// returns once synthesis has completed; playback happens in the background.
+func (s *Speaker) Say(ctx context.Context, text string) error {
+	pcm, err := s.Synthesizer.Synthesize(ctx, text)
+	if err != nil {
+		return err
+	}
+	s.Play(pcm)
+	return nil
+}
+
+// Play queues already synthesized speech to be spoken.
+func (s *Speaker) Play(pcm []int16) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queue = append(s.queue, pcm)
+	if !s.running {
+		s.running = true
+		go s.process()
+	}
+}
+
+// Stop stops speaking and discards any queued speech.
+func (s *Speaker) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queue = nil
+	s.stop = s.running
+}
+
+// Speaking returns true if speech is being played.
+func (s *Speaker) Speaking() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.running
+}
+
+// Wait blocks until all queued speech has been played.
+func (s *Speaker) Wait() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for s.running {
+		s.idle.Wait()
+	}
+}
+
+func (s *Speaker) process() {
+	interval := s.client.AudioInterval()
+	frameSize := s.client.AudioFrameSize()
+
+	outgoing := s.Outgoing()
+	defer close(outgoing)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var current []int16
+	for range ticker.C {
+		s.mu.Lock()
+		if s.stop {
+			current = nil
+			s.stop = false
+		}
+		if len(current) == 0 && len(s.queue) > 0 {
+			current = s.queue[0]
+			s.queue = s.queue[1:]
+		}
+		if len(current) == 0 {
+			s.running = false
+			s.idle.Broadcast()
+			s.mu.Unlock()
+			return
+		}
+		volume := s.Volume
+		s.mu.Unlock()
+
+		frame := make(gumble.AudioBuffer, frameSize)
+		n := copy(frame, current)
+		current = current[n:]
+		for i := range frame[:n] {
+			frame[i] = int16(volume * float32(frame[i]))
+		}
+		outgoing <- frame
+	}
+}
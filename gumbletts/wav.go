@@ -0,0 +1,99 @@
+package gumbletts
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"layeh.com/gumble/gumble"
+)
+
+var errInvalidWAV = errors.New("gumbletts: invalid or unsupported WAV data")
+
+// DecodeWAV decodes 16-bit PCM WAV data into mono samples at
+// gumble.AudioSampleRate. Multi-channel audio is downmixed and other sample
+// rates are resampled.
+func DecodeWAV(data []byte) ([]int16, error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return nil, errInvalidWAV
+	}
+	data = data[12:]
+
+	var (
+		channels   int
+		sampleRate int
+		haveFormat bool
+	)
+	for len(data) >= 8 {
+		id := string(data[0:4])
+		size := int(binary.LittleEndian.Uint32(data[4:8]))
+		data = data[8:]
+		if size > len(data) || size < 0 {
+			// Streaming encoders may write a bogus data chunk size.
+			size = len(data)
+		}
+		chunk := data[:size]
+		switch id {
+		case "fmt ":
+			if len(chunk) < 16 {
+				return nil, errInvalidWAV
+			}
+			format := binary.LittleEndian.Uint16(chunk[0:])
+			channels = int(binary.LittleEndian.Uint16(chunk[2:]))
+			sampleRate = int(binary.LittleEndian.Uint32(chunk[4:]))
+			bits := binary.LittleEndian.Uint16(chunk[14:])
+			// 0xFFFE is WAVE_FORMAT_EXTENSIBLE
+			if (format != 1 && format != 0xFFFE) || bits != 16 || channels < 1 {
+				return nil, errInvalidWAV
+			}
+			haveFormat = true
+		case "data":
+			if !haveFormat {
+				return nil, errInvalidWAV
+			}
+			return DecodePCM(chunk, channels, sampleRate), nil
+		}
+		data = data[size:]
+		if size%2 == 1 && len(data) > 0 {
+			data = data[1:]
+		}
+	}
+	return nil, errInvalidWAV
+}
+
+// DecodePCM decodes raw little-endian 16-bit PCM with the given number of
+// interleaved channels and sample rate into mono samples at
+// gumble.AudioSampleRate.
+func DecodePCM(data []byte, channels, sampleRate int) []int16 {
+	frames := len(data) / (2 * channels)
+	pcm := make([]int16, frames)
+	for i := range pcm {
+		var sum int32
+		for c := 0; c < channels; c++ {
+			sum += int32(int16(binary.LittleEndian.Uint16(data[(i*channels+c)*2:])))
+		}
+		pcm[i] = int16(sum / int32(channels))
+	}
+	return Resample(pcm, sampleRate)
+}
+
+// Resample converts mono samples at the given sample rate to
+// gumble.AudioSampleRate using linear interpolation.
+func Resample(pcm []int16, sampleRate int) []int16 {
+	if sampleRate == gumble.AudioSampleRate || sampleRate <= 0 || len(pcm) == 0 {
+		return pcm
+	}
+	n := int(int64(len(pcm)) * gumble.AudioSampleRate / int64(sampleRate))
+	out := make([]int16, n)
+	step := float64(sampleRate) / gumble.AudioSampleRate
+	for i := range out {
+		pos := float64(i) * step
+		j := int(pos)
+		if j+1 >= len(pcm) {
+			out[i] = pcm[len(pcm)-1]
+			continue
+		}
+		frac := pos - float64(j)
+		out[i] = int16(float64(pcm[j])*(1-frac) + float64(pcm[j+1])*frac)
+	}
+	return out
+}
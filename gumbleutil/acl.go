@@ -53,3 +53,43 @@ func UserGroups(client *gumble.Client, user *gumble.User, channel *gumble.Channe
 
 	return ch
 }
+
+// EffectivePermissions fetches the ACL of the given channel and computes the
+// user's permissions in it (see gumble.ACL.Permissions). The permissions are
+// sent via the returned channel. On error, the returned channel is closed
+// without sending a value.
+func EffectivePermissions(client *gumble.Client, user *gumble.User, channel *gumble.Channel) <-chan gumble.Permission {
+	ch := make(chan gumble.Permission, 1)
+
+	var detacher gumble.Detacher
+	listener := Listener{
+		Disconnect: func(e *gumble.DisconnectEvent) {
+			detacher.Detach()
+			close(ch)
+		},
+		ChannelChange: func(e *gumble.ChannelChangeEvent) {
+			if e.Channel == channel && e.Type.Has(gumble.ChannelChangeRemoved) {
+				detacher.Detach()
+				close(ch)
+			}
+		},
+		PermissionDenied: func(e *gumble.PermissionDeniedEvent) {
+			if e.Channel == channel && e.Type == gumble.PermissionDeniedPermission && (e.Permission&gumble.PermissionWrite) != 0 {
+				detacher.Detach()
+				close(ch)
+			}
+		},
+		ACL: func(e *gumble.ACLEvent) {
+			if e.ACL.Channel != channel {
+				return
+			}
+			detacher.Detach()
+			ch <- e.ACL.Permissions(user)
+			close(ch)
+		},
+	}
+	detacher = client.Config.Attach(&listener)
+	channel.RequestACL()
+
+	return ch
+}
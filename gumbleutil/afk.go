@@ -0,0 +1,202 @@
+package gumbleutil
+
+import (
+	"sync"
+	"time"
+
+	"layeh.com/gumble/gumble"
+)
+
+// AFKMover moves users who have been idle for too long to an AFK channel, and
+// moves them back to their previous channel when they become active again.
+//
+// A user's idle time is taken from their stats, which the AFK mover requests
+// periodically. Users become active again when they speak, send a text
+// message, or unmute themselves.
+//
+// The client must have permission to move users in and out of the AFK channel,
+// and to request user stats.
+type AFKMover struct {
+	// The path of the AFK channel (see FindChannelByPath).
+	Channel string
+	// How long a user must be idle before they are moved.
+	IdleTime time.Duration
+	// How often user stats are requested. If zero, one minute is used.
+	CheckInterval time.Duration
+	// Whether users are moved back to their previous channel when they become
+	// active again.
+	MoveBack bool
+	// If non-nil, users for whom Exempt returns true are never moved.
+	Exempt func(user *gumble.User) bool
+
+	mu     sync.Mutex
+	client *gumble.Client
+	moved  map[uint32]uint32
+	stop   chan struct{}
+}
+
+// NewAFKMover returns a new AFKMover that moves users who have been idle for
+// idleTime to the channel at the given path.
+func NewAFKMover(channel string, idleTime time.Duration) *AFKMover {
+	return &AFKMover{
+		Channel:  channel,
+		IdleTime: idleTime,
+		MoveBack: true,
+	}
+}
+
+// Attach attaches the AFK mover's event and audio listeners to config.
+func (a *AFKMover) Attach(config *gumble.Config) gumble.Detacher {
+	return multiDetacher{
+		config.Attach(Listener{
+			Connect:     a.onConnect,
+			Disconnect:  a.onDisconnect,
+			UserChange:  a.onUserChange,
+			TextMessage: a.onTextMessage,
+		}),
+		config.AttachAudio(a),
+	}
+}
+
+func (a *AFKMover) onConnect(e *gumble.ConnectEvent) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.stop != nil {
+		close(a.stop)
+	}
+	a.client = e.Client
+	a.moved = make(map[uint32]uint32)
+	a.stop = make(chan struct{})
+	interval := a.CheckInterval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	go a.checkRoutine(e.Client, interval, a.stop)
+}
+
+func (a *AFKMover) onDisconnect(e *gumble.DisconnectEvent) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.stop != nil {
+		close(a.stop)
+		a.stop = nil
+	}
+	a.client = nil
+	a.moved = nil
+}
+
+// checkRoutine periodically requests the stats of the users that are not in
+// the AFK channel.
+func (a *AFKMover) checkRoutine(client *gumble.Client, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		client.Do(func() {
+			afk := FindChannelByPath(client, a.Channel)
+			if afk == nil {
+				return
+			}
+			for _, user := range client.Users {
+				if user == client.Self || user.Channel == afk {
+					continue
+				}
+				user.RequestStats()
+			}
+		})
+	}
+}
+
+func (a *AFKMover) onUserChange(e *gumble.UserChangeEvent) {
+	if e.User == nil || e.User == e.Client.Self {
+		return
+	}
+	switch {
+	case e.Type.HasAny(gumble.UserChangeDisconnected | gumble.UserChangeKicked | gumble.UserChangeBanned):
+		a.mu.Lock()
+		delete(a.moved, e.User.Session)
+		a.mu.Unlock()
+
+	case e.Type.Has(gumble.UserChangeChannel):
+		// The user left the AFK channel by themselves (or was moved by
+		// someone else).
+		afk := FindChannelByPath(e.Client, a.Channel)
+		if e.User.Channel != afk {
+			a.mu.Lock()
+			delete(a.moved, e.User.Session)
+			a.mu.Unlock()
+		}
+
+	case e.Type.HasAny(gumble.UserChangeSelfMuted | gumble.UserChangeSelfDeafened):
+		if !e.User.SelfMuted && !e.User.SelfDeafened {
+			a.active(e.User)
+		}
+
+	case e.Type.Has(gumble.UserChangeStats):
+		a.checkIdle(e.Client, e.User)
+	}
+}
+
+func (a *AFKMover) onTextMessage(e *gumble.TextMessageEvent) {
+	if e.Sender != nil {
+		a.active(e.Sender)
+	}
+}
+
+// OnAudioStream implements gumble.AudioListener.
+func (a *AFKMover) OnAudioStream(e *gumble.AudioStreamEvent) {
+	go func() {
+		for packet := range e.C {
+			packet.Release()
+			a.active(e.User)
+		}
+	}()
+}
+
+// checkIdle moves the user to the AFK channel if they have been idle for
+// too long.
+func (a *AFKMover) checkIdle(client *gumble.Client, user *gumble.User) {
+	if user.Stats == nil || user.Stats.Idle < a.IdleTime {
+		return
+	}
+	if a.Exempt != nil && a.Exempt(user) {
+		return
+	}
+	afk := FindChannelByPath(client, a.Channel)
+	if afk == nil || user.Channel == nil || user.Channel == afk {
+		return
+	}
+	a.mu.Lock()
+	if a.moved != nil {
+		a.moved[user.Session] = user.Channel.ID
+	}
+	a.mu.Unlock()
+	user.Move(afk)
+}
+
+// active moves the user back to their previous channel, if they were moved to
+// the AFK channel.
+func (a *AFKMover) active(user *gumble.User) {
+	if !a.MoveBack {
+		return
+	}
+	a.mu.Lock()
+	id, ok := a.moved[user.Session]
+	client := a.client
+	if ok {
+		delete(a.moved, user.Session)
+	}
+	a.mu.Unlock()
+	if !ok || client == nil {
+		return
+	}
+	client.Do(func() {
+		if channel := client.Channels[id]; channel != nil {
+			user.Move(channel)
+		}
+	})
+}
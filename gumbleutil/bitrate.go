@@ -20,6 +20,9 @@ var autoBitrate = &Listener{
 
 // AutoBitrate is a gumble.EventListener that automatically sets the client's
 // AudioDataBytes to suitable value, based on the server's bitrate.
+//
+// gumble.Config.AdjustToBandwidth does the same more exactly, and also
+// handles changes to the server's bandwidth while the client is connected.
 var AutoBitrate gumble.EventListener
 
 func init() {
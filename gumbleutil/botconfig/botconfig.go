@@ -0,0 +1,338 @@
+// Package botconfig loads the configuration of a gumble bot from a YAML, TOML,
+// or JSON file and from environment variables. It is kept apart from
+// gumbleutil so that programs that do not use it do not depend on the YAML
+// and TOML parsers.
+package botconfig
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+	"layeh.com/gumble/gumble"
+	"layeh.com/gumble/gumbleutil"
+)
+
+// DefaultEnvPrefix is the prefix of the environment variables read by Load.
+const DefaultEnvPrefix = "GUMBLE_"
+
+// Config is the configuration of a bot, as loaded from a file by Load. An
+// example YAML file:
+//  server: mumble.example.com:64738
+//  username: my-bot
+//  certificate: my-bot.pem
+//  tokens: [music]
+//  audio_interval: 20ms
+//  reconnect:
+//    min_delay: 1s
+//    max_delay: 2m
+type Config struct {
+	// The address of the server. If it does not contain a port,
+	// gumble.DefaultPort is used.
+	Server   string   `json:"server" yaml:"server" toml:"server"`
+	Username string   `json:"username" yaml:"username" toml:"username"`
+	Password string   `json:"password" yaml:"password" toml:"password"`
+	Tokens   []string `json:"tokens" yaml:"tokens" toml:"tokens"`
+
+	// The PEM file that contains the client's certificate, and its key unless
+	// Key is set.
+	Certificate string `json:"certificate" yaml:"certificate" toml:"certificate"`
+	Key         string `json:"key" yaml:"key" toml:"key"`
+	// A PEM file of CA certificates used to verify the server's certificate,
+	// instead of the system's.
+	CAFile string `json:"ca_file" yaml:"ca_file" toml:"ca_file"`
+	// The name used to verify the server's certificate, if it differs from
+	// the host in Server.
+	ServerName string `json:"server_name" yaml:"server_name" toml:"server_name"`
+	Insecure   bool   `json:"insecure" yaml:"insecure" toml:"insecure"`
+
+	AudioInterval  gumbleutil.Duration `json:"audio_interval" yaml:"audio_interval" toml:"audio_interval"`
+	AudioDataBytes int                 `json:"audio_data_bytes" yaml:"audio_data_bytes" toml:"audio_data_bytes"`
+
+	// If non-nil, the client reconnects according to the given policy. Unset
+	// values default to those of gumble.NewReconnectPolicy.
+	Reconnect *ReconnectConfig `json:"reconnect" yaml:"reconnect" toml:"reconnect"`
+}
+
+// ReconnectConfig is the configuration of a gumble.ReconnectPolicy.
+type ReconnectConfig struct {
+	MinDelay    gumbleutil.Duration `json:"min_delay" yaml:"min_delay" toml:"min_delay"`
+	MaxDelay    gumbleutil.Duration `json:"max_delay" yaml:"max_delay" toml:"max_delay"`
+	Multiplier  float64             `json:"multiplier" yaml:"multiplier" toml:"multiplier"`
+	Jitter      *float64            `json:"jitter" yaml:"jitter" toml:"jitter"`
+	MaxAttempts int                 `json:"max_attempts" yaml:"max_attempts" toml:"max_attempts"`
+	AfterKick   bool                `json:"after_kick" yaml:"after_kick" toml:"after_kick"`
+}
+
+// Error is an invalid value in a Config.
+type Error struct {
+	// The name of the invalid field, as written in configuration files (e.g.
+	// "reconnect.max_delay").
+	Field string
+	// Where the value came from (a file name, or an environment variable).
+	// Empty if unknown.
+	Source string
+	Reason string
+}
+
+func (e *Error) Error() string {
+	if e.Source != "" {
+		return "botconfig: " + e.Source + ": " + e.Field + ": " + e.Reason
+	}
+	return "botconfig: " + e.Field + ": " + e.Reason
+}
+
+// Load reads a Config from the given file, whose format is chosen
+// by its extension: .yaml or .yml, .toml, or .json. Unknown keys are
+// rejected. Values are then overridden by environment variables (see
+// Config.LoadEnv) with the DefaultEnvPrefix prefix, and validated.
+//
+// If filename is empty, the configuration is only read from the environment.
+func Load(filename string) (*Config, error) {
+	c := &Config{}
+	if filename != "" {
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.decode(filename, data); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.LoadEnv(DefaultEnvPrefix); err != nil {
+		return nil, err
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Config) decode(filename string, data []byte) error {
+	var err error
+	switch ext := strings.ToLower(filepath.Ext(filename)); ext {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(c)
+		if err == io.EOF {
+			err = nil
+		}
+	case ".toml":
+		var meta toml.MetaData
+		meta, err = toml.Decode(string(data), c)
+		if err == nil {
+			if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+				return &Error{Field: undecoded[0].String(), Source: filename, Reason: "unknown key"}
+			}
+		}
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(c)
+	default:
+		return fmt.Errorf("botconfig: %s: unknown configuration format %q (use .yaml, .toml, or .json)", filename, ext)
+	}
+	if err != nil {
+		return fmt.Errorf("botconfig: %s: %v", filename, err)
+	}
+	return nil
+}
+
+// LoadEnv overrides the configuration with the values of the following
+// environment variables, if they are set:
+//  <prefix>SERVER
+//  <prefix>USERNAME
+//  <prefix>PASSWORD
+//  <prefix>TOKENS (comma separated)
+//  <prefix>CERTIFICATE
+//  <prefix>KEY
+//  <prefix>CA_FILE
+//  <prefix>SERVER_NAME
+//  <prefix>INSECURE
+//  <prefix>AUDIO_INTERVAL
+//  <prefix>AUDIO_DATA_BYTES
+func (c *Config) LoadEnv(prefix string) error {
+	strs := []struct {
+		name string
+		v    *string
+	}{
+		{"SERVER", &c.Server},
+		{"USERNAME", &c.Username},
+		{"PASSWORD", &c.Password},
+		{"CERTIFICATE", &c.Certificate},
+		{"KEY", &c.Key},
+		{"CA_FILE", &c.CAFile},
+		{"SERVER_NAME", &c.ServerName},
+	}
+	for _, s := range strs {
+		if value, ok := os.LookupEnv(prefix + s.name); ok {
+			*s.v = value
+		}
+	}
+	if value, ok := os.LookupEnv(prefix + "TOKENS"); ok {
+		c.Tokens = nil
+		for _, token := range strings.Split(value, ",") {
+			if token = strings.TrimSpace(token); token != "" {
+				c.Tokens = append(c.Tokens, token)
+			}
+		}
+	}
+	if value, ok := os.LookupEnv(prefix + "INSECURE"); ok {
+		insecure, err := strconv.ParseBool(value)
+		if err != nil {
+			return &Error{Field: "insecure", Source: prefix + "INSECURE", Reason: fmt.Sprintf("invalid boolean %q (use true or false)", value)}
+		}
+		c.Insecure = insecure
+	}
+	if value, ok := os.LookupEnv(prefix + "AUDIO_INTERVAL"); ok {
+		if err := c.AudioInterval.UnmarshalText([]byte(value)); err != nil {
+			return &Error{Field: "audio_interval", Source: prefix + "AUDIO_INTERVAL", Reason: err.Error()}
+		}
+	}
+	if value, ok := os.LookupEnv(prefix + "AUDIO_DATA_BYTES"); ok {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return &Error{Field: "audio_data_bytes", Source: prefix + "AUDIO_DATA_BYTES", Reason: fmt.Sprintf("invalid integer %q", value)}
+		}
+		c.AudioDataBytes = n
+	}
+	return nil
+}
+
+// Validate checks that the configuration's values are valid. The returned
+// error contains an *Error for each invalid value.
+func (c *Config) Validate() error {
+	var errs []error
+	invalid := func(field, reason string, args ...interface{}) {
+		errs = append(errs, &Error{Field: field, Reason: fmt.Sprintf(reason, args...)})
+	}
+
+	if c.Server == "" {
+		invalid("server", "missing server address")
+	} else if _, port, err := net.SplitHostPort(c.Server); err == nil {
+		if n, err := strconv.Atoi(port); err != nil || n <= 0 || n > 65535 {
+			invalid("server", "invalid port %q", port)
+		}
+	}
+	if c.Username == "" {
+		invalid("username", "missing username")
+	}
+	if c.Key != "" && c.Certificate == "" {
+		invalid("key", "key is set without a certificate")
+	}
+	switch time.Duration(c.AudioInterval) {
+	case 0, 10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond, 60 * time.Millisecond:
+	default:
+		invalid("audio_interval", "%v is not valid (use 10ms, 20ms, 40ms, or 60ms)", time.Duration(c.AudioInterval))
+	}
+	if c.AudioDataBytes < 0 {
+		invalid("audio_data_bytes", "must not be negative")
+	}
+	if r := c.Reconnect; r != nil {
+		if r.MinDelay < 0 {
+			invalid("reconnect.min_delay", "must not be negative")
+		}
+		if r.MaxDelay < 0 {
+			invalid("reconnect.max_delay", "must not be negative")
+		} else if r.MaxDelay != 0 && r.MaxDelay < r.MinDelay {
+			invalid("reconnect.max_delay", "%v is less than min_delay (%v)", time.Duration(r.MaxDelay), time.Duration(r.MinDelay))
+		}
+		if r.Multiplier != 0 && r.Multiplier < 1 {
+			invalid("reconnect.multiplier", "%v is less than 1", r.Multiplier)
+		}
+		if r.Jitter != nil && (*r.Jitter < 0 || *r.Jitter > 1) {
+			invalid("reconnect.jitter", "%v is not between 0 and 1", *r.Jitter)
+		}
+		if r.MaxAttempts < 0 {
+			invalid("reconnect.max_attempts", "must not be negative")
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Address returns the server address, with gumble.DefaultPort added if it
+// does not contain a port.
+func (c *Config) Address() string {
+	if _, _, err := net.SplitHostPort(c.Server); err == nil {
+		return c.Server
+	}
+	return net.JoinHostPort(strings.Trim(c.Server, "[]"), strconv.Itoa(gumble.DefaultPort))
+}
+
+// GumbleConfig returns a new gumble.Config with the configuration's values set.
+func (c *Config) GumbleConfig() *gumble.Config {
+	config := gumble.NewConfig()
+	config.Username = c.Username
+	config.Password = c.Password
+	config.Tokens = gumble.AccessTokens(c.Tokens)
+	if c.AudioInterval != 0 {
+		config.AudioInterval = time.Duration(c.AudioInterval)
+	}
+	if c.AudioDataBytes != 0 {
+		config.AudioDataBytes = c.AudioDataBytes
+	}
+	if r := c.Reconnect; r != nil {
+		policy := gumble.NewReconnectPolicy()
+		if r.MinDelay != 0 {
+			policy.MinDelay = time.Duration(r.MinDelay)
+		}
+		if r.MaxDelay != 0 {
+			policy.MaxDelay = time.Duration(r.MaxDelay)
+		}
+		if r.Multiplier != 0 {
+			policy.Multiplier = r.Multiplier
+		}
+		if r.Jitter != nil {
+			policy.Jitter = *r.Jitter
+		}
+		policy.MaxAttempts = r.MaxAttempts
+		policy.AfterKick = r.AfterKick
+		config.ReconnectPolicy = policy
+	}
+	return config
+}
+
+// TLSConfig returns a new tls.Config with the configuration's certificates and
+// server verification settings.
+func (c *Config) TLSConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.Insecure,
+	}
+	if c.Certificate != "" {
+		key := c.Key
+		if key == "" {
+			key = c.Certificate
+		}
+		cert, err := tls.LoadX509KeyPair(c.Certificate, key)
+		if err != nil {
+			return nil, &Error{Field: "certificate", Reason: err.Error()}
+		}
+		tlsConfig.Certificates = append(tlsConfig.Certificates, cert)
+	}
+	if c.CAFile != "" {
+		data, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, &Error{Field: "ca_file", Reason: err.Error()}
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, &Error{Field: "ca_file", Reason: "no certificates found in " + c.CAFile}
+		}
+		tlsConfig.RootCAs = pool
+	}
+	return tlsConfig, nil
+}
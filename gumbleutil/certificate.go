@@ -0,0 +1,158 @@
+package gumbleutil
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"time"
+)
+
+// CertificateValidity is how long certificates created by GenerateCertificate
+// and GenerateRSACertificate are valid for.
+const CertificateValidity = 20 * 365 * 24 * time.Hour
+
+// GenerateCertificate creates a self-signed certificate, with an ECDSA P-256
+// key, that can be used as a client's identity (i.e. in
+// tls.Config.Certificates). It returns the certificate and its hash (see
+// CertificateHash).
+func GenerateCertificate(name string) (tls.Certificate, string, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, "", err
+	}
+	return generateCertificate(name, key)
+}
+
+// GenerateRSACertificate is like GenerateCertificate, but creates an RSA key
+// of the given size. Older Mumble servers only support RSA certificates.
+func GenerateRSACertificate(name string, bits int) (tls.Certificate, string, error) {
+	key, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		return tls.Certificate{}, "", err
+	}
+	return generateCertificate(name, key)
+}
+
+func generateCertificate(name string, key crypto.Signer) (tls.Certificate, string, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, "", err
+	}
+	now := time.Now()
+	template := x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			CommonName: name,
+		},
+		NotBefore:   now.Add(-time.Hour),
+		NotAfter:    now.Add(CertificateValidity),
+		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, key.Public(), key)
+	if err != nil {
+		return tls.Certificate{}, "", err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, "", err
+	}
+	cert := tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}
+	return cert, CertificateHash(cert), nil
+}
+
+// CertificateHash returns the hash of the certificate, as computed by the
+// server: the hex encoded SHA-1 hash of the leaf certificate. This is the
+// value of User.Hash and Ban.Hash, and what servers use to identify
+// registered users.
+//
+// The empty string is returned if cert does not contain a certificate.
+func CertificateHash(cert tls.Certificate) string {
+	if len(cert.Certificate) == 0 {
+		return ""
+	}
+	hash := sha1.Sum(cert.Certificate[0])
+	return hex.EncodeToString(hash[:])
+}
+
+// EncodeCertificate returns the PEM encoding of the certificate chain followed
+// by the certificate's private key.
+func EncodeCertificate(cert tls.Certificate) ([]byte, error) {
+	if len(cert.Certificate) == 0 {
+		return nil, errors.New("gumbleutil: missing certificate")
+	}
+	key, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	var data []byte
+	for _, der := range cert.Certificate {
+		data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	}
+	data = append(data, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: key})...)
+	return data, nil
+}
+
+// SaveCertificate writes the certificate and its private key to the given
+// file, in the format returned by EncodeCertificate. The file is only
+// readable by its owner.
+func SaveCertificate(filename string, cert tls.Certificate) error {
+	data, err := EncodeCertificate(cert)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, data, 0600)
+}
+
+// LoadCertificate reads a certificate and its private key from the given PEM
+// file, such as one written by SaveCertificate.
+func LoadCertificate(filename string) (tls.Certificate, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	cert, err := tls.X509KeyPair(data, data)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	if cert.Leaf == nil {
+		cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			return tls.Certificate{}, err
+		}
+	}
+	return cert, nil
+}
+
+// LoadOrGenerateCertificate loads the certificate in the given file. If the
+// file does not exist, a new certificate is generated using
+// GenerateCertificate and saved to the file.
+func LoadOrGenerateCertificate(filename, name string) (tls.Certificate, error) {
+	cert, err := LoadCertificate(filename)
+	if !errors.Is(err, os.ErrNotExist) {
+		return cert, err
+	}
+	cert, _, err = GenerateCertificate(name)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	if err := SaveCertificate(filename, cert); err != nil {
+		return tls.Certificate{}, err
+	}
+	return cert, nil
+}
@@ -0,0 +1,87 @@
+package gumbleutil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGenerateCertificate(t *testing.T) {
+	tests := []struct {
+		name     string
+		generate func() (tls.Certificate, string, error)
+	}{
+		{"ECDSA", func() (tls.Certificate, string, error) {
+			return GenerateCertificate("gumble")
+		}},
+		{"RSA", func() (tls.Certificate, string, error) {
+			return GenerateRSACertificate("gumble", 2048)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert, hash, err := tt.generate()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cert.Leaf == nil || cert.Leaf.Subject.CommonName != "gumble" {
+				t.Fatalf("Leaf = %v; want a certificate for gumble", cert.Leaf)
+			}
+			if cert.Leaf.ExtKeyUsage[0] != x509.ExtKeyUsageClientAuth {
+				t.Errorf("ExtKeyUsage = %v; want client authentication", cert.Leaf.ExtKeyUsage)
+			}
+			if want := CertificateHash(cert); hash != want || len(hash) != 40 {
+				t.Errorf("hash = %q; want %q", hash, want)
+			}
+
+			// The certificate must survive a round trip through a file.
+			filename := filepath.Join(t.TempDir(), "cert.pem")
+			if err := SaveCertificate(filename, cert); err != nil {
+				t.Fatal(err)
+			}
+			if info, err := os.Stat(filename); err != nil || info.Mode().Perm() != 0600 {
+				t.Errorf("file mode = %v, %v; want 0600", info.Mode().Perm(), err)
+			}
+			loaded, err := LoadCertificate(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if CertificateHash(loaded) != hash || loaded.Leaf == nil {
+				t.Errorf("loaded certificate hash = %q; want %q", CertificateHash(loaded), hash)
+			}
+		})
+	}
+}
+
+func TestLoadOrGenerateCertificate(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "cert.pem")
+	generated, err := LoadOrGenerateCertificate(filename, "gumble")
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadOrGenerateCertificate(filename, "gumble")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if CertificateHash(loaded) != CertificateHash(generated) {
+		t.Error("the saved certificate was not loaded")
+	}
+
+	if err := os.WriteFile(filename, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadOrGenerateCertificate(filename, "gumble"); err == nil {
+		t.Error("LoadOrGenerateCertificate replaced an invalid file; want an error")
+	}
+}
+
+func TestCertificateHashEmpty(t *testing.T) {
+	if hash := CertificateHash(tls.Certificate{}); hash != "" {
+		t.Errorf("CertificateHash of an empty certificate = %q; want \"\"", hash)
+	}
+	if _, err := EncodeCertificate(tls.Certificate{}); err == nil {
+		t.Error("EncodeCertificate of an empty certificate succeeded; want an error")
+	}
+}
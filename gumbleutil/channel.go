@@ -1,6 +1,10 @@
 package gumbleutil
 
 import (
+	"regexp"
+	"sort"
+	"strings"
+
 	"layeh.com/gumble/gumble"
 )
 
@@ -16,3 +20,120 @@ func ChannelPath(channel *gumble.Channel) []string {
 	}
 	return pieces
 }
+
+// ChannelPathString returns the full path of the given channel, starting with
+// the root channel (e.g. "/Root/Games/FPS").
+func ChannelPathString(channel *gumble.Channel) string {
+	return "/" + strings.Join(ChannelPath(channel), "/")
+}
+
+// FindChannelByPath returns the channel at the given "/"-separated path, or
+// nil if the channel does not exist. Channel names are matched
+// case-insensitively.
+//
+// A path that starts with "/" is absolute, and its first element must be the
+// name of the root channel (i.e. the format returned by ChannelPathString).
+// Other paths are relative to the root channel (e.g. "Games/FPS").
+func FindChannelByPath(client *gumble.Client, path string) *gumble.Channel {
+	channel := client.Channels[0]
+	if channel == nil {
+		return nil
+	}
+	if strings.HasPrefix(path, "/") {
+		path = path[1:]
+		var root string
+		if i := strings.IndexByte(path, '/'); i >= 0 {
+			root, path = path[:i], path[i+1:]
+		} else {
+			root, path = path, ""
+		}
+		if !strings.EqualFold(root, channel.Name) {
+			return nil
+		}
+	}
+	for _, name := range strings.Split(path, "/") {
+		if name == "" {
+			continue
+		}
+		if channel = channel.FindFold(name); channel == nil {
+			return nil
+		}
+	}
+	return channel
+}
+
+// MatchChannels returns the channels whose names best match query, as typed
+// by a user (e.g. in a chat command), sorted by ID. The first of the
+// following that matches any channel is used:
+//
+//  1. names equal to query
+//  2. names equal to query, ignoring case
+//  3. names that start with query, ignoring case
+//  4. names that contain query, ignoring case
+//
+// Callers usually act on the result if it contains one channel, and ask the
+// user to be more specific if it contains several.
+func MatchChannels(channels gumble.Channels, query string) []*gumble.Channel {
+	if query == "" {
+		return nil
+	}
+	var best []*gumble.Channel
+	bestRank := matchNone
+	for _, channel := range channels {
+		rank := matchName(channel.Name, query)
+		if rank < bestRank {
+			best, bestRank = best[:0], rank
+		}
+		if rank == bestRank && rank != matchNone {
+			best = append(best, channel)
+		}
+	}
+	sortChannels(best)
+	return best
+}
+
+// How closely a name matches a query, from best to worst. See MatchChannels.
+const (
+	matchEqual = iota
+	matchEqualFold
+	matchPrefix
+	matchContains
+	matchNone
+)
+
+// matchName returns how closely name matches query.
+func matchName(name, query string) int {
+	switch {
+	case name == query:
+		return matchEqual
+	case strings.EqualFold(name, query):
+		return matchEqualFold
+	}
+	name, query = strings.ToLower(name), strings.ToLower(query)
+	switch {
+	case strings.HasPrefix(name, query):
+		return matchPrefix
+	case strings.Contains(name, query):
+		return matchContains
+	}
+	return matchNone
+}
+
+// MatchChannelsRegexp returns the channels whose names match re, sorted by
+// ID.
+func MatchChannelsRegexp(channels gumble.Channels, re *regexp.Regexp) []*gumble.Channel {
+	var matches []*gumble.Channel
+	for _, channel := range channels {
+		if re.MatchString(channel.Name) {
+			matches = append(matches, channel)
+		}
+	}
+	sortChannels(matches)
+	return matches
+}
+
+func sortChannels(channels []*gumble.Channel) {
+	sort.Slice(channels, func(i, j int) bool {
+		return channels[i].ID < channels[j].ID
+	})
+}
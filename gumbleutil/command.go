@@ -0,0 +1,404 @@
+package gumbleutil
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"layeh.com/gumble/gumble"
+)
+
+// Command is a text command that can be run by users through a CommandRouter.
+type Command struct {
+	// The name that is used to run the command (e.g. "play" for "!play").
+	Name string
+	// Alternative names for the command.
+	Aliases []string
+	// A description of the command's arguments (e.g. "<url> [volume]"),
+	// shown in help messages.
+	Usage string
+	// A short description of the command, shown in help messages.
+	Description string
+
+	// If non-nil, only the users with the given names can run the command.
+	Users []string
+	// If non-nil, only registered users that are members of one of the given
+	// ACL groups, in the channel they are in, can run the command. The client
+	// must have permission to view the channel's ACL.
+	Groups []string
+	// If non-zero, the minimum time between runs of the command by the same
+	// user.
+	Cooldown time.Duration
+
+	// The function that is called when the command is run.
+	Handler func(ctx *CommandContext)
+}
+
+// CommandContext holds the details of a command that is being run.
+type CommandContext struct {
+	Client  *gumble.Client
+	Event   *gumble.TextMessageEvent
+	Sender  *gumble.User
+	Command *Command
+	// The name that was used to run the command.
+	Name string
+	// The arguments that followed the command name.
+	Args []string
+}
+
+// Reply sends a message to where the command was received from: the channel,
+// if the command was sent to a channel, the channel tree, if it was sent to a
+// tree, or otherwise the sender.
+func (ctx *CommandContext) Reply(message string) error {
+	if len(ctx.Event.Channels) > 0 {
+		return ctx.Event.Channels[0].Send(message, false)
+	}
+	if len(ctx.Event.Trees) > 0 {
+		return ctx.Event.Trees[0].Send(message, true)
+	}
+	return ctx.Sender.Send(message)
+}
+
+// ReplyLong is like Reply, but splits message into multiple messages if it is
+// longer than the server's message length limit (see SendLongMessage).
+func (ctx *CommandContext) ReplyLong(message string) error {
+	return sendLongMessage(ctx.Client, ctx.Reply, message)
+}
+
+// CooldownError is returned when a user runs a command before its cooldown
+// has passed.
+type CooldownError struct {
+	Remaining time.Duration
+}
+
+func (e *CooldownError) Error() string {
+	return fmt.Sprintf("please wait %s before using this command again", e.Remaining.Round(time.Second))
+}
+
+var (
+	// ErrCommandDenied is returned when a user does not have permission to run
+	// a command.
+	ErrCommandDenied = errors.New("gumbleutil: permission denied")
+	// ErrUnterminatedQuote is returned by SplitArgs when a quoted argument is
+	// not closed.
+	ErrUnterminatedQuote = errors.New("gumbleutil: unterminated quote")
+)
+
+// CommandRouter runs Commands in response to text messages that start with a
+// prefix. It must be attached to a gumble.Config using its Listener method:
+//  router := gumbleutil.NewCommandRouter("!")
+//  router.Add(&gumbleutil.Command{
+//    Name:        "ping",
+//    Description: "checks if the bot is alive",
+//    Handler: func(ctx *gumbleutil.CommandContext) {
+//      ctx.Reply("pong")
+//    },
+//  })
+//  config.Attach(router.Listener())
+type CommandRouter struct {
+	// The prefix that messages must start with to be treated as commands.
+	Prefix string
+	// The name of the automatically generated help command. If empty, no help
+	// command is added.
+	HelpCommand string
+	// Called when a command cannot be run. If nil, err is sent as a reply.
+	OnError func(ctx *CommandContext, err error)
+
+	mu       sync.Mutex
+	commands map[string]*Command
+	lastRun  map[cooldownKey]time.Time
+	acls     map[*gumble.Channel]*gumble.ACL
+}
+
+type cooldownKey struct {
+	command *Command
+	user    string
+}
+
+// NewCommandRouter returns a new CommandRouter with the given prefix, and with
+// a "help" command.
+func NewCommandRouter(prefix string) *CommandRouter {
+	return &CommandRouter{
+		Prefix:      prefix,
+		HelpCommand: "help",
+	}
+}
+
+// Add adds a command to the router, replacing any existing command with the
+// same name or alias.
+func (r *CommandRouter) Add(command *Command) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.commands == nil {
+		r.commands = make(map[string]*Command)
+	}
+	r.commands[strings.ToLower(command.Name)] = command
+	for _, alias := range command.Aliases {
+		r.commands[strings.ToLower(alias)] = command
+	}
+}
+
+// Remove removes the command with the given name, along with its aliases.
+func (r *CommandRouter) Remove(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	command := r.commands[strings.ToLower(name)]
+	if command == nil {
+		return
+	}
+	for key, c := range r.commands {
+		if c == command {
+			delete(r.commands, key)
+		}
+	}
+}
+
+// Commands returns the router's commands, sorted by name.
+func (r *CommandRouter) Commands() []*Command {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	seen := make(map[*Command]bool)
+	var commands []*Command
+	for _, command := range r.commands {
+		if !seen[command] {
+			seen[command] = true
+			commands = append(commands, command)
+		}
+	}
+	sort.Slice(commands, func(i, j int) bool {
+		return commands[i].Name < commands[j].Name
+	})
+	return commands
+}
+
+// Help returns an HTML help message that lists the router's commands.
+func (r *CommandRouter) Help() string {
+	var b strings.Builder
+	b.WriteString("<b>Commands:</b>")
+	for _, command := range r.Commands() {
+		b.WriteString("<br />")
+		b.WriteString(r.usage(command))
+		if command.Description != "" {
+			b.WriteString(" - ")
+			b.WriteString(escapeHTML(command.Description))
+		}
+	}
+	return b.String()
+}
+
+func (r *CommandRouter) usage(command *Command) string {
+	usage := "<b>" + escapeHTML(r.Prefix+command.Name) + "</b>"
+	if command.Usage != "" {
+		usage += " " + escapeHTML(command.Usage)
+	}
+	return usage
+}
+
+// Listener returns a gumble.EventListener that runs the router's commands.
+func (r *CommandRouter) Listener() gumble.EventListener {
+	return Listener{
+		TextMessage: r.handleTextMessage,
+		ACL:         r.handleACL,
+	}
+}
+
+func (r *CommandRouter) handleACL(e *gumble.ACLEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.acls == nil {
+		r.acls = make(map[*gumble.Channel]*gumble.ACL)
+	}
+	r.acls[e.ACL.Channel] = e.ACL
+}
+
+func (r *CommandRouter) handleTextMessage(e *gumble.TextMessageEvent) {
+	if e.Sender == nil || r.Prefix == "" {
+		return
+	}
+	text := strings.TrimSpace(PlainText(&e.TextMessage))
+	if !strings.HasPrefix(text, r.Prefix) {
+		return
+	}
+	ctx := &CommandContext{
+		Client: e.Client,
+		Event:  e,
+		Sender: e.Sender,
+	}
+	args, err := SplitArgs(text[len(r.Prefix):])
+	if err != nil {
+		r.error(ctx, err)
+		return
+	}
+	if len(args) == 0 {
+		return
+	}
+	ctx.Name, ctx.Args = args[0], args[1:]
+
+	if r.HelpCommand != "" && strings.EqualFold(ctx.Name, r.HelpCommand) {
+		r.help(ctx)
+		return
+	}
+
+	r.mu.Lock()
+	ctx.Command = r.commands[strings.ToLower(ctx.Name)]
+	r.mu.Unlock()
+	if ctx.Command == nil || ctx.Command.Handler == nil {
+		return
+	}
+	if err := r.check(ctx); err != nil {
+		r.error(ctx, err)
+		return
+	}
+	ctx.Command.Handler(ctx)
+}
+
+func (r *CommandRouter) help(ctx *CommandContext) {
+	if len(ctx.Args) > 0 {
+		r.mu.Lock()
+		command := r.commands[strings.ToLower(ctx.Args[0])]
+		r.mu.Unlock()
+		if command != nil {
+			message := r.usage(command)
+			if command.Description != "" {
+				message += "<br />" + escapeHTML(command.Description)
+			}
+			ctx.ReplyLong(message)
+			return
+		}
+	}
+	ctx.ReplyLong(r.Help())
+}
+
+// check returns an error if the command cannot be run by the sender.
+func (r *CommandRouter) check(ctx *CommandContext) error {
+	command := ctx.Command
+	if command.Users != nil && !contains(command.Users, ctx.Sender.Name) {
+		return ErrCommandDenied
+	}
+	if command.Groups != nil && !r.inGroup(ctx, command.Groups) {
+		return ErrCommandDenied
+	}
+	if command.Cooldown > 0 {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.lastRun == nil {
+			r.lastRun = make(map[cooldownKey]time.Time)
+		}
+		key := cooldownKey{
+			command: command,
+			user:    ctx.Sender.Name,
+		}
+		now := time.Now()
+		if last, ok := r.lastRun[key]; ok {
+			if elapsed := now.Sub(last); elapsed < command.Cooldown {
+				return &CooldownError{
+					Remaining: command.Cooldown - elapsed,
+				}
+			}
+		}
+		r.lastRun[key] = now
+	}
+	return nil
+}
+
+// inGroup returns true if the sender is a member of one of the given groups in
+// the ACL of their current channel. If the ACL has not been received, it is
+// requested and false is returned.
+func (r *CommandRouter) inGroup(ctx *CommandContext, groups []string) bool {
+	user := ctx.Sender
+	var channel *gumble.Channel
+	ctx.Client.Do(func() {
+		channel = user.Channel
+	})
+	if !user.IsRegistered() || channel == nil {
+		return false
+	}
+	r.mu.Lock()
+	acl := r.acls[channel]
+	r.mu.Unlock()
+	if acl == nil {
+		channel.RequestACL()
+		return false
+	}
+	for _, group := range acl.Groups {
+		if !contains(groups, group.Name) {
+			continue
+		}
+		if group.UsersRemove[user.UserID] != nil {
+			continue
+		}
+		if group.UsersAdd[user.UserID] != nil || group.UsersInherited[user.UserID] != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *CommandRouter) error(ctx *CommandContext, err error) {
+	if r.OnError != nil {
+		r.OnError(ctx, err)
+		return
+	}
+	ctx.Reply(escapeHTML(err.Error()))
+}
+
+// SplitArgs splits s into space-separated arguments. Arguments can be quoted
+// with single or double quotes to include spaces, and a backslash escapes the
+// character that follows it.
+func SplitArgs(s string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inArg := false
+	var quote rune
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+			inArg = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, ErrUnterminatedQuote
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+var htmlEscaper = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	`"`, "&quot;",
+)
+
+func escapeHTML(s string) string {
+	return htmlEscaper.Replace(s)
+}
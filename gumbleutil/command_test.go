@@ -0,0 +1,35 @@
+package gumbleutil
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		s    string
+		want []string
+		err  error
+	}{
+		{"", nil, nil},
+		{"   ", nil, nil},
+		{"one", []string{"one"}, nil},
+		{"one two  three", []string{"one", "two", "three"}, nil},
+		{" \tone\ntwo ", []string{"one", "two"}, nil},
+		{`"one two" three`, []string{"one two", "three"}, nil},
+		{`'one "two"' three`, []string{`one "two"`, "three"}, nil},
+		{`one" two "three`, []string{"one two three"}, nil},
+		{`""`, []string{""}, nil},
+		{`one\ two`, []string{"one two"}, nil},
+		{`"one \" two"`, []string{`one " two`}, nil},
+		{`\\`, []string{`\`}, nil},
+		{`"one two`, nil, ErrUnterminatedQuote},
+		{`'one`, nil, ErrUnterminatedQuote},
+	}
+	for _, tt := range tests {
+		got, err := SplitArgs(tt.s)
+		if err != tt.err || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitArgs(%q) = %q, %v; want %q, %v", tt.s, got, err, tt.want, tt.err)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package gumbleutil
+
+import (
+	"fmt"
+	"time"
+)
+
+// Duration is a time.Duration that is written in configuration files and
+// status reports as a string (e.g. "20ms", "1m30s").
+type Duration time.Duration
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return fmt.Errorf("invalid duration %q (e.g. \"20ms\", \"1m30s\")", text)
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
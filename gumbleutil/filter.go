@@ -0,0 +1,171 @@
+package gumbleutil
+
+import (
+	"strings"
+
+	"layeh.com/gumble/gumble"
+)
+
+// Filter reports whether an event should be passed to a listener.
+type Filter func(e gumble.Event) bool
+
+// Filtered returns a gumble.EventListener that passes events to listener only
+// if every filter returns true for the event.
+//
+// Example:
+//  client.Attach(gumbleutil.Filtered(gumbleutil.Listener{
+//    TextMessage: func(e *gumble.TextMessageEvent) {
+//      // handle !help
+//    },
+//  }, gumbleutil.ByMessagePrefix("!help")))
+func Filtered(listener gumble.EventListener, filters ...Filter) gumble.EventListener {
+	return ListenerFunc(func(e interface{}) {
+		event := e.(gumble.Event)
+		for _, filter := range filters {
+			if !filter(event) {
+				return
+			}
+		}
+		dispatch(listener, event)
+	})
+}
+
+// ByUser returns a Filter that matches events associated with a user that has
+// one of the given names. The user of a TextMessageEvent is its sender.
+// Events that are not associated with a user do not match.
+func ByUser(names ...string) Filter {
+	return func(e gumble.Event) bool {
+		var user *gumble.User
+		switch e := e.(type) {
+		case *gumble.TextMessageEvent:
+			user = e.Sender
+		case *gumble.UserChangeEvent:
+			user = e.User
+		case *gumble.PermissionDeniedEvent:
+			user = e.User
+		}
+		return user != nil && contains(names, user.Name)
+	}
+}
+
+// ByChannel returns a Filter that matches events associated with a channel
+// that has one of the given names. A TextMessageEvent matches if it was sent to
+// one of the channels, and a UserChangeEvent matches if the user is in one of
+// the channels. Events that are not associated with a channel do not match.
+func ByChannel(names ...string) Filter {
+	return func(e gumble.Event) bool {
+		switch e := e.(type) {
+		case *gumble.TextMessageEvent:
+			for _, channel := range e.Channels {
+				if contains(names, channel.Name) {
+					return true
+				}
+			}
+			for _, channel := range e.Trees {
+				if contains(names, channel.Name) {
+					return true
+				}
+			}
+		case *gumble.UserChangeEvent:
+			return e.User != nil && e.User.Channel != nil && contains(names, e.User.Channel.Name)
+		case *gumble.ChannelChangeEvent:
+			return e.Channel != nil && contains(names, e.Channel.Name)
+		case *gumble.PermissionDeniedEvent:
+			return e.Channel != nil && contains(names, e.Channel.Name)
+		}
+		return false
+	}
+}
+
+// ByMessagePrefix returns a Filter that matches TextMessageEvents whose plain
+// text message starts with prefix. Other events do not match.
+func ByMessagePrefix(prefix string) Filter {
+	return func(e gumble.Event) bool {
+		tm, ok := e.(*gumble.TextMessageEvent)
+		if !ok {
+			return false
+		}
+		return strings.HasPrefix(PlainText(&tm.TextMessage), prefix)
+	}
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// dispatch calls the method of listener that corresponds to the type of e.
+func dispatch(listener gumble.EventListener, e gumble.Event) {
+	switch e := e.(type) {
+	case *gumble.ConnectEvent:
+		listener.OnConnect(e)
+	case *gumble.DisconnectEvent:
+		listener.OnDisconnect(e)
+	case *gumble.TextMessageEvent:
+		listener.OnTextMessage(e)
+	case *gumble.UserChangeEvent:
+		listener.OnUserChange(e)
+	case *gumble.ChannelChangeEvent:
+		listener.OnChannelChange(e)
+	case *gumble.PermissionDeniedEvent:
+		listener.OnPermissionDenied(e)
+	case *gumble.UserListEvent:
+		listener.OnUserList(e)
+	case *gumble.ACLEvent:
+		listener.OnACL(e)
+	case *gumble.BanListEvent:
+		listener.OnBanList(e)
+	case *gumble.ContextActionChangeEvent:
+		listener.OnContextActionChange(e)
+	case *gumble.ServerConfigEvent:
+		listener.OnServerConfig(e)
+	case *gumble.ListenerErrorEvent:
+		if l, ok := listener.(gumble.ListenerErrorListener); ok {
+			l.OnListenerError(e)
+		}
+	case *gumble.PingUpdatedEvent:
+		if l, ok := listener.(gumble.PingUpdatedListener); ok {
+			l.OnPingUpdated(e)
+		}
+	case *gumble.PermissionQueryEvent:
+		if l, ok := listener.(gumble.PermissionQueryListener); ok {
+			l.OnPermissionQuery(e)
+		}
+	case *gumble.QueryUsersEvent:
+		if l, ok := listener.(gumble.QueryUsersListener); ok {
+			l.OnQueryUsers(e)
+		}
+	case *gumble.ChannelTreeChangeEvent:
+		if l, ok := listener.(gumble.ChannelTreeChangeListener); ok {
+			l.OnChannelTreeChange(e)
+		}
+	case *gumble.ProtocolErrorEvent:
+		if l, ok := listener.(gumble.ProtocolErrorListener); ok {
+			l.OnProtocolError(e)
+		}
+	case *gumble.SlowListenerEvent:
+		if l, ok := listener.(gumble.SlowListenerListener); ok {
+			l.OnSlowListener(e)
+		}
+	case *gumble.WriteErrorEvent:
+		if l, ok := listener.(gumble.WriteErrorListener); ok {
+			l.OnWriteError(e)
+		}
+	case *gumble.AudioBandwidthEvent:
+		if l, ok := listener.(gumble.AudioBandwidthListener); ok {
+			l.OnAudioBandwidth(e)
+		}
+	case *gumble.ContextActionEvent:
+		if l, ok := listener.(gumble.ContextActionListener); ok {
+			l.OnContextAction(e)
+		}
+	case *gumble.ServerVersionEvent:
+		if l, ok := listener.(gumble.ServerVersionListener); ok {
+			l.OnServerVersion(e)
+		}
+	}
+}
@@ -0,0 +1,325 @@
+package gumbleutil
+
+import (
+	"html"
+	"strings"
+)
+
+// htmlToken is a piece of an HTML document: either text or a tag.
+type htmlToken struct {
+	// Raw text, with entities not yet decoded. Empty for tags.
+	text string
+
+	// Lowercase tag name.
+	tag         string
+	closing     bool
+	selfClosing bool
+	attrs       []htmlAttr
+	// The original source of the tag.
+	raw string
+}
+
+type htmlAttr struct {
+	name, value string
+}
+
+func (t *htmlToken) attr(name string) (string, bool) {
+	for _, a := range t.attrs {
+		if a.name == name {
+			return a.value, true
+		}
+	}
+	return "", false
+}
+
+// tokenizeHTML splits s into text and tags. It is lenient: text that cannot be
+// parsed as a tag (e.g. "a < b") is returned as text. Comments, doctypes, and
+// processing instructions are dropped.
+func tokenizeHTML(s string) []htmlToken {
+	var tokens []htmlToken
+	text := 0
+	flush := func(end int) {
+		if end > text {
+			tokens = append(tokens, htmlToken{text: s[text:end]})
+		}
+	}
+	for i := 0; i < len(s); {
+		if s[i] != '<' {
+			i++
+			continue
+		}
+		if strings.HasPrefix(s[i:], "<!--") {
+			flush(i)
+			end := strings.Index(s[i+4:], "-->")
+			if end < 0 {
+				i = len(s)
+			} else {
+				i += 4 + end + 3
+			}
+			text = i
+			continue
+		}
+		if i+1 < len(s) && (s[i+1] == '!' || s[i+1] == '?') {
+			flush(i)
+			end := strings.IndexByte(s[i:], '>')
+			if end < 0 {
+				i = len(s)
+			} else {
+				i += end + 1
+			}
+			text = i
+			continue
+		}
+		token, n := parseTag(s[i:])
+		if n == 0 {
+			i++
+			continue
+		}
+		flush(i)
+		tokens = append(tokens, token)
+		i += n
+		text = i
+	}
+	flush(len(s))
+	return tokens
+}
+
+// parseTag parses the tag at the start of s. It returns the number of bytes
+// consumed, or zero if s does not start with a tag.
+func parseTag(s string) (htmlToken, int) {
+	var t htmlToken
+	i := 1
+	if i < len(s) && s[i] == '/' {
+		t.closing = true
+		i++
+	}
+	start := i
+	for i < len(s) && isTagNameByte(s[i]) {
+		i++
+	}
+	if i == start || !isLetter(s[start]) {
+		return t, 0
+	}
+	t.tag = strings.ToLower(s[start:i])
+
+	for {
+		for i < len(s) && isSpace(s[i]) {
+			i++
+		}
+		if i >= len(s) {
+			return t, 0
+		}
+		switch s[i] {
+		case '>':
+			t.raw = s[:i+1]
+			return t, i + 1
+		case '/':
+			t.selfClosing = true
+			i++
+			continue
+		}
+		start := i
+		for i < len(s) && !isSpace(s[i]) && s[i] != '=' && s[i] != '>' && s[i] != '/' {
+			i++
+		}
+		attr := htmlAttr{
+			name: strings.ToLower(s[start:i]),
+		}
+		for i < len(s) && isSpace(s[i]) {
+			i++
+		}
+		if i < len(s) && s[i] == '=' {
+			i++
+			for i < len(s) && isSpace(s[i]) {
+				i++
+			}
+			if i >= len(s) {
+				return t, 0
+			}
+			if q := s[i]; q == '"' || q == '\'' {
+				end := strings.IndexByte(s[i+1:], q)
+				if end < 0 {
+					return t, 0
+				}
+				attr.value = s[i+1 : i+1+end]
+				i += end + 2
+			} else {
+				start := i
+				for i < len(s) && !isSpace(s[i]) && s[i] != '>' {
+					i++
+				}
+				attr.value = s[start:i]
+			}
+			attr.value = html.UnescapeString(attr.value)
+		}
+		if attr.name != "" {
+			t.attrs = append(t.attrs, attr)
+		}
+	}
+}
+
+func isLetter(b byte) bool {
+	return (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
+
+func isTagNameByte(b byte) bool {
+	return isLetter(b) || (b >= '0' && b <= '9')
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r' || b == '\f'
+}
+
+// htmlVoidTags are tags that never have content or a closing tag.
+var htmlVoidTags = map[string]bool{
+	"br":   true,
+	"hr":   true,
+	"img":  true,
+	"meta": true,
+	"link": true,
+}
+
+// htmlBlockTags are tags that start on a new line when converted to plain
+// text.
+var htmlBlockTags = map[string]bool{
+	"address": true, "article": true, "aside": true, "audio": true,
+	"blockquote": true, "canvas": true, "dd": true, "div": true, "dl": true,
+	"fieldset": true, "figcaption": true, "figure": true, "footer": true,
+	"form": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true,
+	"h6": true, "header": true, "hgroup": true, "hr": true, "li": true,
+	"noscript": true, "ol": true, "output": true, "p": true, "pre": true,
+	"section": true, "table": true, "tfoot": true, "tr": true, "ul": true,
+	"video": true,
+}
+
+// htmlHiddenTags are tags whose content is not displayed.
+var htmlHiddenTags = map[string]bool{
+	"head":   true,
+	"script": true,
+	"style":  true,
+	"title":  true,
+}
+
+// sanitizeTags lists the tags, and their attributes, that are kept by
+// Sanitize. These are the tags that Mumble clients display.
+var sanitizeTags = map[string][]string{
+	"a":          {"href"},
+	"b":          nil,
+	"blockquote": nil,
+	"br":         nil,
+	"code":       nil,
+	"del":        nil,
+	"div":        nil,
+	"em":         nil,
+	"font":       {"color"},
+	"h1":         nil,
+	"h2":         nil,
+	"h3":         nil,
+	"h4":         nil,
+	"h5":         nil,
+	"h6":         nil,
+	"hr":         nil,
+	"i":          nil,
+	"img":        {"src", "alt", "width", "height"},
+	"li":         nil,
+	"ol":         nil,
+	"p":          nil,
+	"pre":        nil,
+	"s":          nil,
+	"span":       nil,
+	"strike":     nil,
+	"strong":     nil,
+	"sub":        nil,
+	"sup":        nil,
+	"table":      nil,
+	"tbody":      nil,
+	"td":         nil,
+	"th":         nil,
+	"thead":      nil,
+	"tr":         nil,
+	"tt":         nil,
+	"u":          nil,
+	"ul":         nil,
+}
+
+// Sanitize returns s with all HTML removed, except for the tags and
+// attributes that Mumble clients display (e.g. b, i, a, img, lists, and
+// tables). Removed tags are dropped, but their content is kept; the content of
+// script and style tags is dropped. Links must use the http, https, ftp, or
+// mailto schemes, and images must use data URLs. Tags that are left open are
+// closed at the end of the string.
+func Sanitize(s string) string {
+	var b strings.Builder
+	var open []string
+	hidden := 0
+	for _, t := range tokenizeHTML(s) {
+		if t.tag == "" {
+			if hidden == 0 {
+				b.WriteString(html.EscapeString(html.UnescapeString(t.text)))
+			}
+			continue
+		}
+		if htmlHiddenTags[t.tag] {
+			if t.closing {
+				if hidden > 0 {
+					hidden--
+				}
+			} else if !t.selfClosing {
+				hidden++
+			}
+			continue
+		}
+		if hidden > 0 {
+			continue
+		}
+		allowed, ok := sanitizeTags[t.tag]
+		if !ok {
+			continue
+		}
+		if t.closing {
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i] == t.tag {
+					for j := len(open) - 1; j >= i; j-- {
+						b.WriteString("</" + open[j] + ">")
+					}
+					open = open[:i]
+					break
+				}
+			}
+			continue
+		}
+		b.WriteString("<" + t.tag)
+		for _, attr := range t.attrs {
+			if !contains(allowed, attr.name) || !safeAttr(t.tag, attr) {
+				continue
+			}
+			b.WriteString(" " + attr.name + `="` + html.EscapeString(attr.value) + `"`)
+		}
+		if htmlVoidTags[t.tag] {
+			b.WriteString(" />")
+			continue
+		}
+		b.WriteString(">")
+		open = append(open, t.tag)
+	}
+	for i := len(open) - 1; i >= 0; i-- {
+		b.WriteString("</" + open[i] + ">")
+	}
+	return b.String()
+}
+
+func safeAttr(tag string, attr htmlAttr) bool {
+	value := strings.ToLower(strings.TrimSpace(attr.value))
+	switch {
+	case tag == "a" && attr.name == "href":
+		for _, scheme := range []string{"http:", "https:", "ftp:", "mailto:"} {
+			if strings.HasPrefix(value, scheme) {
+				return true
+			}
+		}
+		return false
+	case tag == "img" && attr.name == "src":
+		return strings.HasPrefix(value, "data:image/")
+	}
+	return true
+}
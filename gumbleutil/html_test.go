@@ -0,0 +1,43 @@
+package gumbleutil
+
+import (
+	"testing"
+)
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{"plain & text", "plain &amp; text"},
+		{"a < b", "a &lt; b"},
+		{"<p>a &amp;&lt; b</p>", "<p>a &amp;&lt; b</p>"},
+		{"<b>bold</b>", "<b>bold</b>"},
+		{"<B>upper</B>", "<b>upper</b>"},
+		{"<br>", "<br />"},
+
+		{`<b onclick="x()">x</b>`, "<b>x</b>"},
+		{`<font color="red" size="9">r</font>`, `<font color="red">r</font>`},
+		{"<unknown>kept</unknown>", "kept"},
+		{"<script>alert(1)</script>after", "after"},
+		{"<style>p{}</style>ok", "ok"},
+		{"<!-- comment -->text", "text"},
+		{"<scr<script>ipt>", "ipt&gt;"},
+
+		{`<a href="https://x.y">l</a>`, `<a href="https://x.y">l</a>`},
+		{`<a href="javascript:alert(1)">l</a>`, "<a>l</a>"},
+		{`<a href=" JAVASCRIPT:x">l</a>`, "<a>l</a>"},
+		{`<img src="data:image/png;base64,AA" alt="a" onerror="x">`, `<img src="data:image/png;base64,AA" alt="a" />`},
+		{`<img src="http://x/y.png">`, "<img />"},
+
+		{"<b><i>unclosed", "<b><i>unclosed</i></b>"},
+		{"<b><i>x</b>y", "<b><i>x</i></b>y"},
+		{"</b>stray", "stray"},
+		{"<b", "&lt;b"},
+	}
+	for _, tt := range tests {
+		if got := Sanitize(tt.s); got != tt.want {
+			t.Errorf("Sanitize(%q) = %q; want %q", tt.s, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package gumbleutil
+
+import (
+	"crypto/tls"
+	"strings"
+
+	"layeh.com/gumble/gumble"
+)
+
+// Identity is who a client connects as: its username, certificate, and access
+// tokens. Servers identify registered users by the hash of their certificate,
+// so the same certificate should be used for every connection.
+type Identity struct {
+	Username    string
+	Certificate tls.Certificate
+	Tokens      gumble.AccessTokens
+}
+
+// NewIdentity returns a new Identity with a newly generated certificate (see
+// GenerateCertificate).
+func NewIdentity(username string) (*Identity, error) {
+	cert, _, err := GenerateCertificate(username)
+	if err != nil {
+		return nil, err
+	}
+	return &Identity{
+		Username:    username,
+		Certificate: cert,
+	}, nil
+}
+
+// LoadIdentity returns an Identity whose certificate is loaded from the given
+// file. If the file does not exist, a new certificate is generated and saved
+// to it (see LoadOrGenerateCertificate).
+func LoadIdentity(username, filename string) (*Identity, error) {
+	cert, err := LoadOrGenerateCertificate(filename, username)
+	if err != nil {
+		return nil, err
+	}
+	return &Identity{
+		Username:    username,
+		Certificate: cert,
+	}, nil
+}
+
+// Hash returns the hash of the identity's certificate, as computed by the
+// server (see CertificateHash).
+func (i *Identity) Hash() string {
+	return CertificateHash(i.Certificate)
+}
+
+// Matches returns true if the user is connected with the identity's
+// certificate.
+func (i *Identity) Matches(user *gumble.User) bool {
+	hash := i.Hash()
+	return hash != "" && strings.EqualFold(hash, user.Hash)
+}
+
+// Apply sets the identity's username and access tokens in config, and adds its
+// certificate to tlsConfig.
+func (i *Identity) Apply(config *gumble.Config, tlsConfig *tls.Config) {
+	config.Username = i.Username
+	config.Tokens = i.Tokens
+	if len(i.Certificate.Certificate) > 0 {
+		tlsConfig.Certificates = append(tlsConfig.Certificates, i.Certificate)
+	}
+}
+
+// SamePerson returns true if the two users are the same person: they are
+// registered with the same user ID, or are connected with the same
+// certificate. Unlike user names, neither can be impersonated.
+func SamePerson(a, b *gumble.User) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	if a.IsRegistered() || b.IsRegistered() {
+		return a.UserID == b.UserID
+	}
+	return a.Hash != "" && strings.EqualFold(a.Hash, b.Hash)
+}
@@ -0,0 +1,148 @@
+package gumbleutil
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"strings"
+
+	_ "image/gif" // registers the GIF decoder for ResizeImage
+)
+
+// ErrImageTooLarge is returned by ResizeImage when the image cannot be made
+// small enough.
+var ErrImageTooLarge = errors.New("gumbleutil: image cannot be made small enough")
+
+// minimumImageSize is the width and height below which ResizeImage stops
+// downscaling an image.
+const minimumImageSize = 16
+
+// resizeJPEGQualities are the qualities with which ResizeImage encodes an
+// image at each size, before downscaling it further.
+var resizeJPEGQualities = []int{90, 75, 60, 45}
+
+// ResizeImage re-encodes the image in data so that it is no larger than
+// maxBytes, as the Mumble client does with pasted images. The resized image
+// and its MIME type are returned.
+//
+// An image that is already small enough is returned unchanged. Otherwise, it
+// is decoded and re-encoded as a JPEG with decreasing quality, or as a PNG if
+// it is transparent, and downscaled until it fits. JPEG, PNG, and GIF images
+// can be decoded; other formats (e.g. WebP) can be decoded if their decoder
+// has been registered with the image package (e.g. by importing
+// golang.org/x/image/webp). Animated GIFs lose their animation.
+func ResizeImage(data []byte, maxBytes int) ([]byte, string, error) {
+	mimeType := http.DetectContentType(data)
+	if len(data) <= maxBytes && strings.HasPrefix(mimeType, "image/") {
+		return data, mimeType, nil
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+	opaque := isOpaque(src)
+
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	var rgba *image.RGBA
+	var buf bytes.Buffer
+	for scale := 1.0; ; scale *= 0.75 {
+		w, h := int(float64(width)*scale), int(float64(height)*scale)
+		if w < minimumImageSize || h < minimumImageSize {
+			return nil, "", ErrImageTooLarge
+		}
+		img := src
+		if scale < 1 {
+			if rgba == nil {
+				rgba = toRGBA(src)
+			}
+			img = scaleImage(rgba, w, h)
+		}
+
+		if opaque {
+			for _, quality := range resizeJPEGQualities {
+				buf.Reset()
+				if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+					return nil, "", err
+				}
+				if buf.Len() <= maxBytes {
+					return buf.Bytes(), "image/jpeg", nil
+				}
+			}
+			continue
+		}
+		buf.Reset()
+		encoder := png.Encoder{CompressionLevel: png.BestCompression}
+		if err := encoder.Encode(&buf, img); err != nil {
+			return nil, "", err
+		}
+		if buf.Len() <= maxBytes {
+			return buf.Bytes(), "image/png", nil
+		}
+	}
+}
+
+// isOpaque returns true if img has no transparent pixels.
+func isOpaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if _, _, _, a := img.At(x, y).RGBA(); a != 0xFFFF {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// toRGBA returns a copy of img as an *image.RGBA whose bounds start at 0, 0.
+func toRGBA(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, bounds.Min, draw.Src)
+	return rgba
+}
+
+// scaleImage returns src scaled to width by height, each pixel of which is
+// the average of the pixels of src that it covers.
+func scaleImage(src *image.RGBA, width, height int) *image.RGBA {
+	bounds := src.Bounds()
+	srcWidth, srcHeight := bounds.Dx(), bounds.Dy()
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := y * srcHeight / height
+		y1 := (y + 1) * srcHeight / height
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < width; x++ {
+			x0 := x * srcWidth / width
+			x1 := (x + 1) * srcWidth / width
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+			var r, g, b, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					c := src.RGBAAt(bounds.Min.X+sx, bounds.Min.Y+sy)
+					r += uint32(c.R)
+					g += uint32(c.G)
+					b += uint32(c.B)
+					a += uint32(c.A)
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{uint8(r / n), uint8(g / n), uint8(b / n), uint8(a / n)})
+		}
+	}
+	return dst
+}
@@ -18,6 +18,17 @@ type Listener struct {
 	BanList             func(e *gumble.BanListEvent)
 	ContextActionChange func(e *gumble.ContextActionChangeEvent)
 	ServerConfig        func(e *gumble.ServerConfigEvent)
+	ListenerError       func(e *gumble.ListenerErrorEvent)
+	PingUpdated         func(e *gumble.PingUpdatedEvent)
+	PermissionQuery     func(e *gumble.PermissionQueryEvent)
+	QueryUsers          func(e *gumble.QueryUsersEvent)
+	ChannelTreeChange   func(e *gumble.ChannelTreeChangeEvent)
+	ProtocolError       func(e *gumble.ProtocolErrorEvent)
+	SlowListener        func(e *gumble.SlowListenerEvent)
+	WriteError          func(e *gumble.WriteErrorEvent)
+	AudioBandwidth      func(e *gumble.AudioBandwidthEvent)
+	ContextAction       func(e *gumble.ContextActionEvent)
+	ServerVersion       func(e *gumble.ServerVersionEvent)
 }
 
 var _ gumble.EventListener = (*Listener)(nil)
@@ -98,3 +109,80 @@ func (l Listener) OnServerConfig(e *gumble.ServerConfigEvent) {
 		l.ServerConfig(e)
 	}
 }
+
+// OnListenerError implements gumble.ListenerErrorListener.OnListenerError.
+func (l Listener) OnListenerError(e *gumble.ListenerErrorEvent) {
+	if l.ListenerError != nil {
+		l.ListenerError(e)
+	}
+}
+
+// OnPingUpdated implements gumble.PingUpdatedListener.OnPingUpdated.
+func (l Listener) OnPingUpdated(e *gumble.PingUpdatedEvent) {
+	if l.PingUpdated != nil {
+		l.PingUpdated(e)
+	}
+}
+
+// OnPermissionQuery implements gumble.PermissionQueryListener.OnPermissionQuery.
+func (l Listener) OnPermissionQuery(e *gumble.PermissionQueryEvent) {
+	if l.PermissionQuery != nil {
+		l.PermissionQuery(e)
+	}
+}
+
+// OnQueryUsers implements gumble.QueryUsersListener.OnQueryUsers.
+func (l Listener) OnQueryUsers(e *gumble.QueryUsersEvent) {
+	if l.QueryUsers != nil {
+		l.QueryUsers(e)
+	}
+}
+
+// OnChannelTreeChange implements gumble.ChannelTreeChangeListener.OnChannelTreeChange.
+func (l Listener) OnChannelTreeChange(e *gumble.ChannelTreeChangeEvent) {
+	if l.ChannelTreeChange != nil {
+		l.ChannelTreeChange(e)
+	}
+}
+
+// OnProtocolError implements gumble.ProtocolErrorListener.OnProtocolError.
+func (l Listener) OnProtocolError(e *gumble.ProtocolErrorEvent) {
+	if l.ProtocolError != nil {
+		l.ProtocolError(e)
+	}
+}
+
+// OnSlowListener implements gumble.SlowListenerListener.OnSlowListener.
+func (l Listener) OnSlowListener(e *gumble.SlowListenerEvent) {
+	if l.SlowListener != nil {
+		l.SlowListener(e)
+	}
+}
+
+// OnWriteError implements gumble.WriteErrorListener.OnWriteError.
+func (l Listener) OnWriteError(e *gumble.WriteErrorEvent) {
+	if l.WriteError != nil {
+		l.WriteError(e)
+	}
+}
+
+// OnAudioBandwidth implements gumble.AudioBandwidthListener.OnAudioBandwidth.
+func (l Listener) OnAudioBandwidth(e *gumble.AudioBandwidthEvent) {
+	if l.AudioBandwidth != nil {
+		l.AudioBandwidth(e)
+	}
+}
+
+// OnContextAction implements gumble.ContextActionListener.OnContextAction.
+func (l Listener) OnContextAction(e *gumble.ContextActionEvent) {
+	if l.ContextAction != nil {
+		l.ContextAction(e)
+	}
+}
+
+// OnServerVersion implements gumble.ServerVersionListener.OnServerVersion.
+func (l Listener) OnServerVersion(e *gumble.ServerVersionEvent) {
+	if l.ServerVersion != nil {
+		l.ServerVersion(e)
+	}
+}
@@ -78,3 +78,58 @@ func (lf ListenerFunc) OnContextActionChange(e *gumble.ContextActionChangeEvent)
 func (lf ListenerFunc) OnServerConfig(e *gumble.ServerConfigEvent) {
 	lf(e)
 }
+
+// OnListenerError implements gumble.ListenerErrorListener.OnListenerError.
+func (lf ListenerFunc) OnListenerError(e *gumble.ListenerErrorEvent) {
+	lf(e)
+}
+
+// OnPingUpdated implements gumble.PingUpdatedListener.OnPingUpdated.
+func (lf ListenerFunc) OnPingUpdated(e *gumble.PingUpdatedEvent) {
+	lf(e)
+}
+
+// OnPermissionQuery implements gumble.PermissionQueryListener.OnPermissionQuery.
+func (lf ListenerFunc) OnPermissionQuery(e *gumble.PermissionQueryEvent) {
+	lf(e)
+}
+
+// OnQueryUsers implements gumble.QueryUsersListener.OnQueryUsers.
+func (lf ListenerFunc) OnQueryUsers(e *gumble.QueryUsersEvent) {
+	lf(e)
+}
+
+// OnChannelTreeChange implements gumble.ChannelTreeChangeListener.OnChannelTreeChange.
+func (lf ListenerFunc) OnChannelTreeChange(e *gumble.ChannelTreeChangeEvent) {
+	lf(e)
+}
+
+// OnProtocolError implements gumble.ProtocolErrorListener.OnProtocolError.
+func (lf ListenerFunc) OnProtocolError(e *gumble.ProtocolErrorEvent) {
+	lf(e)
+}
+
+// OnSlowListener implements gumble.SlowListenerListener.OnSlowListener.
+func (lf ListenerFunc) OnSlowListener(e *gumble.SlowListenerEvent) {
+	lf(e)
+}
+
+// OnWriteError implements gumble.WriteErrorListener.OnWriteError.
+func (lf ListenerFunc) OnWriteError(e *gumble.WriteErrorEvent) {
+	lf(e)
+}
+
+// OnAudioBandwidth implements gumble.AudioBandwidthListener.OnAudioBandwidth.
+func (lf ListenerFunc) OnAudioBandwidth(e *gumble.AudioBandwidthEvent) {
+	lf(e)
+}
+
+// OnContextAction implements gumble.ContextActionListener.OnContextAction.
+func (lf ListenerFunc) OnContextAction(e *gumble.ContextActionEvent) {
+	lf(e)
+}
+
+// OnServerVersion implements gumble.ServerVersionListener.OnServerVersion.
+func (lf ListenerFunc) OnServerVersion(e *gumble.ServerVersionEvent) {
+	lf(e)
+}
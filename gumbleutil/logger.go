@@ -0,0 +1,276 @@
+package gumbleutil
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"layeh.com/gumble/gumble"
+)
+
+// AttachLogger attaches a listener to config that logs every event to logger,
+// with the event's details as structured attributes (e.g. user, channel,
+// session, message).
+//
+// level returns the level at which an event is logged. If level is nil,
+// DefaultLogLevel is used.
+func AttachLogger(config *gumble.Config, logger *slog.Logger, level func(e gumble.Event) slog.Level) gumble.Detacher {
+	if level == nil {
+		level = DefaultLogLevel
+	}
+	return config.AttachAll(func(e gumble.Event) {
+		lvl := level(e)
+		ctx := context.Background()
+		if !logger.Enabled(ctx, lvl) {
+			return
+		}
+		msg, attrs := eventAttrs(e)
+		logger.LogAttrs(ctx, lvl, msg, attrs...)
+	})
+}
+
+// DefaultLogLevel returns the level at which AttachLogger logs events by
+// default: slog.LevelWarn for disconnects, denied permissions, listener
+// panics, protocol errors, slow listeners, and write errors; slog.LevelDebug
+// for ping updates; and slog.LevelInfo for all other events.
+func DefaultLogLevel(e gumble.Event) slog.Level {
+	switch e.(type) {
+	case *gumble.DisconnectEvent, *gumble.PermissionDeniedEvent, *gumble.ListenerErrorEvent, *gumble.ProtocolErrorEvent, *gumble.SlowListenerEvent, *gumble.WriteErrorEvent:
+		return slog.LevelWarn
+	case *gumble.PingUpdatedEvent:
+		return slog.LevelDebug
+	}
+	return slog.LevelInfo
+}
+
+func userAttrs(key string, user *gumble.User) slog.Attr {
+	if user == nil {
+		return slog.Attr{}
+	}
+	return slog.Group(key,
+		slog.String("name", user.Name),
+		slog.Uint64("session", uint64(user.Session)),
+	)
+}
+
+func channelAttrs(key string, channel *gumble.Channel) slog.Attr {
+	if channel == nil {
+		return slog.Attr{}
+	}
+	return slog.Group(key,
+		slog.String("name", channel.Name),
+		slog.Uint64("id", uint64(channel.ID)),
+	)
+}
+
+// eventAttrs returns the log message and attributes for the given event.
+func eventAttrs(e gumble.Event) (string, []slog.Attr) {
+	switch e := e.(type) {
+	case *gumble.ConnectEvent:
+		attrs := []slog.Attr{}
+		if e.Client.Self != nil {
+			attrs = append(attrs, userAttrs("self", e.Client.Self))
+		}
+		return "connected", attrs
+	case *gumble.DisconnectEvent:
+		attrs := []slog.Attr{
+			slog.String("type", e.Type.String()),
+			slog.Bool("reconnect", e.Reconnect),
+		}
+		if e.String != "" {
+			attrs = append(attrs, slog.String("reason", e.String))
+		}
+		if e.Err != nil {
+			attrs = append(attrs, slog.Any("error", e.Err))
+		}
+		return "disconnected", attrs
+	case *gumble.TextMessageEvent:
+		attrs := []slog.Attr{
+			userAttrs("sender", e.Sender),
+			slog.String("message", PlainText(&e.TextMessage)),
+		}
+		for _, channel := range e.Channels {
+			attrs = append(attrs, channelAttrs("channel", channel))
+		}
+		return "text message", attrs
+	case *gumble.UserChangeEvent:
+		attrs := []slog.Attr{
+			slog.String("type", e.Type.String()),
+			userAttrs("user", e.User),
+			userAttrs("actor", e.Actor),
+		}
+		if e.User != nil {
+			attrs = append(attrs, channelAttrs("channel", e.User.Channel))
+		}
+		if e.String != "" {
+			attrs = append(attrs, slog.String("reason", e.String))
+		}
+		return "user changed", attrs
+	case *gumble.ChannelChangeEvent:
+		attrs := []slog.Attr{
+			slog.String("type", e.Type.String()),
+			channelAttrs("channel", e.Channel),
+		}
+		if e.Channel != nil {
+			attrs = append(attrs, channelAttrs("parent", e.Channel.Parent))
+		}
+		return "channel changed", attrs
+	case *gumble.PermissionDeniedEvent:
+		return "permission denied", []slog.Attr{
+			slog.Int("type", int(e.Type)),
+			userAttrs("user", e.User),
+			channelAttrs("channel", e.Channel),
+			slog.Int("permission", int(e.Permission)),
+			slog.String("reason", e.String),
+		}
+	case *gumble.UserListEvent:
+		return "user list received", []slog.Attr{
+			slog.Int("count", len(e.UserList)),
+		}
+	case *gumble.ACLEvent:
+		return "ACL received", []slog.Attr{
+			channelAttrs("channel", e.ACL.Channel),
+			slog.Int("groups", len(e.ACL.Groups)),
+			slog.Int("rules", len(e.ACL.Rules)),
+		}
+	case *gumble.BanListEvent:
+		return "ban list received", []slog.Attr{
+			slog.Int("count", len(e.BanList)),
+		}
+	case *gumble.ContextActionChangeEvent:
+		return "context action changed", []slog.Attr{
+			slog.Int("type", int(e.Type)),
+			slog.String("name", e.ContextAction.Name),
+		}
+	case *gumble.ContextActionEvent:
+		return "context action triggered", []slog.Attr{
+			slog.String("name", e.Action),
+			slog.Int("type", int(e.Type)),
+			userAttrs("user", e.User),
+			channelAttrs("channel", e.Channel),
+		}
+	case *gumble.ServerVersionEvent:
+		major, minor, patch := e.Version.FullVersion()
+		return "server version", []slog.Attr{
+			slog.String("version", fmt.Sprintf("%d.%d.%d", major, minor, patch)),
+			slog.String("release", e.Version.Release),
+			slog.String("os", e.Version.OS),
+			slog.String("os_version", e.Version.OSVersion),
+		}
+	case *gumble.ServerConfigEvent:
+		attrs := []slog.Attr{}
+		if e.MaximumBitrate != nil {
+			attrs = append(attrs, slog.Int("maximum_bitrate", *e.MaximumBitrate))
+		}
+		if e.MaximumUsers != nil {
+			attrs = append(attrs, slog.Int("maximum_users", *e.MaximumUsers))
+		}
+		if e.MaximumMessageLength != nil {
+			attrs = append(attrs, slog.Int("maximum_message_length", *e.MaximumMessageLength))
+		}
+		return "server config", attrs
+	case *gumble.ListenerErrorEvent:
+		return "listener panic", []slog.Attr{
+			slog.String("event", fmt.Sprintf("%T", e.Event)),
+			slog.Any("panic", e.Panic),
+			slog.String("stack", string(e.Stack)),
+		}
+	case *gumble.PingUpdatedEvent:
+		return "ping", []slog.Attr{
+			slog.Duration("latency", e.Latency),
+			slog.Duration("average", e.Average),
+			slog.Float64("variance", float64(e.Variance)),
+		}
+	case *gumble.PermissionQueryEvent:
+		attrs := []slog.Attr{
+			channelAttrs("channel", e.Channel),
+			slog.Bool("flush", e.Flush),
+		}
+		if e.Permission != nil {
+			attrs = append(attrs, slog.Int("permission", int(*e.Permission)))
+		}
+		return "permission query", attrs
+	case *gumble.QueryUsersEvent:
+		return "user query", []slog.Attr{
+			slog.Int("count", len(e.Users)),
+		}
+	case *gumble.ChannelTreeChangeEvent:
+		return "channel tree changed", []slog.Attr{
+			slog.Int("created", len(e.Created)),
+			slog.Int("removed", len(e.Removed)),
+			slog.Int("moved", len(e.Moved)),
+			slog.Int("changed", len(e.Changed)),
+		}
+	case *gumble.ProtocolErrorEvent:
+		return "protocol error", []slog.Attr{
+			slog.Int("packet_type", int(e.PacketType)),
+			slog.Any("error", e.Err),
+		}
+	case *gumble.SlowListenerEvent:
+		return "slow listener", []slog.Attr{
+			slog.String("listener", fmt.Sprintf("%T", e.Listener)),
+			slog.String("event", fmt.Sprintf("%T", e.Event)),
+			slog.Duration("duration", e.Duration),
+			slog.Bool("detached", e.Detached),
+		}
+	case *gumble.AudioBandwidthEvent:
+		return "audio bandwidth", []slog.Attr{
+			slog.Int("maximum_bitrate", e.MaximumBitrate),
+			slog.Duration("audio_interval", e.AudioInterval),
+			slog.Int("audio_data_bytes", e.AudioDataBytes),
+			slog.Int("bitrate", e.Bitrate),
+		}
+	case *gumble.WriteErrorEvent:
+		return "write error", []slog.Attr{
+			slog.Any("error", e.Err),
+		}
+	}
+	return fmt.Sprintf("%T", e), nil
+}
+
+// AuditLogger returns a function, for gumble.Config.AuditHook, that logs
+// audit records to logger at slog.LevelInfo, or slog.LevelWarn for
+// rejections, disconnects, and permission denials.
+func AuditLogger(logger *slog.Logger) func(record *gumble.AuditRecord) {
+	return func(r *gumble.AuditRecord) {
+		lvl := slog.LevelInfo
+		switch r.Type {
+		case gumble.AuditRejected, gumble.AuditDisconnected, gumble.AuditPermissionDenied:
+			lvl = slog.LevelWarn
+		}
+		attrs := []slog.Attr{
+			slog.Time("time", r.Time),
+			slog.String("server", r.Server),
+			slog.String("server_fingerprint", r.ServerFingerprint),
+			slog.String("username", r.Username),
+		}
+		if r.CertificateHash != "" {
+			attrs = append(attrs, slog.String("certificate_hash", r.CertificateHash))
+		}
+		if r.User != "" {
+			attrs = append(attrs, slog.String("user", r.User))
+		}
+		if r.UserHash != "" {
+			attrs = append(attrs, slog.String("user_hash", r.UserHash))
+		}
+		if r.Actor != "" {
+			attrs = append(attrs, slog.String("actor", r.Actor))
+		}
+		if r.Channel != "" {
+			attrs = append(attrs, slog.String("channel", r.Channel))
+		}
+		switch r.Type {
+		case gumble.AuditDisconnected:
+			attrs = append(attrs, slog.String("disconnect", r.Disconnect.String()))
+		case gumble.AuditPermissionDenied:
+			attrs = append(attrs, slog.String("permission", r.Permission.String()))
+		}
+		if r.Reason != "" {
+			attrs = append(attrs, slog.String("reason", r.Reason))
+		}
+		if r.Err != nil {
+			attrs = append(attrs, slog.Any("error", r.Err))
+		}
+		logger.LogAttrs(context.Background(), lvl, "audit: "+r.Type.String(), attrs...)
+	}
+}
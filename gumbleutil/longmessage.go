@@ -0,0 +1,255 @@
+package gumbleutil
+
+import (
+	"errors"
+	"html"
+	"strings"
+	"unicode/utf8"
+
+	"layeh.com/gumble/gumble"
+)
+
+// Message length limits used when the server has not sent its own. These are
+// the defaults of the Mumble server.
+const (
+	DefaultMaximumMessageLength      = 5000
+	DefaultMaximumImageMessageLength = 131072
+)
+
+// ErrInvalidTarget is returned by SendLongMessage when the target is not a
+// *gumble.User or *gumble.Channel.
+var ErrInvalidTarget = errors.New("gumbleutil: invalid message target")
+
+// SendLongMessage sends an HTML message to target, which must be a
+// *gumble.User or a *gumble.Channel. If the message is longer than the
+// server's message length limit, it is split into multiple messages.
+//
+// Messages are split between HTML tags and, where possible, at whitespace.
+// Tags that are open at the end of a message are closed, and reopened at the
+// start of the next message, so that each message is valid HTML by itself.
+func SendLongMessage(client *gumble.Client, target interface{}, text string) error {
+	send, err := messageSender(target)
+	if err != nil {
+		return err
+	}
+	return sendLongMessage(client, send, text)
+}
+
+// sendLongMessage splits text as SendLongMessage does, and passes each message
+// to send.
+func sendLongMessage(client *gumble.Client, send func(message string) error, text string) error {
+	limit, imageLimit := messageLimits(client)
+	for _, message := range SplitMessage(text, limit, imageLimit) {
+		if err := send(message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// messageSender returns a function that sends a message to target, which must
+// be a *gumble.User or a *gumble.Channel.
+func messageSender(target interface{}) (func(message string) error, error) {
+	switch target := target.(type) {
+	case *gumble.User:
+		return target.Send, nil
+	case *gumble.Channel:
+		return func(message string) error {
+			return target.Send(message, false)
+		}, nil
+	}
+	return nil, ErrInvalidTarget
+}
+
+// messageLimits returns the server's message length limits, or the defaults
+// if the server has not sent them.
+func messageLimits(client *gumble.Client) (limit, imageLimit int) {
+	limit = client.MaximumMessageLength()
+	if limit == 0 {
+		limit = DefaultMaximumMessageLength
+	}
+	imageLimit = client.MaximumImageMessageLength()
+	if imageLimit == 0 {
+		imageLimit = DefaultMaximumImageMessageLength
+	}
+	return
+}
+
+// SplitMessage splits an HTML message into messages that are no longer than
+// limit bytes, or imageLimit bytes for messages that contain an image. A limit
+// of zero means that there is no limit. See SendLongMessage.
+//
+// It can be set as gumble.Config.SplitMessage, so that long messages are split
+// when they are sent.
+func SplitMessage(text string, limit, imageLimit int) []string {
+	if imageLimit < limit {
+		imageLimit = limit
+	}
+	s := &messageSplitter{
+		limit:      limit,
+		imageLimit: imageLimit,
+	}
+	for _, t := range tokenizeHTML(text) {
+		if t.tag == "" {
+			s.addText(html.UnescapeString(t.text))
+		} else {
+			s.addTag(t)
+		}
+	}
+	s.flush()
+	return s.messages
+}
+
+type messageSplitter struct {
+	limit, imageLimit int
+
+	messages []string
+	current  strings.Builder
+	// Whether current contains anything other than empty tags.
+	content bool
+	image   bool
+	// The tags that are open.
+	open []htmlToken
+	// The offsets in current of the trailing opening tags that do not have
+	// any content yet. They are removed from the message if it ends before
+	// they get any content.
+	pending []int
+}
+
+// closing returns the closing tags for the first n open tags.
+func (s *messageSplitter) closing(n int) string {
+	var b strings.Builder
+	for i := n - 1; i >= 0; i-- {
+		b.WriteString("</" + s.open[i].tag + ">")
+	}
+	return b.String()
+}
+
+// fits returns true if n more bytes can be added to the current message,
+// while leaving room to close the open tags.
+func (s *messageSplitter) fits(n int, image bool) bool {
+	limit := s.limit
+	if s.image || image {
+		limit = s.imageLimit
+	}
+	if limit <= 0 {
+		return true
+	}
+	return s.current.Len()+n+len(s.closing(len(s.open))) <= limit
+}
+
+// flush ends the current message, and starts a new one with the open tags
+// reopened.
+func (s *messageSplitter) flush() {
+	if s.content {
+		message := s.current.String()
+		open := len(s.open)
+		if len(s.pending) > 0 {
+			message = message[:s.pending[0]]
+			open -= len(s.pending)
+		}
+		s.messages = append(s.messages, message+s.closing(open))
+	}
+	s.current.Reset()
+	s.content = false
+	s.image = false
+	s.pending = s.pending[:0]
+	for _, t := range s.open {
+		s.pending = append(s.pending, s.current.Len())
+		s.current.WriteString(t.raw)
+	}
+}
+
+// write adds content to the current message.
+func (s *messageSplitter) write(str string, image bool) {
+	s.current.WriteString(str)
+	s.content = true
+	s.image = s.image || image
+	s.pending = s.pending[:0]
+}
+
+func (s *messageSplitter) addTag(t htmlToken) {
+	if t.closing {
+		for i := len(s.open) - 1; i >= 0; i-- {
+			if s.open[i].tag != t.tag {
+				continue
+			}
+			if i == len(s.open)-1 && len(s.pending) > 0 {
+				// The tag is empty; remove it.
+				last := s.pending[len(s.pending)-1]
+				s.pending = s.pending[:len(s.pending)-1]
+				rest := s.current.String()[:last]
+				s.current.Reset()
+				s.current.WriteString(rest)
+			} else {
+				// Closing tags fit, as room for them is always left.
+				s.current.WriteString("</" + t.tag + ">")
+				s.pending = s.pending[:0]
+			}
+			s.open = append(s.open[:i], s.open[i+1:]...)
+			break
+		}
+		return
+	}
+
+	image := t.tag == "img"
+	void := t.selfClosing || htmlVoidTags[t.tag]
+	size := len(t.raw)
+	if !void {
+		size += len("</" + t.tag + ">")
+	}
+	if !s.fits(size, image) && s.content {
+		s.flush()
+	}
+	if void {
+		s.write(t.raw, image)
+		return
+	}
+	s.pending = append(s.pending, s.current.Len())
+	s.current.WriteString(t.raw)
+	s.open = append(s.open, t)
+}
+
+func (s *messageSplitter) addText(text string) {
+	for text != "" {
+		escaped := html.EscapeString(text)
+		if s.fits(len(escaped), false) {
+			s.write(escaped, false)
+			return
+		}
+		n := s.textPrefix(text)
+		if n == 0 {
+			if s.content {
+				s.flush()
+				continue
+			}
+			// Not even a single character fits; send it anyway.
+			_, n = utf8.DecodeRuneInString(text)
+		}
+		s.write(html.EscapeString(text[:n]), false)
+		text = strings.TrimLeft(text[n:], " ")
+		s.flush()
+	}
+}
+
+// textPrefix returns the length of the longest prefix of text that fits in the
+// current message once escaped, preferring to end it at whitespace.
+func (s *messageSplitter) textPrefix(text string) int {
+	n, space := 0, 0
+	size := 0
+	for i, r := range text {
+		rsize := len(html.EscapeString(string(r)))
+		if !s.fits(size+rsize, false) {
+			break
+		}
+		size += rsize
+		n = i + utf8.RuneLen(r)
+		if r == ' ' || r == '\n' || r == '\t' {
+			space = n
+		}
+	}
+	if space > 0 && n < len(text) {
+		return space
+	}
+	return n
+}
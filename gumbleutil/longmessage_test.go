@@ -0,0 +1,105 @@
+package gumbleutil
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitMessage(t *testing.T) {
+	tests := []struct {
+		name              string
+		text              string
+		limit, imageLimit int
+		want              []string
+	}{
+		{
+			name:  "short",
+			text:  "hello",
+			limit: 10,
+			want:  []string{"hello"},
+		},
+		{
+			name:  "no limit",
+			text:  "hello world",
+			limit: 0,
+			want:  []string{"hello world"},
+		},
+		{
+			name:  "whitespace",
+			text:  "hello world foo",
+			limit: 11,
+			want:  []string{"hello ", "world foo"},
+		},
+		{
+			name:  "no whitespace",
+			text:  "abcdefghij",
+			limit: 4,
+			want:  []string{"abcd", "efgh", "ij"},
+		},
+		{
+			name:  "multibyte",
+			text:  "héllo",
+			limit: 3,
+			want:  []string{"hé", "llo"},
+		},
+		{
+			name:  "reopened tags",
+			text:  "<b>hello world</b>",
+			limit: 12,
+			want:  []string{"<b>hello</b>", "<b>world</b>"},
+		},
+		{
+			name:  "nested tags",
+			text:  "<p><i>one two</i></p>",
+			limit: 20,
+			want:  []string{"<p><i>one </i></p>", "<p><i>two</i></p>"},
+		},
+		{
+			name:  "tag moved to the next message",
+			text:  "<i>x</i><b>yyyyyyyyy</b>",
+			limit: 12,
+			want:  []string{"<i>x</i>", "<b>yyyyy</b>", "<b>yyyy</b>"},
+		},
+		{
+			name:  "escaped text",
+			text:  "a &amp; b &amp; c",
+			limit: 9,
+			want:  []string{"a &amp; ", "b &amp; c"},
+		},
+		{
+			name:  "escaped character is not split",
+			text:  "it's",
+			limit: 6,
+			want:  []string{"it", "&#39;s"},
+		},
+		{
+			name:       "image limit",
+			text:       `text <img src="data:image/png;base64,AAAAAAAA">`,
+			limit:      20,
+			imageLimit: 100,
+			want:       []string{`text <img src="data:image/png;base64,AAAAAAAA">`},
+		},
+		{
+			name:       "image over the image limit",
+			text:       `text <img src="data:image/png;base64,AAAAAAAA">`,
+			limit:      10,
+			imageLimit: 20,
+			want:       []string{"text ", `<img src="data:image/png;base64,AAAAAAAA">`},
+		},
+		{
+			name:       "image limit below limit",
+			text:       "hello world",
+			limit:      20,
+			imageLimit: 5,
+			want:       []string{"hello world"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SplitMessage(tt.text, tt.limit, tt.imageLimit)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitMessage(%q, %d, %d) = %q; want %q", tt.text, tt.limit, tt.imageLimit, got, tt.want)
+			}
+		})
+	}
+}
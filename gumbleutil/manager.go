@@ -0,0 +1,203 @@
+package gumbleutil
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"sort"
+	"sync"
+
+	"layeh.com/gumble/gumble"
+)
+
+// ManagedEvent is an event of one of a Manager's clients.
+type ManagedEvent struct {
+	// The name of the server whose client triggered the event.
+	Server string
+	Event  gumble.Event
+}
+
+// Errors returned by Manager.Add.
+var (
+	ErrServerExists  = errors.New("gumbleutil: server already added")
+	ErrManagerClosed = errors.New("gumbleutil: manager is closed")
+)
+
+// DefaultManagerBufferSize is the buffer size of the channel returned by
+// Manager.Events.
+const DefaultManagerBufferSize = 256
+
+// Manager maintains connections to multiple servers. Each server's client is
+// reconnected by an AutoReconnect, and the events of all of the clients are
+// passed to a single channel.
+type Manager struct {
+	// If non-nil, the identity that all clients connect as. It is applied to
+	// each server's config when it is added.
+	Identity *Identity
+	// The dialer used to connect. If nil, a zero net.Dialer is used.
+	Dialer *net.Dialer
+
+	events chan ManagedEvent
+	done   chan struct{}
+	// sending is read locked while an event is being sent to events.
+	sending sync.RWMutex
+
+	mu      sync.Mutex
+	servers map[string]*managedServer
+	closed  bool
+}
+
+type managedServer struct {
+	config    *gumble.Config
+	reconnect *AutoReconnect
+	detacher  gumble.Detacher
+}
+
+// NewManager returns a new Manager.
+func NewManager(identity *Identity) *Manager {
+	return &Manager{
+		Identity: identity,
+		events:   make(chan ManagedEvent, DefaultManagerBufferSize),
+		done:     make(chan struct{}),
+		servers:  make(map[string]*managedServer),
+	}
+}
+
+// Events returns the channel to which the events of all of the clients are
+// sent. The channel is closed by Close.
+//
+// Clients block until their events are received, so the channel must be
+// drained.
+func (m *Manager) Events() <-chan ManagedEvent {
+	return m.events
+}
+
+// Add connects to the server at addr, and reconnects to it when disconnected
+// (see AttachAutoReconnect). name is used to refer to the server in the
+// manager's methods and events.
+//
+// config and tlsConfig must not be shared with other servers. If config is
+// nil, gumble.NewConfig() is used. If the initial connection fails, the
+// server is not added and the error is returned.
+func (m *Manager) Add(name, addr string, config *gumble.Config, tlsConfig *tls.Config) error {
+	if config == nil {
+		config = gumble.NewConfig()
+	}
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{}
+	}
+	if m.Identity != nil {
+		m.Identity.Apply(config, tlsConfig)
+	}
+
+	server := &managedServer{
+		config: config,
+	}
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return ErrManagerClosed
+	}
+	if _, ok := m.servers[name]; ok {
+		m.mu.Unlock()
+		return ErrServerExists
+	}
+	m.servers[name] = server
+	m.mu.Unlock()
+
+	server.detacher = config.AttachAll(func(e gumble.Event) {
+		m.send(name, e)
+	})
+	server.reconnect = AttachAutoReconnect(config, addr, tlsConfig)
+	server.reconnect.Dialer = m.Dialer
+
+	dialer := m.Dialer
+	if dialer == nil {
+		dialer = new(net.Dialer)
+	}
+	if _, err := gumble.DialWithDialer(dialer, addr, config, tlsConfig); err != nil {
+		m.Remove(name)
+		return err
+	}
+	return nil
+}
+
+func (m *Manager) send(name string, e gumble.Event) {
+	m.sending.RLock()
+	defer m.sending.RUnlock()
+	select {
+	case <-m.done:
+	default:
+		select {
+		case m.events <- ManagedEvent{Server: name, Event: e}:
+		case <-m.done:
+		}
+	}
+}
+
+// Client returns the current client of the given server, or nil if the server
+// has not been added.
+func (m *Manager) Client(name string) *gumble.Client {
+	m.mu.Lock()
+	server := m.servers[name]
+	m.mu.Unlock()
+	if server == nil {
+		return nil
+	}
+	return server.reconnect.Client()
+}
+
+// Servers returns the names of the servers, sorted.
+func (m *Manager) Servers() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	names := make([]string, 0, len(m.servers))
+	for name := range m.servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Remove stops reconnecting to the given server and disconnects its client.
+// false is returned if the server has not been added.
+func (m *Manager) Remove(name string) bool {
+	m.mu.Lock()
+	server := m.servers[name]
+	delete(m.servers, name)
+	m.mu.Unlock()
+	if server == nil {
+		return false
+	}
+	server.reconnect.Stop()
+	if client := server.reconnect.Client(); client != nil {
+		client.Disconnect()
+	}
+	return true
+}
+
+// Close removes all of the servers and closes the events channel.
+func (m *Manager) Close() {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return
+	}
+	m.closed = true
+	servers := m.servers
+	m.servers = make(map[string]*managedServer)
+	m.mu.Unlock()
+
+	for _, server := range servers {
+		server.reconnect.Stop()
+		server.detacher.Detach()
+		if client := server.reconnect.Client(); client != nil {
+			client.Disconnect()
+		}
+	}
+
+	close(m.done)
+	m.sending.Lock()
+	close(m.events)
+	m.sending.Unlock()
+}
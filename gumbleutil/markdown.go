@@ -0,0 +1,234 @@
+package gumbleutil
+
+import (
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Markdown converts a subset of Markdown into the HTML that Mumble clients
+// display. The following syntax is supported:
+//  # Heading (levels 1 to 6)
+//  **bold** or __bold__
+//  *italics* or _italics_
+//  ~~strikethrough~~
+//  `code`
+//  [link text](https://example.com)
+//  - unordered list items (also * and +)
+//  1. ordered list items
+//  ```
+//  code blocks
+//  ```
+//
+// Paragraphs are separated by blank lines, and line breaks within a paragraph
+// are kept. All other text is escaped, so the result is safe to send. Links
+// must use the http, https, ftp, or mailto schemes; other links are rendered
+// as text.
+func Markdown(text string) string {
+	lines := strings.Split(strings.Replace(text, "\r\n", "\n", -1), "\n")
+	var b strings.Builder
+	var paragraph []string
+	var list string
+
+	flushParagraph := func() {
+		if len(paragraph) == 0 {
+			return
+		}
+		b.WriteString("<p>")
+		for i, line := range paragraph {
+			if i > 0 {
+				b.WriteString("<br />")
+			}
+			b.WriteString(markdownInline(line))
+		}
+		b.WriteString("</p>")
+		paragraph = nil
+	}
+	closeList := func() {
+		if list != "" {
+			b.WriteString("</" + list + ">")
+			list = ""
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, "```") {
+			flushParagraph()
+			closeList()
+			var code []string
+			for i++; i < len(lines); i++ {
+				if strings.HasPrefix(strings.TrimSpace(lines[i]), "```") {
+					break
+				}
+				code = append(code, lines[i])
+			}
+			b.WriteString("<pre>" + html.EscapeString(strings.Join(code, "\n")) + "</pre>")
+			continue
+		}
+
+		if trimmed == "" {
+			flushParagraph()
+			closeList()
+			continue
+		}
+
+		if level := headingLevel(trimmed); level > 0 {
+			flushParagraph()
+			closeList()
+			tag := "h" + string(rune('0'+level))
+			content := strings.TrimSpace(strings.TrimRight(trimmed[level:], "#"))
+			b.WriteString("<" + tag + ">" + markdownInline(content) + "</" + tag + ">")
+			continue
+		}
+
+		if kind, item := listItem(trimmed); kind != "" {
+			flushParagraph()
+			if list != kind {
+				closeList()
+				b.WriteString("<" + kind + ">")
+				list = kind
+			}
+			b.WriteString("<li>" + markdownInline(item) + "</li>")
+			continue
+		}
+
+		closeList()
+		paragraph = append(paragraph, trimmed)
+	}
+	flushParagraph()
+	closeList()
+	return b.String()
+}
+
+// headingLevel returns the level of the heading on the given line, or zero if
+// the line is not a heading.
+func headingLevel(line string) int {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 || level == len(line) || line[level] != ' ' {
+		return 0
+	}
+	return level
+}
+
+// listItem returns "ul" or "ol" and the item's content if the line is a list
+// item.
+func listItem(line string) (string, string) {
+	if len(line) >= 2 && (line[0] == '-' || line[0] == '*' || line[0] == '+') && line[1] == ' ' {
+		return "ul", strings.TrimSpace(line[2:])
+	}
+	i := 0
+	for i < len(line) && line[i] >= '0' && line[i] <= '9' {
+		i++
+	}
+	if i > 0 && i+1 < len(line) && (line[i] == '.' || line[i] == ')') && line[i+1] == ' ' {
+		return "ol", strings.TrimSpace(line[i+2:])
+	}
+	return "", ""
+}
+
+// markdownInline converts inline Markdown syntax into HTML.
+func markdownInline(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && strings.IndexByte("\\`*_~[]()#+-.!", s[i+1]) >= 0:
+			b.WriteString(html.EscapeString(s[i+1 : i+2]))
+			i += 2
+			continue
+
+		case c == '`':
+			if end := strings.IndexByte(s[i+1:], '`'); end >= 0 {
+				b.WriteString("<code>" + html.EscapeString(s[i+1:i+1+end]) + "</code>")
+				i += end + 2
+				continue
+			}
+
+		case c == '[':
+			if text, url, n := markdownLink(s[i:]); n > 0 {
+				if safeAttr("a", htmlAttr{name: "href", value: url}) {
+					b.WriteString(`<a href="` + html.EscapeString(url) + `">` + markdownInline(text) + "</a>")
+				} else {
+					b.WriteString(markdownInline(text))
+				}
+				i += n
+				continue
+			}
+
+		case c == '~' && strings.HasPrefix(s[i:], "~~"):
+			if end := strings.Index(s[i+2:], "~~"); end > 0 {
+				b.WriteString("<s>" + markdownInline(s[i+2:i+2+end]) + "</s>")
+				i += end + 4
+				continue
+			}
+
+		case (c == '*' || c == '_') && i+1 < len(s) && s[i+1] == c:
+			delim := s[i : i+2]
+			if end := strings.Index(s[i+2:], delim); end > 0 && canOpen(s, i, 2) {
+				b.WriteString("<b>" + markdownInline(s[i+2:i+2+end]) + "</b>")
+				i += end + 4
+				continue
+			}
+
+		case c == '*' || c == '_':
+			if end := strings.IndexByte(s[i+1:], c); end > 0 && canOpen(s, i, 1) {
+				b.WriteString("<i>" + markdownInline(s[i+1:i+1+end]) + "</i>")
+				i += end + 2
+				continue
+			}
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		b.WriteString(html.EscapeString(s[i : i+size]))
+		i += size
+	}
+	return b.String()
+}
+
+// canOpen returns true if the emphasis delimiter of length n at s[i] can open
+// emphasis: it must be followed by a non-space character, and an underscore
+// must not be inside a word (e.g. snake_case).
+func canOpen(s string, i, n int) bool {
+	if i+n >= len(s) || s[i+n] == ' ' {
+		return false
+	}
+	if s[i] == '_' && i > 0 {
+		r, _ := utf8.DecodeLastRuneInString(s[:i])
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// markdownLink parses a link of the form [text](url) at the start of s. It
+// returns the number of bytes consumed, or zero if s does not start with a
+// link.
+func markdownLink(s string) (string, string, int) {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				if i+1 >= len(s) || s[i+1] != '(' {
+					return "", "", 0
+				}
+				end := strings.IndexByte(s[i+2:], ')')
+				if end < 0 {
+					return "", "", 0
+				}
+				return s[1:i], strings.TrimSpace(s[i+2 : i+2+end]), i + 2 + end + 1
+			}
+		}
+	}
+	return "", "", 0
+}
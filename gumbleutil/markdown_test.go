@@ -0,0 +1,48 @@
+package gumbleutil
+
+import (
+	"testing"
+)
+
+func TestMarkdown(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"hello", "<p>hello</p>"},
+		{"line one\nline two\n\nnext", "<p>line one<br />line two</p><p>next</p>"},
+		{"CR\r\nLF", "<p>CR<br />LF</p>"},
+		{"<script>&", "<p>&lt;script&gt;&amp;</p>"},
+
+		{"# Title", "<h1>Title</h1>"},
+		{"###### six ##", "<h6>six</h6>"},
+		{"####### seven", "<p>####### seven</p>"},
+		{"#nospace", "<p>#nospace</p>"},
+
+		{"**bold** and __bold__", "<p><b>bold</b> and <b>bold</b></p>"},
+		{"*it* and _it_", "<p><i>it</i> and <i>it</i></p>"},
+		{"snake_case_name", "<p>snake_case_name</p>"},
+		{"a ** b", "<p>a ** b</p>"},
+		{"~~gone~~", "<p><s>gone</s></p>"},
+		{"`a < b`", "<p><code>a &lt; b</code></p>"},
+		{`\*not italics\*`, "<p>*not italics*</p>"},
+
+		{"[site](https://example.com)", `<p><a href="https://example.com">site</a></p>`},
+		{"**[x](http://a.b)**", `<p><b><a href="http://a.b">x</a></b></p>`},
+		{"[bad](javascript:alert)", "<p>bad</p>"},
+		{"[not a link]", "<p>[not a link]</p>"},
+
+		{"- one\n* two\n+ three", "<ul><li>one</li><li>two</li><li>three</li></ul>"},
+		{"1. one\n2) two", "<ol><li>one</li><li>two</li></ol>"},
+		{"- a\n1. b", "<ul><li>a</li></ul><ol><li>b</li></ol>"},
+		{"text\n- item", "<p>text</p><ul><li>item</li></ul>"},
+
+		{"```\n<b>x</b>\n  **y**\n```", "<pre>&lt;b&gt;x&lt;/b&gt;\n  **y**</pre>"},
+		{"```\nunterminated", "<pre>unterminated</pre>"},
+	}
+	for _, tt := range tests {
+		if got := Markdown(tt.text); got != tt.want {
+			t.Errorf("Markdown(%q) = %q; want %q", tt.text, got, tt.want)
+		}
+	}
+}
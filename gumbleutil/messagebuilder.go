@@ -0,0 +1,178 @@
+package gumbleutil
+
+import (
+	"encoding/base64"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"layeh.com/gumble/gumble"
+)
+
+// MessageBuilder composes an HTML text message. The zero value is an empty
+// message that is ready to use:
+//
+//  var b gumbleutil.MessageBuilder
+//  b.Text("Now playing: " + title).LineBreak()
+//  b.Image(cover, "", "album cover")
+//  if err := b.Send(client, client.Self.Channel); err != nil {
+//    // the message is too long for the server
+//  }
+type MessageBuilder struct {
+	b      strings.Builder
+	images int
+}
+
+// Text appends text to the message. HTML special characters are escaped, and
+// line breaks are converted to br tags.
+func (m *MessageBuilder) Text(text string) *MessageBuilder {
+	for i, line := range strings.Split(text, "\n") {
+		if i > 0 {
+			m.LineBreak()
+		}
+		m.b.WriteString(html.EscapeString(line))
+	}
+	return m
+}
+
+// HTML appends s to the message without escaping it.
+func (m *MessageBuilder) HTML(s string) *MessageBuilder {
+	m.b.WriteString(s)
+	return m
+}
+
+// LineBreak appends a line break to the message.
+func (m *MessageBuilder) LineBreak() *MessageBuilder {
+	m.b.WriteString("<br />")
+	return m
+}
+
+// Link appends a link to url, whose text is text (or url, if text is empty).
+func (m *MessageBuilder) Link(url, text string) *MessageBuilder {
+	if text == "" {
+		text = url
+	}
+	m.b.WriteString(`<a href="` + html.EscapeString(url) + `">`)
+	m.Text(text)
+	m.b.WriteString("</a>")
+	return m
+}
+
+// Image appends an image to the message, embedded as a base64 data URI.
+// If mimeType is empty, it is detected from data (e.g. "image/png"). alt is
+// the image's alternative text, and can be empty.
+//
+// Servers allow a message that contains an image to be longer than other
+// messages (see gumble.Client.MaximumImageMessageLength), but images are
+// still limited to a few tens of kilobytes by most servers.
+func (m *MessageBuilder) Image(data []byte, mimeType, alt string) *MessageBuilder {
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+	m.b.WriteString(`<img src="data:` + html.EscapeString(mimeType) + ";base64,")
+	m.b.WriteString(base64.StdEncoding.EncodeToString(data))
+	m.b.WriteString(`"`)
+	if alt != "" {
+		m.b.WriteString(` alt="` + html.EscapeString(alt) + `"`)
+	}
+	m.b.WriteString(" />")
+	m.images++
+	return m
+}
+
+// FitImage appends an image to the message like Image, but first resizes it
+// with ResizeImage, if needed, so that the message fits the server's image
+// message length limit (see Check). ErrImageTooLarge is returned, and nothing
+// is appended, if the image cannot be made small enough.
+func (m *MessageBuilder) FitImage(client *gumble.Client, data []byte, alt string) error {
+	_, imageLimit := messageLimits(client)
+	var empty MessageBuilder
+	empty.Image(nil, "image/jpeg", alt)
+	available := imageLimit - m.Len() - empty.Len()
+	// Base64 encodes 3 bytes in 4 characters.
+	resized, mimeType, err := ResizeImage(data, available/4*3)
+	if err != nil {
+		return err
+	}
+	m.Image(resized, mimeType, alt)
+	return nil
+}
+
+// Len returns the length of the message, in bytes.
+func (m *MessageBuilder) Len() int {
+	return m.b.Len()
+}
+
+// HasImage returns true if an image has been appended to the message.
+func (m *MessageBuilder) HasImage() bool {
+	return m.images > 0
+}
+
+// String returns the message.
+func (m *MessageBuilder) String() string {
+	return m.b.String()
+}
+
+// Reset empties the message.
+func (m *MessageBuilder) Reset() {
+	m.b.Reset()
+	m.images = 0
+}
+
+// MessageTooLongError is returned by MessageBuilder.Check and
+// MessageBuilder.Send when the message is longer than the server allows.
+type MessageTooLongError struct {
+	Length int
+	Limit  int
+	// Does the message contain an image (and so Limit is the server's image
+	// message length limit)?
+	Image bool
+}
+
+func (e *MessageTooLongError) Error() string {
+	kind := "message"
+	if e.Image {
+		kind = "image message"
+	}
+	return "gumbleutil: " + kind + " is " + strconv.Itoa(e.Length) + " bytes, longer than the server's limit of " + strconv.Itoa(e.Limit)
+}
+
+// Is returns true if target is gumble.ErrMessageTooLong.
+func (e *MessageTooLongError) Is(target error) bool {
+	return target == gumble.ErrMessageTooLong
+}
+
+// Check returns a *MessageTooLongError if the message is longer than the
+// server's message length limit, or its image message length limit if the
+// message contains an image. The defaults of the Mumble server are used if
+// the server has not sent its limits.
+func (m *MessageBuilder) Check(client *gumble.Client) error {
+	limit, imageLimit := messageLimits(client)
+	image := m.HasImage()
+	if image && imageLimit > limit {
+		limit = imageLimit
+	}
+	if m.Len() > limit {
+		return &MessageTooLongError{
+			Length: m.Len(),
+			Limit:  limit,
+			Image:  image,
+		}
+	}
+	return nil
+}
+
+// Send sends the message to target, which must be a *gumble.User or a
+// *gumble.Channel, if it passes Check. Unlike SendLongMessage, the message is
+// never split, as images cannot be split between messages.
+func (m *MessageBuilder) Send(client *gumble.Client, target interface{}) error {
+	send, err := messageSender(target)
+	if err != nil {
+		return err
+	}
+	if err := m.Check(client); err != nil {
+		return err
+	}
+	return send(m.String())
+}
@@ -0,0 +1,181 @@
+package gumbleutil
+
+import (
+	"errors"
+	"sync"
+
+	"layeh.com/gumble/gumble"
+)
+
+// Plugin is an independent bot component (e.g. a soundboard, or an AFK mover)
+// that is managed by a PluginRegistry.
+type Plugin interface {
+	// Attach attaches the plugin's event and audio listeners to config. It is
+	// called once, when the plugin is registered. The returned Detacher is
+	// used to detach the listeners when the plugin is unregistered.
+	Attach(config *gumble.Config) gumble.Detacher
+	// Init is called each time the client connects to the server, including
+	// after reconnecting, and when the plugin is registered while the client
+	// is connected. It is called before the plugin's ConnectEvent listeners.
+	Init(client *gumble.Client) error
+	// Shutdown is called when the plugin is unregistered, after its listeners
+	// have been detached.
+	Shutdown()
+}
+
+// ErrPluginRegistered is returned by PluginRegistry.Register when a plugin is
+// registered more than once.
+var ErrPluginRegistered = errors.New("gumbleutil: plugin already registered")
+
+// AsPlugin returns a Plugin whose Init and Shutdown methods do nothing, for
+// components that only attach listeners (e.g. *Tracker, *AFKMover).
+func AsPlugin(component interface {
+	Attach(config *gumble.Config) gumble.Detacher
+}) Plugin {
+	return attachPlugin{component}
+}
+
+type attachPlugin struct {
+	component interface {
+		Attach(config *gumble.Config) gumble.Detacher
+	}
+}
+
+func (p attachPlugin) Attach(config *gumble.Config) gumble.Detacher {
+	return p.component.Attach(config)
+}
+
+func (attachPlugin) Init(client *gumble.Client) error { return nil }
+
+func (attachPlugin) Shutdown() {}
+
+// PluginRegistry manages the lifecycle of the plugins of a single
+// gumble.Config. The same config, and therefore the same plugins, can be used
+// across reconnects.
+type PluginRegistry struct {
+	// OnError, if non-nil, is called when a plugin's Init method returns an
+	// error. The plugin remains registered.
+	OnError func(plugin Plugin, err error)
+
+	config   *gumble.Config
+	detacher gumble.Detacher
+
+	mu      sync.Mutex
+	client  *gumble.Client
+	plugins []*pluginEntry
+}
+
+type pluginEntry struct {
+	plugin   Plugin
+	detacher gumble.Detacher
+}
+
+// NewPluginRegistry returns a new PluginRegistry for the given config. It
+// should be created before the client connects.
+func NewPluginRegistry(config *gumble.Config) *PluginRegistry {
+	r := &PluginRegistry{
+		config: config,
+	}
+	r.detacher = config.Attach(Listener{
+		Connect:    r.onConnect,
+		Disconnect: r.onDisconnect,
+	})
+	return r
+}
+
+func (r *PluginRegistry) onConnect(e *gumble.ConnectEvent) {
+	r.mu.Lock()
+	r.client = e.Client
+	plugins := r.list()
+	r.mu.Unlock()
+	for _, plugin := range plugins {
+		r.init(plugin, e.Client)
+	}
+}
+
+func (r *PluginRegistry) onDisconnect(e *gumble.DisconnectEvent) {
+	r.mu.Lock()
+	if r.client == e.Client {
+		r.client = nil
+	}
+	r.mu.Unlock()
+}
+
+func (r *PluginRegistry) init(plugin Plugin, client *gumble.Client) {
+	if err := plugin.Init(client); err != nil && r.OnError != nil {
+		r.OnError(plugin, err)
+	}
+}
+
+// list returns the registered plugins.
+//
+// r.mu must be held when calling this function.
+func (r *PluginRegistry) list() []Plugin {
+	plugins := make([]Plugin, len(r.plugins))
+	for i, entry := range r.plugins {
+		plugins[i] = entry.plugin
+	}
+	return plugins
+}
+
+// Register attaches the plugin's listeners to the registry's config. If the
+// client is connected, the plugin's Init method is called immediately, and
+// its error is returned.
+func (r *PluginRegistry) Register(plugin Plugin) error {
+	r.mu.Lock()
+	for _, entry := range r.plugins {
+		if entry.plugin == plugin {
+			r.mu.Unlock()
+			return ErrPluginRegistered
+		}
+	}
+	r.plugins = append(r.plugins, &pluginEntry{
+		plugin:   plugin,
+		detacher: plugin.Attach(r.config),
+	})
+	client := r.client
+	r.mu.Unlock()
+	if client != nil {
+		return plugin.Init(client)
+	}
+	return nil
+}
+
+// Unregister detaches the plugin's listeners and calls its Shutdown method.
+// false is returned if the plugin is not registered.
+func (r *PluginRegistry) Unregister(plugin Plugin) bool {
+	r.mu.Lock()
+	var entry *pluginEntry
+	for i, e := range r.plugins {
+		if e.plugin == plugin {
+			entry = e
+			r.plugins = append(r.plugins[:i], r.plugins[i+1:]...)
+			break
+		}
+	}
+	r.mu.Unlock()
+	if entry == nil {
+		return false
+	}
+	entry.detacher.Detach()
+	plugin.Shutdown()
+	return true
+}
+
+// Plugins returns the registered plugins, in the order in which they were
+// registered.
+func (r *PluginRegistry) Plugins() []Plugin {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.list()
+}
+
+// Close unregisters all of the plugins, in the reverse order in which they
+// were registered, and detaches the registry from its config.
+func (r *PluginRegistry) Close() {
+	plugins := r.Plugins()
+	for i := len(plugins) - 1; i >= 0; i-- {
+		r.Unregister(plugins[i])
+	}
+	r.detacher.Detach()
+}
@@ -0,0 +1,225 @@
+package gumbleutil
+
+import (
+	"crypto/tls"
+	"net"
+	"sync"
+	"time"
+
+	"layeh.com/gumble/gumble"
+)
+
+// AutoReconnect reconnects to a server after the client is disconnected, and
+// restores the client's previous state: its channel, self mute and deafen
+// state, comment, and voice targets.
+//
+// It is created with AttachAutoReconnect.
+type AutoReconnect struct {
+	// The dialer used to reconnect. If nil, a zero net.Dialer is used.
+	Dialer *net.Dialer
+	// If non-nil, called to reconnect instead of gumble.DialWithDialer (e.g.
+	// to connect over a wrapped connection with gumble.DialWithConn).
+	Dial func(config *gumble.Config) (*gumble.Client, error)
+	// Voice targets that are sent to the server after reconnecting, in
+	// addition to the client's current VoiceTarget. Only channel targets are
+	// restored, as users receive new sessions when they reconnect.
+	VoiceTargets []*gumble.VoiceTarget
+
+	// Called with the new client after reconnecting and restoring its state.
+	OnReconnect func(client *gumble.Client)
+	// Called when a reconnect attempt fails.
+	OnError func(attempt int, err error)
+	// Called when reconnecting is abandoned because the policy's MaxAttempts
+	// has been reached.
+	OnGiveUp func()
+
+	config    *gumble.Config
+	addr      string
+	tlsConfig *tls.Config
+	policy    *gumble.ReconnectPolicy
+	detacher  gumble.Detacher
+
+	mu      sync.Mutex
+	client  *gumble.Client
+	state   *reconnectState
+	stop    chan struct{}
+	stopped bool
+}
+
+// reconnectState is the client state that is restored after reconnecting.
+type reconnectState struct {
+	channelID    uint32
+	channelPath  []string
+	selfMuted    bool
+	selfDeafened bool
+	comment      string
+	voiceTarget  *gumble.VoiceTarget
+}
+
+// AttachAutoReconnect attaches a listener to config that reconnects to addr
+// when the client is disconnected.
+//
+// Reconnect attempts are made according to config.ReconnectPolicy, or
+// gumble.NewReconnectPolicy() if it is nil. The client is not reconnected if
+// it was disconnected by calling Client.Disconnect, or if it was banned.
+func AttachAutoReconnect(config *gumble.Config, addr string, tlsConfig *tls.Config) *AutoReconnect {
+	policy := config.ReconnectPolicy
+	if policy == nil {
+		policy = gumble.NewReconnectPolicy()
+	}
+	a := &AutoReconnect{
+		config:    config,
+		addr:      addr,
+		tlsConfig: tlsConfig,
+		policy:    policy,
+		stop:      make(chan struct{}),
+	}
+	a.detacher = config.Attach(Listener{
+		Connect:    a.onConnect,
+		Disconnect: a.onDisconnect,
+	})
+	return a
+}
+
+// Client returns the most recently connected client.
+func (a *AutoReconnect) Client() *gumble.Client {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.client
+}
+
+// Stop detaches the listener and cancels any pending reconnect attempt.
+func (a *AutoReconnect) Stop() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.stopped {
+		return
+	}
+	a.stopped = true
+	close(a.stop)
+	a.detacher.Detach()
+}
+
+func (a *AutoReconnect) onConnect(e *gumble.ConnectEvent) {
+	a.mu.Lock()
+	a.client = e.Client
+	state := a.state
+	a.state = nil
+	a.mu.Unlock()
+
+	if state == nil {
+		return
+	}
+	a.restore(e.Client, state)
+	if a.OnReconnect != nil {
+		a.OnReconnect(e.Client)
+	}
+}
+
+func (a *AutoReconnect) onDisconnect(e *gumble.DisconnectEvent) {
+	if !a.policy.ShouldReconnect(e.Type) {
+		return
+	}
+	state := &reconnectState{}
+	if self := e.Client.Self; self != nil {
+		state.selfMuted = self.SelfMuted
+		state.selfDeafened = self.SelfDeafened
+		state.comment = self.Comment
+		if self.Channel != nil {
+			state.channelID = self.Channel.ID
+			for c := self.Channel; c != nil && !c.IsRoot(); c = c.Parent {
+				state.channelPath = append([]string{c.Name}, state.channelPath...)
+			}
+		}
+	}
+	state.voiceTarget = e.Client.VoiceTarget
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.stopped {
+		return
+	}
+	a.state = state
+	go a.reconnect()
+}
+
+func (a *AutoReconnect) reconnect() {
+	dialer := a.Dialer
+	if dialer == nil {
+		dialer = new(net.Dialer)
+	}
+	for attempt := 0; ; attempt++ {
+		delay, ok := a.policy.Delay(attempt)
+		if !ok {
+			if a.OnGiveUp != nil {
+				a.OnGiveUp()
+			}
+			return
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-a.stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		var err error
+		if a.Dial != nil {
+			_, err = a.Dial(a.config)
+		} else {
+			_, err = gumble.DialWithDialer(dialer, a.addr, a.config, a.tlsConfig)
+		}
+		if err == nil {
+			return
+		}
+		if a.OnError != nil {
+			a.OnError(attempt, err)
+		}
+	}
+}
+
+// restore applies the state saved before the disconnect to client.
+func (a *AutoReconnect) restore(client *gumble.Client, state *reconnectState) {
+	self := client.Self
+	if self == nil {
+		return
+	}
+
+	channel := client.Channels[state.channelID]
+	if channel == nil || !channelPathMatches(channel, state.channelPath) {
+		if root := client.Channels[0]; root != nil {
+			channel = root.Find(state.channelPath...)
+		}
+	}
+	if channel != nil && channel != self.Channel {
+		self.Move(channel)
+	}
+	if state.selfMuted != self.SelfMuted {
+		self.SetSelfMuted(state.selfMuted)
+	}
+	if state.selfDeafened != self.SelfDeafened {
+		self.SetSelfDeafened(state.selfDeafened)
+	}
+	if state.comment != "" && state.comment != self.Comment {
+		self.SetComment(state.comment)
+	}
+	for _, target := range a.VoiceTargets {
+		client.Send(target)
+	}
+	if state.voiceTarget != nil {
+		if state.voiceTarget != gumble.VoiceTargetLoopback {
+			client.Send(state.voiceTarget)
+		}
+		client.VoiceTarget = state.voiceTarget
+	}
+}
+
+func channelPathMatches(channel *gumble.Channel, path []string) bool {
+	for i := len(path) - 1; i >= 0; i-- {
+		if channel == nil || channel.Name != path[i] {
+			return false
+		}
+		channel = channel.Parent
+	}
+	return channel != nil && channel.IsRoot()
+}
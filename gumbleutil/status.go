@@ -0,0 +1,175 @@
+package gumbleutil
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"layeh.com/gumble/gumble"
+	"layeh.com/gumble/gumbleffmpeg"
+)
+
+// Status is the report written by StatusHandler.
+type Status struct {
+	// The state of the client (see gumble.State).
+	State  string `json:"state"`
+	Server string `json:"server,omitempty"`
+	// How long the client has been connected to the server.
+	Uptime Duration `json:"uptime,omitempty"`
+	// The average TCP ping latency to the server.
+	Ping Duration `json:"ping,omitempty"`
+	// The client's name and channel (see ChannelPathString).
+	Username string `json:"username,omitempty"`
+	Channel  string `json:"channel,omitempty"`
+	// The number of users connected to the server, and the channels that have
+	// users in them, sorted by path.
+	Users    int             `json:"users"`
+	Channels []ChannelStatus `json:"channels,omitempty"`
+	// The stream set by StatusHandler.NowPlaying, if it has not stopped.
+	NowPlaying *NowPlayingStatus `json:"now_playing,omitempty"`
+}
+
+// ChannelStatus is the occupancy of a channel in a Status.
+type ChannelStatus struct {
+	Path  string   `json:"path"`
+	Users []string `json:"users"`
+}
+
+// NowPlayingStatus is the now-playing information in a Status.
+type NowPlayingStatus struct {
+	Title   string   `json:"title"`
+	Paused  bool     `json:"paused,omitempty"`
+	Elapsed Duration `json:"elapsed"`
+}
+
+// StatusHandler is an http.Handler that reports the status of the clients
+// that use a gumble.Config as JSON, for health checks and status pages:
+//
+//  http.Handle("/status", gumbleutil.NewStatusHandler(config))
+//
+// The handler responds with 503 Service Unavailable while the client is not
+// connected to the server.
+type StatusHandler struct {
+	detacher gumble.Detacher
+
+	mu     sync.Mutex
+	client *gumble.Client
+	stream *gumbleffmpeg.Stream
+	title  string
+}
+
+// NewStatusHandler returns a new StatusHandler that reports the status of the
+// clients that use config.
+func NewStatusHandler(config *gumble.Config) *StatusHandler {
+	h := &StatusHandler{}
+	h.detacher = config.Attach(Listener{
+		Connect: func(e *gumble.ConnectEvent) {
+			h.mu.Lock()
+			h.client = e.Client
+			h.mu.Unlock()
+		},
+		Disconnect: func(e *gumble.DisconnectEvent) {
+			h.mu.Lock()
+			if h.client == e.Client {
+				h.client = nil
+			}
+			h.mu.Unlock()
+		},
+	})
+	return h
+}
+
+// NowPlaying sets the stream that is reported as now playing, with the given
+// title. The stream is no longer reported once it stops. A nil stream clears
+// the now-playing information.
+func (h *StatusHandler) NowPlaying(stream *gumbleffmpeg.Stream, title string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.stream = stream
+	h.title = title
+}
+
+// Detach stops the handler from tracking the clients. The handler then always
+// reports that the client is disconnected.
+func (h *StatusHandler) Detach() {
+	h.detacher.Detach()
+	h.mu.Lock()
+	h.client = nil
+	h.mu.Unlock()
+}
+
+// Status returns the current status.
+func (h *StatusHandler) Status() *Status {
+	h.mu.Lock()
+	client, stream, title := h.client, h.stream, h.title
+	h.mu.Unlock()
+
+	status := &Status{
+		State: gumble.StateDisconnected.String(),
+	}
+	if stream != nil {
+		switch stream.State() {
+		case gumbleffmpeg.StatePlaying, gumbleffmpeg.StatePaused:
+			status.NowPlaying = &NowPlayingStatus{
+				Title:   title,
+				Paused:  stream.State() == gumbleffmpeg.StatePaused,
+				Elapsed: Duration(stream.Elapsed()),
+			}
+		}
+	}
+	if client == nil {
+		return status
+	}
+
+	snapshot := client.Snapshot()
+	status.State = snapshot.State
+	status.Server = snapshot.Server.Address
+	status.Ping = Duration(snapshot.Ping)
+	status.Users = len(snapshot.Users)
+	if !snapshot.Stats.Connected.IsZero() {
+		status.Uptime = Duration(time.Since(snapshot.Stats.Connected).Truncate(time.Second))
+	}
+	client.Do(func() {
+		if client.Self != nil {
+			status.Username = client.Self.Name
+			if client.Self.Channel != nil {
+				status.Channel = ChannelPathString(client.Self.Channel)
+			}
+		}
+		for _, channel := range client.Channels {
+			if len(channel.Users) == 0 {
+				continue
+			}
+			c := ChannelStatus{
+				Path: ChannelPathString(channel),
+			}
+			for _, user := range channel.Users {
+				c.Users = append(c.Users, user.Name)
+			}
+			sort.Strings(c.Users)
+			status.Channels = append(status.Channels, c)
+		}
+	})
+	sort.Slice(status.Channels, func(i, j int) bool {
+		return status.Channels[i].Path < status.Channels[j].Path
+	})
+	return status
+}
+
+// ServeHTTP implements http.Handler.
+func (h *StatusHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	status := h.Status()
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if status.State != gumble.StateSynced.String() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if r.Method == http.MethodHead {
+		return
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(status)
+}
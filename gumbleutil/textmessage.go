@@ -1,45 +1,74 @@
 package gumbleutil
 
 import (
-	"bytes"
-	"encoding/xml"
+	"html"
 	"strings"
 
 	"layeh.com/gumble/gumble"
 )
 
 // PlainText returns the Message string without HTML tags or entities.
+//
+// Line breaks are added for block elements (e.g. p, div) and br tags. Links
+// are replaced by their text, followed by their URL in parentheses if it
+// differs from the text. Images are replaced by their alt text, or "[image]".
+// The result has leading and trailing whitespace removed.
 func PlainText(tm *gumble.TextMessage) string {
-	d := xml.NewDecoder(strings.NewReader(tm.Message))
-	d.Strict = false
-	d.AutoClose = xml.HTMLAutoClose
-	d.Entity = xml.HTMLEntity
-
-	var b bytes.Buffer
-	newline := false
-	for {
-		t, _ := d.Token()
-		if t == nil {
-			break
+	var b strings.Builder
+	newline := true
+	hidden := 0
+	var link string
+	var linkText strings.Builder
+	write := func(s string) {
+		if link != "" {
+			linkText.WriteString(s)
 		}
-		switch node := t.(type) {
-		case xml.CharData:
-			if len(node) > 0 {
-				b.Write(node)
-				newline = false
+		b.WriteString(s)
+		newline = strings.HasSuffix(s, "\n")
+	}
+	for _, t := range tokenizeHTML(tm.Message) {
+		if t.tag == "" {
+			if hidden == 0 && t.text != "" {
+				write(html.UnescapeString(t.text))
 			}
-		case xml.StartElement:
-			switch node.Name.Local {
-			case "address", "article", "aside", "audio", "blockquote", "canvas", "dd", "div", "dl", "fieldset", "figcaption", "figure", "footer", "form", "h1", "h2", "h3", "h4", "h5", "h6", "header", "hgroup", "hr", "noscript", "ol", "output", "p", "pre", "section", "table", "tfoot", "ul", "video":
-				if !newline {
-					b.WriteByte('\n')
-					newline = true
+			continue
+		}
+		if htmlHiddenTags[t.tag] {
+			if t.closing {
+				if hidden > 0 {
+					hidden--
 				}
-			case "br":
-				b.WriteByte('\n')
-				newline = true
+			} else if !t.selfClosing {
+				hidden++
+			}
+			continue
+		}
+		if hidden > 0 {
+			continue
+		}
+		switch {
+		case t.tag == "br" && !t.closing:
+			write("\n")
+		case t.tag == "img" && !t.closing:
+			if alt, ok := t.attr("alt"); ok && alt != "" {
+				write(alt)
+			} else {
+				write("[image]")
+			}
+		case t.tag == "a" && !t.closing:
+			link, _ = t.attr("href")
+			linkText.Reset()
+		case t.tag == "a" && t.closing:
+			href := link
+			link = ""
+			if href != "" && strings.TrimSpace(linkText.String()) != href {
+				write(" (" + href + ")")
+			}
+		case htmlBlockTags[t.tag]:
+			if !newline {
+				write("\n")
 			}
 		}
 	}
-	return b.String()
+	return strings.TrimSpace(b.String())
 }
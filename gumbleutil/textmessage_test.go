@@ -0,0 +1,34 @@
+package gumbleutil
+
+import (
+	"testing"
+
+	"layeh.com/gumble/gumble"
+)
+
+func TestPlainText(t *testing.T) {
+	tests := []struct {
+		message string
+		want    string
+	}{
+		{"plain text", "plain text"},
+		{"<p>a &amp;&lt; b</p>", "a &< b"},
+		{`<a href="https://x.y">l</a>`, "l (https://x.y)"},
+		{`<img src="data:image/png;base64,AA" alt="a">`, "a"},
+		{`<img src="data:image/png;base64,AA">`, "[image]"},
+		{"<script>alert(1)</script>after", "after"},
+
+		// Malformed HTML.
+		{"a < b", "a < b"},
+		{"<b", "<b"},
+		{"<b><i>unclosed", "unclosed"},
+		{"<b><i>x</b>y", "xy"},
+		{"</b>stray", "stray"},
+		{"<scr<script>ipt>", "ipt>"},
+	}
+	for _, tt := range tests {
+		if got := PlainText(&gumble.TextMessage{Message: tt.message}); got != tt.want {
+			t.Errorf("PlainText(%q) = %q; want %q", tt.message, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package gumbleutil
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+)
+
+// FingerprintStore stores the certificate fingerprints of servers, for
+// VerifyServer.
+type FingerprintStore interface {
+	// Fingerprint returns the stored fingerprint of the server at addr. The
+	// empty string is returned if there is no stored fingerprint.
+	Fingerprint(addr string) (string, error)
+	// SetFingerprint stores the fingerprint of the server at addr.
+	SetFingerprint(addr, fingerprint string) error
+}
+
+// CertificateChangedError is returned when a server's certificate does not
+// match its stored fingerprint.
+type CertificateChangedError struct {
+	Addr string
+	// The stored and presented fingerprints.
+	Expected, Actual string
+	// The certificate presented by the server.
+	Certificate *x509.Certificate
+}
+
+func (e *CertificateChangedError) Error() string {
+	return "gumbleutil: certificate of " + e.Addr + " has changed (expected fingerprint " + e.Expected + ", got " + e.Actual + ")"
+}
+
+// CertificateFingerprint returns the hex encoded SHA-256 hash of the
+// certificate.
+func CertificateFingerprint(cert *x509.Certificate) string {
+	hash := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(hash[:])
+}
+
+// VerifyServer configures tlsConfig to verify the server at addr by trust on
+// first use, instead of by its certificate chain: the fingerprint of the
+// server's certificate is stored on the first connection, and the connection
+// fails with a *CertificateChangedError on later connections if the server
+// presents a different certificate.
+//
+// If onChanged is non-nil, it is called when the certificate has changed. If
+// it returns true, the new fingerprint is stored and the connection
+// continues.
+func VerifyServer(tlsConfig *tls.Config, addr string, store FingerprintStore, onChanged func(e *CertificateChangedError) bool) {
+	tlsConfig.InsecureSkipVerify = true
+	tlsConfig.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return errors.New("gumbleutil: server did not present a certificate")
+		}
+		cert, err := x509.ParseCertificate(rawCerts[0])
+		if err != nil {
+			return err
+		}
+		actual := CertificateFingerprint(cert)
+		expected, err := store.Fingerprint(addr)
+		if err != nil {
+			return err
+		}
+		if expected == "" {
+			return store.SetFingerprint(addr, actual)
+		}
+		if strings.EqualFold(expected, actual) {
+			return nil
+		}
+		changed := &CertificateChangedError{
+			Addr:        addr,
+			Expected:    expected,
+			Actual:      actual,
+			Certificate: cert,
+		}
+		if onChanged != nil && onChanged(changed) {
+			return store.SetFingerprint(addr, actual)
+		}
+		return changed
+	}
+}
+
+// MemoryFingerprintStore is a FingerprintStore that keeps fingerprints in
+// memory.
+type MemoryFingerprintStore struct {
+	mu           sync.Mutex
+	fingerprints map[string]string
+}
+
+// Fingerprint implements FingerprintStore.
+func (m *MemoryFingerprintStore) Fingerprint(addr string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.fingerprints[addr], nil
+}
+
+// SetFingerprint implements FingerprintStore.
+func (m *MemoryFingerprintStore) SetFingerprint(addr, fingerprint string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.fingerprints == nil {
+		m.fingerprints = make(map[string]string)
+	}
+	m.fingerprints[addr] = fingerprint
+	return nil
+}
+
+// FileFingerprintStore is a FingerprintStore that keeps fingerprints in a
+// text file, with one "<addr> <fingerprint>" pair per line (similar to SSH's
+// known_hosts file). The file is created when the first fingerprint is
+// stored.
+type FileFingerprintStore struct {
+	Filename string
+
+	mu sync.Mutex
+}
+
+// Fingerprint implements FingerprintStore.
+func (f *FileFingerprintStore) Fingerprint(addr string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fingerprints, err := f.read()
+	if err != nil {
+		return "", err
+	}
+	return fingerprints[addr], nil
+}
+
+// SetFingerprint implements FingerprintStore.
+func (f *FileFingerprintStore) SetFingerprint(addr, fingerprint string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fingerprints, err := f.read()
+	if err != nil {
+		return err
+	}
+	fingerprints[addr] = fingerprint
+
+	var b strings.Builder
+	for a, fp := range fingerprints {
+		b.WriteString(a + " " + fp + "\n")
+	}
+	return os.WriteFile(f.Filename, []byte(b.String()), 0600)
+}
+
+func (f *FileFingerprintStore) read() (map[string]string, error) {
+	fingerprints := make(map[string]string)
+	file, err := os.Open(f.Filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return fingerprints, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && !strings.HasPrefix(fields[0], "#") {
+			fingerprints[fields[0]] = fields[1]
+		}
+	}
+	return fingerprints, scanner.Err()
+}
@@ -0,0 +1,110 @@
+package gumbleutil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// serverCertificate returns the raw certificate chain of a new self-signed
+// certificate, as passed to tls.Config.VerifyPeerCertificate, and its
+// fingerprint.
+func serverCertificate(t *testing.T) ([][]byte, string) {
+	cert, _, err := GenerateCertificate("server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert.Certificate, CertificateFingerprint(leaf)
+}
+
+func TestVerifyServer(t *testing.T) {
+	const addr = "example.com:64738"
+	first, firstFingerprint := serverCertificate(t)
+	second, secondFingerprint := serverCertificate(t)
+
+	tests := []struct {
+		name string
+		// The fingerprint stored before connecting.
+		stored string
+		certs  [][]byte
+		accept bool
+		// Whether onChanged is called, whether the connection is allowed,
+		// and the fingerprint stored afterwards.
+		changed    bool
+		ok         bool
+		wantStored string
+	}{
+		{"first use", "", first, false, false, true, firstFingerprint},
+		{"same certificate", firstFingerprint, first, false, false, true, firstFingerprint},
+		{"fingerprint case", strings.ToUpper(firstFingerprint), first, false, false, true, strings.ToUpper(firstFingerprint)},
+		{"changed", firstFingerprint, second, false, true, false, firstFingerprint},
+		{"changed and accepted", firstFingerprint, second, true, true, true, secondFingerprint},
+		{"no certificate", firstFingerprint, nil, true, false, false, firstFingerprint},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &MemoryFingerprintStore{}
+			if tt.stored != "" {
+				store.SetFingerprint(addr, tt.stored)
+			}
+			var changed *CertificateChangedError
+			var tlsConfig tls.Config
+			VerifyServer(&tlsConfig, addr, store, func(e *CertificateChangedError) bool {
+				changed = e
+				return tt.accept
+			})
+			if !tlsConfig.InsecureSkipVerify {
+				t.Error("InsecureSkipVerify is not set")
+			}
+
+			err := tlsConfig.VerifyPeerCertificate(tt.certs, nil)
+			if ok := err == nil; ok != tt.ok {
+				t.Errorf("VerifyPeerCertificate error = %v; want ok = %v", err, tt.ok)
+			}
+			if !tt.changed {
+				if changed != nil {
+					t.Errorf("onChanged called with %+v; want no call", changed)
+				}
+			} else if changed == nil || changed.Addr != addr || changed.Expected != tt.stored || changed.Actual != secondFingerprint {
+				t.Errorf("onChanged called with %+v", changed)
+			} else if !tt.ok && err != error(changed) {
+				t.Errorf("VerifyPeerCertificate error = %v; want the CertificateChangedError", err)
+			}
+			if stored, _ := store.Fingerprint(addr); stored != tt.wantStored {
+				t.Errorf("stored fingerprint = %q; want %q", stored, tt.wantStored)
+			}
+		})
+	}
+}
+
+func TestFileFingerprintStore(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "known_servers")
+	store := &FileFingerprintStore{Filename: filename}
+
+	if fingerprint, err := store.Fingerprint("a:1"); err != nil || fingerprint != "" {
+		t.Fatalf("Fingerprint before the file exists = %q, %v; want \"\", nil", fingerprint, err)
+	}
+	if err := store.SetFingerprint("a:1", "aa"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetFingerprint("b:2", "bb"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetFingerprint("a:1", "cc"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new store reads the same file.
+	store = &FileFingerprintStore{Filename: filename}
+	for addr, want := range map[string]string{"a:1": "cc", "b:2": "bb", "c:3": ""} {
+		if fingerprint, err := store.Fingerprint(addr); err != nil || fingerprint != want {
+			t.Errorf("Fingerprint(%q) = %q, %v; want %q", addr, fingerprint, err, want)
+		}
+	}
+}
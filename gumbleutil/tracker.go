@@ -0,0 +1,285 @@
+package gumbleutil
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"layeh.com/gumble/gumble"
+)
+
+// UserActivityType is the type of a UserActivity.
+type UserActivityType int
+
+// User activity types.
+const (
+	UserJoined UserActivityType = iota
+	UserLeft
+	UserMoved
+	UserRenamed
+)
+
+// String returns the name of the activity type.
+func (t UserActivityType) String() string {
+	switch t {
+	case UserJoined:
+		return "joined"
+	case UserLeft:
+		return "left"
+	case UserMoved:
+		return "moved"
+	case UserRenamed:
+		return "renamed"
+	}
+	return "unknown"
+}
+
+// UserActivity is an entry in a user's history, recorded by a Tracker.
+type UserActivity struct {
+	Time time.Time
+	Type UserActivityType
+	// The path of the user's channel after the activity (see
+	// ChannelPathString).
+	Channel string
+	// The user's name after the activity.
+	Name string
+}
+
+// UserRecord is what a Tracker knows about a user.
+type UserRecord struct {
+	// The user's most recent name.
+	Name string
+	// The user's registered user ID, or zero if the user is not registered.
+	UserID uint32
+	// The user's most recent session.
+	Session uint32
+	// Whether the user is currently connected.
+	Online bool
+	// The path of the user's current (or last) channel.
+	Channel string
+
+	// When the user was first and last seen connected.
+	FirstSeen time.Time
+	LastSeen  time.Time
+	// When the user last joined the server.
+	LastJoined time.Time
+	// The total time the user has spent speaking, and when they last spoke.
+	SpeakingTime time.Duration
+	LastSpoke    time.Time
+
+	// The user's recent activity, oldest first.
+	History []UserActivity
+}
+
+// Tracker records when users join, leave, move, and rename, and how long they
+// speak for. Users are identified by their user ID if they are registered,
+// and by their name otherwise, so that their records persist across sessions.
+//
+// A Tracker must be attached to a gumble.Config using Attach.
+type Tracker struct {
+	// The maximum number of activities kept per user. If zero, 100 are kept.
+	MaxHistory int
+
+	mu       sync.Mutex
+	records  map[string]*UserRecord
+	sessions map[uint32]string
+}
+
+// NewTracker returns a new Tracker.
+func NewTracker() *Tracker {
+	return &Tracker{}
+}
+
+// Attach attaches the tracker's event and audio listeners to config.
+func (t *Tracker) Attach(config *gumble.Config) gumble.Detacher {
+	return multiDetacher{
+		config.Attach(Listener{
+			Connect:    t.onConnect,
+			UserChange: t.onUserChange,
+			Disconnect: t.onDisconnect,
+		}),
+		config.AttachAudio(t),
+	}
+}
+
+type multiDetacher []gumble.Detacher
+
+func (m multiDetacher) Detach() {
+	for _, d := range m {
+		d.Detach()
+	}
+}
+
+// Seen returns the record of the user with the given name. The match is case
+// insensitive.
+func (t *Tracker) Seen(name string) (UserRecord, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, record := range t.records {
+		if strings.EqualFold(record.Name, name) {
+			return copyRecord(record), true
+		}
+	}
+	return UserRecord{}, false
+}
+
+// Users returns the records of all of the users that have been seen, sorted
+// by name.
+func (t *Tracker) Users() []UserRecord {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	records := make([]UserRecord, 0, len(t.records))
+	for _, record := range t.records {
+		records = append(records, copyRecord(record))
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Name < records[j].Name
+	})
+	return records
+}
+
+func copyRecord(record *UserRecord) UserRecord {
+	r := *record
+	r.History = append([]UserActivity(nil), record.History...)
+	return r
+}
+
+func trackerKey(user *gumble.User) string {
+	if user.IsRegistered() {
+		return "id:" + strconv.FormatUint(uint64(user.UserID), 10)
+	}
+	return "name:" + user.Name
+}
+
+// record returns the record for the given user, creating it if needed.
+//
+// t.mu must be held when calling this function.
+func (t *Tracker) record(user *gumble.User) *UserRecord {
+	if t.records == nil {
+		t.records = make(map[string]*UserRecord)
+		t.sessions = make(map[uint32]string)
+	}
+	key := trackerKey(user)
+	if old, ok := t.sessions[user.Session]; ok && old != key {
+		// The user registered or was renamed; move the record.
+		if record := t.records[old]; record != nil {
+			delete(t.records, old)
+			t.records[key] = record
+		}
+	}
+	t.sessions[user.Session] = key
+	record := t.records[key]
+	if record == nil {
+		record = &UserRecord{}
+		t.records[key] = record
+	}
+	record.Name = user.Name
+	record.UserID = user.UserID
+	record.Session = user.Session
+	if user.Channel != nil {
+		record.Channel = ChannelPathString(user.Channel)
+	}
+	return record
+}
+
+// addActivity appends an activity to the record's history.
+//
+// t.mu must be held when calling this function.
+func (t *Tracker) addActivity(record *UserRecord, activityType UserActivityType, now time.Time) {
+	max := t.MaxHistory
+	if max <= 0 {
+		max = 100
+	}
+	record.History = append(record.History, UserActivity{
+		Time:    now,
+		Type:    activityType,
+		Channel: record.Channel,
+		Name:    record.Name,
+	})
+	if len(record.History) > max {
+		record.History = append(record.History[:0], record.History[len(record.History)-max:]...)
+	}
+}
+
+func (t *Tracker) join(user *gumble.User, now time.Time) {
+	record := t.record(user)
+	if record.FirstSeen.IsZero() {
+		record.FirstSeen = now
+	}
+	record.Online = true
+	record.LastSeen = now
+	record.LastJoined = now
+	t.addActivity(record, UserJoined, now)
+}
+
+func (t *Tracker) onConnect(e *gumble.ConnectEvent) {
+	now := time.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, user := range e.Client.Users {
+		t.join(user, now)
+	}
+}
+
+func (t *Tracker) onDisconnect(e *gumble.DisconnectEvent) {
+	now := time.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, record := range t.records {
+		if record.Online {
+			record.Online = false
+			record.LastSeen = now
+		}
+	}
+	t.sessions = make(map[uint32]string)
+}
+
+func (t *Tracker) onUserChange(e *gumble.UserChangeEvent) {
+	if e.User == nil {
+		return
+	}
+	now := time.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if e.Type.Has(gumble.UserChangeConnected) {
+		t.join(e.User, now)
+		return
+	}
+	if e.Type.HasAny(gumble.UserChangeDisconnected | gumble.UserChangeKicked | gumble.UserChangeBanned) {
+		record := t.record(e.User)
+		record.Online = false
+		record.LastSeen = now
+		t.addActivity(record, UserLeft, now)
+		delete(t.sessions, e.User.Session)
+		return
+	}
+	record := t.record(e.User)
+	record.LastSeen = now
+	if e.Type.Has(gumble.UserChangeName) {
+		t.addActivity(record, UserRenamed, now)
+	}
+	if e.Type.Has(gumble.UserChangeChannel) {
+		t.addActivity(record, UserMoved, now)
+	}
+}
+
+// OnAudioStream implements gumble.AudioListener. It records how long users
+// speak for.
+func (t *Tracker) OnAudioStream(e *gumble.AudioStreamEvent) {
+	go func() {
+		for packet := range e.C {
+			duration := time.Duration(len(packet.AudioBuffer)) * time.Second / gumble.AudioSampleRate
+			packet.Release()
+			now := time.Now()
+			t.mu.Lock()
+			record := t.record(e.User)
+			record.SpeakingTime += duration
+			record.LastSpoke = now
+			record.LastSeen = now
+			t.mu.Unlock()
+		}
+	}()
+}
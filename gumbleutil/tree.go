@@ -0,0 +1,211 @@
+package gumbleutil
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"layeh.com/gumble/gumble"
+)
+
+// ChannelNode is a snapshot of a channel and its sub-channels, as returned by
+// SnapshotTree. It can be encoded as JSON.
+type ChannelNode struct {
+	ID          uint32         `json:"id"`
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Position    int32          `json:"position"`
+	MaxUsers    uint32         `json:"max_users,omitempty"`
+	Temporary   bool           `json:"temporary,omitempty"`
+	Links       []uint32       `json:"links,omitempty"`
+	Users       []UserNode     `json:"users,omitempty"`
+	Children    []*ChannelNode `json:"children,omitempty"`
+}
+
+// UserNode is a snapshot of a user, as returned by SnapshotTree.
+type UserNode struct {
+	Session         uint32 `json:"session"`
+	UserID          uint32 `json:"user_id,omitempty"`
+	Name            string `json:"name"`
+	Registered      bool   `json:"registered,omitempty"`
+	Muted           bool   `json:"muted,omitempty"`
+	Deafened        bool   `json:"deafened,omitempty"`
+	Suppressed      bool   `json:"suppressed,omitempty"`
+	SelfMuted       bool   `json:"self_muted,omitempty"`
+	SelfDeafened    bool   `json:"self_deafened,omitempty"`
+	PrioritySpeaker bool   `json:"priority_speaker,omitempty"`
+	Recording       bool   `json:"recording,omitempty"`
+	Comment         string `json:"comment,omitempty"`
+}
+
+// SnapshotTree returns a snapshot of the client's channel tree, starting at the
+// root channel, or nil if the client does not know of the root channel.
+// Channels are sorted by position and then by name, and users by name.
+//
+// SnapshotTree must not be called from inside of a function passed to
+// Client.Do.
+func SnapshotTree(client *gumble.Client) *ChannelNode {
+	var root *ChannelNode
+	client.Do(func() {
+		if channel := client.Channels[0]; channel != nil {
+			root = snapshotChannel(channel)
+		}
+	})
+	return root
+}
+
+func snapshotChannel(channel *gumble.Channel) *ChannelNode {
+	node := &ChannelNode{
+		ID:          channel.ID,
+		Name:        channel.Name,
+		Description: channel.Description,
+		Position:    channel.Position,
+		MaxUsers:    channel.MaxUsers,
+		Temporary:   channel.Temporary,
+	}
+	for id := range channel.Links {
+		node.Links = append(node.Links, id)
+	}
+	sort.Slice(node.Links, func(i, j int) bool {
+		return node.Links[i] < node.Links[j]
+	})
+	for _, user := range channel.Users {
+		node.Users = append(node.Users, UserNode{
+			Session:         user.Session,
+			UserID:          user.UserID,
+			Name:            user.Name,
+			Registered:      user.IsRegistered(),
+			Muted:           user.Muted,
+			Deafened:        user.Deafened,
+			Suppressed:      user.Suppressed,
+			SelfMuted:       user.SelfMuted,
+			SelfDeafened:    user.SelfDeafened,
+			PrioritySpeaker: user.PrioritySpeaker,
+			Recording:       user.Recording,
+			Comment:         user.Comment,
+		})
+	}
+	sort.Slice(node.Users, func(i, j int) bool {
+		return node.Users[i].Name < node.Users[j].Name
+	})
+	for _, child := range channel.Children {
+		node.Children = append(node.Children, snapshotChannel(child))
+	}
+	sort.Slice(node.Children, func(i, j int) bool {
+		a, b := node.Children[i], node.Children[j]
+		if a.Position != b.Position {
+			return a.Position < b.Position
+		}
+		return a.Name < b.Name
+	})
+	return node
+}
+
+// Walk calls f for the node and each of its descendants, depth first. depth is
+// zero for n.
+func (n *ChannelNode) Walk(f func(node *ChannelNode, depth int)) {
+	n.walk(f, 0)
+}
+
+func (n *ChannelNode) walk(f func(node *ChannelNode, depth int), depth int) {
+	f(n, depth)
+	for _, child := range n.Children {
+		child.walk(f, depth+1)
+	}
+}
+
+// WriteTree writes a human readable representation of the tree to w, e.g.:
+//  Root
+//  ├── Lobby (2 users)
+//  │   ├── @alice [muted]
+//  │   └── @bob
+//  └── Games
+func (n *ChannelNode) WriteTree(w io.Writer) error {
+	if _, err := fmt.Fprintln(w, n.label()); err != nil {
+		return err
+	}
+	return n.writeChildren(w, "")
+}
+
+// String returns the tree as written by WriteTree.
+func (n *ChannelNode) String() string {
+	var b strings.Builder
+	n.WriteTree(&b)
+	return b.String()
+}
+
+func (n *ChannelNode) label() string {
+	label := n.Name
+	switch len(n.Users) {
+	case 0:
+	case 1:
+		label += " (1 user)"
+	default:
+		label += fmt.Sprintf(" (%d users)", len(n.Users))
+	}
+	if n.Temporary {
+		label += " [temporary]"
+	}
+	if len(n.Links) > 0 {
+		label += fmt.Sprintf(" [linked to %d]", len(n.Links))
+	}
+	return label
+}
+
+func (n *ChannelNode) writeChildren(w io.Writer, prefix string) error {
+	count := len(n.Users) + len(n.Children)
+	i := 0
+	line := func(label string) (string, error) {
+		i++
+		branch, indent := "├── ", "│   "
+		if i == count {
+			branch, indent = "└── ", "    "
+		}
+		_, err := fmt.Fprintln(w, prefix+branch+label)
+		return prefix + indent, err
+	}
+	for _, user := range n.Users {
+		if _, err := line("@" + user.label()); err != nil {
+			return err
+		}
+	}
+	for _, child := range n.Children {
+		indent, err := line(child.label())
+		if err != nil {
+			return err
+		}
+		if err := child.writeChildren(w, indent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (u *UserNode) label() string {
+	var flags []string
+	switch {
+	case u.Deafened:
+		flags = append(flags, "deafened")
+	case u.SelfDeafened:
+		flags = append(flags, "self-deafened")
+	}
+	switch {
+	case u.Muted:
+		flags = append(flags, "muted")
+	case u.Suppressed:
+		flags = append(flags, "suppressed")
+	case u.SelfMuted:
+		flags = append(flags, "self-muted")
+	}
+	if u.PrioritySpeaker {
+		flags = append(flags, "priority speaker")
+	}
+	if u.Recording {
+		flags = append(flags, "recording")
+	}
+	if len(flags) == 0 {
+		return u.Name
+	}
+	return u.Name + " [" + strings.Join(flags, ", ") + "]"
+}
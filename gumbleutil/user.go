@@ -0,0 +1,34 @@
+package gumbleutil
+
+import (
+	"sort"
+
+	"layeh.com/gumble/gumble"
+)
+
+// MatchUsers returns the users whose names best match query, as typed by a
+// user in a chat command (e.g. "!kick jo"), sorted by session. Names are
+// matched as by MatchChannels.
+//
+// Callers usually act on the result if it contains one user, and ask the user
+// to be more specific if it contains several.
+func MatchUsers(users gumble.Users, query string) []*gumble.User {
+	if query == "" {
+		return nil
+	}
+	var best []*gumble.User
+	bestRank := matchNone
+	for _, user := range users {
+		rank := matchName(user.Name, query)
+		if rank < bestRank {
+			best, bestRank = best[:0], rank
+		}
+		if rank == bestRank && rank != matchNone {
+			best = append(best, user)
+		}
+	}
+	sort.Slice(best, func(i, j int) bool {
+		return best[i].Session < best[j].Session
+	})
+	return best
+}
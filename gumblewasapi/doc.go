@@ -0,0 +1,8 @@
+// Package gumblewasapi is a Windows Audio Session API (WASAPI) audio system
+// for gumble.
+//
+// It uses the native Windows audio stack, so clients do not have to ship
+// OpenAL DLLs. Devices can be opened in shared or exclusive mode. In shared
+// mode, the streams follow the system's default device: when the default
+// playback or capture device changes, the stream is moved to the new device.
+package gumblewasapi
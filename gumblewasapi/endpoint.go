@@ -0,0 +1,249 @@
+//go:build windows
+// +build windows
+
+package gumblewasapi
+
+import (
+	"runtime"
+	"time"
+	"unsafe"
+
+	"github.com/go-ole/go-ole"
+	"github.com/moutend/go-wca/pkg/wca"
+	"layeh.com/gumble/gumble"
+)
+
+const (
+	audclntStreamFlagsAutoConvertPCM    = 0x80000000
+	audclntStreamFlagsSrcDefaultQuality = 0x08000000
+	audclntBufferFlagsSilent            = 0x2
+	waveFormatPCM                       = 1
+)
+
+const (
+	bufferDuration = 100 * time.Millisecond
+	pollInterval   = 5 * time.Millisecond
+	// REFERENCE_TIME values are expressed in 100ns units.
+	referenceTimeUnit = 100 * time.Nanosecond
+)
+
+// endpoint is a running render or capture loop on a WASAPI endpoint. COM
+// requires all calls for an endpoint to be made from the same OS thread, so
+// the device is opened and driven from a dedicated goroutine.
+type endpoint struct {
+	end  chan struct{}
+	done chan struct{}
+}
+
+// startEndpoint opens the default render (or capture) endpoint and starts
+// calling process with the samples to fill (or the samples captured).
+func startEndpoint(capture bool, mode ShareMode, process func([]int16)) (*endpoint, error) {
+	e := &endpoint{
+		end:  make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	started := make(chan error)
+	go e.run(capture, mode, process, started)
+	if err := <-started; err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (e *endpoint) stop() {
+	close(e.end)
+	<-e.done
+}
+
+func (e *endpoint) run(capture bool, mode ShareMode, process func([]int16), started chan<- error) {
+	defer close(e.done)
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	if err := ole.CoInitializeEx(0, ole.COINIT_APARTMENTTHREADED); err != nil {
+		started <- err
+		return
+	}
+	defer ole.CoUninitialize()
+
+	var mmde *wca.IMMDeviceEnumerator
+	if err := wca.CoCreateInstance(wca.CLSID_MMDeviceEnumerator, 0, wca.CLSCTX_ALL, wca.IID_IMMDeviceEnumerator, &mmde); err != nil {
+		started <- err
+		return
+	}
+	defer mmde.Release()
+
+	flow := uint32(wca.ERender)
+	if capture {
+		flow = wca.ECapture
+	}
+	var mmd *wca.IMMDevice
+	if err := mmde.GetDefaultAudioEndpoint(flow, wca.EConsole, &mmd); err != nil {
+		started <- err
+		return
+	}
+	defer mmd.Release()
+
+	var ac *wca.IAudioClient
+	if err := mmd.Activate(wca.IID_IAudioClient, wca.CLSCTX_ALL, nil, &ac); err != nil {
+		started <- err
+		return
+	}
+	defer ac.Release()
+
+	channels := uint16(gumble.AudioChannels)
+	if mode == Exclusive {
+		// Many devices only support stereo in exclusive mode.
+		channels = 2
+	}
+	format := &wca.WAVEFORMATEX{
+		WFormatTag:      waveFormatPCM,
+		NChannels:       channels,
+		NSamplesPerSec:  gumble.AudioSampleRate,
+		WBitsPerSample:  16,
+		NBlockAlign:     2 * channels,
+		NAvgBytesPerSec: gumble.AudioSampleRate * 2 * uint32(channels),
+	}
+	duration := wca.REFERENCE_TIME(bufferDuration / referenceTimeUnit)
+	var err error
+	switch mode {
+	case Exclusive:
+		err = ac.Initialize(wca.AUDCLNT_SHAREMODE_EXCLUSIVE, 0, duration, duration, format, nil)
+	default:
+		err = ac.Initialize(wca.AUDCLNT_SHAREMODE_SHARED, audclntStreamFlagsAutoConvertPCM|audclntStreamFlagsSrcDefaultQuality, duration, 0, format, nil)
+	}
+	if err != nil {
+		started <- err
+		return
+	}
+
+	var bufferFrames uint32
+	if err := ac.GetBufferSize(&bufferFrames); err != nil {
+		started <- err
+		return
+	}
+
+	if capture {
+		var acc *wca.IAudioCaptureClient
+		if err := ac.GetService(wca.IID_IAudioCaptureClient, &acc); err != nil {
+			started <- err
+			return
+		}
+		defer acc.Release()
+		if err := ac.Start(); err != nil {
+			started <- err
+			return
+		}
+		defer ac.Stop()
+		started <- nil
+		e.captureLoop(acc, int(channels), process)
+		return
+	}
+
+	var arc *wca.IAudioRenderClient
+	if err := ac.GetService(wca.IID_IAudioRenderClient, &arc); err != nil {
+		started <- err
+		return
+	}
+	defer arc.Release()
+	if err := ac.Start(); err != nil {
+		started <- err
+		return
+	}
+	defer ac.Stop()
+	started <- nil
+	e.renderLoop(ac, arc, bufferFrames, int(channels), process)
+}
+
+func (e *endpoint) renderLoop(ac *wca.IAudioClient, arc *wca.IAudioRenderClient, bufferFrames uint32, channels int, process func([]int16)) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	var pcm []int16
+	for {
+		select {
+		case <-e.end:
+			return
+		case <-ticker.C:
+		}
+		var padding uint32
+		if err := ac.GetCurrentPadding(&padding); err != nil {
+			return
+		}
+		frames := bufferFrames - padding
+		if frames == 0 {
+			continue
+		}
+		if cap(pcm) < int(frames) {
+			pcm = make([]int16, frames)
+		}
+		pcm = pcm[:frames]
+		process(pcm)
+
+		var data *byte
+		if err := arc.GetBuffer(frames, &data); err != nil {
+			return
+		}
+		out := (*[1 << 28]int16)(unsafe.Pointer(data))[: int(frames)*channels : int(frames)*channels]
+		for i, sample := range pcm {
+			for c := 0; c < channels; c++ {
+				out[i*channels+c] = sample
+			}
+		}
+		if err := arc.ReleaseBuffer(frames, 0); err != nil {
+			return
+		}
+	}
+}
+
+func (e *endpoint) captureLoop(acc *wca.IAudioCaptureClient, channels int, process func([]int16)) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	var pcm []int16
+	for {
+		select {
+		case <-e.end:
+			return
+		case <-ticker.C:
+		}
+		for {
+			var packetFrames uint32
+			if err := acc.GetNextPacketSize(&packetFrames); err != nil {
+				return
+			}
+			if packetFrames == 0 {
+				break
+			}
+			var data *byte
+			var frames, flags uint32
+			var devicePosition, qpcPosition uint64
+			if err := acc.GetBuffer(&data, &frames, &flags, &devicePosition, &qpcPosition); err != nil {
+				return
+			}
+			if cap(pcm) < int(frames) {
+				pcm = make([]int16, frames)
+			}
+			pcm = pcm[:frames]
+			if flags&audclntBufferFlagsSilent != 0 || data == nil {
+				for i := range pcm {
+					pcm[i] = 0
+				}
+			} else {
+				in := (*[1 << 28]int16)(unsafe.Pointer(data))[: int(frames)*channels : int(frames)*channels]
+				for i := range pcm {
+					var sum int32
+					for c := 0; c < channels; c++ {
+						sum += int32(in[i*channels+c])
+					}
+					pcm[i] = int16(sum / int32(channels))
+				}
+			}
+			if err := acc.ReleaseBuffer(frames); err != nil {
+				return
+			}
+			process(pcm)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+//go:build windows
+// +build windows
+
+package gumblewasapi
+
+import (
+	"runtime"
+
+	"github.com/go-ole/go-ole"
+	"github.com/moutend/go-wca/pkg/wca"
+)
+
+// notifier calls a function when the system's default console render or
+// capture device changes.
+type notifier struct {
+	end  chan struct{}
+	done chan struct{}
+}
+
+func newNotifier(changed func(capture bool)) (*notifier, error) {
+	n := &notifier{
+		end:  make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	// Device changes are reported on a system thread; the stream is restarted
+	// from a separate goroutine so that the callback returns promptly.
+	events := make(chan bool, 4)
+	started := make(chan error)
+	go n.run(events, started)
+	if err := <-started; err != nil {
+		return nil, err
+	}
+	go func() {
+		for {
+			select {
+			case <-n.end:
+				return
+			case capture := <-events:
+				changed(capture)
+			}
+		}
+	}()
+	return n, nil
+}
+
+func (n *notifier) run(events chan<- bool, started chan<- error) {
+	defer close(n.done)
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	if err := ole.CoInitializeEx(0, ole.COINIT_APARTMENTTHREADED); err != nil {
+		started <- err
+		return
+	}
+	defer ole.CoUninitialize()
+
+	var mmde *wca.IMMDeviceEnumerator
+	if err := wca.CoCreateInstance(wca.CLSID_MMDeviceEnumerator, 0, wca.CLSCTX_ALL, wca.IID_IMMDeviceEnumerator, &mmde); err != nil {
+		started <- err
+		return
+	}
+	defer mmde.Release()
+
+	callback := wca.IMMNotificationClientCallback{
+		OnDefaultDeviceChanged: func(flow wca.EDataFlow, role wca.ERole, deviceID string) error {
+			if role != wca.EConsole {
+				return nil
+			}
+			select {
+			case events <- flow == wca.ECapture:
+			default:
+			}
+			return nil
+		},
+	}
+	mmnc := wca.NewIMMNotificationClient(callback)
+	if err := mmde.RegisterEndpointNotificationCallback(mmnc); err != nil {
+		started <- err
+		return
+	}
+	defer mmde.UnregisterEndpointNotificationCallback(mmnc)
+
+	started <- nil
+	<-n.end
+}
+
+func (n *notifier) close() {
+	close(n.end)
+	<-n.done
+}
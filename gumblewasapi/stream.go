@@ -0,0 +1,182 @@
+//go:build windows
+// +build windows
+
+package gumblewasapi
+
+import (
+	"errors"
+	"sync"
+
+	"layeh.com/gumble/gumble"
+	"layeh.com/gumble/internal/mix"
+)
+
+var (
+	ErrState = errors.New("gumblewasapi: invalid state")
+)
+
+// ShareMode selects how the audio devices are opened.
+type ShareMode int
+
+// Valid share modes.
+const (
+	// Shared mode mixes gumble's audio with other applications. The device's
+	// format is converted by the system.
+	Shared ShareMode = iota
+	// Exclusive mode gives gumble sole access to the device, which lowers
+	// latency. The device must support 48kHz 16-bit PCM.
+	Exclusive
+)
+
+// Stream plays incoming audio and captures outgoing audio using the default
+// WASAPI endpoints.
+type Stream struct {
+	client *gumble.Client
+	link   gumble.Detacher
+	mode   ShareMode
+
+	mixer mix.Mixer
+
+	sinkLock sync.Mutex
+	sink     *endpoint
+	notifier *notifier
+
+	sourceLock     sync.Mutex
+	source         *endpoint
+	sourceOutgoing chan<- gumble.AudioBuffer
+}
+
+// New opens the default render endpoint in the given mode and attaches the
+// stream to the client as an audio listener. Playback starts immediately;
+// capture is started with StartSource.
+func New(client *gumble.Client, mode ShareMode) (*Stream, error) {
+	s := &Stream{
+		client: client,
+		mode:   mode,
+	}
+
+	sink, err := startEndpoint(false, mode, s.render)
+	if err != nil {
+		return nil, err
+	}
+	s.sink = sink
+
+	if mode == Shared {
+		n, err := newNotifier(s.onDefaultDeviceChanged)
+		if err != nil {
+			sink.stop()
+			return nil, err
+		}
+		s.notifier = n
+	}
+
+	s.link = client.Config.AttachAudio(s)
+
+	return s, nil
+}
+
+// Destroy stops capture and playback and releases the devices.
+func (s *Stream) Destroy() {
+	s.link.Detach()
+	if s.notifier != nil {
+		s.notifier.close()
+	}
+	s.StopSource()
+	s.sinkLock.Lock()
+	if s.sink != nil {
+		s.sink.stop()
+		s.sink = nil
+	}
+	s.sinkLock.Unlock()
+}
+
+// StartSource begins capturing audio from the default capture endpoint and
+// sending it to the server.
+func (s *Stream) StartSource() error {
+	s.sourceLock.Lock()
+	defer s.sourceLock.Unlock()
+	if s.source != nil {
+		return ErrState
+	}
+	s.sourceOutgoing = s.client.AudioOutgoing()
+	source, err := startEndpoint(true, s.mode, s.newCapture())
+	if err != nil {
+		close(s.sourceOutgoing)
+		s.sourceOutgoing = nil
+		return err
+	}
+	s.source = source
+	return nil
+}
+
+// StopSource stops capturing audio.
+func (s *Stream) StopSource() error {
+	s.sourceLock.Lock()
+	defer s.sourceLock.Unlock()
+	if s.source == nil {
+		return ErrState
+	}
+	s.source.stop()
+	s.source = nil
+	close(s.sourceOutgoing)
+	s.sourceOutgoing = nil
+	return nil
+}
+
+// OnAudioStream implements gumble.AudioListener.
+func (s *Stream) OnAudioStream(e *gumble.AudioStreamEvent) {
+	go func() {
+		for packet := range e.C {
+			s.mixer.Write(e.User, packet.AudioBuffer)
+			packet.Release()
+		}
+	}()
+}
+
+func (s *Stream) onDefaultDeviceChanged(capture bool) {
+	if capture {
+		s.sourceLock.Lock()
+		defer s.sourceLock.Unlock()
+		if s.source == nil {
+			return
+		}
+		s.source.stop()
+		source, err := startEndpoint(true, s.mode, s.newCapture())
+		if err != nil {
+			s.source = nil
+			close(s.sourceOutgoing)
+			s.sourceOutgoing = nil
+			return
+		}
+		s.source = source
+		return
+	}
+	s.sinkLock.Lock()
+	defer s.sinkLock.Unlock()
+	if s.sink == nil {
+		return
+	}
+	s.sink.stop()
+	s.sink, _ = startEndpoint(false, s.mode, s.render)
+}
+
+func (s *Stream) render(out []int16) {
+	s.mixer.Read(out)
+}
+
+// newCapture returns a function that groups captured samples into frames and
+// sends them to the server.
+func (s *Stream) newCapture() func([]int16) {
+	frameSize := s.client.AudioFrameSize()
+	outgoing := s.sourceOutgoing
+	buffer := make([]int16, 0, frameSize)
+	return func(in []int16) {
+		for _, sample := range in {
+			buffer = append(buffer, sample)
+			if len(buffer) == frameSize {
+				outgoing <- gumble.AudioBuffer(buffer)
+				buffer = make([]int16, 0, frameSize)
+			}
+		}
+	}
+}
@@ -0,0 +1,112 @@
+// Package mix provides a simple PCM mixer shared by the audio backends.
+package mix
+
+import (
+	"sync"
+)
+
+// DefaultMaxBuffered is the default number of samples that are buffered per
+// source before old samples start being discarded.
+const DefaultMaxBuffered = 48000 / 2
+
+// Mixer combines several independent streams of PCM samples into one.
+//
+// Sources are written to with Write and the mixed result is retrieved with
+// Read. A Mixer is safe for concurrent use.
+type Mixer struct {
+	// MaxBuffered is the maximum number of samples that are queued per
+	// source. If zero, DefaultMaxBuffered is used.
+	MaxBuffered int
+
+	mu      sync.Mutex
+	sources map[interface{}][]int16
+	acc     []int32
+}
+
+// Write queues samples for the given source key.
+func (m *Mixer) Write(key interface{}, samples []int16) {
+	if len(samples) == 0 {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.sources == nil {
+		m.sources = make(map[interface{}][]int16)
+	}
+	max := m.MaxBuffered
+	if max <= 0 {
+		max = DefaultMaxBuffered
+	}
+	buf := append(m.sources[key], samples...)
+	if over := len(buf) - max; over > 0 {
+		buf = append(buf[:0], buf[over:]...)
+	}
+	m.sources[key] = buf
+}
+
+// Remove discards any queued samples for the given source key.
+func (m *Mixer) Remove(key interface{}) {
+	m.mu.Lock()
+	delete(m.sources, key)
+	m.mu.Unlock()
+}
+
+// Active returns the number of sources that currently have queued samples.
+func (m *Mixer) Active() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.sources)
+}
+
+// Read fills out with the mix of all queued sources. Samples that are not
+// available are filled with silence. The number of sources that contributed
+// to the output is returned.
+func (m *Mixer) Read(out []int16) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if cap(m.acc) < len(out) {
+		m.acc = make([]int32, len(out))
+	}
+	acc := m.acc[:len(out)]
+	for i := range acc {
+		acc[i] = 0
+	}
+
+	n := 0
+	for key, buf := range m.sources {
+		if len(buf) == 0 {
+			continue
+		}
+		n++
+		count := len(buf)
+		if count > len(acc) {
+			count = len(acc)
+		}
+		for i := 0; i < count; i++ {
+			acc[i] += int32(buf[i])
+		}
+		if count == len(buf) {
+			delete(m.sources, key)
+		} else {
+			m.sources[key] = append(buf[:0], buf[count:]...)
+		}
+	}
+
+	for i, v := range acc {
+		out[i] = Clamp(v)
+	}
+	return n
+}
+
+// Clamp clips a 32-bit sample to the 16-bit range.
+func Clamp(v int32) int16 {
+	switch {
+	case v > 32767:
+		return 32767
+	case v < -32768:
+		return -32768
+	}
+	return int16(v)
+}
@@ -0,0 +1,101 @@
+package opus
+
+// Decoder uses the Opus library directly, rather than through gopus, so that
+// it can decode into a buffer provided by the caller. The library itself is
+// linked by gopus, which is used by Encoder.
+
+// typedef struct OpusDecoder OpusDecoder;
+//
+// int opus_decoder_get_size(int channels);
+// int opus_decoder_init(OpusDecoder *st, int Fs, int channels);
+// int opus_decode(OpusDecoder *st, const unsigned char *data, int len, short *pcm, int frame_size, int decode_fec);
+// int opus_decoder_ctl(OpusDecoder *st, int request, ...);
+//
+// static int gumble_opus_decoder_reset(OpusDecoder *st) {
+//   return opus_decoder_ctl(st, 4028); // OPUS_RESET_STATE
+// }
+import "C"
+
+import (
+	"unsafe"
+
+	"layeh.com/gopus"
+	"layeh.com/gumble/gumble"
+)
+
+type Decoder struct {
+	state    []byte
+	channels int
+}
+
+func newDecoder(sampleRate, channels int) (*Decoder, error) {
+	d := &Decoder{
+		state:    make([]byte, int(C.opus_decoder_get_size(C.int(channels)))),
+		channels: channels,
+	}
+	if ret := C.opus_decoder_init(d.cState(), C.int(sampleRate), C.int(channels)); ret < 0 {
+		return nil, opusError(ret)
+	}
+	return d, nil
+}
+
+func (d *Decoder) cState() *C.OpusDecoder {
+	return (*C.OpusDecoder)(unsafe.Pointer(&d.state[0]))
+}
+
+func (*Decoder) ID() int {
+	return 4
+}
+
+func (d *Decoder) Decode(data []byte, frameSize int) ([]int16, error) {
+	// Allocate a buffer for the packet's duration, which is usually much
+	// shorter than the maximum frame size.
+	if n := packetSamples(data); n > 0 && n < frameSize {
+		frameSize = n
+	}
+	return d.DecodeBuffer(make([]int16, frameSize*d.channels), data)
+}
+
+// DecodeBuffer implements gumble.AudioBufferDecoder.
+func (d *Decoder) DecodeBuffer(pcm []int16, data []byte) ([]int16, error) {
+	frameSize := len(pcm) / d.channels
+	if frameSize == 0 {
+		return nil, gopus.ErrSmallBuffer
+	}
+	var dataPtr *C.uchar
+	if len(data) > 0 {
+		dataPtr = (*C.uchar)(unsafe.Pointer(&data[0]))
+	}
+	ret := C.opus_decode(d.cState(), dataPtr, C.int(len(data)), (*C.short)(unsafe.Pointer(&pcm[0])), C.int(frameSize), 0)
+	if ret < 0 {
+		return nil, opusError(ret)
+	}
+	return pcm[:int(ret)*d.channels], nil
+}
+
+func (d *Decoder) Reset() {
+	C.gumble_opus_decoder_reset(d.cState())
+}
+
+var _ gumble.AudioBufferDecoder = (*Decoder)(nil)
+
+// opusError returns the gopus error for an Opus error code.
+func opusError(code C.int) error {
+	switch code {
+	case -1:
+		return gopus.ErrBadArgument
+	case -2:
+		return gopus.ErrSmallBuffer
+	case -3:
+		return gopus.ErrInternal
+	case -4:
+		return gopus.ErrInvalidPacket
+	case -5:
+		return gopus.ErrUnimplemented
+	case -6:
+		return gopus.ErrInvalidState
+	case -7:
+		return gopus.ErrAllocFail
+	}
+	return gopus.ErrUnknown
+}
@@ -32,10 +32,8 @@ func (*generator) NewEncoder() gumble.AudioEncoder {
 }
 
 func (*generator) NewDecoder() gumble.AudioDecoder {
-	d, _ := gopus.NewDecoder(gumble.AudioSampleRate, gumble.AudioChannels)
-	return &Decoder{
-		d,
-	}
+	d, _ := newDecoder(gumble.AudioSampleRate, gumble.AudioChannels)
+	return d
 }
 
 // encoder
@@ -56,20 +54,34 @@ func (e *Encoder) Reset() {
 	e.Encoder.ResetState()
 }
 
-// decoder
-
-type Decoder struct {
-	*gopus.Decoder
-}
-
-func (*Decoder) ID() int {
-	return 4
+// frameSamples is the number of samples (at 48 kHz) in each frame of an Opus
+// packet, indexed by the configuration number in the packet's TOC byte.
+var frameSamples = [32]int{
+	// SILK: 10, 20, 40, 60 ms
+	480, 960, 1920, 2880, 480, 960, 1920, 2880, 480, 960, 1920, 2880,
+	// Hybrid: 10, 20 ms
+	480, 960, 480, 960,
+	// CELT: 2.5, 5, 10, 20 ms
+	120, 240, 480, 960, 120, 240, 480, 960, 120, 240, 480, 960, 120, 240, 480, 960,
 }
 
-func (d *Decoder) Decode(data []byte, frameSize int) ([]int16, error) {
-	return d.Decoder.Decode(data, frameSize, false)
-}
-
-func (d *Decoder) Reset() {
-	d.Decoder.ResetState()
+// packetSamples returns the number of samples per channel in the Opus packet,
+// or 0 if it cannot be determined (see RFC 6716, section 3.1).
+func packetSamples(data []byte) int {
+	if len(data) < 1 {
+		return 0
+	}
+	var frames int
+	switch data[0] & 0x3 {
+	case 0:
+		frames = 1
+	case 1, 2:
+		frames = 2
+	case 3:
+		if len(data) < 2 {
+			return 0
+		}
+		frames = int(data[1] & 0x3F)
+	}
+	return frames * frameSamples[data[0]>>3]
 }
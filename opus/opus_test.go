@@ -0,0 +1,71 @@
+package opus
+
+import (
+	"math"
+	"testing"
+
+	"layeh.com/gumble/gumble"
+)
+
+func encodedFrame(t testing.TB) []byte {
+	frame := make([]int16, gumble.AudioDefaultFrameSize)
+	for i := range frame {
+		frame[i] = int16(8000 * math.Sin(2*math.Pi*440*float64(i)/gumble.AudioSampleRate))
+	}
+	data, err := Codec.NewEncoder().Encode(frame, len(frame), gumble.AudioMaximumFrameSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestDecodeBuffer(t *testing.T) {
+	data := encodedFrame(t)
+	decoder := Codec.NewDecoder().(gumble.AudioBufferDecoder)
+	buffer := make([]int16, gumble.AudioMaximumFrameSize)
+
+	pcm, err := decoder.DecodeBuffer(buffer, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pcm) != gumble.AudioDefaultFrameSize {
+		t.Fatalf("decoded %d samples; want %d", len(pcm), gumble.AudioDefaultFrameSize)
+	}
+	if &pcm[0] != &buffer[0] {
+		t.Fatal("samples were not decoded into the buffer")
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		if _, err := decoder.DecodeBuffer(buffer, data); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("DecodeBuffer allocated %v times; want 0", allocs)
+	}
+}
+
+func BenchmarkDecode(b *testing.B) {
+	data := encodedFrame(b)
+	decoder := Codec.NewDecoder()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := decoder.Decode(data, gumble.AudioMaximumFrameSize); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeBuffer(b *testing.B) {
+	data := encodedFrame(b)
+	decoder := Codec.NewDecoder().(gumble.AudioBufferDecoder)
+	buffer := make([]int16, gumble.AudioMaximumFrameSize)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := decoder.DecodeBuffer(buffer, data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}